@@ -0,0 +1,258 @@
+// Package backup copy a prefix of a store to another bucket or account on a schedule and restore it from any run.
+// Every run write a manifest listing the files of the prefix at that time and where their content is kept,
+// an incremental run only copy the files changed since the previous run and point to the earlier copies for the others.
+//
+//	Example:
+//	job := backup.NewJob(store, "tenants/", offsite)
+//	go job.Run(ctx, backup.MustParseSchedule("30 2 * * *"), func(err error) { log.Println("backup:", err) })
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// DefaultPrefix is where backups are kept in the destination store by default
+const DefaultPrefix = "backups/"
+
+// runIDFormat is the UTC start time identifying a run, sorted like the runs
+const runIDFormat = "20060102T150405Z"
+
+// ErrNoBackup returned when no run started before the requested time
+var ErrNoBackup = errors.New("backup: no backup")
+
+// Mode of a backup run
+type Mode string
+
+const (
+	// Full copy every file
+	Full Mode = "full"
+	// Incremental copy the files changed since the previous run, the first run is full
+	Incremental Mode = "incremental"
+)
+
+// Entry is a file of a backup run
+type Entry struct {
+	// Key of the file in the source store
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"content_type,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	// Object is the key of the copy in the destination store, written by this run or an earlier one
+	Object string `json:"object"`
+}
+
+// Manifest describe a backup run, it is written once every file is copied
+type Manifest struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Mode   Mode   `json:"mode"`
+	// Base is the run an incremental run compared the files to
+	Base       string    `json:"base,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Copied and Bytes count the files copied by the run
+	Copied  int     `json:"copied"`
+	Bytes   int64   `json:"bytes"`
+	Entries []Entry `json:"entries"`
+}
+
+// Job back up the files under SourcePrefix of Source into Dest. Runs are kept under <Prefix><Name>/:
+// manifests/<id>.json and data/<id>/<key>. The manifest of a run list every file, it is held in memory.
+type Job struct {
+	Source       file.IFile
+	SourcePrefix string
+	// Dest is another container or account, e.g. in another region
+	Dest file.IFile
+	// Prefix of the backups in Dest, DefaultPrefix when empty
+	Prefix string
+	// Name tell the backups of several prefixes apart, the source prefix when empty
+	Name string
+	// Mode of the runs, Incremental when empty
+	Mode Mode
+	// Workers is the number of files copied at once, file.DefaultWalkWorkers when 0
+	Workers int
+}
+
+// NewJob create an incremental backup of prefix of source into dest
+func NewJob(source file.IFile, prefix string, dest file.IFile) *Job {
+	return &Job{Source: source, SourcePrefix: prefix, Dest: dest}
+}
+
+// base return the prefix of the runs of the job in Dest
+func (j *Job) base() string {
+	prefix := j.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	name := j.Name
+	if name == "" {
+		name = strings.Trim(j.SourcePrefix, file.Delimiter)
+	}
+	if name == "" {
+		name = "all"
+	}
+	return prefix + strings.Replace(name, file.Delimiter, "_", -1) + file.Delimiter
+}
+
+// ManifestKey return the key of the manifest of run id in Dest
+func (j *Job) ManifestKey(id string) string {
+	return j.base() + "manifests/" + id + ".json"
+}
+
+// Manifests return the manifest keys of the finished runs, oldest first
+func (j *Job) Manifests(ctx context.Context) ([]string, error) {
+	files, err := j.Dest.List(ctx, j.base()+"manifests/")
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".json") {
+			keys = append(keys, f.Name)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ManifestAt return the key of the last run started at or before t, for a point in time restore.
+// It return ErrNoBackup when there is none.
+func (j *Job) ManifestAt(ctx context.Context, t time.Time) (string, error) {
+	keys, err := j.Manifests(ctx)
+	if err != nil {
+		return "", err
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		id := strings.TrimSuffix(keys[i][strings.LastIndex(keys[i], file.Delimiter)+1:], ".json")
+		started, err := time.Parse(runIDFormat, id)
+		if err == nil && !started.After(t) {
+			return keys[i], nil
+		}
+	}
+	return "", ErrNoBackup
+}
+
+// LoadManifest read the manifest at key of store
+func LoadManifest(ctx context.Context, store file.IFile, key string) (Manifest, error) {
+	var m Manifest
+	data, err := store.Download(ctx, key)
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+// Backup run a backup now and return its manifest. A failed run write no manifest, its copies are
+// left for the next run to overwrite.
+func (j *Job) Backup(ctx context.Context) (Manifest, error) {
+	started := time.Now().UTC()
+	m := Manifest{ID: started.Format(runIDFormat), Source: j.SourcePrefix, Mode: Full, StartedAt: started}
+
+	previous := map[string]Entry{}
+	if j.Mode != Full {
+		keys, err := j.Manifests(ctx)
+		if err != nil {
+			return m, err
+		}
+		if len(keys) > 0 {
+			last, err := LoadManifest(ctx, j.Dest, keys[len(keys)-1])
+			if err != nil {
+				return m, err
+			}
+			for _, e := range last.Entries {
+				previous[e.Key] = e
+			}
+			m.Mode, m.Base = Incremental, last.ID
+		}
+	}
+
+	var mu sync.Mutex
+	err := file.Walk(ctx, j.Source, j.SourcePrefix, func(ctx context.Context, info file.ObjectInfo) error {
+		e := Entry{Key: info.Name, Size: info.Size, ContentType: info.ContentType, ETag: info.ETag,
+			LastModified: info.LastModified, Metadata: info.Metadata}
+		if prev, ok := previous[info.Name]; ok && unchanged(prev, e) {
+			e.Object = prev.Object
+		} else {
+			e.Object = j.base() + "data/" + m.ID + file.Delimiter + strings.TrimPrefix(info.Name, j.SourcePrefix)
+			err := transfer(ctx, j.Source, info.Name, j.Dest, e.Object, e.ContentType, e.Metadata)
+			if err == file.ErrNotFound {
+				// deleted since listed
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			m.Copied++
+			m.Bytes += info.Size
+			mu.Unlock()
+		}
+		mu.Lock()
+		m.Entries = append(m.Entries, e)
+		mu.Unlock()
+		return nil
+	}, file.WalkOptions{Workers: j.Workers})
+	if err != nil {
+		return m, err
+	}
+
+	sort.Slice(m.Entries, func(a, b int) bool { return m.Entries[a].Key < m.Entries[b].Key })
+	m.FinishedAt = time.Now().UTC()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	_, err = j.Dest.Upload(ctx, j.ManifestKey(m.ID), "application/json", data)
+	return m, err
+}
+
+// Run back up at every time of schedule until ctx is done, onError is called with failed runs
+func (j *Job) Run(ctx context.Context, schedule *Schedule, onError func(err error)) error {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("backup: schedule never match")
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if _, err := j.Backup(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+	}
+}
+
+// unchanged report whether the file of e is the one backed up as prev
+func unchanged(prev, e Entry) bool {
+	if prev.ETag != "" && e.ETag != "" {
+		return prev.ETag == e.ETag
+	}
+	return prev.Size == e.Size && prev.LastModified.Equal(e.LastModified)
+}
+
+// transfer copy src of from to dst of to, the stores may be in different accounts
+func transfer(ctx context.Context, from file.IFile, src string, to file.IFile, dst, contentType string, metadata map[string]string) error {
+	data, err := from.Download(ctx, src)
+	if err != nil {
+		return err
+	}
+	for k, v := range metadata {
+		ctx = file.WithMetadata(ctx, k, v)
+	}
+	_, err = to.Upload(ctx, dst, contentType, data)
+	return err
+}
@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// Conflict is what Restore do with files already present at the restored key
+type Conflict string
+
+const (
+	// ConflictOverwrite replace the present file
+	ConflictOverwrite Conflict = "overwrite"
+	// ConflictSkip keep the present file
+	ConflictSkip Conflict = "skip"
+	// ConflictRename restore beside the present file, "a.jpg" is restored as "a.restored-<run id>.jpg"
+	ConflictRename Conflict = "rename"
+)
+
+// RestoreProgress is reported to RestoreOptions.Progress as files are restored
+type RestoreProgress struct {
+	// Total is the number of files of the run selected by the filters
+	Total    int
+	Restored int
+	Skipped  int
+	Failed   int
+	Bytes    int64
+}
+
+// RestoreOptions configure Restore
+type RestoreOptions struct {
+	// Workers is the number of files restored at once, file.DefaultWalkWorkers when 0
+	Workers int
+	// Prefix restore only the files whose source key start with it
+	Prefix string
+	// ModifiedSince and ModifiedBefore restore only the files last modified in that range, unbounded when zero
+	ModifiedSince  time.Time
+	ModifiedBefore time.Time
+	// Conflict policy for files already present, ConflictOverwrite when empty
+	Conflict Conflict
+	// Progress is called after every file from the workers, it must not block
+	Progress func(RestoreProgress)
+}
+
+// RestoreResult count the files restored by Restore
+type RestoreResult struct {
+	RestoreProgress
+	// Renamed map the keys of the files restored with ConflictRename to the key they were restored at
+	Renamed map[string]string
+	// Failures are the files that could not be restored, running Restore again retry them
+	Failures []file.FileError
+}
+
+// selected report whether e is restored with opts
+func (opts RestoreOptions) selected(e Entry) bool {
+	return strings.HasPrefix(e.Key, opts.Prefix) &&
+		(opts.ModifiedSince.IsZero() || !e.LastModified.Before(opts.ModifiedSince)) &&
+		(opts.ModifiedBefore.IsZero() || e.LastModified.Before(opts.ModifiedBefore))
+}
+
+// Restore write the files of the run of manifestKey back into Source under destPrefix, replacing the source prefix of the run.
+// An empty destPrefix restore the files at their original keys. Files already present are handled by opts.Conflict.
+//
+//	Example:
+//	key, err := job.ManifestAt(ctx, time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC))
+//	res, err := job.Restore(ctx, key, "", backup.RestoreOptions{
+//		Prefix:   "tenants/42/",
+//		Conflict: backup.ConflictSkip,
+//		Progress: func(p backup.RestoreProgress) { log.Printf("%d/%d", p.Restored+p.Skipped+p.Failed, p.Total) },
+//	})
+func (j *Job) Restore(ctx context.Context, manifestKey, destPrefix string, opts RestoreOptions) (RestoreResult, error) {
+	var result RestoreResult
+	m, err := LoadManifest(ctx, j.Dest, manifestKey)
+	if err != nil {
+		return result, err
+	}
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictOverwrite
+	case ConflictOverwrite, ConflictSkip, ConflictRename:
+	default:
+		return result, fmt.Errorf("backup: unknown conflict policy %q", opts.Conflict)
+	}
+	var entries []Entry
+	for _, e := range m.Entries {
+		if opts.selected(e) {
+			entries = append(entries, e)
+		}
+	}
+	result.Total = len(entries)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = file.DefaultWalkWorkers
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		next = make(chan Entry)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range next {
+				target := e.Key
+				if destPrefix != "" {
+					target = destPrefix + strings.TrimPrefix(e.Key, m.Source)
+				}
+				restored, err := j.restoreEntry(ctx, e, target, m.ID, opts.Conflict)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.Failed++
+					result.Failures = append(result.Failures, file.FileError{Path: e.Key, Err: err})
+				case restored == "":
+					result.Skipped++
+				default:
+					result.Restored++
+					result.Bytes += e.Size
+					if restored != target {
+						if result.Renamed == nil {
+							result.Renamed = map[string]string{}
+						}
+						result.Renamed[target] = restored
+					}
+				}
+				progress := result.RestoreProgress
+				mu.Unlock()
+				if opts.Progress != nil {
+					opts.Progress(progress)
+				}
+			}
+		}()
+	}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- e
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if len(result.Failures) > 0 {
+		return result, fmt.Errorf("backup: %d files not restored, first %v", len(result.Failures), result.Failures[0])
+	}
+	return result, nil
+}
+
+// restoreEntry restore e at target following conflict, it return the key restored, empty when skipped
+func (j *Job) restoreEntry(ctx context.Context, e Entry, target, runID string, conflict Conflict) (string, error) {
+	if conflict != ConflictOverwrite {
+		present, err := j.Source.List(ctx, target, file.WithMaxResults(1))
+		if err != nil {
+			return "", err
+		}
+		if len(present) > 0 && present[0].Name == target {
+			if conflict == ConflictSkip {
+				return "", nil
+			}
+			ext := path.Ext(target)
+			target = strings.TrimSuffix(target, ext) + ".restored-" + runID + ext
+		}
+	}
+	return target, transfer(ctx, j.Dest, e.Object, j.Source, target, e.ContentType, e.Metadata)
+}
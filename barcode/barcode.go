@@ -0,0 +1,236 @@
+// Package barcode render QR codes and Code128 barcodes as PNG or SVG, e.g. for tickets, shipping labels
+// or links printed on invoices, and store them like any other asset.
+//
+//	Example:
+//	qr, err := barcode.QR("https://example.com/orders/42", barcode.LevelM)
+//	url, err := barcode.Upload(ctx, store, "orders/42/qr.png", qr, barcode.WithSize(512))
+package barcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	// DefaultSize is the width of rendered symbols in pixels by default
+	DefaultSize = 256
+	// ContentTypePNG and ContentTypeSVG are the content types of rendered symbols
+	ContentTypePNG = "image/png"
+	ContentTypeSVG = "image/svg+xml"
+)
+
+var (
+	// ErrTooLong returned when the content doesn't fit in the largest symbol
+	ErrTooLong = errors.New("barcode: content too long")
+	// ErrUnencodable returned when the content has characters the symbology can't encode
+	ErrUnencodable = errors.New("barcode: content not encodable")
+)
+
+// Symbol is an encoded barcode, a grid of dark and light modules. Linear barcodes have a single row
+// stretched to the rendered height.
+type Symbol struct {
+	// Width and Height are the size in modules, without quiet zone
+	Width, Height int
+	// Quiet is the light margin required around the symbol in modules
+	Quiet int
+
+	dark []bool
+}
+
+func newSymbol(width, height, quiet int) *Symbol {
+	return &Symbol{Width: width, Height: height, Quiet: quiet, dark: make([]bool, width*height)}
+}
+
+// Dark report whether the module at column x and row y is dark
+func (s *Symbol) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= s.Width || y >= s.Height {
+		return false
+	}
+	return s.dark[y*s.Width+x]
+}
+
+func (s *Symbol) set(x, y int, dark bool) {
+	s.dark[y*s.Width+x] = dark
+}
+
+func (s *Symbol) linear() bool {
+	return s.Height == 1
+}
+
+// Option configure the rendering of symbols
+type Option func(*options)
+
+type options struct {
+	size       int
+	height     int
+	quiet      int
+	foreground color.Color
+	background color.Color
+}
+
+// WithSize set the width of the rendered image in pixels, the square side for QR codes.
+// Modules are whole pixels, the symbol is centered when size isn't a multiple of its width,
+// and the image is wider when size is smaller than one pixel per module.
+func WithSize(px int) Option {
+	return func(o *options) {
+		o.size = px
+	}
+}
+
+// WithHeight set the height of rendered linear barcodes in pixels, a third of the width by default
+func WithHeight(px int) Option {
+	return func(o *options) {
+		o.height = px
+	}
+}
+
+// WithQuietZone set the light margin around the symbol in modules, the margin required by the symbology by default
+func WithQuietZone(modules int) Option {
+	return func(o *options) {
+		o.quiet = modules
+	}
+}
+
+// WithColors set the colors of dark and light modules, black on white by default.
+// A transparent background is left out of the svg.
+func WithColors(foreground, background color.Color) Option {
+	return func(o *options) {
+		o.foreground, o.background = foreground, background
+	}
+}
+
+// layout is the placement of the modules in the rendered image
+type layout struct {
+	width, height int
+	// scale is the size of a module, x0 and y0 the position of the first module
+	scale, x0, y0 int
+	// bar is the height of the bars of linear barcodes
+	bar int
+}
+
+func (s *Symbol) layout(opts []Option) (layout, options) {
+	o := options{size: DefaultSize, quiet: -1, foreground: color.Black, background: color.White}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	quiet := s.Quiet
+	if o.quiet >= 0 {
+		quiet = o.quiet
+	}
+
+	modules := s.Width + 2*quiet
+	l := layout{scale: o.size / modules}
+	if l.scale < 1 {
+		l.scale = 1
+	}
+	l.width = o.size
+	if l.width < modules*l.scale {
+		l.width = modules * l.scale
+	}
+	l.x0 = (l.width - s.Width*l.scale) / 2
+	if !s.linear() {
+		l.height = l.width
+		l.y0 = (l.height - s.Height*l.scale) / 2
+		return l, o
+	}
+
+	l.height = o.height
+	if l.height <= 0 {
+		l.height = l.width / 3
+	}
+	// the quiet zone of linear barcodes is only on their sides
+	l.bar = l.height
+	return l, o
+}
+
+// runs call fn with the position and length of every horizontal run of dark modules
+func (s *Symbol) runs(fn func(x, y, n int)) {
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; {
+			if !s.Dark(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < s.Width && s.Dark(x, y) {
+				x++
+			}
+			fn(start, y, x-start)
+		}
+	}
+}
+
+// PNG render the symbol as png
+func (s *Symbol) PNG(opts ...Option) ([]byte, error) {
+	l, o := s.layout(opts)
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: o.background}, image.Point{}, draw.Src)
+	fg := &image.Uniform{C: o.foreground}
+	s.runs(func(x, y, n int) {
+		r := image.Rect(l.x0+x*l.scale, l.y0+y*l.scale, l.x0+(x+n)*l.scale, l.y0+(y+1)*l.scale)
+		if s.linear() {
+			r.Max.Y = l.y0 + l.bar
+		}
+		draw.Draw(img, r, fg, image.Point{}, draw.Src)
+	})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG render the symbol as svg, dark modules are a single path
+func (s *Symbol) SVG(opts ...Option) []byte {
+	l, o := s.layout(opts)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		l.width, l.height, l.width, l.height)
+	if _, _, _, a := o.background.RGBA(); a != 0 {
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, l.width, l.height, hexColor(o.background))
+	}
+	fmt.Fprintf(&b, `<path fill="%s" d="`, hexColor(o.foreground))
+	s.runs(func(x, y, n int) {
+		height := l.scale
+		if s.linear() {
+			height = l.bar
+		}
+		fmt.Fprintf(&b, "M%d %dh%dv%dh-%dz", l.x0+x*l.scale, l.y0+y*l.scale, n*l.scale, height, n*l.scale)
+	})
+	b.WriteString(`"/></svg>`)
+	return []byte(b.String())
+}
+
+func hexColor(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if rgba.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3g)", rgba.R, rgba.G, rgba.B, float64(rgba.A)/0xff)
+}
+
+// Upload render s and store it at key, as svg when key ends with ".svg", png otherwise
+//
+//	Example:
+//	code, err := barcode.Code128("PKG-000042")
+//	url, err := barcode.Upload(ctx, store, "labels/42.svg", code, barcode.WithSize(600), barcode.WithHeight(120))
+func Upload(ctx context.Context, store file.IFile, key string, s *Symbol, opts ...Option) (string, error) {
+	if strings.HasSuffix(strings.ToLower(key), ".svg") {
+		return store.Upload(ctx, key, ContentTypeSVG, s.SVG(opts...))
+	}
+	data, err := s.PNG(opts...)
+	if err != nil {
+		return "", err
+	}
+	return store.Upload(ctx, key, ContentTypePNG, data)
+}
@@ -0,0 +1,107 @@
+package barcode
+
+// code128Quiet is the quiet zone of Code128 barcodes in modules
+const code128Quiet = 10
+
+// code128 values switching code sets and starting or stopping a barcode
+const (
+	code128Shift  = 98
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// code128Patterns are the bar and space widths of every value, starting with a bar
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+// Code128 encode ASCII content as a Code128 barcode, runs of digits are packed two per symbol.
+// It return ErrUnencodable when content is empty or has characters outside of ASCII.
+func Code128(content string) (*Symbol, error) {
+	if content == "" {
+		return nil, ErrUnencodable
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] > 127 {
+			return nil, ErrUnencodable
+		}
+	}
+
+	var values []int
+	codeC := false
+	for i := 0; i < len(content); {
+		// pairs of digits halve the barcode when the run pay for switching code set
+		digits := 0
+		for i+digits < len(content) && content[i+digits] >= '0' && content[i+digits] <= '9' {
+			digits++
+		}
+		edge := i == 0 || i+digits == len(content)
+		if digits >= 6 || digits >= 4 && edge || codeC && digits >= 2 {
+			switch {
+			case len(values) == 0:
+				values = append(values, code128StartC)
+			case !codeC:
+				values = append(values, code128CodeC)
+			}
+			codeC = true
+			for ; digits >= 2; digits -= 2 {
+				values = append(values, int(content[i]-'0')*10+int(content[i+1]-'0'))
+				i += 2
+			}
+			continue
+		}
+
+		switch {
+		case len(values) == 0:
+			values = append(values, code128StartB)
+		case codeC:
+			values = append(values, code128CodeB)
+		}
+		codeC = false
+		c := int(content[i])
+		if c < ' ' {
+			// control characters are only in code set A, shifted to for one character
+			values = append(values, code128Shift, c+64)
+		} else {
+			values = append(values, c-' ')
+		}
+		i++
+	}
+
+	checksum := values[0]
+	for i, v := range values[1:] {
+		checksum += (i + 1) * v
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	width := 0
+	for _, v := range values {
+		for _, w := range code128Patterns[v] {
+			width += int(w - '0')
+		}
+	}
+	s := newSymbol(width, 1, code128Quiet)
+	x := 0
+	for _, v := range values {
+		for i, w := range code128Patterns[v] {
+			for n := 0; n < int(w-'0'); n++ {
+				s.set(x, 0, i%2 == 0)
+				x++
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,519 @@
+package barcode
+
+import (
+	"strings"
+)
+
+// Level is the error correction level of QR codes, higher levels survive more damage in larger symbols
+type Level int
+
+const (
+	// LevelL recover about 7% of the symbol
+	LevelL Level = iota
+	// LevelM recover about 15% of the symbol
+	LevelM
+	// LevelQ recover about 25% of the symbol
+	LevelQ
+	// LevelH recover about 30% of the symbol, leaving room for a logo over the center
+	LevelH
+)
+
+// qrQuiet is the quiet zone of QR codes in modules
+const qrQuiet = 4
+
+// formatBits is the level in the format information
+var formatBits = [...]int{LevelL: 1, LevelM: 0, LevelQ: 3, LevelH: 2}
+
+// eccPerBlock and eccBlocks are the error correction codewords per block and the number of blocks by level and version
+var eccPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var eccBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// qrMode is a data encoding mode, the most compact mode holding the whole content is used
+type qrMode struct {
+	indicator int
+	// countBits is the size of the character count for versions 1-9, 10-26 and 27-40
+	countBits [3]int
+}
+
+var (
+	modeNumeric      = qrMode{indicator: 0x1, countBits: [3]int{10, 12, 14}}
+	modeAlphanumeric = qrMode{indicator: 0x2, countBits: [3]int{9, 11, 13}}
+	modeByte         = qrMode{indicator: 0x4, countBits: [3]int{8, 16, 16}}
+)
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+func (m qrMode) count(version int) int {
+	switch {
+	case version <= 9:
+		return m.countBits[0]
+	case version <= 26:
+		return m.countBits[1]
+	}
+	return m.countBits[2]
+}
+
+// QR encode content in the smallest QR code with error correction level, text is encoded as UTF-8 bytes.
+// It return ErrTooLong when content doesn't fit in a version 40 symbol.
+func QR(content string, level Level) (*Symbol, error) {
+	if level < LevelL || level > LevelH {
+		level = LevelM
+	}
+	mode := modeByte
+	switch {
+	case strings.Trim(content, "0123456789") == "":
+		mode = modeNumeric
+	case strings.Trim(content, alphanumeric) == "":
+		mode = modeAlphanumeric
+	}
+
+	var payload bitBuffer
+	switch mode {
+	case modeNumeric:
+		for i := 0; i < len(content); i += 3 {
+			group := content[i:min(i+3, len(content))]
+			n := 0
+			for _, c := range group {
+				n = n*10 + int(c-'0')
+			}
+			payload.append(n, len(group)*3+1)
+		}
+	case modeAlphanumeric:
+		for i := 0; i+1 < len(content); i += 2 {
+			payload.append(strings.IndexByte(alphanumeric, content[i])*45+strings.IndexByte(alphanumeric, content[i+1]), 11)
+		}
+		if len(content)%2 == 1 {
+			payload.append(strings.IndexByte(alphanumeric, content[len(content)-1]), 6)
+		}
+	default:
+		for i := 0; i < len(content); i++ {
+			payload.append(int(content[i]), 8)
+		}
+	}
+
+	version := 0
+	for v := 1; v <= 40; v++ {
+		countBits := mode.count(v)
+		if len(content) < 1<<uint(countBits) && 4+countBits+len(payload) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	var data bitBuffer
+	data.append(mode.indicator, 4)
+	data.append(len(content), mode.count(version))
+	data = append(data, payload...)
+	capacity := dataCodewords(version, level) * 8
+	data.append(0, min(4, capacity-len(data)))
+	data.append(0, (8-len(data)%8)%8)
+	for pad := 0xec; len(data) < capacity; pad ^= 0xec ^ 0x11 {
+		data.append(pad, 8)
+	}
+
+	q := newQRCode(version)
+	q.drawFunctionPatterns()
+	q.drawCodewords(addECC(data.bytes(), version, level))
+	best, penalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(level, mask)
+		if p := q.penalty(); penalty < 0 || p < penalty {
+			best, penalty = mask, p
+		}
+		// masks are xor, applying it again restore the modules
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(level, best)
+	return q.Symbol, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// bitBuffer is a sequence of bits, most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		*b = append(*b, value>>uint(i)&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, (len(b)+7)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return out
+}
+
+// rawModules is the number of modules of a version available for codewords
+func rawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawModules(version)/8 - eccPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// addECC split data in blocks, append the Reed-Solomon codewords of each block and interleave them
+func addECC(data []byte, version int, level Level) []byte {
+	blocks := eccBlocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	raw := rawModules(version) / 8
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+	divisor := rsDivisor(eccLen)
+
+	all := make([][]byte, blocks)
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < short {
+			// aligned with long blocks, skipped when interleaving
+			block = append(block, 0)
+		}
+		all[i] = append(block, ecc...)
+	}
+
+	out := make([]byte, 0, raw)
+	for i := range all[0] {
+		for j, block := range all {
+			if i != shortLen-eccLen || j >= short {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+// gfMul multiply in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		z ^= int(y>>uint(i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor return the generator polynomial of degree, highest coefficient first and the leading 1 left out
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// qrCode is a QR code being drawn, function modules are not masked
+type qrCode struct {
+	*Symbol
+	version  int
+	function []bool
+}
+
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	return &qrCode{Symbol: newSymbol(size, size, qrQuiet), version: version, function: make([]bool, size*size)}
+}
+
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.set(x, y, dark)
+	q.function[y*q.Width+x] = true
+}
+
+func (q *qrCode) drawFunctionPatterns() {
+	size := q.Width
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(size-4, 3)
+	q.drawFinder(3, size-4)
+
+	positions := alignmentPositions(q.version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the finder patterns take these corners
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// reserve the format modules, drawn once the mask is chosen
+	q.drawFormat(LevelL, 0)
+	if q.version >= 7 {
+		rem := q.version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1f25
+		}
+		bits := q.version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>uint(i)&1 == 1
+			a, b := size-11+i%3, i/3
+			q.setFunction(a, b, dark)
+			q.setFunction(b, a, dark)
+		}
+	}
+}
+
+// drawFinder draw the finder pattern centered on x, y with its separator
+func (q *qrCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= q.Width || yy >= q.Height {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			q.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (q *qrCode) drawFormat(level Level, mask int) {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool {
+		return bits>>uint(i)&1 == 1
+	}
+
+	size := q.Width
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, size-15+i, bit(i))
+	}
+	q.setFunction(8, size-8, true)
+}
+
+// drawCodewords place data in the zigzag of two columns wide strips from the bottom right corner
+func (q *qrCode) drawCodewords(data []byte) {
+	size := q.Width
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// the vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if !q.function[y*size+x] && i < len(data)*8 {
+					q.set(x, y, data[i/8]>>uint(7-i%8)&1 == 1)
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.Height; y++ {
+		for x := 0; x < q.Width; x++ {
+			if q.function[y*q.Width+x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.set(x, y, !q.Dark(x, y))
+			}
+		}
+	}
+}
+
+// finderLike is the 1:1:3:1:1 pattern with four light modules on one side, penalized as it look like a finder
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty score the masked symbol, the mask with the lowest score is kept
+func (q *qrCode) penalty() int {
+	size := q.Width
+	score := 0
+	dark := 0
+	for _, horizontal := range []bool{true, false} {
+		at := func(line, i int) bool {
+			if horizontal {
+				return q.Dark(i, line)
+			}
+			return q.Dark(line, i)
+		}
+		for line := 0; line < size; line++ {
+			run := 0
+			for i := 0; i < size; i++ {
+				if i > 0 && at(line, i) == at(line, i-1) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					score += 3
+				} else if run > 5 {
+					score++
+				}
+			}
+			for i := 0; i+11 <= size; i++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, d := range pattern {
+						if at(line, i+k) != d {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			d := q.Dark(x, y)
+			if d {
+				dark++
+			}
+			if x+1 < size && y+1 < size && d == q.Dark(x+1, y) && d == q.Dark(x, y+1) && d == q.Dark(x+1, y+1) {
+				score += 3
+			}
+		}
+	}
+	total := size * size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return score + k*10
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
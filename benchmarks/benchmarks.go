@@ -0,0 +1,283 @@
+// Package benchmarks run reproducible upload and download benchmarks across backends,
+// object sizes, transfer part sizes and concurrency levels, so transfer settings can be tuned with data.
+//
+//	Example:
+//	results, err := benchmarks.Run(ctx, benchmarks.Config{
+//		Backends: []benchmarks.Backend{{
+//			Name: "azure",
+//			New: func(s benchmarks.Settings) file.IFile {
+//				return file.New(account, accessKey, rootURL, container, apiVersion, file.WithTransfer(s.PartSize, s.Parallelism))
+//			},
+//		}},
+//		ObjectSizes: []int64{1 << 20, 64 << 20},
+//		PartSizes:   []int64{4 << 20, 8 << 20},
+//		Concurrency: []int{1, 8},
+//	})
+//	err = benchmarks.WriteCSV(os.Stdout, results)
+package benchmarks
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	OpUpload   = "upload"
+	OpDownload = "download"
+
+	DefaultIterations = 10
+	DefaultPrefix     = "benchmarks/"
+	DefaultSeed       = 1
+)
+
+// Settings is the transfer configuration a backend is created with
+type Settings struct {
+	PartSize    int64
+	Parallelism uint16
+}
+
+// Backend create the store under test for given settings
+type Backend struct {
+	Name string
+	New  func(s Settings) file.IFile
+}
+
+// Config is the benchmark matrix, every combination of backend, object size,
+// part size, parallelism and concurrency is run
+type Config struct {
+	Backends    []Backend
+	ObjectSizes []int64
+	PartSizes   []int64
+	Parallelism []uint16
+	// Concurrency is the number of operations running at once
+	Concurrency []int
+	// Iterations is the number of objects transferred per combination and operation
+	Iterations int
+	// Prefix is where benchmark objects are written, they are deleted afterwards
+	Prefix string
+	// Seed make object content reproducible
+	Seed int64
+}
+
+// Result is the measurement of one operation for one combination
+type Result struct {
+	Backend     string        `json:"backend"`
+	Op          string        `json:"op"`
+	ObjectSize  int64         `json:"object_size"`
+	PartSize    int64         `json:"part_size"`
+	Parallelism uint16        `json:"parallelism"`
+	Concurrency int           `json:"concurrency"`
+	Ops         int           `json:"ops"`
+	Errors      int           `json:"errors"`
+	Bytes       int64         `json:"bytes"`
+	Duration    time.Duration `json:"duration_ns"`
+	MBps        float64       `json:"mbps"`
+	P50         time.Duration `json:"p50_ns"`
+	P95         time.Duration `json:"p95_ns"`
+	Max         time.Duration `json:"max_ns"`
+}
+
+// Run execute the benchmark matrix, failing operations are counted in Result.Errors
+func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = DefaultIterations
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = DefaultSeed
+	}
+	if len(cfg.PartSizes) == 0 {
+		cfg.PartSizes = []int64{0}
+	}
+	if len(cfg.Parallelism) == 0 {
+		cfg.Parallelism = []uint16{0}
+	}
+	if len(cfg.Concurrency) == 0 {
+		cfg.Concurrency = []int{1}
+	}
+
+	var results []Result
+	for _, backend := range cfg.Backends {
+		for _, size := range cfg.ObjectSizes {
+			payload := make([]byte, size)
+			rand.New(rand.NewSource(cfg.Seed + size)).Read(payload)
+
+			for _, part := range cfg.PartSizes {
+				for _, parallelism := range cfg.Parallelism {
+					store := backend.New(Settings{PartSize: part, Parallelism: parallelism})
+					for _, concurrency := range cfg.Concurrency {
+						if err := ctx.Err(); err != nil {
+							return results, err
+						}
+
+						base := Result{
+							Backend:     backend.Name,
+							ObjectSize:  size,
+							PartSize:    part,
+							Parallelism: parallelism,
+							Concurrency: concurrency,
+						}
+						prefix := fmt.Sprintf("%s%s/%d-%d-%d-%d/", cfg.Prefix, backend.Name, size, part, parallelism, concurrency)
+
+						up, down := run(ctx, store, base, prefix, payload, cfg.Iterations)
+						results = append(results, up, down)
+					}
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func run(ctx context.Context, store file.IFile, base Result, prefix string, payload []byte, iterations int) (up, down Result) {
+	keys := make([]string, iterations)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%sobject-%04d", prefix, i)
+	}
+
+	up = measure(ctx, base, OpUpload, keys, func(key string) (int64, error) {
+		_, err := store.Upload(ctx, key, "application/octet-stream", payload)
+		return int64(len(payload)), err
+	})
+	down = measure(ctx, base, OpDownload, keys, func(key string) (int64, error) {
+		data, err := store.Download(ctx, key)
+		return int64(len(data)), err
+	})
+
+	for _, key := range keys {
+		store.Delete(ctx, key)
+	}
+	return
+}
+
+func measure(ctx context.Context, base Result, op string, keys []string, fn func(key string) (int64, error)) Result {
+	res := base
+	res.Op = op
+
+	concurrency := base.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+	work := make(chan string)
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				t := time.Now()
+				n, err := fn(key)
+				elapsed := time.Since(t)
+
+				mu.Lock()
+				res.Ops++
+				if err != nil {
+					res.Errors++
+				} else {
+					res.Bytes += n
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+
+	res.Duration = time.Since(start)
+	if res.Duration > 0 {
+		res.MBps = float64(res.Bytes) / (1 << 20) / res.Duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		res.P50 = percentile(latencies, 0.50)
+		res.P95 = percentile(latencies, 0.95)
+		res.Max = latencies[len(latencies)-1]
+	}
+	return res
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+var csvHeader = []string{
+	"backend", "op", "object_size", "part_size", "parallelism", "concurrency",
+	"ops", "errors", "bytes", "duration_ms", "mbps", "p50_ms", "p95_ms", "max_ms",
+}
+
+// WriteCSV write results as CSV with a header row, durations in milliseconds
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	for _, r := range results {
+		err := cw.Write([]string{
+			r.Backend,
+			r.Op,
+			strconv.FormatInt(r.ObjectSize, 10),
+			strconv.FormatInt(r.PartSize, 10),
+			strconv.Itoa(int(r.Parallelism)),
+			strconv.Itoa(r.Concurrency),
+			strconv.Itoa(r.Ops),
+			strconv.Itoa(r.Errors),
+			strconv.FormatInt(r.Bytes, 10),
+			ms(r.Duration),
+			strconv.FormatFloat(r.MBps, 'f', 3, 64),
+			ms(r.P50),
+			ms(r.P95),
+			ms(r.Max),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON write results as indented JSON array
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
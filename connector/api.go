@@ -0,0 +1,158 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+var (
+	// RateLimitRetries is how many times a rate limited request is retried before APIError is returned
+	RateLimitRetries = 5
+	// MaxRetryWait bound the wait before retrying a rate limited request
+	MaxRetryWait = time.Minute
+)
+
+// APIError returned when a source API answer with a non 2xx status
+type APIError struct {
+	Source     string
+	StatusCode int
+	Message    string
+	// RetryAfter is the wait the source asked for when rate limited
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("connector: %s: status %d: %s", e.Source, e.StatusCode, e.Message)
+}
+
+// RateLimited report whether the source refused the request because of its rate limit
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable ||
+		e.StatusCode == http.StatusForbidden && strings.Contains(e.Message, "ateLimitExceeded")
+}
+
+// ErrorClass classify the error by its status for file.Classify, rate limits are throttling
+func (e *APIError) ErrorClass() file.ErrorClass {
+	if e.RateLimited() {
+		return file.ClassThrottled
+	}
+	return file.ClassifyStatus(e.StatusCode)
+}
+
+// apiRequest is a source API call, Body is sent as JSON when not nil
+type apiRequest struct {
+	Method string
+	URL    string
+	Body   interface{}
+	Header http.Header
+}
+
+// do send an API request authenticated with the token of ctx, decoding a JSON answer into v when not nil.
+// The response body is returned open when v is nil. Rate limited requests are retried after the wait
+// asked by the source, or an exponential backoff, up to RateLimitRetries times.
+func do(ctx context.Context, client *http.Client, source string, r apiRequest, v interface{}) (*http.Response, error) {
+	token := Token(ctx)
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = json.Marshal(r.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		resp, err := send(ctx, client, source, token, r, body)
+		apiErr, ok := err.(*APIError)
+		if !ok || !apiErr.RateLimited() || attempt >= RateLimitRetries {
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return resp, nil
+			}
+			defer resp.Body.Close()
+			return resp, json.NewDecoder(resp.Body).Decode(v)
+		}
+
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > MaxRetryWait {
+			wait = MaxRetryWait
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func send(ctx context.Context, client *http.Client, source, token string, r apiRequest, body []byte) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(r.Method, r.URL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, values := range r.Header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, &APIError{
+		Source:     source,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(msg)),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// retryAfter parse a Retry-After header, in seconds or an http date
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
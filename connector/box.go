@@ -0,0 +1,181 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// BoxURL is the Box API endpoint
+const BoxURL = "https://api.box.com/2.0"
+
+// boxRoot is the id of the Box root folder
+const boxRoot = "0"
+
+// Box list and download Box files with the OAuth token of ctx.
+// Changes follow the event stream of the user, the cursor is a Box stream position.
+type Box struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewBox create a Box source sending requests with client, http.DefaultClient when nil
+func NewBox(client *http.Client) *Box {
+	return &Box{Client: client, BaseURL: BoxURL}
+}
+
+// Name return "box"
+func (b *Box) Name() string {
+	return "box"
+}
+
+type boxItem struct {
+	Type           string    `json:"type"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Size           int64     `json:"size"`
+	ModifiedAt     time.Time `json:"modified_at"`
+	PathCollection struct {
+		Entries []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"entries"`
+	} `json:"path_collection"`
+}
+
+func (it boxItem) item() Item {
+	return Item{ID: it.ID, Name: it.Name, Size: it.Size, Modified: it.ModifiedAt, Folder: it.Type == "folder"}
+}
+
+// List return the items of the folder id, the root when empty
+func (b *Box) List(ctx context.Context, folder string) ([]Item, error) {
+	if folder == "" {
+		folder = boxRoot
+	}
+	q := url.Values{}
+	q.Set("fields", "type,id,name,size,modified_at")
+	q.Set("limit", "1000")
+	q.Set("usemarker", "true")
+
+	var items []Item
+	for {
+		var page struct {
+			Entries    []boxItem `json:"entries"`
+			NextMarker string    `json:"next_marker"`
+		}
+		req := apiRequest{Method: http.MethodGet, URL: b.BaseURL + "/folders/" + url.PathEscape(folder) + "/items?" + q.Encode()}
+		if _, err := do(ctx, b.Client, b.Name(), req, &page); err != nil {
+			return nil, err
+		}
+		for _, it := range page.Entries {
+			// web links have no content
+			if it.Type == "file" || it.Type == "folder" {
+				items = append(items, it.item())
+			}
+		}
+		if page.NextMarker == "" {
+			return items, nil
+		}
+		q.Set("marker", page.NextMarker)
+	}
+}
+
+// boxEvents are the event types of the Box event stream changing file content or location
+var boxEvents = map[string]bool{
+	"ITEM_UPLOAD":             true,
+	"ITEM_CREATE":             true,
+	"ITEM_COPY":               true,
+	"ITEM_MOVE":               true,
+	"ITEM_RENAME":             true,
+	"ITEM_UNDELETE_VIA_TRASH": true,
+	"ITEM_TRASH":              true,
+}
+
+// Changes return the file changes under folder since the stream position cursor.
+// With an empty cursor every file is listed and the current stream position returned.
+// A renamed or moved file is imported at its new path, the old path is left in the store.
+func (b *Box) Changes(ctx context.Context, folder, cursor string) ([]Change, string, error) {
+	if folder == "" {
+		folder = boxRoot
+	}
+	if cursor == "" {
+		next, err := b.streamPosition(ctx, "now", nil)
+		if err != nil {
+			return nil, "", err
+		}
+		changes, err := listAll(ctx, b, folder, "")
+		return changes, next, err
+	}
+
+	var changes []Change
+	next, err := b.streamPosition(ctx, cursor, func(eventType string, it boxItem) {
+		if it.Type != "file" || !boxEvents[eventType] {
+			return
+		}
+		// path_collection start at the root, keep the names below folder
+		dir, inside := "", folder == boxRoot
+		for _, e := range it.PathCollection.Entries {
+			if inside && e.ID != boxRoot {
+				dir = path.Join(dir, safeName(e.Name))
+			}
+			if e.ID == folder {
+				inside = true
+			}
+		}
+		if !inside {
+			return
+		}
+		item := it.item()
+		item.Path = path.Join(dir, safeName(item.Name))
+		changes = append(changes, Change{Item: item, Deleted: eventType == "ITEM_TRASH"})
+	})
+	if err != nil {
+		return nil, cursor, err
+	}
+	return changes, next, nil
+}
+
+// streamPosition read the change events from position until the end of the stream, return the next position
+func (b *Box) streamPosition(ctx context.Context, position string, fn func(eventType string, it boxItem)) (string, error) {
+	for {
+		var page struct {
+			ChunkSize          int         `json:"chunk_size"`
+			NextStreamPosition json.Number `json:"next_stream_position"`
+			Entries            []struct {
+				EventType string  `json:"event_type"`
+				Source    boxItem `json:"source"`
+			} `json:"entries"`
+		}
+		q := url.Values{"stream_type": {"changes"}, "stream_position": {position}, "limit": {"500"}}
+		req := apiRequest{Method: http.MethodGet, URL: b.BaseURL + "/events?" + q.Encode()}
+		if _, err := do(ctx, b.Client, b.Name(), req, &page); err != nil {
+			return "", err
+		}
+		if page.NextStreamPosition != "" {
+			position = page.NextStreamPosition.String()
+		}
+		if fn == nil || page.ChunkSize == 0 {
+			return position, nil
+		}
+		for _, e := range page.Entries {
+			fn(e.EventType, e.Source)
+		}
+	}
+}
+
+// Open download the content of item, ErrNotDownloadable for folders
+func (b *Box) Open(ctx context.Context, item Item) (io.ReadCloser, error) {
+	if item.Folder {
+		return nil, ErrNotDownloadable
+	}
+	u := b.BaseURL + "/files/" + url.PathEscape(item.ID) + "/content"
+	resp, err := do(ctx, b.Client, b.Name(), apiRequest{Method: http.MethodGet, URL: u}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,224 @@
+// Package connector import media libraries from third party storage, such as Google Drive, OneDrive, Dropbox or Box,
+// streaming every file of a folder into a store and through a processing pipeline.
+//
+//	Example:
+//	ctx = connector.WithToken(ctx, oauthAccessToken)
+//	importer := connector.NewImporter(store, "imports/drive/")
+//	importer.Runner, importer.Pipeline = runner, "renditions"
+//	result, err := importer.Import(ctx, connector.NewGoogleDrive(nil), folderID)
+package connector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+var (
+	// ErrNoToken returned when ctx carry no OAuth access token, see WithToken
+	ErrNoToken = errors.New("connector: no access token")
+	// ErrNotDownloadable returned when opening items without content, e.g. folders or Google Docs documents
+	ErrNotDownloadable = errors.New("connector: item not downloadable")
+)
+
+// Item is a file or folder of a source
+type Item struct {
+	ID   string
+	Name string
+	// Path is the item path relative to the imported folder, set by Importer
+	Path        string
+	Size        int64
+	ContentType string
+	Modified    time.Time
+	Folder      bool
+}
+
+// Source list and download the files of a third party storage, authenticated with the token of ctx
+type Source interface {
+	// Name identify the source in errors, e.g. "googledrive"
+	Name() string
+	// List return the items of folder, the root folder when empty
+	List(ctx context.Context, folder string) ([]Item, error)
+	// Open return the content of item, the caller must close it
+	Open(ctx context.Context, item Item) (io.ReadCloser, error)
+}
+
+type tokenKey struct{}
+
+// WithToken return ctx carrying the OAuth access token sources send, e.g. the token of the importing user
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// Token return the OAuth access token of ctx, empty when not set
+func Token(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
+
+// Imported is a file copied into the store
+type Imported struct {
+	Item Item
+	Key  string
+	URL  string
+	// JobID is the pipeline job processing the file, empty without Runner
+	JobID string
+}
+
+// ImportError is a file that could not be imported
+type ImportError struct {
+	Item Item
+	Err  error
+}
+
+// ImportResult list what Import copied and what failed
+type ImportResult struct {
+	Imported []Imported
+	Failed   []ImportError
+	// Skipped are items without content, e.g. Google Docs documents
+	Skipped []Item
+	// Deleted are files deleted from the store by Sync as they were deleted from the source
+	Deleted []Item
+}
+
+// Importer copy the files of a source folder under Prefix of Store and submit them to a pipeline
+type Importer struct {
+	Store  file.IFile
+	Prefix string
+	// Recursive import sub folders, keeping their path under Prefix
+	Recursive bool
+	// MaxSize skip larger files as failed with file.ErrTooLarge, unlimited when zero
+	MaxSize int64
+	// AllowedTypes are accepted content type prefixes, e.g. "image/", others fail with
+	// file.ErrContentTypeNotAllowed. Any type when empty
+	AllowedTypes []string
+	// Runner and Pipeline submit every imported file, not processed when Runner is nil
+	Runner   *pipeline.Runner
+	Pipeline string
+}
+
+// NewImporter import recursively into store under prefix
+func NewImporter(store file.IFile, prefix string) *Importer {
+	return &Importer{Store: store, Prefix: prefix, Recursive: true}
+}
+
+// Import copy the files of folder, the source root when empty. A failed file doesn't stop the import,
+// it is reported in ImportResult.Failed. The error is returned for listing failures and cancellation.
+func (im *Importer) Import(ctx context.Context, src Source, folder string) (ImportResult, error) {
+	var result ImportResult
+	err := im.walk(ctx, src, folder, "", &result)
+	return result, err
+}
+
+func (im *Importer) walk(ctx context.Context, src Source, folder, dir string, result *ImportResult) error {
+	items, err := src.List(ctx, folder)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item.Path = path.Join(dir, safeName(item.Name))
+		if item.Folder {
+			if im.Recursive {
+				if err := im.walk(ctx, src, item.ID, item.Path, result); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		imported, err := im.importItem(ctx, src, item)
+		switch {
+		case err == ErrNotDownloadable:
+			result.Skipped = append(result.Skipped, item)
+		case err != nil:
+			result.Failed = append(result.Failed, ImportError{Item: item, Err: err})
+		default:
+			result.Imported = append(result.Imported, imported)
+		}
+	}
+	return nil
+}
+
+// safeName replace separators and dot names so a source item name can't leave the imported folder
+func safeName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "_" + name
+	}
+	return name
+}
+
+// importItem stream item into the store, buffered for stores not implementing file.StreamUploader
+func (im *Importer) importItem(ctx context.Context, src Source, item Item) (Imported, error) {
+	if im.MaxSize > 0 && item.Size > im.MaxSize {
+		return Imported{}, file.ErrTooLarge
+	}
+	body, err := src.Open(ctx, item)
+	if err != nil {
+		return Imported{}, err
+	}
+	defer body.Close()
+
+	contentType := item.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(item.Name))
+	}
+	if !im.allowed(contentType) {
+		return Imported{}, file.ErrContentTypeNotAllowed
+	}
+	key := im.Prefix + item.Path
+	var r io.Reader = body
+	if im.MaxSize > 0 {
+		r = file.SizeLimitReader(body, im.MaxSize)
+	}
+
+	var url string
+	if streamer, ok := im.Store.(file.StreamUploader); ok {
+		size := item.Size
+		if size <= 0 {
+			size = -1
+		}
+		url, err = streamer.UploadStream(ctx, key, contentType, r, size)
+	} else {
+		var data []byte
+		if data, err = ioutil.ReadAll(r); err == nil {
+			url, err = im.Store.Upload(ctx, key, contentType, data)
+		}
+	}
+	if err != nil {
+		return Imported{}, err
+	}
+
+	imported := Imported{Item: item, Key: key, URL: url}
+	if im.Runner != nil {
+		if imported.JobID, err = im.Runner.Submit(ctx, im.Pipeline, key, contentType); err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
+
+// allowed report whether contentType match AllowedTypes
+func (im *Importer) allowed(contentType string) bool {
+	if len(im.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, prefix := range im.AllowedTypes {
+		if mediaType != "" && strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// ErrCursorReset returned by Changes when the source expired the cursor, import again with an empty cursor
+var ErrCursorReset = errors.New("connector: cursor reset by source")
+
+// Change is a file added, modified or deleted under a folder since a cursor
+type Change struct {
+	// Item Path is relative to the synchronized folder
+	Item    Item
+	Deleted bool
+}
+
+// DeltaSource is implemented by sources reporting changes since a cursor, for incremental imports
+type DeltaSource interface {
+	Source
+	// Changes return the changes of the files under folder, recursively, since cursor
+	// and the cursor of the next call. Every file is returned as added when cursor is empty.
+	Changes(ctx context.Context, folder, cursor string) ([]Change, string, error)
+}
+
+// Sync import the files of folder changed since cursor and delete the deleted ones,
+// return the cursor to pass to the next Sync, e.g. saved with the import settings.
+// An empty cursor import every file. When the source reset the cursor ErrCursorReset is returned,
+// call Sync again with an empty cursor.
+//
+//	Example:
+//	result, cursor, err := importer.Sync(ctx, connector.NewDropbox(nil), "", settings.Cursor)
+//	settings.Cursor = cursor
+func (im *Importer) Sync(ctx context.Context, src DeltaSource, folder, cursor string) (ImportResult, string, error) {
+	var result ImportResult
+	changes, next, err := src.Changes(ctx, folder, cursor)
+	if err != nil {
+		return result, cursor, err
+	}
+
+	for _, change := range changes {
+		if err := ctx.Err(); err != nil {
+			return result, cursor, err
+		}
+		item := change.Item
+		if item.Folder {
+			continue
+		}
+
+		if change.Deleted {
+			_, err := im.Store.Delete(ctx, im.Prefix+item.Path)
+			switch {
+			case err == nil || err == file.ErrNotFound:
+				result.Deleted = append(result.Deleted, item)
+			default:
+				result.Failed = append(result.Failed, ImportError{Item: item, Err: err})
+			}
+			continue
+		}
+
+		imported, err := im.importItem(ctx, src, item)
+		switch {
+		case err == ErrNotDownloadable:
+			result.Skipped = append(result.Skipped, item)
+		case err != nil:
+			result.Failed = append(result.Failed, ImportError{Item: item, Err: err})
+		default:
+			result.Imported = append(result.Imported, imported)
+		}
+	}
+	return result, next, nil
+}
+
+// listAll return every file under folder with its relative path, for sources without a recursive listing
+func listAll(ctx context.Context, src Source, folder, dir string) ([]Change, error) {
+	items, err := src.List(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, item := range items {
+		item.Path = path.Join(dir, safeName(item.Name))
+		if !item.Folder {
+			changes = append(changes, Change{Item: item})
+			continue
+		}
+		sub, err := listAll(ctx, src, item.ID, item.Path)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, sub...)
+	}
+	return changes, nil
+}
+
+// relativePath return p relative to the folder path dir, both slash separated, false when p is outside dir
+func relativePath(dir, p string) (string, bool) {
+	dir = strings.Trim(dir, "/")
+	p = strings.Trim(p, "/")
+	if dir != "" {
+		// Dropbox and Box paths are case insensitive
+		if len(p) <= len(dir) || p[len(dir)] != '/' || !strings.EqualFold(p[:len(dir)], dir) {
+			return "", false
+		}
+		p = p[len(dir)+1:]
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = safeName(s)
+	}
+	return strings.Join(segments, "/"), true
+}
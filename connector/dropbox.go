@@ -0,0 +1,150 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Dropbox API endpoints
+const (
+	DropboxURL        = "https://api.dropboxapi.com/2"
+	DropboxContentURL = "https://content.dropboxapi.com/2"
+)
+
+// Dropbox list and download Dropbox files with the OAuth token of ctx, it needs the files.content.read scope.
+// Folders are given by id, e.g. "id:a4ayc_80_OEAAAAAAAAAXw", or by path, the root when empty.
+type Dropbox struct {
+	Client     *http.Client
+	BaseURL    string
+	ContentURL string
+}
+
+// NewDropbox create a Dropbox source sending requests with client, http.DefaultClient when nil
+func NewDropbox(client *http.Client) *Dropbox {
+	return &Dropbox{Client: client, BaseURL: DropboxURL, ContentURL: DropboxContentURL}
+}
+
+// Name return "dropbox"
+func (d *Dropbox) Name() string {
+	return "dropbox"
+}
+
+type dropboxEntry struct {
+	Tag            string    `json:".tag"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	PathDisplay    string    `json:"path_display"`
+	Size           int64     `json:"size"`
+	ServerModified time.Time `json:"server_modified"`
+}
+
+type dropboxPage struct {
+	Entries []dropboxEntry `json:"entries"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
+func (e dropboxEntry) item() Item {
+	return Item{ID: e.ID, Name: e.Name, Size: e.Size, Modified: e.ServerModified, Folder: e.Tag == "folder"}
+}
+
+// List return the items of folder
+func (d *Dropbox) List(ctx context.Context, folder string) ([]Item, error) {
+	var items []Item
+	_, err := d.listFolder(ctx, folder, false, "", func(e dropboxEntry) {
+		if e.Tag != "deleted" {
+			items = append(items, e.item())
+		}
+	})
+	return items, err
+}
+
+// listFolder call fn for every entry of folder, or of the changes since cursor when set, return the next cursor
+func (d *Dropbox) listFolder(ctx context.Context, folder string, recursive bool, cursor string, fn func(dropboxEntry)) (string, error) {
+	req := apiRequest{Method: http.MethodPost, URL: d.BaseURL + "/files/list_folder", Body: map[string]interface{}{
+		"path":      folder,
+		"recursive": recursive,
+	}}
+	if cursor != "" {
+		req = apiRequest{Method: http.MethodPost, URL: d.BaseURL + "/files/list_folder/continue", Body: map[string]string{"cursor": cursor}}
+	}
+
+	for {
+		var page dropboxPage
+		if _, err := do(ctx, d.Client, d.Name(), req, &page); err != nil {
+			if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusConflict && strings.Contains(apiErr.Message, "reset") {
+				return "", ErrCursorReset
+			}
+			return "", err
+		}
+		for _, e := range page.Entries {
+			fn(e)
+		}
+		if !page.HasMore {
+			return page.Cursor, nil
+		}
+		req = apiRequest{Method: http.MethodPost, URL: d.BaseURL + "/files/list_folder/continue", Body: map[string]string{"cursor": page.Cursor}}
+	}
+}
+
+// Changes return the file changes under folder since cursor using the list_folder cursor of Dropbox
+func (d *Dropbox) Changes(ctx context.Context, folder, cursor string) ([]Change, string, error) {
+	dir, err := d.folderPath(ctx, folder)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	var changes []Change
+	next, err := d.listFolder(ctx, folder, true, cursor, func(e dropboxEntry) {
+		if e.Tag == "folder" {
+			return
+		}
+		p, ok := relativePath(dir, e.PathDisplay)
+		if !ok {
+			return
+		}
+		item := e.item()
+		item.Path = p
+		changes = append(changes, Change{Item: item, Deleted: e.Tag == "deleted"})
+	})
+	if err != nil {
+		return nil, cursor, err
+	}
+	return changes, next, nil
+}
+
+// folderPath return the display path of the folder id, paths and the root are returned as is
+func (d *Dropbox) folderPath(ctx context.Context, folder string) (string, error) {
+	if !strings.HasPrefix(folder, "id:") {
+		return folder, nil
+	}
+	var meta dropboxEntry
+	req := apiRequest{Method: http.MethodPost, URL: d.BaseURL + "/files/get_metadata", Body: map[string]string{"path": folder}}
+	if _, err := do(ctx, d.Client, d.Name(), req, &meta); err != nil {
+		return "", err
+	}
+	return meta.PathDisplay, nil
+}
+
+// Open download the content of item, ErrNotDownloadable for folders
+func (d *Dropbox) Open(ctx context.Context, item Item) (io.ReadCloser, error) {
+	if item.Folder {
+		return nil, ErrNotDownloadable
+	}
+	arg, err := json.Marshal(map[string]string{"path": item.ID})
+	if err != nil {
+		return nil, err
+	}
+	req := apiRequest{Method: http.MethodPost, URL: d.ContentURL + "/files/download", Header: http.Header{
+		"Dropbox-Api-Arg": {string(arg)},
+	}}
+	resp, err := do(ctx, d.Client, d.Name(), req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
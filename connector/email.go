@@ -0,0 +1,236 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// MetadataMessageID is the metadata key of the Message-ID of the email an attachment came from
+const MetadataMessageID = "email_message_id"
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	// ContentID reference inline attachments from the html body, e.g. "<logo@example.com>"
+	ContentID string
+	Inline    bool
+	Data      []byte
+}
+
+// Email is an inbound email with its attachments. It is a Source listing its attachments,
+// named "<n>-<filename>" in message order, so an Importer can store them.
+type Email struct {
+	MessageID   string
+	From        string
+	Subject     string
+	Date        time.Time
+	Attachments []Attachment
+}
+
+// ParseEmail parse a raw RFC 822 message and extract its attachments
+//
+//	Example:
+//	email, err := connector.ParseEmail(r.Body)
+//	result, err := importer.ImportEmail(ctx, email)
+func ParseEmail(r io.Reader) (*Email, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	return EmailFromMessage(msg)
+}
+
+// EmailFromMessage extract the attachments of a message parsed with net/mail, reading its body
+func EmailFromMessage(msg *mail.Message) (*Email, error) {
+	dec := new(mime.WordDecoder)
+	e := &Email{MessageID: strings.TrimSpace(msg.Header.Get("Message-Id"))}
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	e.Subject = subject
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		e.From = from.Address
+	}
+	e.Date, _ = msg.Header.Date()
+
+	if err := e.walk(msg.Header, msg.Body, dec); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// partHeader is the header of a message or of a multipart part
+type partHeader interface {
+	Get(key string) string
+}
+
+// walk collect the attachments of a part, descending into multipart parts
+func (e *Email) walk(h partHeader, body io.Reader, dec *mime.WordDecoder) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := e.walk(part.Header, part, dec); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := dec.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	// text bodies are the message, not attachments
+	if disposition != "attachment" && filename == "" {
+		return nil
+	}
+
+	// multipart.Part already decode quoted-printable parts and remove their encoding header
+	encoding := strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding")))
+	if encoding == "quoted-printable" {
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if encoding == "base64" {
+		data, err = decodeBase64(data)
+		if err != nil {
+			return fmt.Errorf("connector: attachment %q: %w", filename, err)
+		}
+	}
+
+	if filename == "" {
+		filename = "attachment" + extension(mediaType)
+	}
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = http.DetectContentType(data)
+	}
+	e.Attachments = append(e.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		ContentID:   h.Get("Content-Id"),
+		Inline:      disposition == "inline",
+		Data:        data,
+	})
+	return nil
+}
+
+// decodeBase64 decode base64 split over lines
+func decodeBase64(data []byte) ([]byte, error) {
+	clean := bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, data)
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(clean)))
+	n, err := base64.StdEncoding.Decode(out, clean)
+	return out[:n], err
+}
+
+func extension(mediaType string) string {
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// Key return the traceable key prefix of the email attachments: the UTC date of the email
+// and a hash of its Message-ID, e.g. "2020/03/01/9f86d081884c7d65"
+func (e *Email) Key() string {
+	date := e.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	id := e.MessageID
+	if id == "" {
+		h := sha256.New()
+		for _, a := range e.Attachments {
+			h.Write(a.Data)
+		}
+		id = e.From + e.Subject + hex.EncodeToString(h.Sum(nil))
+	}
+	sum := sha256.Sum256([]byte(id))
+	return date.UTC().Format("2006/01/02") + "/" + hex.EncodeToString(sum[:8])
+}
+
+// Name return "email"
+func (e *Email) Name() string {
+	return "email"
+}
+
+// List return the attachments, the email has no folders
+func (e *Email) List(ctx context.Context, folder string) ([]Item, error) {
+	items := make([]Item, len(e.Attachments))
+	for i, a := range e.Attachments {
+		items[i] = Item{
+			ID:          strconv.Itoa(i),
+			Name:        strconv.Itoa(i+1) + "-" + a.Filename,
+			Size:        int64(len(a.Data)),
+			ContentType: a.ContentType,
+			Modified:    e.Date,
+		}
+	}
+	return items, nil
+}
+
+// Open return the content of the attachment item
+func (e *Email) Open(ctx context.Context, item Item) (io.ReadCloser, error) {
+	i, err := strconv.Atoi(item.ID)
+	if err != nil || i < 0 || i >= len(e.Attachments) {
+		return nil, file.ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(e.Attachments[i].Data)), nil
+}
+
+// ImportEmail store the attachments of e under Prefix + e.Key(), with the Message-ID as metadata,
+// validated and submitted to the pipeline like any import, e.g. for inbound email to ticket workflows
+//
+//	Example:
+//	importer := connector.NewImporter(store, "tickets/attachments/")
+//	importer.AllowedTypes = []string{"image/", "application/pdf"}
+//	importer.MaxSize = 20 << 20
+//	result, err := importer.ImportEmail(ctx, email)
+func (im *Importer) ImportEmail(ctx context.Context, e *Email) (ImportResult, error) {
+	scoped := *im
+	scoped.Prefix = im.Prefix + e.Key() + "/"
+	if e.MessageID != "" {
+		ctx = file.WithMetadata(ctx, MetadataMessageID, e.MessageID)
+	}
+	return scoped.Import(ctx, e, "")
+}
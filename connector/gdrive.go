@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoogleDriveURL is the Google Drive API v3 endpoint
+const GoogleDriveURL = "https://www.googleapis.com/drive/v3"
+
+// googleAppsType prefix native Google Docs, Sheets and Slides items, which have no downloadable content
+const googleAppsType = "application/vnd.google-apps."
+
+// GoogleDrive list and download Google Drive files with the OAuth token of ctx,
+// it needs the drive.readonly scope
+type GoogleDrive struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewGoogleDrive create a Google Drive source sending requests with client, http.DefaultClient when nil
+func NewGoogleDrive(client *http.Client) *GoogleDrive {
+	return &GoogleDrive{Client: client, BaseURL: GoogleDriveURL}
+}
+
+// Name return "googledrive"
+func (d *GoogleDrive) Name() string {
+	return "googledrive"
+}
+
+type driveFile struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	MimeType     string `json:"mimeType"`
+	Size         string `json:"size"`
+	ModifiedTime string `json:"modifiedTime"`
+}
+
+// List return the items of the folder id, "root" when empty, excluding trashed items
+func (d *GoogleDrive) List(ctx context.Context, folder string) ([]Item, error) {
+	if folder == "" {
+		folder = "root"
+	}
+	q := url.Values{}
+	q.Set("q", "'"+strings.Replace(folder, "'", `\'`, -1)+"' in parents and trashed = false")
+	q.Set("fields", "nextPageToken,files(id,name,mimeType,size,modifiedTime)")
+	q.Set("pageSize", "1000")
+
+	var items []Item
+	for {
+		var page struct {
+			NextPageToken string      `json:"nextPageToken"`
+			Files         []driveFile `json:"files"`
+		}
+		req := apiRequest{Method: http.MethodGet, URL: d.BaseURL + "/files?" + q.Encode()}
+		if _, err := do(ctx, d.Client, d.Name(), req, &page); err != nil {
+			return nil, err
+		}
+		for _, f := range page.Files {
+			items = append(items, f.item())
+		}
+		if page.NextPageToken == "" {
+			return items, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+func (f driveFile) item() Item {
+	size, _ := strconv.ParseInt(f.Size, 10, 64)
+	modified, _ := time.Parse(time.RFC3339, f.ModifiedTime)
+	return Item{
+		ID:          f.ID,
+		Name:        f.Name,
+		Size:        size,
+		ContentType: f.MimeType,
+		Modified:    modified,
+		Folder:      f.MimeType == googleAppsType+"folder",
+	}
+}
+
+// Open download the content of item, ErrNotDownloadable for folders and Google Docs documents
+func (d *GoogleDrive) Open(ctx context.Context, item Item) (io.ReadCloser, error) {
+	if item.Folder || strings.HasPrefix(item.ContentType, googleAppsType) {
+		return nil, ErrNotDownloadable
+	}
+	u := d.BaseURL + "/files/" + url.PathEscape(item.ID) + "?alt=media"
+	resp, err := do(ctx, d.Client, d.Name(), apiRequest{Method: http.MethodGet, URL: u}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,88 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// OneDriveURL is the Microsoft Graph drive of the signed in user
+const OneDriveURL = "https://graph.microsoft.com/v1.0/me/drive"
+
+// OneDrive list and download OneDrive and SharePoint files with the OAuth token of ctx,
+// it needs the Files.Read or Files.Read.All permission
+type OneDrive struct {
+	Client *http.Client
+	// BaseURL is the drive, e.g. OneDriveURL or "https://graph.microsoft.com/v1.0/drives/{drive-id}"
+	BaseURL string
+}
+
+// NewOneDrive create a OneDrive source of the signed in user drive, sending requests with client,
+// http.DefaultClient when nil
+func NewOneDrive(client *http.Client) *OneDrive {
+	return &OneDrive{Client: client, BaseURL: OneDriveURL}
+}
+
+// Name return "onedrive"
+func (d *OneDrive) Name() string {
+	return "onedrive"
+}
+
+type driveItem struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	File                 *struct {
+		MimeType string `json:"mimeType"`
+	} `json:"file"`
+	Folder *struct{} `json:"folder"`
+}
+
+// List return the items of the folder id, the drive root when empty
+func (d *OneDrive) List(ctx context.Context, folder string) ([]Item, error) {
+	next := d.BaseURL + "/root/children?$top=999"
+	if folder != "" {
+		next = d.BaseURL + "/items/" + url.PathEscape(folder) + "/children?$top=999"
+	}
+
+	var items []Item
+	for next != "" {
+		var page struct {
+			NextLink string      `json:"@odata.nextLink"`
+			Value    []driveItem `json:"value"`
+		}
+		if _, err := do(ctx, d.Client, d.Name(), apiRequest{Method: http.MethodGet, URL: next}, &page); err != nil {
+			return nil, err
+		}
+		for _, it := range page.Value {
+			items = append(items, it.item())
+		}
+		next = page.NextLink
+	}
+	return items, nil
+}
+
+func (it driveItem) item() Item {
+	item := Item{ID: it.ID, Name: it.Name, Size: it.Size, Modified: it.LastModifiedDateTime, Folder: it.Folder != nil}
+	if it.File != nil {
+		item.ContentType = it.File.MimeType
+	}
+	return item
+}
+
+// Open download the content of item, ErrNotDownloadable for folders.
+// Graph redirect to a pre-authenticated url, the token is not forwarded to it.
+func (d *OneDrive) Open(ctx context.Context, item Item) (io.ReadCloser, error) {
+	if item.Folder {
+		return nil, ErrNotDownloadable
+	}
+	u := d.BaseURL + "/items/" + url.PathEscape(item.ID) + "/content"
+	resp, err := do(ctx, d.Client, d.Name(), apiRequest{Method: http.MethodGet, URL: u}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
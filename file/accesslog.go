@@ -0,0 +1,322 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessOp is the kind of a logged request
+type AccessOp string
+
+const (
+	AccessRead   AccessOp = "read"
+	AccessWrite  AccessOp = "write"
+	AccessDelete AccessOp = "delete"
+	AccessOther  AccessOp = "other"
+)
+
+// Storage tiers recommended by RecommendTiers
+const (
+	TierCool    = "Cool"
+	TierArchive = "Archive"
+)
+
+// AccessRecord is one request of a storage access log
+type AccessRecord struct {
+	Time      time.Time
+	Op        AccessOp
+	Operation string
+	Container string
+	Key       string
+	Status    int
+	Bytes     int64
+}
+
+// AzureLogPrefix return the $logs container prefix of the Storage Analytics blob logs of the hour of t
+func AzureLogPrefix(t time.Time) string {
+	return t.UTC().Format("blob/2006/01/02/15")
+}
+
+// ReadAccessLog call fn for every request of a Storage Analytics log (versions 1.0 and 2.0)
+// or S3 server access log file, the format is detected per line and ".gz" files are decompressed.
+// Lines of other formats are ignored.
+func ReadAccessLog(ctx context.Context, store Opener, name string, fn func(AccessRecord) error) error {
+	rc, err := store.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rec, ok := parseAccessLine(sc.Text())
+		if !ok {
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func parseAccessLine(line string) (AccessRecord, bool) {
+	if strings.HasPrefix(line, "1.0;") || strings.HasPrefix(line, "2.0;") {
+		return parseAzureLog(line)
+	}
+	return parseS3Log(line)
+}
+
+// parseAzureLog parse a semicolon delimited Storage Analytics line, quoted fields may hold semicolons
+func parseAzureLog(line string) (AccessRecord, bool) {
+	fields := splitLog(line, ';')
+	if len(fields) < 21 || fields[10] != "blob" {
+		return AccessRecord{}, false
+	}
+
+	rec := AccessRecord{Operation: fields[2]}
+	rec.Time, _ = time.Parse(time.RFC3339Nano, fields[1])
+	rec.Status, _ = strconv.Atoi(fields[4])
+	rec.Bytes, _ = strconv.ParseInt(fields[20], 10, 64)
+
+	// requested object key is /account/container/blob
+	parts := strings.SplitN(strings.TrimPrefix(fields[12], "/"), "/", 3)
+	if len(parts) > 1 {
+		rec.Container = parts[1]
+	}
+	if len(parts) > 2 {
+		rec.Key = parts[2]
+	}
+
+	switch rec.Operation {
+	case "GetBlob":
+		rec.Op = AccessRead
+	case "PutBlob", "PutBlock", "PutBlockList", "CopyBlob", "AppendBlock", "PutPage":
+		rec.Op = AccessWrite
+	case "DeleteBlob":
+		rec.Op = AccessDelete
+	default:
+		rec.Op = AccessOther
+	}
+	return rec, true
+}
+
+// parseS3Log parse a space delimited S3 server access log line, the time is [bracketed] and the request uri quoted
+func parseS3Log(line string) (AccessRecord, bool) {
+	fields := splitLog(line, ' ')
+	if len(fields) < 12 || !strings.HasPrefix(fields[6], "REST.") {
+		return AccessRecord{}, false
+	}
+
+	rec := AccessRecord{Operation: fields[6], Container: fields[1]}
+	rec.Time, _ = time.Parse("02/Jan/2006:15:04:05 -0700", fields[2])
+	rec.Status, _ = strconv.Atoi(fields[9])
+	rec.Bytes, _ = strconv.ParseInt(fields[11], 10, 64)
+	if fields[7] != "-" {
+		rec.Key = fields[7]
+		// S3 log keys are url encoded
+		if key, err := url.QueryUnescape(fields[7]); err == nil {
+			rec.Key = key
+		}
+	}
+
+	switch rec.Operation {
+	case "REST.GET.OBJECT":
+		rec.Op = AccessRead
+	case "REST.PUT.OBJECT", "REST.POST.UPLOAD", "REST.COPY.OBJECT", "REST.COPY.OBJECT_GET":
+		rec.Op = AccessWrite
+	case "REST.DELETE.OBJECT":
+		rec.Op = AccessDelete
+	default:
+		rec.Op = AccessOther
+	}
+	return rec, true
+}
+
+// splitLog split line on sep outside of "quoted" and [bracketed] fields, removing the quotes and brackets
+func splitLog(line string, sep byte) []string {
+	var fields []string
+	var field strings.Builder
+	var closing byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case closing != 0 && c == closing:
+			closing = 0
+		case closing != 0:
+			field.WriteByte(c)
+		case c == '"' && field.Len() == 0:
+			closing = '"'
+		case c == '[' && field.Len() == 0 && sep == ' ':
+			closing = ']'
+		case c == sep:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// KeyAccess is the access counts of one key
+type KeyAccess struct {
+	Key        string
+	Reads      int64
+	Writes     int64
+	Bytes      int64
+	LastAccess time.Time
+}
+
+// Heatmap count successful requests per key from access logs, to pick the keys worth
+// pre-warming in caches and the ones worth moving to a cooler tier. Safe for concurrent use.
+type Heatmap struct {
+	mu   sync.RWMutex
+	keys map[string]*KeyAccess
+}
+
+// NewHeatmap create an empty heatmap
+func NewHeatmap() *Heatmap {
+	return &Heatmap{keys: map[string]*KeyAccess{}}
+}
+
+// Add count rec when it succeeded, deletes forget the key
+func (h *Heatmap) Add(rec AccessRecord) {
+	if rec.Key == "" || rec.Status >= 400 || rec.Op == AccessOther {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if rec.Op == AccessDelete {
+		delete(h.keys, rec.Key)
+		return
+	}
+	k, ok := h.keys[rec.Key]
+	if !ok {
+		k = &KeyAccess{Key: rec.Key}
+		h.keys[rec.Key] = k
+	}
+	if rec.Op == AccessRead {
+		k.Reads++
+		k.Bytes += rec.Bytes
+	} else {
+		k.Writes++
+	}
+	if rec.Time.After(k.LastAccess) {
+		k.LastAccess = rec.Time
+	}
+}
+
+// ReadLogs add every request of the access log files names
+//
+//	Example:
+//	logs := file.New(account, accessKey, rootURL, "$logs", apiVersion).(*file.File)
+//	list, err := logs.List(ctx, file.AzureLogPrefix(time.Now().Add(-time.Hour)))
+//	for _, f := range list {
+//		err = heatmap.ReadLogs(ctx, logs, f.Name)
+//	}
+func (h *Heatmap) ReadLogs(ctx context.Context, store Opener, names ...string) error {
+	for _, name := range names {
+		if err := ReadAccessLog(ctx, store, name, func(rec AccessRecord) error {
+			h.Add(rec)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("file: access log %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Get return the access counts of key
+func (h *Heatmap) Get(key string) (KeyAccess, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	k, ok := h.keys[key]
+	if !ok {
+		return KeyAccess{}, false
+	}
+	return *k, true
+}
+
+// Hottest return the n most read keys, most read first
+func (h *Heatmap) Hottest(n int) []KeyAccess {
+	h.mu.RLock()
+	all := make([]KeyAccess, 0, len(h.keys))
+	for _, k := range h.keys {
+		all = append(all, *k)
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Reads != all[j].Reads {
+			return all[i].Reads > all[j].Reads
+		}
+		return all[i].Key < all[j].Key
+	})
+	if n >= 0 && n < len(all) {
+		all = all[:n]
+	}
+	return all
+}
+
+// HottestKeys return the keys of the n most read keys, e.g. to pre-warm a cache
+func (h *Heatmap) HottestKeys(n int) []string {
+	hottest := h.Hottest(n)
+	keys := make([]string, len(hottest))
+	for i, k := range hottest {
+		keys[i] = k.Key
+	}
+	return keys
+}
+
+// TierRecommendation is the tier a file should move to
+type TierRecommendation struct {
+	Key        string
+	Tier       string
+	LastAccess time.Time
+}
+
+// RecommendTiers return the files not accessed, nor modified, for coolAfter as TierCool
+// and for archiveAfter as TierArchive. archiveAfter 0 never recommend archiving.
+// The heatmap must cover the period, files missing from the logs are treated as never read.
+func (h *Heatmap) RecommendTiers(files []ObjectInfo, coolAfter, archiveAfter time.Duration, now time.Time) []TierRecommendation {
+	var recs []TierRecommendation
+	for _, f := range files {
+		last := f.LastModified
+		if k, ok := h.Get(f.Name); ok && k.LastAccess.After(last) {
+			last = k.LastAccess
+		}
+		idle := now.Sub(last)
+		switch {
+		case archiveAfter > 0 && idle >= archiveAfter:
+			recs = append(recs, TierRecommendation{Key: f.Name, Tier: TierArchive, LastAccess: last})
+		case idle >= coolAfter:
+			recs = append(recs, TierRecommendation{Key: f.Name, Tier: TierCool, LastAccess: last})
+		}
+	}
+	return recs
+}
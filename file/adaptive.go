@@ -0,0 +1,163 @@
+package file
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultAdaptiveCooldown is the minimum time between two concurrency decreases
+const DefaultAdaptiveCooldown = time.Second
+
+// IsThrottled report whether err is storage asking the client to slow down:
+// ErrThrottled, 429 Too Many Requests, 503 Server Busy or a throttling error code, see Classify
+func IsThrottled(err error) bool {
+	return Classify(err) == ClassThrottled
+}
+
+// AdaptiveLimiter bound concurrent operations with a limit adjusted by throttling feedback (AIMD):
+// the limit grow by one after a full window of successful operations and is halved when storage throttle.
+type AdaptiveLimiter struct {
+	Min int
+	Max int
+	// Cooldown is the minimum time between two decreases, so a burst of throttled
+	// operations started at the same limit only halve it once
+	Cooldown time.Duration
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	limit        float64
+	inflight     int
+	lastDecrease time.Time
+}
+
+// NewAdaptiveLimiter create limiter starting at min concurrent operations, growing up to max
+//
+//	Example:
+//	limiter := file.NewAdaptiveLimiter(4, 256)
+//	err := file.Walk(ctx, store, "products/", retag, file.WalkOptions{Workers: 256, Limiter: limiter})
+func NewAdaptiveLimiter(min, max int) *AdaptiveLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	l := &AdaptiveLimiter{Min: min, Max: max, Cooldown: DefaultAdaptiveCooldown, limit: float64(min)}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Limit return the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire wait until an operation can start, every successful Acquire must be followed by Done
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	// wake waiters when ctx is canceled
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.cond.Broadcast()
+			l.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inflight >= int(l.limit) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	l.inflight++
+	return nil
+}
+
+// Done release an operation and adjust the limit from its error
+func (l *AdaptiveLimiter) Done(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	switch {
+	case IsThrottled(err):
+		if time.Since(l.lastDecrease) >= l.Cooldown {
+			l.lastDecrease = time.Now()
+			l.limit /= 2
+			if l.limit < float64(l.Min) {
+				l.limit = float64(l.Min)
+			}
+		}
+	case err == nil:
+		l.limit += 1 / l.limit
+		if l.limit > float64(l.Max) {
+			l.limit = float64(l.Max)
+		}
+	}
+	l.cond.Broadcast()
+}
+
+// Adaptive wrap an IFile running transfers through an AdaptiveLimiter
+type Adaptive struct {
+	IFile
+	Limiter *AdaptiveLimiter
+}
+
+// NewAdaptive wrap next limiting concurrent uploads, downloads, copies and deletes with limiter
+func NewAdaptive(next IFile, limiter *AdaptiveLimiter) *Adaptive {
+	return &Adaptive{IFile: next, Limiter: limiter}
+}
+
+// Unwrap return the wrapped store
+func (a *Adaptive) Unwrap() IFile {
+	return a.IFile
+}
+
+// Upload file once the limiter allow it
+func (a *Adaptive) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	if err := a.Limiter.Acquire(ctx); err != nil {
+		return "", err
+	}
+	url, err := a.IFile.Upload(ctx, filePath, contentType, buffBytes)
+	a.Limiter.Done(err)
+	return url, err
+}
+
+// Download file once the limiter allow it
+func (a *Adaptive) Download(ctx context.Context, filePath string) ([]byte, error) {
+	if err := a.Limiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	data, err := a.IFile.Download(ctx, filePath)
+	a.Limiter.Done(err)
+	return data, err
+}
+
+// Copy file once the limiter allow it
+func (a *Adaptive) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	if err := a.Limiter.Acquire(ctx); err != nil {
+		return "", err
+	}
+	url, err := a.IFile.Copy(ctx, srcPath, dstPath)
+	a.Limiter.Done(err)
+	return url, err
+}
+
+// Delete file once the limiter allow it
+func (a *Adaptive) Delete(ctx context.Context, filePath string) (string, error) {
+	if err := a.Limiter.Acquire(ctx); err != nil {
+		return "", err
+	}
+	url, err := a.IFile.Delete(ctx, filePath)
+	a.Limiter.Done(err)
+	return url, err
+}
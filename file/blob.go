@@ -0,0 +1,70 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"time"
+)
+
+// Blob describe a stored upload, persist it to verify later downloads of the same content
+type Blob struct {
+	URL  string
+	Key  string
+	Size int64
+	// ETag is the version of the file returned by storage, empty when the store does not report it
+	ETag string
+	// ContentMD5 is the MD5 of the uploaded content, stored as the file Content-MD5 on Azure,
+	// or as its MetadataContentMD5 metadata when compressed with a Content-Encoding
+	ContentMD5   []byte
+	LastModified time.Time
+}
+
+// MD5 return ContentMD5 base64 encoded, as in the Content-MD5 header
+func (b Blob) MD5() string {
+	return base64.StdEncoding.EncodeToString(b.ContentMD5)
+}
+
+// Verify return ErrChecksumMismatch when data is not the uploaded content
+func (b Blob) Verify(data []byte) error {
+	sum := md5.Sum(data)
+	if int64(len(data)) != b.Size || !bytes.Equal(sum[:], b.ContentMD5) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// BlobUploader is implemented by stores returning the ETag of uploads, e.g. *File
+type BlobUploader interface {
+	UploadBlob(ctx context.Context, filePath, contentType string, buffBytes []byte) (Blob, error)
+}
+
+// UploadBlob upload buffBytes to store and return its Blob. Stores not implementing BlobUploader are uploaded to
+// with Upload, their ETag is then listed when they report it.
+//
+//	Example:
+//	blob, err := file.UploadBlob(ctx, store, "exports/2020.csv", "text/csv", data)
+//	record.ETag, record.MD5 = blob.ETag, blob.MD5()
+//	...
+//	data, err = store.Download(ctx, "exports/2020.csv")
+//	err = blob.Verify(data)
+func UploadBlob(ctx context.Context, store IFile, filePath, contentType string, buffBytes []byte) (Blob, error) {
+	if u, ok := store.(BlobUploader); ok {
+		return u.UploadBlob(ctx, filePath, contentType, buffBytes)
+	}
+	url, err := store.Upload(ctx, filePath, contentType, buffBytes)
+	if err != nil {
+		return Blob{}, err
+	}
+	blob := newBlob(url, filePath, buffBytes)
+	if infos, err := store.List(ctx, filePath, WithMaxResults(1)); err == nil && len(infos) > 0 && infos[0].Name == filePath {
+		blob.ETag, blob.LastModified = infos[0].ETag, infos[0].LastModified
+	}
+	return blob, nil
+}
+
+func newBlob(url, key string, buffBytes []byte) Blob {
+	sum := md5.Sum(buffBytes)
+	return Blob{URL: url, Key: key, Size: int64(len(buffBytes)), ContentMD5: sum[:]}
+}
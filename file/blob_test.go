@@ -0,0 +1,47 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadBlobCompressedMD5(t *testing.T) {
+	content := bytes.Repeat([]byte("orders,2020-06\n"), 200)
+	var header http.Header
+	var stored []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		stored, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("ETag", `"0x8D8"`)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12", WithCompression("gzip")).(*File)
+	blob, err := c.UploadBlob(context.Background(), "lake/orders.csv", "text/csv", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, compressed := md5.Sum(content), md5.Sum(stored)
+	if !bytes.Equal(blob.ContentMD5, plain[:]) {
+		t.Errorf("Blob.ContentMD5 = %s, want the MD5 of the uncompressed content", hex.EncodeToString(blob.ContentMD5))
+	}
+	if got := header.Get("x-ms-blob-content-md5"); got != base64.StdEncoding.EncodeToString(compressed[:]) {
+		t.Errorf("Content-MD5 = %s, want the MD5 of the stored compressed bytes", got)
+	}
+	if got := header.Get("x-ms-meta-" + MetadataContentMD5); got != blob.MD5() {
+		t.Errorf("%s metadata = %q, want %s", MetadataContentMD5, got, blob.MD5())
+	}
+	if header.Get("x-ms-blob-content-encoding") != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", header.Get("x-ms-blob-content-encoding"))
+	}
+}
@@ -0,0 +1,182 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BundleEntry is one asset of a bundle manifest
+type BundleEntry struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex SHA-256 of the content
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url"`
+}
+
+// Verify return ErrChecksumMismatch when data is not the content of e
+func (e BundleEntry) Verify(data []byte) error {
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != e.Size || hex.EncodeToString(sum[:]) != e.SHA256 {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// BundleManifest list the assets of a bundle with their checksums, signed with ed25519 so clients,
+// e.g. a desktop app shipping the public key, detect tampered manifests and assets
+type BundleManifest struct {
+	Name      string        `json:"name"`
+	CreatedAt time.Time     `json:"created_at"`
+	Entries   []BundleEntry `json:"entries"`
+	// KeyID name the signing key, so clients pick the public key among rotated ones
+	KeyID string `json:"key_id,omitempty"`
+	// Signature is the base64 ed25519 signature of SigningPayload
+	Signature string `json:"signature"`
+}
+
+// BundleOptions configure BuildBundle
+type BundleOptions struct {
+	// Name of the bundle, e.g. "desktop-2.4.0"
+	Name string
+	// URLExpiry presign the entry urls for that long, public urls when 0
+	URLExpiry time.Duration
+	// Workers is the number of assets downloaded at once, DefaultWalkWorkers when 0
+	Workers int
+}
+
+// BuildBundle download the assets of keys from store to checksum them and return their manifest, sorted by key.
+// Sign it before publishing.
+//
+//	Example:
+//	m, err := file.BuildBundle(ctx, store, keys, file.BundleOptions{Name: "desktop-2.4.0", URLExpiry: 24 * time.Hour})
+//	err = m.Sign("2020-06", privateKey)
+//	data, err := json.Marshal(m)
+//	url, err := store.Upload(ctx, "bundles/desktop-2.4.0.json", "application/json", data)
+func BuildBundle(ctx context.Context, store IFile, keys []string, opts BundleOptions) (*BundleManifest, error) {
+	urls := map[string]string{}
+	if opts.URLExpiry > 0 {
+		var err error
+		if urls, err = store.PresignMany(ctx, keys, opts.URLExpiry); err != nil {
+			return nil, err
+		}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWalkWorkers
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		entries  = make([]BundleEntry, 0, len(keys))
+		next     = make(chan string)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range next {
+				data, err := store.Download(ctx, key)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = FileError{Path: key, Err: err}
+					}
+				} else {
+					sum := sha256.Sum256(data)
+					url, ok := urls[key]
+					if !ok {
+						url = store.GetBlobURL(key, false)
+					}
+					entries = append(entries, BundleEntry{Key: key, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), URL: url})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed || ctx.Err() != nil {
+			break
+		}
+		next <- key
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return &BundleManifest{Name: opts.Name, CreatedAt: time.Now().UTC().Truncate(time.Second), Entries: entries}, nil
+}
+
+// SigningPayload return the bytes signed, simple to rebuild in any language:
+// the name, the RFC 3339 creation time and the key id on a line each, then a line per entry of
+// its key, size, SHA-256 and url separated by tabs, every line ending with "\n"
+func (m *BundleManifest) SigningPayload() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(m.Name + "\n" + m.CreatedAt.UTC().Format(time.RFC3339) + "\n" + m.KeyID + "\n")
+	for _, e := range m.Entries {
+		buf.WriteString(e.Key + "\t" + strconv.FormatInt(e.Size, 10) + "\t" + e.SHA256 + "\t" + e.URL + "\n")
+	}
+	return buf.Bytes()
+}
+
+// Sign set KeyID and the signature of m with key
+func (m *BundleManifest) Sign(keyID string, key ed25519.PrivateKey) error {
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("file: bundle signing key has %d bytes, want %d", len(key), ed25519.PrivateKeySize)
+	}
+	m.KeyID = keyID
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, m.SigningPayload()))
+	return nil
+}
+
+// Verify return ErrSignatureMismatch when m was not signed by the private key of pub or changed since
+func (m *BundleManifest) Verify(pub ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, m.SigningPayload(), sig) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// ParseBundle decode a JSON bundle manifest and verify its signature with the public key of its KeyID in keys
+//
+//	Example:
+//	m, err := file.ParseBundle(data, map[string]ed25519.PublicKey{"2020-06": publicKey})
+//	for _, e := range m.Entries {
+//		err = e.Verify(downloaded[e.Key])
+//	}
+func ParseBundle(data []byte, keys map[string]ed25519.PublicKey) (*BundleManifest, error) {
+	var m BundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("file: decode bundle manifest: %v", err)
+	}
+	pub, ok := keys[m.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrSignatureMismatch, m.KeyID)
+	}
+	if err := m.Verify(pub); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
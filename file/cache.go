@@ -0,0 +1,262 @@
+package file
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPrewarmWorkers is the number of files Prewarm download at once without a limiter
+const DefaultPrewarmWorkers = 8
+
+// Cache keep file contents close to the application, e.g. in memory or on a local disk
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
+	Delete(key string)
+}
+
+// MemoryCache is an in process least recently used Cache bounded in bytes
+type MemoryCache struct {
+	MaxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewMemoryCache create cache holding up to maxBytes of file contents
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{MaxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get return the cached content of key
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).data, true
+}
+
+// Set cache data for key, evicting the least recently used contents over MaxBytes.
+// Contents larger than MaxBytes are not cached.
+func (m *MemoryCache) Set(key string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	if int64(len(data)) > m.MaxBytes {
+		return
+	}
+	m.entries[key] = m.order.PushFront(&cacheEntry{key: key, data: data})
+	m.size += int64(len(data))
+	for m.size > m.MaxBytes {
+		m.remove(m.order.Back().Value.(*cacheEntry).key)
+	}
+}
+
+// Delete remove key from the cache
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	m.remove(key)
+	m.mu.Unlock()
+}
+
+// remove key, m.mu must be held
+func (m *MemoryCache) remove(key string) {
+	e, ok := m.entries[key]
+	if !ok {
+		return
+	}
+	m.order.Remove(e)
+	delete(m.entries, key)
+	m.size -= int64(len(e.Value.(*cacheEntry).data))
+}
+
+// Prefetcher load urls into a CDN edge ahead of traffic
+type Prefetcher interface {
+	Prefetch(ctx context.Context, urls []string) error
+}
+
+// HTTPPrefetcher request every url through the CDN, which cache them at the edge serving the request
+type HTTPPrefetcher struct {
+	Client *http.Client
+	// BaseURL replace StorageURL at the start of file urls with the CDN endpoint, e.g. "https://cdn.example.com"
+	BaseURL    string
+	StorageURL string
+}
+
+// Prefetch GET every url, discarding the content
+func (p *HTTPPrefetcher) Prefetch(ctx context.Context, urls []string) error {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	for _, u := range urls {
+		if p.BaseURL != "" && p.StorageURL != "" && strings.HasPrefix(u, p.StorageURL) {
+			u = p.BaseURL + strings.TrimPrefix(u, p.StorageURL)
+		}
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("file: prefetch %s: %s", u, resp.Status)
+		}
+	}
+	return nil
+}
+
+// Cached wrap an IFile serving downloads from Cache, writes invalidate the cached content
+type Cached struct {
+	IFile
+	Cache Cache
+	// Limiter govern Prewarm downloads, DefaultPrewarmWorkers at once when nil
+	Limiter *AdaptiveLimiter
+	// CDN is asked to prefetch the warmed files when set
+	CDN Prefetcher
+	// Policy is the CachePolicy of downloads whose context has none set by WithCachePolicy
+	Policy CachePolicy
+
+	mu           sync.Mutex
+	meta         map[string]cacheMeta
+	revalidating map[string]bool
+	writes       uint64
+}
+
+// NewCached wrap next caching downloads in cache
+//
+//	Example:
+//	store := file.NewCached(store, file.NewMemoryCache(512<<20))
+//	err := store.Prewarm(ctx, heatmap.HottestKeys(1000))
+func NewCached(next IFile, cache Cache) *Cached {
+	return &Cached{IFile: next, Cache: cache}
+}
+
+// Unwrap return the wrapped store
+func (c *Cached) Unwrap() IFile {
+	return c.IFile
+}
+
+// Download file from the cache, caching it on a miss.
+// Content older than the MaxAge of the CachePolicy is refreshed, or served stale within its stale durations.
+func (c *Cached) Download(ctx context.Context, filePath string) ([]byte, error) {
+	data, _, err := c.load(ctx, filePath, false)
+	return data, err
+}
+
+// Upload file and invalidate its cached content
+func (c *Cached) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	defer c.invalidate(filePath)
+	return c.IFile.Upload(ctx, filePath, contentType, buffBytes)
+}
+
+// Delete file and its cached content
+func (c *Cached) Delete(ctx context.Context, filePath string) (string, error) {
+	defer c.invalidate(filePath)
+	return c.IFile.Delete(ctx, filePath)
+}
+
+// Copy file and invalidate the cached content of the destination
+func (c *Cached) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	defer c.invalidate(dstPath)
+	return c.IFile.Copy(ctx, srcPath, dstPath)
+}
+
+// Prewarm download keys not cached yet into the cache ahead of a traffic spike, then ask CDN to prefetch them.
+// Downloads go through Limiter, every key is tried and the first error is returned with the failure count.
+func (c *Cached) Prewarm(ctx context.Context, keys []string) error {
+	var (
+		mu       sync.Mutex
+		firstErr error
+		failed   int
+		wg       sync.WaitGroup
+	)
+	fail := func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", key, err)
+		}
+		failed++
+	}
+
+	slots := make(chan struct{}, DefaultPrewarmWorkers)
+	acquire := func() error {
+		if c.Limiter != nil {
+			return c.Limiter.Acquire(ctx)
+		}
+		select {
+		case slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	release := func(err error) {
+		if c.Limiter != nil {
+			c.Limiter.Done(err)
+		} else {
+			<-slots
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := c.Cache.Get(key); ok {
+			continue
+		}
+		if err := acquire(); err != nil {
+			fail(key, err)
+			break
+		}
+
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			data, err := c.IFile.Download(ctx, key)
+			release(err)
+			if err != nil {
+				fail(key, err)
+				return
+			}
+			c.set(key, data, "")
+		}(key)
+	}
+	wg.Wait()
+
+	if c.CDN != nil && ctx.Err() == nil {
+		urls := make([]string, len(keys))
+		for i, key := range keys {
+			urls[i] = c.GetBlobURL(key, false)
+		}
+		if err := c.CDN.Prefetch(ctx, urls); err != nil {
+			fail("cdn", err)
+		}
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("file: prewarm failed for %d keys, %v", failed, firstErr)
+	}
+	return nil
+}
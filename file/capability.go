@@ -0,0 +1,104 @@
+package file
+
+import (
+	"context"
+	"strings"
+)
+
+// CapabilitySet is the optional features of a store, so generic tooling can adapt instead of failing with ErrNotSupported
+type CapabilitySet uint32
+
+const (
+	// CapVersioning is the history of files, see VersionLister and ListAsOf
+	CapVersioning CapabilitySet = 1 << iota
+	// CapTags is index tags set on files and searched, see SetTags and FindByTags
+	CapTags
+	// CapPresign is signed urls granting temporary access to private files
+	CapPresign
+	// CapAppend is appending to stored files without rewriting them
+	CapAppend
+	// CapSelect is querying the content of files on the storage side
+	CapSelect
+	// CapLocking is legal holds and retention policies, deleting a held file fail with a *HoldError
+	CapLocking
+	// CapMultipart is uploading parts committed at once, see MultipartUploader
+	CapMultipart
+	// CapStream is uploading from a reader, see StreamUploader and ObjectWriterCreator
+	CapStream
+	// CapEncryptionScope is rewriting files with another encryption key
+	CapEncryptionScope
+)
+
+var capabilityNames = []string{"versioning", "tags", "presign", "append", "select", "locking", "multipart", "stream", "encryption-scope"}
+
+// Has report whether every capability of c is in s
+func (s CapabilitySet) Has(c CapabilitySet) bool {
+	return s&c == c
+}
+
+// String list the capabilities of s, e.g. "presign,locking"
+func (s CapabilitySet) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if s&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Capabler is implemented by stores reporting their capabilities
+type Capabler interface {
+	Capabilities() CapabilitySet
+}
+
+// tagger is a store with index tags, implemented by *File
+type tagger interface {
+	SetTags(ctx context.Context, filePath string, tags Tags) error
+	GetTags(ctx context.Context, filePath string) (Tags, error)
+}
+
+// Capabilities return the capabilities of store: those it reports as a Capabler, or else the ones of the
+// optional interfaces it implement. Capabilities are not inherited through Unwrap: a decorator such as Guard
+// or CryptoShredder report its own set, none unless it implement them, as the wrapped features bypass it.
+//
+//	Example:
+//	if file.Capabilities(store).Has(file.CapVersioning) {
+//		versions, err = file.ListAsOf(ctx, store, prefix, t)
+//	}
+func Capabilities(store IFile) CapabilitySet {
+	if c, ok := store.(Capabler); ok {
+		return c.Capabilities()
+	}
+
+	var caps CapabilitySet
+	if _, ok := store.(VersionLister); ok {
+		caps |= CapVersioning
+	}
+	if _, ok := store.(tagger); ok {
+		caps |= CapTags
+	}
+	if _, ok := store.(MultipartUploader); ok {
+		caps |= CapMultipart
+	}
+	if _, ok := store.(StreamUploader); ok {
+		caps |= CapStream
+	}
+	if _, ok := store.(ObjectWriterCreator); ok {
+		caps |= CapStream
+	}
+	if _, ok := store.(encryptionScoper); ok {
+		caps |= CapEncryptionScope
+	}
+	return caps
+}
+
+// Capabilities return the features of Azure blob storage the client support.
+// Versioning rely on blob soft delete being enabled on the account.
+func (c *File) Capabilities() CapabilitySet {
+	if c.Anonymous {
+		// public reads only
+		return 0
+	}
+	return CapVersioning | CapTags | CapPresign | CapLocking | CapMultipart | CapStream | CapEncryptionScope
+}
@@ -0,0 +1,27 @@
+package file
+
+import "testing"
+
+func TestCapabilitiesNotInherited(t *testing.T) {
+	store := newTestFile()
+	if caps := Capabilities(store); !caps.Has(CapPresign | CapStream | CapMultipart) {
+		t.Fatalf("Capabilities(*File) = %s", caps)
+	}
+
+	decorators := map[string]IFile{
+		"Guard":          NewGuard(store),
+		"CryptoShredder": NewCryptoShredder(store, nil),
+		"Inspected":      NewInspected(store, nil, InspectFlag),
+		"Counted":        NewCounted(store, nil),
+	}
+	for name, d := range decorators {
+		if caps := Capabilities(d); caps != 0 {
+			t.Errorf("Capabilities(%s over *File) = %s, want none", name, caps)
+		}
+	}
+
+	public := NewPublic("account", "https://%s.blob.core.windows.net/%s", "container")
+	if caps := Capabilities(public); caps != 0 {
+		t.Errorf("Capabilities(NewPublic) = %s, want none", caps)
+	}
+}
@@ -0,0 +1,257 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRecordNotFound returned by Catalog when a key has no record
+var ErrRecordNotFound = errors.New("file: catalog record not found")
+
+// RecordStatus is the state of a catalog record
+type RecordStatus string
+
+const (
+	// RecordPending is written before the file is uploaded
+	RecordPending RecordStatus = "pending"
+	// RecordReady is written once the file is stored
+	RecordReady RecordStatus = "ready"
+	// RecordDeleting is written before the file is deleted
+	RecordDeleting RecordStatus = "deleting"
+)
+
+// CatalogRecord is the application database row describing a stored file
+type CatalogRecord struct {
+	Key         string
+	Status      RecordStatus
+	URL         string
+	ContentType string
+	Size        int64
+	ETag        string
+	UpdatedAt   time.Time
+}
+
+// Catalog is the application database keeping one record per stored file, implement it on top of your own tables
+type Catalog interface {
+	// Put insert or replace the record of rec.Key
+	Put(ctx context.Context, rec CatalogRecord) error
+	// Get return the record of key, ErrRecordNotFound if there is none
+	Get(ctx context.Context, key string) (CatalogRecord, error)
+	// Remove delete the record of key, removing a missing record is not an error
+	Remove(ctx context.Context, key string) error
+	// Stale return records not ready and last updated before t
+	Stale(ctx context.Context, before time.Time) ([]CatalogRecord, error)
+}
+
+// Publish store a file and its catalog record so that neither exist without the other:
+// a pending record is written first, then the file is uploaded and the record marked ready.
+// If the process dies in between, Repair finish or undo the operation from the pending record.
+//
+//	Example:
+//	rec, err := file.Publish(ctx, catalog, store, "products/42/original.jpg", "image/jpeg", buffBytes)
+func Publish(ctx context.Context, catalog Catalog, store IFile, key, contentType string, buffBytes []byte) (CatalogRecord, error) {
+	rec := CatalogRecord{Key: key, Status: RecordPending, ContentType: contentType, UpdatedAt: time.Now()}
+	if err := catalog.Put(ctx, rec); err != nil {
+		return rec, err
+	}
+
+	url, err := store.Upload(ctx, key, contentType, buffBytes)
+	if err != nil {
+		// the upload may have been stored before the error, leave the record for Repair unless it surely was not
+		if _, statErr := stat(ctx, store, key); statErr == ErrNotFound {
+			catalog.Remove(ctx, key)
+		}
+		return rec, err
+	}
+
+	rec.Status = RecordReady
+	rec.URL = url
+	rec.Size = int64(len(buffBytes))
+	rec.UpdatedAt = time.Now()
+	return rec, catalog.Put(ctx, rec)
+}
+
+// Unpublish delete a file and its catalog record: the record is marked deleting,
+// then the file is deleted and the record removed. Repair finish interrupted deletes.
+func Unpublish(ctx context.Context, catalog Catalog, store IFile, key string) error {
+	rec, err := catalog.Get(ctx, key)
+	if err == ErrRecordNotFound {
+		rec = CatalogRecord{Key: key}
+	} else if err != nil {
+		return err
+	}
+
+	rec.Status = RecordDeleting
+	rec.UpdatedAt = time.Now()
+	if err := catalog.Put(ctx, rec); err != nil {
+		return err
+	}
+
+	if _, err := store.Delete(ctx, key); err != nil && !isNotFound(err) {
+		return err
+	}
+	return catalog.Remove(ctx, key)
+}
+
+// RepairReport count the records fixed by Repair
+type RepairReport struct {
+	// Finalized pending records whose file exists, marked ready
+	Finalized int
+	// Removed pending records whose file does not exist and finished deletes
+	Removed int
+	// Failed records are left for the next run
+	Failed int
+	Errors []error
+}
+
+// Repair fix records left pending or deleting for longer than age by interrupted Publish and Unpublish.
+// Pending records are marked ready when their file exists and removed otherwise, deletes are completed.
+// Run it periodically, age must be longer than the slowest upload.
+func Repair(ctx context.Context, catalog Catalog, store IFile, age time.Duration) (RepairReport, error) {
+	var report RepairReport
+
+	stale, err := catalog.Stale(ctx, time.Now().Add(-age))
+	if err != nil {
+		return report, err
+	}
+
+	for _, rec := range stale {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		if err := repairRecord(ctx, catalog, store, rec, &report); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, err)
+		}
+	}
+
+	return report, nil
+}
+
+func repairRecord(ctx context.Context, catalog Catalog, store IFile, rec CatalogRecord, report *RepairReport) error {
+	switch rec.Status {
+	case RecordPending:
+		info, err := stat(ctx, store, rec.Key)
+		if err == ErrNotFound {
+			if err := catalog.Remove(ctx, rec.Key); err != nil {
+				return err
+			}
+			report.Removed++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rec.Status = RecordReady
+		rec.URL = store.GetBlobURL(rec.Key, false)
+		rec.Size = info.Size
+		rec.ETag = info.ETag
+		if rec.ContentType == "" {
+			rec.ContentType = info.ContentType
+		}
+		rec.UpdatedAt = time.Now()
+		if err := catalog.Put(ctx, rec); err != nil {
+			return err
+		}
+		report.Finalized++
+	case RecordDeleting:
+		if _, err := store.Delete(ctx, rec.Key); err != nil && !isNotFound(err) {
+			return err
+		}
+		if err := catalog.Remove(ctx, rec.Key); err != nil {
+			return err
+		}
+		report.Removed++
+	}
+	return nil
+}
+
+// stat return the listing entry of key, ErrNotFound if it does not exist
+func stat(ctx context.Context, store IFile, key string) (ObjectInfo, error) {
+	// key sort before every other name it prefixes
+	files, err := store.List(ctx, key, WithMaxResults(1))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if len(files) == 0 || files[0].Name != key {
+		return ObjectInfo{}, ErrNotFound
+	}
+	return files[0], nil
+}
+
+// MemoryCatalog keep records in memory, for tests and development
+type MemoryCatalog struct {
+	mu      sync.RWMutex
+	records map[string]CatalogRecord
+}
+
+// NewMemoryCatalog create empty catalog
+func NewMemoryCatalog() *MemoryCatalog {
+	return &MemoryCatalog{records: map[string]CatalogRecord{}}
+}
+
+// Put insert or replace record
+func (m *MemoryCatalog) Put(ctx context.Context, rec CatalogRecord) error {
+	m.mu.Lock()
+	m.records[rec.Key] = rec
+	m.mu.Unlock()
+	return nil
+}
+
+// Get return record of key
+func (m *MemoryCatalog) Get(ctx context.Context, key string) (CatalogRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, ok := m.records[key]
+	if !ok {
+		return CatalogRecord{}, ErrRecordNotFound
+	}
+	return rec, nil
+}
+
+// Remove delete record of key
+func (m *MemoryCatalog) Remove(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.records, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// Stale return records not ready updated before t, ordered by key
+func (m *MemoryCatalog) Stale(ctx context.Context, before time.Time) ([]CatalogRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stale []CatalogRecord
+	for _, rec := range m.records {
+		if rec.Status != RecordReady && rec.UpdatedAt.Before(before) {
+			stale = append(stale, rec)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Key < stale[j].Key })
+	return stale, nil
+}
+
+// Walk call fn with every record ordered by key, stopping at the first error
+func (m *MemoryCatalog) Walk(ctx context.Context, fn func(rec CatalogRecord) error) error {
+	m.mu.RLock()
+	records := make([]CatalogRecord, 0, len(m.records))
+	for _, rec := range m.records {
+		records = append(records, rec)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	for _, rec := range records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the failure returned by Chaos
+var ErrInjected = errors.New("file: injected failure")
+
+// ChaosConfig set faults injected by Chaos, rates are probabilities between 0 and 1
+type ChaosConfig struct {
+	// Latency is added before every storage call, plus a random duration up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// ThrottleRate of calls fail with ErrThrottled without reaching storage
+	ThrottleRate float64
+	// FailureRate of calls fail with ErrInjected without reaching storage
+	FailureRate float64
+	// PartialRate of calls reach storage but still fail with ErrInjected,
+	// listings return only part of the result
+	PartialRate float64
+
+	// Seed make injected faults reproducible, 0 use current time
+	Seed int64
+}
+
+// Chaos wrap an IFile injecting latency, throttling and partial failures into storage calls,
+// to validate retry and circuit breaker settings under load tests.
+// Calls that do not reach storage, like GetBlobURL, are passed through.
+type Chaos struct {
+	IFile
+	Config ChaosConfig
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewChaos wrap next with fault injection
+//
+//	Example:
+//	store := file.NewChaos(store, file.ChaosConfig{Latency: 50 * time.Millisecond, ThrottleRate: 0.05})
+func NewChaos(next IFile, cfg ChaosConfig) *Chaos {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Chaos{IFile: next, Config: cfg, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// Unwrap return the wrapped store
+func (c *Chaos) Unwrap() IFile {
+	return c.IFile
+}
+
+func (c *Chaos) roll() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Float64()
+}
+
+// before apply latency and return the error to fail with before reaching storage.
+// partial report whether the call must fail after reaching storage.
+func (c *Chaos) before(ctx context.Context) (partial bool, err error) {
+	delay := c.Config.Latency
+	if c.Config.Jitter > 0 {
+		delay += time.Duration(c.roll() * float64(c.Config.Jitter))
+	}
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	r := c.roll()
+	switch {
+	case r < c.Config.ThrottleRate:
+		return false, ErrThrottled
+	case r < c.Config.ThrottleRate+c.Config.FailureRate:
+		return false, ErrInjected
+	}
+	return c.roll() < c.Config.PartialRate, nil
+}
+
+// Upload file, see ChaosConfig for injected faults
+func (c *Chaos) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := c.IFile.Upload(ctx, filePath, contentType, buffBytes)
+	if err == nil && partial {
+		return "", ErrInjected
+	}
+	return u, err
+}
+
+// Delete file, see ChaosConfig for injected faults
+func (c *Chaos) Delete(ctx context.Context, filePath string) (string, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := c.IFile.Delete(ctx, filePath)
+	if err == nil && partial {
+		return "", ErrInjected
+	}
+	return u, err
+}
+
+// Copy file, see ChaosConfig for injected faults
+func (c *Chaos) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := c.IFile.Copy(ctx, srcPath, dstPath)
+	if err == nil && partial {
+		return "", ErrInjected
+	}
+	return u, err
+}
+
+// SetHeaders change file headers, see ChaosConfig for injected faults
+func (c *Chaos) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.IFile.SetHeaders(ctx, filePath, h)
+	if err == nil && partial {
+		return ErrInjected
+	}
+	return err
+}
+
+// Download file, see ChaosConfig for injected faults
+func (c *Chaos) Download(ctx context.Context, filePath string) ([]byte, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.IFile.Download(ctx, filePath)
+	if err == nil && partial {
+		return data[:len(data)/2], ErrInjected
+	}
+	return data, err
+}
+
+// DownloadIfModified download file, see ChaosConfig for injected faults
+func (c *Chaos) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	data, etag, err := c.IFile.DownloadIfModified(ctx, filePath, knownETag)
+	if err == nil && partial {
+		return data[:len(data)/2], etag, ErrInjected
+	}
+	return data, etag, err
+}
+
+// GetListBlob list files, see ChaosConfig for injected faults
+func (c *Chaos) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.IFile.GetListBlob(ctx, prefix)
+	if err == nil && partial {
+		return list[:len(list)/2], ErrInjected
+	}
+	return list, err
+}
+
+// List files, see ChaosConfig for injected faults
+func (c *Chaos) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.IFile.List(ctx, prefix, opts...)
+	if err == nil && partial {
+		return list[:len(list)/2], ErrInjected
+	}
+	return list, err
+}
+
+// ListDir list directory, see ChaosConfig for injected faults
+func (c *Chaos) ListDir(ctx context.Context, prefix string) (DirList, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return DirList{}, err
+	}
+	dir, err := c.IFile.ListDir(ctx, prefix)
+	if err == nil && partial {
+		dir.Files = dir.Files[:len(dir.Files)/2]
+		return dir, ErrInjected
+	}
+	return dir, err
+}
+
+// Usage aggregate usage, see ChaosConfig for injected faults
+func (c *Chaos) Usage(ctx context.Context, prefix string) (UsageReport, error) {
+	partial, err := c.before(ctx)
+	if err != nil {
+		return UsageReport{}, err
+	}
+	report, err := c.IFile.Usage(ctx, prefix)
+	if err == nil && partial {
+		return UsageReport{}, ErrInjected
+	}
+	return report, err
+}
@@ -0,0 +1,187 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ErrorClass is the kind of a storage failure, it tell whether retrying can succeed
+type ErrorClass int
+
+const (
+	// ClassUnknown is a nil or unrecognized error
+	ClassUnknown ErrorClass = iota
+	// ClassTransient is a network failure, a timeout or a server error, retry with backoff
+	ClassTransient
+	// ClassThrottled is storage asking to slow down, retry with a lower rate
+	ClassThrottled
+	// ClassNotFound is a missing file or container
+	ClassNotFound
+	// ClassAuthZ is a missing or rejected credential or permission
+	ClassAuthZ
+	// ClassInvalid is a request storage will never accept: bad key, argument or size
+	ClassInvalid
+	// ClassConflict is a file whose state forbid the operation: held, leased or changed since read
+	ClassConflict
+)
+
+var errorClassNames = [...]string{"unknown", "transient", "throttled", "not found", "authz", "invalid", "conflict"}
+
+func (c ErrorClass) String() string {
+	if c < 0 || int(c) >= len(errorClassNames) {
+		return "unknown"
+	}
+	return errorClassNames[c]
+}
+
+// Retryable report whether the same request can succeed later
+func (c ErrorClass) Retryable() bool {
+	return c == ClassTransient || c == ClassThrottled
+}
+
+// Classifier is implemented by errors knowing their class, e.g. of an IFile implementation outside this package
+type Classifier interface {
+	ErrorClass() ErrorClass
+}
+
+// errorCodes map the error codes of Azure blob storage and AWS S3 to their class.
+// Codes shared by both providers, such as InternalError, have the same meaning.
+var errorCodes = map[string]ErrorClass{
+	// Azure
+	"ServerBusy":                        ClassThrottled,
+	"OperationTimedOut":                 ClassTransient,
+	"InternalError":                     ClassTransient,
+	"BlobNotFound":                      ClassNotFound,
+	"ContainerNotFound":                 ClassNotFound,
+	"ResourceNotFound":                  ClassNotFound,
+	"AuthenticationFailed":              ClassAuthZ,
+	"AuthorizationFailure":              ClassAuthZ,
+	"AuthorizationPermissionMismatch":   ClassAuthZ,
+	"AuthorizationResourceTypeMismatch": ClassAuthZ,
+	"InsufficientAccountPermissions":    ClassAuthZ,
+	"AccountIsDisabled":                 ClassAuthZ,
+	"InvalidQueryParameterValue":        ClassInvalid,
+	"InvalidHeaderValue":                ClassInvalid,
+	"InvalidResourceName":               ClassInvalid,
+	"InvalidBlobOrBlock":                ClassInvalid,
+	"InvalidBlockList":                  ClassInvalid,
+	"RequestBodyTooLarge":               ClassInvalid,
+	"OutOfRangeInput":                   ClassInvalid,
+	"Md5Mismatch":                       ClassInvalid,
+	"ConditionNotMet":                   ClassConflict,
+	"LeaseIdMissing":                    ClassConflict,
+	"LeaseIdMismatchWithBlobOperation":  ClassConflict,
+	"BlobAlreadyExists":                 ClassConflict,
+	"BlobImmutableDueToPolicy":          ClassConflict,
+	"BlobImmutableDueToLegalHold":       ClassConflict,
+	// AWS
+	"SlowDown":                             ClassThrottled,
+	"Throttling":                           ClassThrottled,
+	"ThrottlingException":                  ClassThrottled,
+	"RequestLimitExceeded":                 ClassThrottled,
+	"ServiceUnavailable":                   ClassThrottled,
+	"RequestTimeout":                       ClassTransient,
+	"RequestTimeTooSkewed":                 ClassAuthZ,
+	"NoSuchKey":                            ClassNotFound,
+	"NoSuchBucket":                         ClassNotFound,
+	"NoSuchUpload":                         ClassNotFound,
+	"NotFound":                             ClassNotFound,
+	"AccessDenied":                         ClassAuthZ,
+	"InvalidAccessKeyId":                   ClassAuthZ,
+	"SignatureDoesNotMatch":                ClassAuthZ,
+	"ExpiredToken":                         ClassAuthZ,
+	"InvalidToken":                         ClassAuthZ,
+	"AllAccessDisabled":                    ClassAuthZ,
+	"InvalidArgument":                      ClassInvalid,
+	"InvalidRequest":                       ClassInvalid,
+	"InvalidBucketName":                    ClassInvalid,
+	"InvalidDigest":                        ClassInvalid,
+	"BadDigest":                            ClassInvalid,
+	"EntityTooLarge":                       ClassInvalid,
+	"EntityTooSmall":                       ClassInvalid,
+	"KeyTooLongError":                      ClassInvalid,
+	"MalformedXML":                         ClassInvalid,
+	"InvalidPart":                          ClassInvalid,
+	"PreconditionFailed":                   ClassConflict,
+	"OperationAborted":                     ClassConflict,
+	"ObjectLockConfigurationNotFoundError": ClassInvalid,
+}
+
+// Classify return the class of err: errors of this package, Azure storage errors by code then status,
+// errors with a Code() method such as the ones of the AWS SDK, Classifier errors and network errors
+//
+//	Example:
+//	if _, err := store.Upload(ctx, key, "", data); file.Classify(err).Retryable() {
+//		queue.Retry(key)
+//	}
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+	var classifier Classifier
+	if errors.As(err, &classifier) {
+		return classifier.ErrorClass()
+	}
+	switch {
+	case errors.Is(err, ErrThrottled):
+		return ClassThrottled
+	case errors.Is(err, ErrNotFound):
+		return ClassNotFound
+	case errors.Is(err, ErrReadOnly):
+		return ClassAuthZ
+	case errors.Is(err, ErrHeld), errors.Is(err, ErrChanged):
+		return ClassConflict
+	case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrInvalidTag), errors.Is(err, ErrTooLarge),
+		errors.Is(err, ErrInvalidURI), errors.Is(err, ErrNotSupported):
+		return ClassInvalid
+	case errors.Is(err, ErrPending), errors.Is(err, ErrStalled), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassTransient
+	}
+
+	if serr, ok := err.(azblob.StorageError); ok {
+		if class, ok := errorCodes[string(serr.ServiceCode())]; ok {
+			return class
+		}
+		if serr.Response() != nil {
+			return ClassifyStatus(serr.Response().StatusCode)
+		}
+	}
+	var coded interface{ Code() string }
+	if errors.As(err, &coded) {
+		if class, ok := errorCodes[coded.Code()]; ok {
+			return class
+		}
+	}
+	// network errors, also wrapped by the azblob pipeline
+	if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+		return ClassTransient
+	}
+	if t, ok := err.(interface{ Temporary() bool }); ok && t.Temporary() {
+		return ClassTransient
+	}
+	return ClassUnknown
+}
+
+// ClassifyStatus return the class of an HTTP error status
+func ClassifyStatus(code int) ErrorClass {
+	switch {
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		return ClassThrottled
+	case code == http.StatusRequestTimeout || code >= 500:
+		return ClassTransient
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ClassAuthZ
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return ClassNotFound
+	case code == http.StatusConflict || code == http.StatusPreconditionFailed:
+		return ClassConflict
+	case code >= 400:
+		return ClassInvalid
+	}
+	return ClassUnknown
+}
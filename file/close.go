@@ -0,0 +1,249 @@
+package file
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Closer is implemented by stores and workers releasing resources on shutdown
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Close release idle HTTP connections of the client HTTP client.
+// Wrap the client with NewGraceful to wait for in-flight operations first.
+func (c *File) Close(ctx context.Context) error {
+	if c.HTTPClient != nil {
+		c.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// drain count in-flight operations and let Close wait for them
+type drain struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight int
+	stopping chan struct{}
+	idle     chan struct{}
+}
+
+// begin register an operation, false once closed
+func (d *drain) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	d.inflight++
+	return true
+}
+
+func (d *drain) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight--
+	if d.inflight == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// stop return a channel closed once close is called
+func (d *drain) stop() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopping == nil {
+		d.stopping = make(chan struct{})
+	}
+	return d.stopping
+}
+
+// close refuse new operations and wait for in-flight ones until ctx is done
+func (d *drain) close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		if d.stopping == nil {
+			d.stopping = make(chan struct{})
+		}
+		close(d.stopping)
+	}
+	if d.inflight == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Graceful wrap an IFile so it can be shut down without cutting transfers:
+// once Close is called new operations fail with ErrClosed while in-flight ones complete.
+type Graceful struct {
+	IFile
+	d drain
+}
+
+// NewGraceful wrap next tracking in-flight operations
+//
+//	Example:
+//	store := file.NewGraceful(f)
+//	...
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	err := store.Close(ctx)
+func NewGraceful(next IFile) *Graceful {
+	return &Graceful{IFile: next}
+}
+
+// Unwrap return the wrapped store
+func (g *Graceful) Unwrap() IFile {
+	return g.IFile
+}
+
+// Close stop accepting operations, wait for in-flight ones until ctx is done,
+// then close the wrapped store when it implements Closer
+func (g *Graceful) Close(ctx context.Context) error {
+	err := g.d.close(ctx)
+	if closer, ok := g.IFile.(Closer); ok {
+		if cerr := closer.Close(ctx); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Upload file unless closed
+func (g *Graceful) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	if !g.d.begin() {
+		return "", ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.Upload(ctx, filePath, contentType, buffBytes)
+}
+
+// Delete file unless closed
+func (g *Graceful) Delete(ctx context.Context, filePath string) (string, error) {
+	if !g.d.begin() {
+		return "", ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.Delete(ctx, filePath)
+}
+
+// Download file unless closed
+func (g *Graceful) Download(ctx context.Context, filePath string) ([]byte, error) {
+	if !g.d.begin() {
+		return nil, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.Download(ctx, filePath)
+}
+
+// DownloadIfModified download file unless closed
+func (g *Graceful) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	if !g.d.begin() {
+		return nil, "", ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.DownloadIfModified(ctx, filePath, knownETag)
+}
+
+// Copy file unless closed
+func (g *Graceful) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	if !g.d.begin() {
+		return "", ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.Copy(ctx, srcPath, dstPath)
+}
+
+// SetHeaders unless closed
+func (g *Graceful) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	if !g.d.begin() {
+		return ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.SetHeaders(ctx, filePath, h)
+}
+
+// GetListBlob list files unless closed
+func (g *Graceful) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	if !g.d.begin() {
+		return nil, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.GetListBlob(ctx, prefix)
+}
+
+// PresignMany sign keys unless closed
+func (g *Graceful) PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+	if !g.d.begin() {
+		return nil, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.PresignMany(ctx, keys, expiry)
+}
+
+// ListDir list directory unless closed
+func (g *Graceful) ListDir(ctx context.Context, prefix string) (DirList, error) {
+	if !g.d.begin() {
+		return DirList{}, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.ListDir(ctx, prefix)
+}
+
+// List files unless closed
+func (g *Graceful) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	if !g.d.begin() {
+		return nil, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.List(ctx, prefix, opts...)
+}
+
+// ListStream stream files unless closed, the stream is in-flight until its channel is closed
+func (g *Graceful) ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem {
+	if !g.d.begin() {
+		ch := make(chan ListItem, 1)
+		ch <- ListItem{Err: ErrClosed}
+		close(ch)
+		return ch
+	}
+
+	items := g.IFile.ListStream(ctx, prefix, opts...)
+	out := make(chan ListItem)
+	go func() {
+		defer g.d.end()
+		defer close(out)
+		for item := range items {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Usage aggregate usage unless closed
+func (g *Graceful) Usage(ctx context.Context, prefix string) (UsageReport, error) {
+	if !g.d.begin() {
+		return UsageReport{}, ErrClosed
+	}
+	defer g.d.end()
+	return g.IFile.Usage(ctx, prefix)
+}
@@ -0,0 +1,37 @@
+package file
+
+import "fmt"
+
+// Cloud is an Azure cloud environment, sovereign clouds use their own storage domain
+type Cloud struct {
+	Name string
+	// StorageSuffix is the storage endpoint domain suffix, e.g. "core.windows.net"
+	StorageSuffix string
+}
+
+var (
+	AzurePublic     = Cloud{Name: "AzurePublicCloud", StorageSuffix: "core.windows.net"}
+	AzureGovernment = Cloud{Name: "AzureUSGovernmentCloud", StorageSuffix: "core.usgovcloudapi.net"}
+	AzureChina      = Cloud{Name: "AzureChinaCloud", StorageSuffix: "core.chinacloudapi.cn"}
+)
+
+// RootURL return the blob root url format expected by New, e.g. "https://%s.blob.core.windows.net/%s"
+func (cl Cloud) RootURL() string {
+	return "https://%s.blob." + cl.StorageSuffix + "/%s"
+}
+
+// QueueURL return the url of queue in account, as expected by NewAzureQueue
+func (cl Cloud) QueueURL(account, queue string) string {
+	return fmt.Sprintf("https://%s.queue.%s/%s", account, cl.StorageSuffix, queue)
+}
+
+// WithCloud use the blob endpoint of cloud, replacing the root url given to New.
+// Shared access signatures only sign account and container so they need no change.
+//
+//	Example:
+//	f := file.New(account, accessKey, "", containerName, apiVersion, file.WithCloud(file.AzureChina))
+func WithCloud(cl Cloud) Option {
+	return func(c *File) {
+		c.RootURL = cl.RootURL()
+	}
+}
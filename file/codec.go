@@ -0,0 +1,231 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContentDecoder open a decompressing reader on stored content
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+// ContentEncoder open a compressing writer, the content written is stored with the Content-Encoding of its codec
+type ContentEncoder func(w io.Writer) (io.WriteCloser, error)
+
+// Codec compress and decompress the content of a Content-Encoding.
+// A codec without Encoder only decode downloads.
+type Codec struct {
+	Encoder ContentEncoder
+	Decoder ContentDecoder
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"gzip": {
+			Encoder: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+			Decoder: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		"deflate": {
+			Encoder: func(w io.Writer) (io.WriteCloser, error) {
+				return zlib.NewWriter(w), nil
+			},
+			Decoder: func(r io.Reader) (io.ReadCloser, error) {
+				return zlib.NewReader(r)
+			},
+		},
+	}
+)
+
+// RegisterCodec compress uploads and decode downloads with Content-Encoding encoding, e.g. "zstd" or "br".
+// gzip and deflate are registered, registering them again replace the standard library implementation.
+//
+//	Example:
+//	file.RegisterCodec("zstd", file.Codec{
+//		Encoder: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+//		Decoder: func(r io.Reader) (io.ReadCloser, error) {
+//			d, err := zstd.NewReader(r)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return d.IOReadCloser(), nil
+//		},
+//	}) // github.com/klauspost/compress/zstd
+func RegisterCodec(encoding string, codec Codec) {
+	codecsMu.Lock()
+	codecs[strings.ToLower(encoding)] = codec
+	codecsMu.Unlock()
+}
+
+// RegisterContentDecoder decode downloads stored with Content-Encoding encoding, e.g. "br" with a brotli package.
+// The encoder of a codec registered for encoding is kept.
+//
+//	Example:
+//	file.RegisterContentDecoder("br", func(r io.Reader) (io.ReadCloser, error) {
+//		return ioutil.NopCloser(brotli.NewReader(r)), nil
+//	})
+func RegisterContentDecoder(encoding string, dec ContentDecoder) {
+	encoding = strings.ToLower(encoding)
+	codecsMu.Lock()
+	codec := codecs[encoding]
+	codec.Decoder = dec
+	codecs[encoding] = codec
+	codecsMu.Unlock()
+}
+
+// Encodings return the registered Content-Encodings, sorted
+func Encodings() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	encodings := make([]string, 0, len(codecs))
+	for enc := range codecs {
+		encodings = append(encodings, enc)
+	}
+	sort.Strings(encodings)
+	return encodings
+}
+
+func lookupCodec(encoding string) (Codec, bool) {
+	codecsMu.RLock()
+	codec, ok := codecs[encoding]
+	codecsMu.RUnlock()
+	return codec, ok
+}
+
+// contentEncoder return the encoder of encoding and the encoding normalized, a nil encoder for identity.
+// It return ErrUnsupportedEncoding when no codec can encode it
+func contentEncoder(encoding string) (ContentEncoder, string, error) {
+	enc := strings.ToLower(strings.TrimSpace(encoding))
+	if enc == "" || enc == "identity" {
+		return nil, "", nil
+	}
+	codec, ok := lookupCodec(enc)
+	if !ok || codec.Encoder == nil {
+		return nil, enc, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, enc)
+	}
+	return codec.Encoder, enc, nil
+}
+
+// encodeReader return a reader of the content of r compressed with encoder, closing it stop the compression
+func encodeReader(r io.Reader, encoder ContentEncoder) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := encoder(pw)
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// EncodeContent compress data with the codec of encoding, it return ErrUnsupportedEncoding when none can encode it
+func EncodeContent(encoding string, data []byte) ([]byte, error) {
+	encoder, enc, err := contentEncoder(encoding)
+	if err != nil {
+		return nil, err
+	}
+	if encoder == nil {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := encoder(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("file: encode %s content: %v", enc, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("file: encode %s content: %v", enc, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("file: encode %s content: %v", enc, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeContent undo every encoding of a Content-Encoding list, last applied first.
+// It return ErrUnsupportedEncoding when no codec decode one of them.
+func DecodeContent(encoding string, data []byte) ([]byte, error) {
+	r, err := decodeReader(encoding, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// decodeReader return r decoded of every encoding of a Content-Encoding list, last applied first.
+// Closing it close the decoders, not r.
+func decodeReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	var decoders decodeChain
+	encodings := strings.Split(encoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		enc := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if enc == "" || enc == "identity" {
+			continue
+		}
+
+		codec, ok := lookupCodec(enc)
+		if !ok || codec.Decoder == nil {
+			decoders.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, enc)
+		}
+		dec, err := codec.Decoder(r)
+		if err != nil {
+			decoders.Close()
+			return nil, fmt.Errorf("file: decode %s content: %v", enc, err)
+		}
+		decoders.closers = append(decoders.closers, dec)
+		r = &decodeErrReader{enc: enc, r: dec}
+	}
+	decoders.r = r
+	return &decoders, nil
+}
+
+// decodeChain read the last decoder of a chain and close them all
+type decodeChain struct {
+	r       io.Reader
+	closers []io.Closer
+}
+
+func (d *decodeChain) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+func (d *decodeChain) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if cerr := d.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decodeErrReader name the encoding in the read errors of its decoder
+type decodeErrReader struct {
+	enc string
+	r   io.Reader
+}
+
+func (d *decodeErrReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("file: decode %s content: %v", d.enc, err)
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ErrNotModified returned by DownloadIfModified when the file still has the known ETag
+var ErrNotModified = errors.New("file: not modified")
+
+// isNotModified report whether err is a storage 304 response
+func isNotModified(err error) bool {
+	if serr, ok := err.(azblob.StorageError); ok {
+		return serr.Response() != nil && serr.Response().StatusCode == http.StatusNotModified
+	}
+	return false
+}
+
+// DownloadIfModified download file unless its ETag is knownETag, in which case ErrNotModified is returned
+// without transferring the content. The current ETag is returned with the content, an empty knownETag always download.
+//
+//	Example:
+//	data, etag, err := file.DownloadIfModified(ctx, "file/image.img", cached.ETag)
+//	if err == file.ErrNotModified {
+//		data = cached.Data
+//	}
+func (c *File) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := azblob.BlobAccessConditions{}
+	if knownETag != "" {
+		conditions.ModifiedAccessConditions.IfNoneMatch = azblob.ETag(knownETag)
+	}
+
+	resp, err := containerURL.NewBlobURL(filePath).Download(ctx, 0, azblob.CountToEnd, conditions, false)
+	if err != nil {
+		if isNotModified(err) {
+			return nil, knownETag, ErrNotModified
+		}
+		if isNotFound(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: 3})
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = c.decodeContent(ctx, resp.ContentEncoding(), data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, string(resp.ETag()), nil
+}
@@ -0,0 +1,185 @@
+package file
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxDeliveries = 5
+	DefaultBatchSize     = 16
+	DefaultPollInterval  = 5 * time.Second
+)
+
+// Message is a single delivery received from a queue
+type Message struct {
+	ID           string
+	Receipt      string
+	Body         []byte
+	DequeueCount int
+}
+
+// Queue is the source of storage event messages, e.g. Azure Queue or SQS.
+// Received messages must become visible again if they are not acknowledged.
+type Queue interface {
+	Receive(ctx context.Context, max int) ([]Message, error)
+	Ack(ctx context.Context, msg Message) error
+}
+
+// DeadLetter receive messages that could not be processed
+type DeadLetter interface {
+	Send(ctx context.Context, msg Message, cause error) error
+}
+
+// HandlerFunc process a single storage event
+type HandlerFunc func(ctx context.Context, event Event) error
+
+// Consumer pull storage events from a queue and dispatch them to registered handlers.
+//
+// Delivery is at-least-once: a message is acknowledged only after every handler succeeded,
+// otherwise it is left on the queue to be redelivered. Once a message has been delivered
+// MaxDeliveries times, or its body can not be parsed, it is sent to DeadLetter (when set) and acknowledged.
+type Consumer struct {
+	Queue         Queue
+	DeadLetter    DeadLetter
+	MaxDeliveries int
+	BatchSize     int
+	// PollInterval is the wait after an empty or failed poll, DefaultPollInterval when 0
+	PollInterval time.Duration
+
+	mu       sync.RWMutex
+	handlers map[EventType][]HandlerFunc
+	d        drain
+}
+
+// NewConsumer create consumer reading from queue, deadLetter may be nil
+func NewConsumer(queue Queue, deadLetter DeadLetter) *Consumer {
+	return &Consumer{
+		Queue:         queue,
+		DeadLetter:    deadLetter,
+		MaxDeliveries: DefaultMaxDeliveries,
+		BatchSize:     DefaultBatchSize,
+		PollInterval:  DefaultPollInterval,
+		handlers:      map[EventType][]HandlerFunc{},
+	}
+}
+
+// Handle register handler for event type, handlers run in registration order
+//
+//	Example:
+//	consumer.Handle(file.EventCreated, func(ctx context.Context, e file.Event) error {
+//		return thumbnail(ctx, e.Key)
+//	})
+func (c *Consumer) Handle(t EventType, h HandlerFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handlers == nil {
+		c.handlers = map[EventType][]HandlerFunc{}
+	}
+	c.handlers[t] = append(c.handlers[t], h)
+}
+
+// Run poll the queue until ctx is cancelled or Close is called, a batch being processed is completed first
+func (c *Consumer) Run(ctx context.Context) error {
+	if !c.d.begin() {
+		return ErrClosed
+	}
+	defer c.d.end()
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		// polling again at once would busy loop on an empty queue
+		interval = DefaultPollInterval
+	}
+	stop := c.d.stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		n, err := c.Poll(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || n == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-stop:
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// Close stop Run once the batch being processed is done, waiting for it until ctx is done
+func (c *Consumer) Close(ctx context.Context) error {
+	return c.d.close(ctx)
+}
+
+// Poll receive one batch of messages and process them, returning number of messages received
+func (c *Consumer) Poll(ctx context.Context) (int, error) {
+	msgs, err := c.Queue.Receive(ctx, c.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range msgs {
+		if err := c.process(ctx, msg); err != nil {
+			return len(msgs), err
+		}
+	}
+
+	return len(msgs), nil
+}
+
+func (c *Consumer) process(ctx context.Context, msg Message) error {
+	events, err := ParseEvents(msg.Body)
+	if err != nil {
+		return c.deadLetter(ctx, msg, err)
+	}
+
+	for _, event := range events {
+		if err = c.dispatch(ctx, event); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		return c.Queue.Ack(ctx, msg)
+	}
+
+	if c.MaxDeliveries > 0 && msg.DequeueCount >= c.MaxDeliveries {
+		return c.deadLetter(ctx, msg, err)
+	}
+
+	// leave the message on the queue, it is redelivered once visibility timeout expires
+	return nil
+}
+
+func (c *Consumer) dispatch(ctx context.Context, event Event) error {
+	c.mu.RLock()
+	handlers := c.handlers[event.Type]
+	c.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) deadLetter(ctx context.Context, msg Message, cause error) error {
+	if c.DeadLetter != nil {
+		if err := c.DeadLetter.Send(ctx, msg, cause); err != nil {
+			return err
+		}
+	}
+	return c.Queue.Ack(ctx, msg)
+}
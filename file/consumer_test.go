@@ -0,0 +1,101 @@
+package file_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filemock"
+)
+
+func TestConsumerZeroPollInterval(t *testing.T) {
+	var receives int32
+	queue := &filemock.QueueMock{
+		ReceiveFunc: func(ctx context.Context, max int) ([]file.Message, error) {
+			atomic.AddInt32(&receives, 1)
+			return nil, nil
+		},
+	}
+	c := file.NewConsumer(queue, nil)
+	c.PollInterval = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Run = %v, want context.DeadlineExceeded", err)
+	}
+	// an empty queue is polled again after DefaultPollInterval
+	if n := atomic.LoadInt32(&receives); n != 1 {
+		t.Errorf("%d polls of an empty queue in 100ms, want 1", n)
+	}
+}
+
+func TestAzureQueueVisibilityTimeout(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Query().Get("visibilitytimeout"))
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte("<QueueMessagesList></QueueMessagesList>"))
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	q, err := file.NewAzureQueue("account", key, srv.URL+"/blob-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, timeout := range []time.Duration{0, 500 * time.Millisecond, time.Minute} {
+		q.VisibilityTimeout = timeout
+		if _, err := q.Receive(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"30", "1", "60"}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("visibilitytimeout sent %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAzureQueueSendCause(t *testing.T) {
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m struct {
+			MessageText string
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &m); err != nil {
+			t.Error(err)
+		}
+		text = m.MessageText
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	q, err := file.NewAzureQueue("account", key, srv.URL+"/blob-events-dlq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := file.Message{ID: "m1", Body: []byte(`[{"eventType":"<unknown>"}]`), DequeueCount: 5}
+	if err := q.Send(context.Background(), msg, errors.New("thumbnail failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err := file.ParseDeadLetter([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.ID != "m1" || dead.DequeueCount != 5 || dead.Cause != "thumbnail failed" || string(dead.Body) != string(msg.Body) {
+		t.Errorf("dead letter %+v, want message %+v with cause", dead, msg)
+	}
+}
@@ -0,0 +1,92 @@
+package file
+
+import (
+	"context"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// Metadata keys filled from context on upload
+const (
+	MetadataActor   = "actor"
+	MetadataPurpose = "purpose"
+	// MetadataContentMD5 is the base64 MD5 of the uncompressed content of uploads compressed with a Content-Encoding,
+	// whose Content-MD5 is the one of the stored compressed bytes
+	MetadataContentMD5 = "content_md5"
+)
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	purposeKey
+	metadataKey
+	tagsKey
+	rawKey
+	cachePolicyKey
+	encodingKey
+)
+
+// WithActor return context carrying the user performing the operation,
+// stored as object metadata by uploads done with it
+//
+//	Example:
+//	ctx = file.WithActor(ctx, userID)
+//	ctx = file.WithPurpose(ctx, "export")
+//	url, err := file.Upload(ctx, "export/data.csv", "text/csv", buffBytes)
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey, userID)
+}
+
+// WithPurpose return context carrying the processing purpose of the operation, e.g. "export"
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	return context.WithValue(ctx, purposeKey, purpose)
+}
+
+// ActorFromContext return actor set by WithActor, empty if none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// PurposeFromContext return purpose set by WithPurpose, empty if none
+func PurposeFromContext(ctx context.Context) string {
+	purpose, _ := ctx.Value(purposeKey).(string)
+	return purpose
+}
+
+// WithMetadata return context adding key and value to the metadata of files uploaded with it.
+// key must be a valid C# identifier, e.g. "review_status"
+func WithMetadata(ctx context.Context, key, value string) context.Context {
+	metadata := map[string]string{}
+	if parent, ok := ctx.Value(metadataKey).(map[string]string); ok {
+		for k, v := range parent {
+			metadata[k] = v
+		}
+	}
+	metadata[key] = value
+	return context.WithValue(ctx, metadataKey, metadata)
+}
+
+// MetadataFromContext return metadata of files uploaded with ctx,
+// values set by WithMetadata plus actor and purpose
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata := map[string]string{}
+	if values, ok := ctx.Value(metadataKey).(map[string]string); ok {
+		for k, v := range values {
+			metadata[k] = v
+		}
+	}
+	if actor := ActorFromContext(ctx); actor != "" {
+		metadata[MetadataActor] = actor
+	}
+	if purpose := PurposeFromContext(ctx); purpose != "" {
+		metadata[MetadataPurpose] = purpose
+	}
+	return metadata
+}
+
+// contextMetadata return object metadata carried by ctx
+func contextMetadata(ctx context.Context) azblob.Metadata {
+	return azblob.Metadata(MetadataFromContext(ctx))
+}
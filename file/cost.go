@@ -0,0 +1,278 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded returned for non-essential operations once the period budget is spent
+var ErrBudgetExceeded = errors.New("file: budget exceeded")
+
+// OpClass is the billing class of a storage request
+type OpClass string
+
+const (
+	OpWrite  OpClass = "write"
+	OpRead   OpClass = "read"
+	OpList   OpClass = "list"
+	OpDelete OpClass = "delete"
+)
+
+// listPageSize is the number of files returned per listing request
+const listPageSize = 5000
+
+// Prices are provider prices in a currency unit, per 10,000 requests of a class and per GB transferred out
+type Prices struct {
+	Per10k   map[OpClass]float64
+	EgressGB float64
+}
+
+// DefaultPrices approximate Azure hot tier LRS pay as you go prices in USD, replace them with your contract prices
+var DefaultPrices = Prices{
+	Per10k: map[OpClass]float64{
+		OpWrite: 0.065,
+		OpRead:  0.005,
+		OpList:  0.065,
+	},
+	EgressGB: 0.087,
+}
+
+// Costs is the usage accounted in a billing period
+type Costs struct {
+	Period      time.Time
+	Requests    map[OpClass]int64
+	EgressBytes int64
+	Estimated   float64
+}
+
+// Budget limit estimated cost per billing period
+type Budget struct {
+	// Limit is the estimated cost allowed per period, 0 is unlimited
+	Limit float64
+	// OnExceeded is called once per period when Limit is reached
+	OnExceeded func(Costs)
+	// RefuseNonEssential fail listings with ErrBudgetExceeded once Limit is reached,
+	// unless the context is marked with Essential
+	RefuseNonEssential bool
+}
+
+type essentialKey struct{}
+
+// Essential mark operations on ctx as essential, they are never refused by a Metered budget
+func Essential(ctx context.Context) context.Context {
+	return context.WithValue(ctx, essentialKey{}, true)
+}
+
+func isEssential(ctx context.Context) bool {
+	essential, _ := ctx.Value(essentialKey{}).(bool)
+	return essential
+}
+
+// Metered wrap an IFile accounting requests and egress per billing period with estimated costs.
+// Billing periods are calendar months in UTC. Request counts of listings are estimated
+// from the number of returned files.
+type Metered struct {
+	IFile
+	Prices Prices
+	Budget Budget
+
+	mu       sync.Mutex
+	costs    Costs
+	notified bool
+}
+
+// NewMetered wrap next accounting costs with prices
+//
+//	Example:
+//	store := file.NewMetered(store, file.DefaultPrices, file.Budget{
+//		Limit:              50,
+//		RefuseNonEssential: true,
+//		OnExceeded:         func(c file.Costs) { alert(c) },
+//	})
+func NewMetered(next IFile, prices Prices, budget Budget) *Metered {
+	return &Metered{IFile: next, Prices: prices, Budget: budget}
+}
+
+// Unwrap return the wrapped store
+func (m *Metered) Unwrap() IFile {
+	return m.IFile
+}
+
+// Costs return the usage of the current billing period
+func (m *Metered) Costs() Costs {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollover()
+	return m.snapshot()
+}
+
+// snapshot copy current costs, m.mu must be held
+func (m *Metered) snapshot() Costs {
+	c := m.costs
+	c.Requests = make(map[OpClass]int64, len(m.costs.Requests))
+	for k, v := range m.costs.Requests {
+		c.Requests[k] = v
+	}
+	return c
+}
+
+// rollover start a new period when the month changed, m.mu must be held
+func (m *Metered) rollover() {
+	t := time.Now().UTC()
+	period := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !m.costs.Period.Equal(period) {
+		m.costs = Costs{Period: period, Requests: map[OpClass]int64{}}
+		m.notified = false
+	}
+}
+
+// allow return ErrBudgetExceeded when a non-essential operation must be refused
+func (m *Metered) allow(ctx context.Context) error {
+	if !m.Budget.RefuseNonEssential || m.Budget.Limit <= 0 || isEssential(ctx) {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollover()
+	if m.costs.Estimated >= m.Budget.Limit {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (m *Metered) record(class OpClass, requests int64, egress int64) {
+	m.mu.Lock()
+	m.rollover()
+	m.costs.Requests[class] += requests
+	m.costs.EgressBytes += egress
+	m.costs.Estimated += float64(requests)*m.Prices.Per10k[class]/10000 + float64(egress)*m.Prices.EgressGB/(1<<30)
+
+	var exceeded *Costs
+	if m.Budget.Limit > 0 && m.costs.Estimated >= m.Budget.Limit && !m.notified {
+		m.notified = true
+		c := m.snapshot()
+		exceeded = &c
+	}
+	m.mu.Unlock()
+
+	if exceeded != nil && m.Budget.OnExceeded != nil {
+		m.Budget.OnExceeded(*exceeded)
+	}
+}
+
+func listRequests(files int) int64 {
+	return int64(files/listPageSize + 1)
+}
+
+// Upload file accounting one write
+func (m *Metered) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	m.record(OpWrite, 1, 0)
+	return m.IFile.Upload(ctx, filePath, contentType, buffBytes)
+}
+
+// Delete file accounting one delete
+func (m *Metered) Delete(ctx context.Context, filePath string) (string, error) {
+	m.record(OpDelete, 1, 0)
+	return m.IFile.Delete(ctx, filePath)
+}
+
+// Copy file accounting one write
+func (m *Metered) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	m.record(OpWrite, 1, 0)
+	return m.IFile.Copy(ctx, srcPath, dstPath)
+}
+
+// SetHeaders accounting one write
+func (m *Metered) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	m.record(OpWrite, 1, 0)
+	return m.IFile.SetHeaders(ctx, filePath, h)
+}
+
+// Download file accounting one read and its size as egress
+func (m *Metered) Download(ctx context.Context, filePath string) ([]byte, error) {
+	data, err := m.IFile.Download(ctx, filePath)
+	m.record(OpRead, 1, int64(len(data)))
+	return data, err
+}
+
+// DownloadIfModified accounting one read, and the size as egress when the file changed
+func (m *Metered) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	data, etag, err := m.IFile.DownloadIfModified(ctx, filePath, knownETag)
+	m.record(OpRead, 1, int64(len(data)))
+	return data, etag, err
+}
+
+// GetListBlob list files accounting listing requests, refused when over budget
+func (m *Metered) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	if err := m.allow(ctx); err != nil {
+		return nil, err
+	}
+	list, err := m.IFile.GetListBlob(ctx, prefix)
+	m.record(OpList, listRequests(len(list)), 0)
+	return list, err
+}
+
+// List files accounting listing requests, refused when over budget
+func (m *Metered) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	if err := m.allow(ctx); err != nil {
+		return nil, err
+	}
+	list, err := m.IFile.List(ctx, prefix, opts...)
+	m.record(OpList, listRequests(len(list)), 0)
+	return list, err
+}
+
+// ListStream stream files accounting listing requests as pages are consumed, refused when over budget
+func (m *Metered) ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem {
+	if err := m.allow(ctx); err != nil {
+		ch := make(chan ListItem, 1)
+		ch <- ListItem{Err: err}
+		close(ch)
+		return ch
+	}
+
+	m.record(OpList, 1, 0)
+	items := m.IFile.ListStream(ctx, prefix, opts...)
+	out := make(chan ListItem)
+	go func() {
+		defer close(out)
+		n := 0
+		for item := range items {
+			n++
+			if n%listPageSize == 0 {
+				m.record(OpList, 1, 0)
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ListDir list directory accounting listing requests, refused when over budget
+func (m *Metered) ListDir(ctx context.Context, prefix string) (DirList, error) {
+	if err := m.allow(ctx); err != nil {
+		return DirList{}, err
+	}
+	dir, err := m.IFile.ListDir(ctx, prefix)
+	m.record(OpList, listRequests(len(dir.Dirs)+len(dir.Files)), 0)
+	return dir, err
+}
+
+// Usage aggregate usage accounting listing requests, refused when over budget
+func (m *Metered) Usage(ctx context.Context, prefix string) (UsageReport, error) {
+	if err := m.allow(ctx); err != nil {
+		return UsageReport{}, err
+	}
+	report, err := m.IFile.Usage(ctx, prefix)
+	m.record(OpList, listRequests(int(report.Objects)), 0)
+	return report, err
+}
@@ -0,0 +1,67 @@
+package file
+
+import (
+	"context"
+	"time"
+)
+
+// DownloadCounter record downloads per key, e.g. Heatmap in process or a table shared by every instance,
+// to rank popular downloads and find cold data
+type DownloadCounter interface {
+	// CountDownload add one download of key transferring bytes at t, bytes is 0 for not modified answers
+	CountDownload(ctx context.Context, key string, bytes int64, at time.Time) error
+}
+
+// CountDownload add a read of key, so a Heatmap count the downloads of Counted
+func (h *Heatmap) CountDownload(ctx context.Context, key string, bytes int64, at time.Time) error {
+	h.Add(AccessRecord{Time: at, Op: AccessRead, Key: key, Status: 200, Bytes: bytes})
+	return nil
+}
+
+// Counted wrap an IFile counting successful downloads and the last access of every key in Counter.
+// Counting never fail a download, errors are passed to OnError when set.
+type Counted struct {
+	IFile
+	Counter DownloadCounter
+	OnError func(key string, err error)
+}
+
+// NewCounted wrap next counting its downloads in counter
+//
+//	Example:
+//	heatmap := file.NewHeatmap()
+//	store := file.NewCounted(store, heatmap)
+//	popular := heatmap.Hottest(10)
+//	cold := heatmap.RecommendTiers(files, 30*24*time.Hour, 0, time.Now())
+func NewCounted(next IFile, counter DownloadCounter) *Counted {
+	return &Counted{IFile: next, Counter: counter}
+}
+
+// Unwrap return the wrapped store
+func (c *Counted) Unwrap() IFile {
+	return c.IFile
+}
+
+// Download file and count it
+func (c *Counted) Download(ctx context.Context, filePath string) ([]byte, error) {
+	data, err := c.IFile.Download(ctx, filePath)
+	if err == nil {
+		c.count(ctx, filePath, int64(len(data)))
+	}
+	return data, err
+}
+
+// DownloadIfModified download file unless it has knownETag, both count as an access
+func (c *Counted) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	data, etag, err := c.IFile.DownloadIfModified(ctx, filePath, knownETag)
+	if err == nil || err == ErrNotModified {
+		c.count(ctx, filePath, int64(len(data)))
+	}
+	return data, etag, err
+}
+
+func (c *Counted) count(ctx context.Context, key string, bytes int64) {
+	if err := c.Counter.CountDownload(ctx, key, bytes, time.Now()); err != nil && c.OnError != nil {
+		c.OnError(key, err)
+	}
+}
@@ -0,0 +1,72 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ErrExists returned by UploadIfAbsent when the file already exists
+var ErrExists = errors.New("file: already exists")
+
+// CreateUploader is implemented by stores creating a file only when it does not exist yet, atomically
+// across processes, e.g. *File
+type CreateUploader interface {
+	// UploadIfAbsent store buffBytes at filePath unless a file is there, ErrExists then
+	UploadIfAbsent(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error)
+}
+
+// isExists report whether err is a storage response to an If-None-Match: * write of an existing file
+func isExists(err error) bool {
+	if serr, ok := err.(azblob.StorageError); ok && serr.Response() != nil {
+		code := serr.Response().StatusCode
+		return code == http.StatusConflict || code == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+// UploadIfAbsent upload file unless it exists, with If-None-Match: *, see CreateUploader.
+// The content is stored as is, without the Compression of the client.
+//
+//	Example:
+//	url, err := file.UploadIfAbsent(ctx, "locks/import-2020-06", "text/plain", []byte(host))
+//	if err == file.ErrExists {
+//		return nil // another instance run the import
+//	}
+func (c *File) UploadIfAbsent(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return "", err
+	}
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+	if c.MaxUploadSize > 0 && int64(len(buffBytes)) > c.MaxUploadSize {
+		return "", ErrTooLarge
+	}
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", err
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(buffBytes)
+	}
+
+	_, err = containerURL.NewBlockBlobURL(filePath).Upload(ctx,
+		bytes.NewReader(buffBytes),
+		azblob.BlobHTTPHeaders{ContentType: contentType},
+		contextMetadata(ctx),
+		azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfNoneMatch: azblob.ETagAny}})
+	if isExists(err) {
+		return "", ErrExists
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.GetBlobURL(filePath, false), nil
+}
+
+var _ CreateUploader = &File{}
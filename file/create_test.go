@@ -0,0 +1,39 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadIfAbsent(t *testing.T) {
+	exists := false
+	var ifNoneMatch string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = r.Header.Get("If-None-Match")
+		if exists {
+			w.Header().Set("x-ms-error-code", "BlobAlreadyExists")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		exists = true
+		w.Header().Set("ETag", `"0x8D8"`)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12").(*File)
+	ctx := context.Background()
+	if _, err := c.UploadIfAbsent(ctx, "keys/42", "application/octet-stream", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if ifNoneMatch != "*" {
+		t.Errorf("If-None-Match = %q, want *", ifNoneMatch)
+	}
+	if _, err := c.UploadIfAbsent(ctx, "keys/42", "application/octet-stream", []byte("second")); err != ErrExists {
+		t.Errorf("UploadIfAbsent over an existing blob = %v, want ErrExists", err)
+	}
+}
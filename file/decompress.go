@@ -0,0 +1,46 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrUnsupportedEncoding returned when content can't be compressed or decompressed, no codec is registered for its Content-Encoding
+var ErrUnsupportedEncoding = errors.New("file: unsupported content encoding")
+
+// WithRawContent return context whose downloads return the stored bytes, even from a client created WithDecompression
+func WithRawContent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawKey, true)
+}
+
+func isRawContent(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawKey).(bool)
+	return raw
+}
+
+// decodeContent undo every encoding of a Content-Encoding list when the client decompress downloads
+func (c *File) decodeContent(ctx context.Context, encoding string, data []byte) ([]byte, error) {
+	if !c.Decompress || isRawContent(ctx) || encoding == "" {
+		return data, nil
+	}
+	return DecodeContent(encoding, data)
+}
+
+// WithContentEncoding return context whose uploads are compressed with the codec of encoding, see RegisterCodec.
+// "identity" store the content as is, overriding WithCompression.
+func WithContentEncoding(ctx context.Context, encoding string) context.Context {
+	return context.WithValue(ctx, encodingKey, strings.ToLower(strings.TrimSpace(encoding)))
+}
+
+// uploadEncoding return the Content-Encoding of uploads done with ctx, empty when stored as is
+func (c *File) uploadEncoding(ctx context.Context) string {
+	encoding, ok := ctx.Value(encodingKey).(string)
+	if !ok {
+		encoding = c.Compression
+	}
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
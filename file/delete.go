@@ -0,0 +1,52 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// DeleteResult is the result of DeletePrefix
+type DeleteResult struct {
+	// Deleted count the files deleted, files already gone included
+	Deleted int
+	// Held are the files kept by a legal hold or a retention policy, ordered by key. They are not failures.
+	Held []HoldError
+}
+
+// DeletePrefix delete every file under prefix with Walk. Files kept by a legal hold or a retention policy
+// are reported in DeleteResult.Held and the walk continue, other failures are returned in a *WalkError.
+//
+//	Example:
+//	res, err := file.DeletePrefix(ctx, store, "tenants/42/", file.WalkOptions{Workers: 16})
+//	for _, h := range res.Held {
+//		log.Printf("kept %s: %s", h.Key, h.Reason)
+//	}
+func DeletePrefix(ctx context.Context, store IFile, prefix string, opts WalkOptions) (DeleteResult, error) {
+	var (
+		mu  sync.Mutex
+		res DeleteResult
+	)
+	err := Walk(ctx, store, prefix, func(ctx context.Context, info ObjectInfo) error {
+		_, err := store.Delete(ctx, info.Name)
+		var held *HoldError
+		switch {
+		case errors.As(err, &held):
+			mu.Lock()
+			res.Held = append(res.Held, *held)
+			mu.Unlock()
+			return nil
+		case err != nil && err != ErrNotFound:
+			return err
+		}
+		mu.Lock()
+		res.Deleted++
+		mu.Unlock()
+		return nil
+	}, opts)
+	sort.Slice(res.Held, func(i, j int) bool {
+		return res.Held[i].Key < res.Held[j].Key
+	})
+	return res, err
+}
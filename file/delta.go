@@ -0,0 +1,331 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// DefaultChunkSize is the size of the chunks of a ChunkManifest by default
+	DefaultChunkSize = 256 << 10
+	// ChunkManifestSuffix is appended to a file key to get the key of its chunk manifest
+	ChunkManifestSuffix = ".chunks.json"
+)
+
+// Chunk is a part of a file, identified by a rolling checksum to find it at any offset of a local copy
+// and a SHA-256 to confirm it
+type Chunk struct {
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Weak   uint32 `json:"weak"`
+	SHA256 string `json:"sha256"`
+}
+
+// ChunkManifest list the chunks of a file, so clients holding an older copy download only the changed chunks
+type ChunkManifest struct {
+	Key       string `json:"key"`
+	ETag      string `json:"etag,omitempty"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	// SHA256 is the hex SHA-256 of the whole content
+	SHA256 string  `json:"sha256"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// DeltaStats count what DeltaSync reused and downloaded
+type DeltaStats struct {
+	Chunks int
+	// Reused chunks were found in the local copy
+	Reused int
+	// Requests is the number of range requests, adjacent changed chunks are downloaded at once
+	Requests int
+	Fetched  int64
+}
+
+// RangeReader is implemented by stores reading part of a file, e.g. *File and HTTPRangeReader
+type RangeReader interface {
+	// ReadRange return count bytes of the file from offset.
+	// With a non empty etag it fail with ErrChanged when the file has another one.
+	ReadRange(ctx context.Context, filePath string, offset, count int64, etag string) ([]byte, error)
+}
+
+// ChunkManifestKey return the key of the chunk manifest of key
+func ChunkManifestKey(key string) string {
+	return key + ChunkManifestSuffix
+}
+
+// ChunkContent split data into chunks of chunkSize, DefaultChunkSize when 0, and return their manifest
+func ChunkContent(key string, data []byte, chunkSize int64) *ChunkManifest {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	sum := sha256.Sum256(data)
+	m := &ChunkManifest{Key: key, Size: int64(len(data)), ChunkSize: chunkSize, SHA256: hex.EncodeToString(sum[:])}
+	for off := int64(0); off < m.Size; off += chunkSize {
+		end := off + chunkSize
+		if end > m.Size {
+			end = m.Size
+		}
+		part := data[off:end]
+		sum := sha256.Sum256(part)
+		m.Chunks = append(m.Chunks, Chunk{Offset: off, Size: end - off, Weak: newRollingSum(part).sum(), SHA256: hex.EncodeToString(sum[:])})
+	}
+	return m
+}
+
+// PublishChunkManifest download key to chunk it and upload its manifest at ChunkManifestKey, where clients fetch it.
+// Publish again after every change of the file, clients syncing against an outdated manifest fail with ErrChanged.
+//
+//	Example:
+//	m, err := file.PublishChunkManifest(ctx, store, "bundles/maps-eu.pak", 0)
+func PublishChunkManifest(ctx context.Context, store IFile, key string, chunkSize int64) (*ChunkManifest, error) {
+	data, etag, err := store.DownloadIfModified(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	m := ChunkContent(key, data, chunkSize)
+	m.ETag = etag
+	doc, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := store.Upload(ctx, ChunkManifestKey(key), "application/json", doc); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeltaSync rebuild the file of m from local, an older copy, downloading only the chunks not found in it,
+// like rsync: chunks are found at any offset, e.g. after an insertion. Downloaded chunks and the result are checked
+// against their SHA-256, a file changed since m was built fail with ErrChanged.
+//
+//	Example:
+//	var m file.ChunkManifest
+//	err := json.Unmarshal(manifestJSON, &m)
+//	data, stats, err := file.DeltaSync(ctx, &file.HTTPRangeReader{URL: func(string) string { return url }}, &m, local)
+//	log.Printf("downloaded %d bytes, reused %d/%d chunks", stats.Fetched, stats.Reused, stats.Chunks)
+func DeltaSync(ctx context.Context, reader RangeReader, m *ChunkManifest, local []byte) ([]byte, DeltaStats, error) {
+	stats := DeltaStats{Chunks: len(m.Chunks)}
+	if err := m.validate(); err != nil {
+		return nil, stats, err
+	}
+	found := m.findChunks(local)
+	out := make([]byte, m.Size)
+
+	for i := 0; i < len(m.Chunks); {
+		c := m.Chunks[i]
+		if off, ok := found[i]; ok {
+			copy(out[c.Offset:], local[off:off+c.Size])
+			stats.Reused++
+			i++
+			continue
+		}
+		// download the run of missing chunks at once
+		j := i + 1
+		for j < len(m.Chunks) {
+			if _, ok := found[j]; ok {
+				break
+			}
+			j++
+		}
+		last := m.Chunks[j-1]
+		count := last.Offset + last.Size - c.Offset
+		data, err := reader.ReadRange(ctx, m.Key, c.Offset, count, m.ETag)
+		if err != nil {
+			return nil, stats, err
+		}
+		if int64(len(data)) != count {
+			return nil, stats, fmt.Errorf("file: range of %s returned %d bytes, want %d", m.Key, len(data), count)
+		}
+		for _, missing := range m.Chunks[i:j] {
+			part := data[missing.Offset-c.Offset : missing.Offset-c.Offset+missing.Size]
+			if !chunkMatch(missing, part) {
+				return nil, stats, fmt.Errorf("%w: chunk at %d of %s", ErrChecksumMismatch, missing.Offset, m.Key)
+			}
+		}
+		copy(out[c.Offset:], data)
+		stats.Requests++
+		stats.Fetched += count
+		i = j
+	}
+
+	if m.SHA256 != "" {
+		sum := sha256.Sum256(out)
+		if hex.EncodeToString(sum[:]) != m.SHA256 {
+			return nil, stats, fmt.Errorf("%w: %s", ErrChecksumMismatch, m.Key)
+		}
+	}
+	return out, stats, nil
+}
+
+// validate check the chunks cover the file one after the other, a corrupt manifest could not be applied
+func (m *ChunkManifest) validate() error {
+	var off int64
+	for _, c := range m.Chunks {
+		if c.Offset != off || c.Size <= 0 || c.Size > m.ChunkSize {
+			return fmt.Errorf("file: invalid chunk manifest of %s, chunk at %d", m.Key, c.Offset)
+		}
+		off += c.Size
+	}
+	if off != m.Size {
+		return fmt.Errorf("file: invalid chunk manifest of %s, chunks cover %d of %d bytes", m.Key, off, m.Size)
+	}
+	return nil
+}
+
+// findChunks return the local offset of every chunk of m present in local, scanning it with the rolling checksum
+func (m *ChunkManifest) findChunks(local []byte) map[int]int64 {
+	found := map[int]int64{}
+	byWeak := map[uint32][]int{}
+	for i, c := range m.Chunks {
+		if c.Size == m.ChunkSize {
+			byWeak[c.Weak] = append(byWeak[c.Weak], i)
+		}
+	}
+
+	n := int(m.ChunkSize)
+	if n > 0 && len(local) >= n && len(byWeak) > 0 {
+		rs := newRollingSum(local[:n])
+		for pos := 0; ; {
+			matched := false
+			if candidates, ok := byWeak[rs.sum()]; ok {
+				window := local[pos : pos+n]
+				sum := sha256.Sum256(window)
+				strong := hex.EncodeToString(sum[:])
+				for _, i := range candidates {
+					if _, done := found[i]; !done && m.Chunks[i].SHA256 == strong {
+						found[i] = int64(pos)
+						matched = true
+					}
+				}
+			}
+			if matched {
+				// chunks don't overlap in the new content, continue after the match
+				pos += n
+				if pos+n > len(local) {
+					break
+				}
+				rs = newRollingSum(local[pos : pos+n])
+				continue
+			}
+			if pos+n >= len(local) {
+				break
+			}
+			rs.roll(local[pos], local[pos+n])
+			pos++
+		}
+	}
+
+	// the last chunk is usually shorter, look for it at the end of the local copy
+	if last := len(m.Chunks) - 1; last >= 0 {
+		c := m.Chunks[last]
+		if _, ok := found[last]; !ok && c.Size < m.ChunkSize && int64(len(local)) >= c.Size {
+			off := int64(len(local)) - c.Size
+			if chunkMatch(c, local[off:]) {
+				found[last] = off
+			}
+		}
+	}
+	return found
+}
+
+func chunkMatch(c Chunk, data []byte) bool {
+	sum := sha256.Sum256(data)
+	return int64(len(data)) == c.Size && hex.EncodeToString(sum[:]) == c.SHA256
+}
+
+// rollingSum is the weak checksum of rsync, updated in constant time when the window slide by one byte
+type rollingSum struct {
+	a, b uint32
+	n    uint32
+}
+
+func newRollingSum(window []byte) rollingSum {
+	rs := rollingSum{n: uint32(len(window))}
+	for i, x := range window {
+		rs.a += uint32(x)
+		rs.b += uint32(len(window)-i) * uint32(x)
+	}
+	return rs
+}
+
+// roll remove out from the start of the window and add in at its end
+func (rs *rollingSum) roll(out, in byte) {
+	rs.a += uint32(in) - uint32(out)
+	rs.b += rs.a - rs.n*uint32(out)
+}
+
+func (rs rollingSum) sum() uint32 {
+	return rs.a&0xffff | rs.b<<16
+}
+
+// HTTPRangeReader read ranges of files over HTTP, e.g. with the presigned urls of a BundleManifest
+type HTTPRangeReader struct {
+	// Client send the requests, http.DefaultClient when nil
+	Client *http.Client
+	// URL return the url of a file key
+	URL func(key string) string
+}
+
+// ReadRange GET count bytes of the file from offset with a Range request, see RangeReader
+func (h *HTTPRangeReader) ReadRange(ctx context.Context, filePath string, offset, count int64, etag string) ([]byte, error) {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, h.URL(filePath), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+count-1))
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusOK:
+		// range not supported by the server
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) < offset+count {
+			return nil, fmt.Errorf("file: range of %s past its %d bytes", filePath, len(data))
+		}
+		return data[offset : offset+count], nil
+	case http.StatusPreconditionFailed:
+		return nil, ErrChanged
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("file: range of %s: %s", filePath, resp.Status)
+}
+
+// ReadRange return count bytes of the file from offset, see RangeReader
+func (c *File) ReadRange(ctx context.Context, filePath string, offset, count int64, etag string) ([]byte, error) {
+	body, _, err := c.openRange(ctx, filePath, offset, count, etag)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var _ RangeReader = &File{}
@@ -0,0 +1,218 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// diskCacheExt is the extension of the files of DiskCache entries
+	diskCacheExt = ".entry"
+	// diskCacheTmp start the name of entries being written
+	diskCacheTmp = "tmp-"
+)
+
+// DiskCache is a least recently used EntryCache in a local directory bounded in bytes, kept across runs,
+// so apps embedding the SDK don't download their assets again on every start.
+// Entries are files named by the hash of their key, their modification time is the last use.
+// A directory is used by one DiskCache at a time.
+type DiskCache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type diskEntry struct {
+	name string
+	size int64
+}
+
+// diskHeader is the first line of an entry file, followed by the content
+type diskHeader struct {
+	Key    string    `json:"key"`
+	ETag   string    `json:"etag,omitempty"`
+	Stored time.Time `json:"stored"`
+}
+
+// NewDiskCache create cache holding up to maxBytes of file contents in dir, created if missing.
+// Entries of previous runs are kept, the least recently used first evicted.
+//
+//	Example:
+//	disk, err := file.NewDiskCache(filepath.Join(userCacheDir, "assets"), 2<<30)
+//	store := file.NewCached(store, disk)
+//	store.Policy = file.CachePolicy{MaxAge: time.Hour, StaleIfError: 30 * 24 * time.Hour}
+//	data, err := store.Download(ctx, "packs/maps-eu.pak")
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DiskCache{Dir: dir, MaxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+	var found []os.FileInfo
+	for _, info := range infos {
+		switch {
+		case info.IsDir():
+		case strings.HasSuffix(info.Name(), diskCacheExt):
+			found = append(found, info)
+		case strings.HasPrefix(info.Name(), diskCacheTmp):
+			// interrupted write
+			os.Remove(filepath.Join(dir, info.Name()))
+		}
+	}
+	// most recently used first
+	sort.Slice(found, func(i, j int) bool { return found[i].ModTime().After(found[j].ModTime()) })
+	for _, info := range found {
+		d.entries[info.Name()] = d.order.PushBack(&diskEntry{name: info.Name(), size: info.Size()})
+		d.size += info.Size()
+	}
+	d.mu.Lock()
+	d.evict()
+	d.mu.Unlock()
+	return d, nil
+}
+
+// entryName return the file name of key
+func (d *DiskCache) entryName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskCacheExt
+}
+
+// GetEntry return the cached entry of key, marking it used
+func (d *DiskCache) GetEntry(key string) (CacheEntry, bool) {
+	name := d.entryName(key)
+	d.mu.Lock()
+	e, ok := d.entries[name]
+	if ok {
+		d.order.MoveToFront(e)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return CacheEntry{}, false
+	}
+
+	path := filepath.Join(d.Dir, name)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		d.Delete(key)
+		return CacheEntry{}, false
+	}
+	i := bytes.IndexByte(raw, '\n')
+	var header diskHeader
+	if i < 0 || json.Unmarshal(raw[:i], &header) != nil || header.Key != key {
+		// corrupt entry
+		d.Delete(key)
+		return CacheEntry{}, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return CacheEntry{Data: raw[i+1:], ETag: header.ETag, Stored: header.Stored}, true
+}
+
+// SetEntry write the entry of key, evicting the least recently used entries over MaxBytes.
+// Entries larger than MaxBytes are not cached.
+func (d *DiskCache) SetEntry(key string, e CacheEntry) {
+	header, err := json.Marshal(diskHeader{Key: key, ETag: e.ETag, Stored: e.Stored})
+	if err != nil {
+		return
+	}
+	size := int64(len(header)) + 1 + int64(len(e.Data))
+	name := d.entryName(key)
+	if size > d.MaxBytes {
+		d.Delete(key)
+		return
+	}
+
+	// written aside then renamed, readers never see a partial entry
+	tmp, err := ioutil.TempFile(d.Dir, diskCacheTmp)
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(tmp)
+	w.Write(header)
+	w.WriteByte('\n')
+	w.Write(e.Data)
+	err = w.Flush()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(d.Dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old, ok := d.entries[name]; ok {
+		d.order.Remove(old)
+		d.size -= old.Value.(*diskEntry).size
+	}
+	d.entries[name] = d.order.PushFront(&diskEntry{name: name, size: size})
+	d.size += size
+	d.evict()
+}
+
+// Get return the cached content of key
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	e, ok := d.GetEntry(key)
+	return e.Data, ok
+}
+
+// Set cache data for key, see SetEntry
+func (d *DiskCache) Set(key string, data []byte) {
+	d.SetEntry(key, CacheEntry{Data: data, Stored: time.Now()})
+}
+
+// Delete remove key from the cache
+func (d *DiskCache) Delete(key string) {
+	d.mu.Lock()
+	d.remove(d.entryName(key))
+	d.mu.Unlock()
+}
+
+// Size return the bytes used by the cached entries
+func (d *DiskCache) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// evict the least recently used entries over MaxBytes, d.mu must be held
+func (d *DiskCache) evict() {
+	for d.size > d.MaxBytes && d.order.Len() > 0 {
+		d.remove(d.order.Back().Value.(*diskEntry).name)
+	}
+}
+
+// remove the entry file name, d.mu must be held
+func (d *DiskCache) remove(name string) {
+	e, ok := d.entries[name]
+	if !ok {
+		return
+	}
+	d.order.Remove(e)
+	delete(d.entries, name)
+	d.size -= e.Value.(*diskEntry).size
+	os.Remove(filepath.Join(d.Dir, name))
+}
@@ -0,0 +1,212 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDocumentPrefix is the prefix of documents stored by Documents by default
+	DefaultDocumentPrefix = "documents/"
+	// DefaultDocumentMaxSize is the size limit of documents by default
+	DefaultDocumentMaxSize = 20 << 20
+	// DocumentContentType is the content type of documents
+	DocumentContentType = "application/pdf"
+)
+
+// Metadata and tag keys set on documents
+const (
+	MetadataDocumentType  = "document_type"
+	MetadataDocumentID    = "document_id"
+	MetadataDocumentOwner = "document_owner"
+	MetadataIssuedAt      = "issued_at"
+	MetadataRetention     = "retention"
+)
+
+var (
+	// ErrNoDocumentPolicy returned when a document type has no policy
+	ErrNoDocumentPolicy = errors.New("file: no document policy")
+	// ErrInvalidDocument returned when a document is not a pdf or lack its type, id or owner
+	ErrInvalidDocument = errors.New("file: invalid document")
+)
+
+// DocumentPolicy is the rules of a document type, e.g. invoices kept 10 years and encrypted
+type DocumentPolicy struct {
+	// MaxSize reject larger documents with ErrTooLarge, DefaultDocumentMaxSize when 0
+	MaxSize int64
+	// Retention is the retention class set as tag and metadata, e.g. "10y", lifecycle rules and legal reviews select on it.
+	// Required.
+	Retention string
+	// Encrypt require a CryptoShredder in the store chain, documents are encrypted with the data key of their owner
+	Encrypt bool
+	// EncryptionScope rewrite documents with this encryption scope, the store chain must implement SetEncryptionScope
+	EncryptionScope string
+}
+
+// Document describe a generated document, e.g. an invoice or a receipt
+type Document struct {
+	// Type select the policy, e.g. "invoice"
+	Type string
+	// ID is the document number, unique for the owner and type
+	ID string
+	// Owner is the customer or tenant of the document, the data owner when the policy encrypt
+	Owner string
+	// IssuedAt date the document key, now when zero
+	IssuedAt time.Time
+	// Metadata is added to the metadata of the stored document
+	Metadata map[string]string
+}
+
+// Documents store server generated pdfs such as invoices and receipts the same way for every team:
+// the policy of the document type is enforced, the key follow one scheme and the catalog record is written with Publish.
+type Documents struct {
+	Store   IFile
+	Catalog Catalog
+	// Policies by document type, documents of other types are rejected with ErrNoDocumentPolicy
+	Policies map[string]DocumentPolicy
+	// Prefix of the document keys, DefaultDocumentPrefix when empty
+	Prefix string
+}
+
+// NewDocuments create Documents storing into store and recording into catalog
+//
+//	Example:
+//	docs := file.NewDocuments(store, catalog, map[string]file.DocumentPolicy{
+//		"invoice": {Retention: "10y", Encrypt: true},
+//		"receipt": {Retention: "2y", MaxSize: 2 << 20},
+//	})
+//	rec, err := docs.Put(ctx, file.Document{Type: "invoice", ID: "INV-2020-0042", Owner: customerID}, pdf)
+func NewDocuments(store IFile, catalog Catalog, policies map[string]DocumentPolicy) *Documents {
+	return &Documents{Store: store, Catalog: catalog, Policies: policies}
+}
+
+// Key return the key of doc, <prefix><type>/<owner>/<yyyy>/<mm>/<id>.pdf with the issue date in UTC
+func (d *Documents) Key(doc Document) string {
+	prefix := d.Prefix
+	if prefix == "" {
+		prefix = DefaultDocumentPrefix
+	}
+	issued := doc.IssuedAt
+	if issued.IsZero() {
+		issued = time.Now()
+	}
+	issued = issued.UTC()
+	return prefix + path.Join(doc.Type, doc.Owner, issued.Format("2006/01"), doc.ID+".pdf")
+}
+
+// Put read the pdf from r, check it against the policy of its type and store it with its catalog record
+func (d *Documents) Put(ctx context.Context, doc Document, r io.Reader) (CatalogRecord, error) {
+	policy, ok := d.Policies[doc.Type]
+	if !ok {
+		return CatalogRecord{}, fmt.Errorf("%w: %s", ErrNoDocumentPolicy, doc.Type)
+	}
+	if policy.Retention == "" {
+		return CatalogRecord{}, fmt.Errorf("%w: %s has no retention", ErrNoDocumentPolicy, doc.Type)
+	}
+	for _, segment := range []string{doc.ID, doc.Owner} {
+		if segment == "" || segment == "." || segment == ".." || strings.Contains(segment, "/") {
+			return CatalogRecord{}, fmt.Errorf("%w: id and owner are required and can't contain /", ErrInvalidDocument)
+		}
+	}
+	if doc.IssuedAt.IsZero() {
+		doc.IssuedAt = time.Now()
+	}
+	key := d.Key(doc)
+	if err := CheckKey(key); err != nil {
+		return CatalogRecord{}, err
+	}
+	tags := Tags{MetadataRetention: policy.Retention, MetadataDocumentType: doc.Type}
+	if err := validateTags(tags); err != nil {
+		return CatalogRecord{}, err
+	}
+
+	var scoper encryptionScoper
+	if policy.EncryptionScope != "" {
+		if scoper, ok = asEncryptionScoper(d.Store); !ok {
+			return CatalogRecord{}, fmt.Errorf("file: document policy of %s require an encryption scope the store doesn't support", doc.Type)
+		}
+	}
+	if policy.Encrypt {
+		if !hasShredder(d.Store) {
+			return CatalogRecord{}, fmt.Errorf("file: document policy of %s require a CryptoShredder", doc.Type)
+		}
+		ctx = WithActor(ctx, doc.Owner)
+	}
+
+	maxSize := policy.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultDocumentMaxSize
+	}
+	data, err := ioutil.ReadAll(SizeLimitReader(r, maxSize))
+	if err != nil {
+		return CatalogRecord{}, err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return CatalogRecord{}, fmt.Errorf("%w: not a pdf", ErrInvalidDocument)
+	}
+
+	for k, v := range doc.Metadata {
+		ctx = WithMetadata(ctx, k, v)
+	}
+	ctx = WithMetadata(ctx, MetadataDocumentType, doc.Type)
+	ctx = WithMetadata(ctx, MetadataDocumentID, doc.ID)
+	ctx = WithMetadata(ctx, MetadataDocumentOwner, doc.Owner)
+	ctx = WithMetadata(ctx, MetadataIssuedAt, doc.IssuedAt.UTC().Format(time.RFC3339))
+	ctx = WithMetadata(ctx, MetadataRetention, policy.Retention)
+	ctx = WithTags(ctx, tags)
+
+	rec, err := Publish(ctx, d.Catalog, d.Store, key, DocumentContentType, data)
+	if err != nil {
+		return rec, err
+	}
+	if policy.EncryptionScope != "" {
+		if err := scoper.SetEncryptionScope(ctx, key, policy.EncryptionScope); err != nil {
+			// a document readable without the policy key must not stay stored
+			Unpublish(ctx, d.Catalog, d.Store, key)
+			return CatalogRecord{}, err
+		}
+	}
+	return rec, nil
+}
+
+// encryptionScoper is a store rewriting files with an encryption scope, implemented by *File
+type encryptionScoper interface {
+	SetEncryptionScope(ctx context.Context, filePath, scope string) error
+}
+
+// asEncryptionScoper return the first store of the chain setting encryption scopes, looking through decorators
+func asEncryptionScoper(store IFile) (encryptionScoper, bool) {
+	for store != nil {
+		if s, ok := store.(encryptionScoper); ok {
+			return s, true
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, false
+}
+
+// hasShredder report whether a CryptoShredder is in the store chain
+func hasShredder(store IFile) bool {
+	for store != nil {
+		if _, ok := store.(*CryptoShredder); ok {
+			return true
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ErrNotFound returned when the requested file does not exist
+var ErrNotFound = errors.New("file: not found")
+
+// ErrPending returned when the requested file is being generated and not available yet, e.g. a rendition generated on demand
+var ErrPending = errors.New("file: not available yet")
+
+// isNotFound report whether err is a storage 404 response
+func isNotFound(err error) bool {
+	if err == ErrNotFound {
+		return true
+	}
+	if serr, ok := err.(azblob.StorageError); ok {
+		return serr.Response() != nil && serr.Response().StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// ErrNotSupported returned by implementations that can not provide an operation
+var ErrNotSupported = errors.New("file: operation not supported")
+
+// ErrThrottled returned when storage reject a request because of request rate
+var ErrThrottled = errors.New("file: throttled")
+
+// ErrReadOnly returned by writes on a client without credentials
+var ErrReadOnly = errors.New("file: read only")
+
+// ErrClosed returned by operations started after Close
+var ErrClosed = errors.New("file: closed")
+
+// ErrHeld is wrapped by HoldError, test it with errors.Is(err, file.ErrHeld)
+var ErrHeld = errors.New("file: held")
+
+// Reasons of HoldError
+const (
+	HoldLegal     = "legal hold"
+	HoldRetention = "retention policy"
+)
+
+// HoldError returned by Delete when a legal hold or an unexpired retention policy keep the file,
+// instead of the provider response that scripts take for a permission error
+type HoldError struct {
+	Key string
+	// Reason is HoldLegal or HoldRetention
+	Reason string
+}
+
+func (e *HoldError) Error() string {
+	return "file: " + e.Key + " is held by a " + e.Reason
+}
+
+// Unwrap return ErrHeld
+func (e *HoldError) Unwrap() error {
+	return ErrHeld
+}
+
+// holdReason return the hold of a storage 409 response refusing to change an immutable blob
+func holdReason(err error) (string, bool) {
+	serr, ok := err.(azblob.StorageError)
+	if !ok || serr.Response() == nil || serr.Response().StatusCode != http.StatusConflict {
+		return "", false
+	}
+	switch serr.ServiceCode() {
+	case "BlobImmutableDueToLegalHold":
+		return HoldLegal, true
+	case "BlobImmutableDueToPolicy":
+		return HoldRetention, true
+	}
+	return "", false
+}
@@ -0,0 +1,208 @@
+package file
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EventType is the kind of change reported by a storage event
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventDeleted EventType = "deleted"
+	// EventUpdated is emitted for header and encryption changes in dry run mode
+	EventUpdated EventType = "updated"
+)
+
+// ErrUnknownEvent returned when a message body is not a storage event
+var ErrUnknownEvent = errors.New("file: unknown event payload")
+
+// Event describes an object change delivered by Azure Event Grid or S3 event notifications
+type Event struct {
+	Type        EventType
+	Key         string
+	Container   string
+	URL         string
+	ContentType string
+	ETag        string
+	Size        int64
+	Time        time.Time
+
+	// Source is the copied file for events emitted by dry run copies
+	Source string
+	// DryRun is set on events describing an operation skipped in dry run mode
+	DryRun  bool
+	Actor   string
+	Purpose string
+}
+
+type eventGridEvent struct {
+	EventType string    `json:"eventType"`
+	Type      string    `json:"type"`
+	Subject   string    `json:"subject"`
+	EventTime time.Time `json:"eventTime"`
+	Time      time.Time `json:"time"`
+	Data      struct {
+		URL           string `json:"url"`
+		ContentType   string `json:"contentType"`
+		ContentLength int64  `json:"contentLength"`
+		ETag          string `json:"eTag"`
+	} `json:"data"`
+}
+
+type s3Notification struct {
+	Records []struct {
+		EventName string    `json:"eventName"`
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+type snsEnvelope struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// ParseEvents decode queue message body into storage events.
+// Accepts Event Grid (single event or batch, event grid or cloud event schema),
+// S3 event notifications and S3 notifications wrapped in an SNS envelope.
+// Body may be base64 encoded as Azure Queue delivers Event Grid messages that way.
+// Events other than object created/deleted are skipped.
+func ParseEvents(body []byte) ([]Event, error) {
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) > 0 && body[0] != '{' && body[0] != '[' {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, ErrUnknownEvent
+		}
+		body = decoded
+	}
+
+	if len(body) > 0 && body[0] == '[' {
+		var batch []eventGridEvent
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		return fromEventGrid(batch), nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case probe["Records"] != nil:
+		var n s3Notification
+		if err := json.Unmarshal(body, &n); err != nil {
+			return nil, err
+		}
+		return fromS3(n), nil
+	case probe["Message"] != nil && probe["Type"] != nil:
+		var env snsEnvelope
+		if err := json.Unmarshal(body, &env); err != nil {
+			return nil, err
+		}
+		return ParseEvents([]byte(env.Message))
+	case probe["subject"] != nil:
+		var e eventGridEvent
+		if err := json.Unmarshal(body, &e); err != nil {
+			return nil, err
+		}
+		return fromEventGrid([]eventGridEvent{e}), nil
+	case probe["Event"] != nil:
+		// s3:TestEvent sent when a notification is configured
+		return nil, nil
+	}
+
+	return nil, ErrUnknownEvent
+}
+
+func fromEventGrid(batch []eventGridEvent) (events []Event) {
+	for _, e := range batch {
+		name := e.EventType
+		if name == "" {
+			name = e.Type
+		}
+
+		var t EventType
+		switch name {
+		case "Microsoft.Storage.BlobCreated":
+			t = EventCreated
+		case "Microsoft.Storage.BlobDeleted":
+			t = EventDeleted
+		default:
+			continue
+		}
+
+		at := e.EventTime
+		if at.IsZero() {
+			at = e.Time
+		}
+
+		// subject: /blobServices/default/containers/{container}/blobs/{key}
+		container, key := "", ""
+		if i := strings.Index(e.Subject, "/containers/"); i >= 0 {
+			rest := e.Subject[i+len("/containers/"):]
+			if j := strings.Index(rest, "/blobs/"); j >= 0 {
+				container, key = rest[:j], rest[j+len("/blobs/"):]
+			}
+		}
+
+		events = append(events, Event{
+			Type:        t,
+			Key:         key,
+			Container:   container,
+			URL:         e.Data.URL,
+			ContentType: e.Data.ContentType,
+			ETag:        e.Data.ETag,
+			Size:        e.Data.ContentLength,
+			Time:        at,
+		})
+	}
+	return
+}
+
+func fromS3(n s3Notification) (events []Event) {
+	for _, r := range n.Records {
+		var t EventType
+		switch {
+		case strings.HasPrefix(r.EventName, "ObjectCreated:"):
+			t = EventCreated
+		case strings.HasPrefix(r.EventName, "ObjectRemoved:"):
+			t = EventDeleted
+		default:
+			continue
+		}
+
+		// S3 keys are form encoded in notifications
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			key = r.S3.Object.Key
+		}
+
+		events = append(events, Event{
+			Type:      t,
+			Key:       key,
+			Container: r.S3.Bucket.Name,
+			ETag:      r.S3.Object.ETag,
+			Size:      r.S3.Object.Size,
+			Time:      r.EventTime,
+		})
+	}
+	return
+}
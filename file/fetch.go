@@ -0,0 +1,173 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultFetchMaxSize is the largest resource UploadFromURL accept by default
+	DefaultFetchMaxSize = 10 << 20
+	// DefaultFetchTimeout bound the whole fetch by default
+	DefaultFetchTimeout = 30 * time.Second
+)
+
+var (
+	// ErrTooLarge returned when content exceed the allowed size
+	ErrTooLarge = errors.New("file: content too large")
+	// ErrContentTypeNotAllowed returned when fetched content type is not allowed
+	ErrContentTypeNotAllowed = errors.New("file: content type not allowed")
+	// ErrChecksumMismatch returned when fetched content does not match the expected checksum
+	ErrChecksumMismatch = errors.New("file: checksum mismatch")
+)
+
+// FetchError returned when the remote server answer with a non 2xx status
+type FetchError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("file: fetch %s: status %d", e.URL, e.StatusCode)
+}
+
+// FetchOptions configure UploadFromURL, zero values use the defaults
+type FetchOptions struct {
+	// Client send the request, http.DefaultClient when nil
+	Client *http.Client
+	// MaxSize is the largest accepted content, DefaultFetchMaxSize when 0
+	MaxSize int64
+	// Timeout bound the whole fetch, DefaultFetchTimeout when 0
+	Timeout time.Duration
+	// AllowedTypes are accepted content type prefixes, e.g. "image/", any type when empty
+	AllowedTypes []string
+	// ContentType stored instead of the fetched one when set
+	ContentType string
+	// SHA256 is the expected hex encoded checksum of the content, not verified when empty
+	SHA256 string
+}
+
+// UploadFromURL fetch a remote http(s) resource and upload it to destPath of store.
+// The content type is taken from the response, sniffed when missing or generic.
+//
+//	Example:
+//	url, err := file.UploadFromURL(ctx, store, "avatars/42.jpg", profile.PictureURL, file.FetchOptions{
+//		MaxSize:      2 << 20,
+//		AllowedTypes: []string{"image/"},
+//	})
+func UploadFromURL(ctx context.Context, store IFile, destPath, srcURL string, opts FetchOptions) (string, error) {
+	data, contentType, err := Fetch(ctx, srcURL, opts)
+	if err != nil {
+		return "", err
+	}
+	return store.Upload(ctx, destPath, contentType, data)
+}
+
+// UploadFromURL fetch a remote resource into destPath using the client HTTP client, see UploadFromURL
+func (c *File) UploadFromURL(ctx context.Context, destPath, srcURL string, opts FetchOptions) (string, error) {
+	if opts.Client == nil {
+		opts.Client = c.HTTPClient
+	}
+	return UploadFromURL(ctx, c, destPath, srcURL, opts)
+}
+
+// Fetch download a remote http(s) resource enforcing opts, return the content and its content type
+func Fetch(ctx context.Context, srcURL string, opts FetchOptions) ([]byte, string, error) {
+	u, err := url.Parse(srcURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, "", fmt.Errorf("file: fetch %s: unsupported scheme %q", srcURL, u.Scheme)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultFetchMaxSize
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, srcURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", &FetchError{URL: srcURL, StatusCode: resp.StatusCode}
+	}
+	if resp.ContentLength > maxSize {
+		return nil, "", ErrTooLarge
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "" && mediaType != "application/octet-stream" && !typeAllowed(mediaType, opts.AllowedTypes) {
+		return nil, "", ErrContentTypeNotAllowed
+	}
+
+	// read one more byte than allowed to detect content without length
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", ErrTooLarge
+	}
+
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+		if !typeAllowed(mediaType, opts.AllowedTypes) {
+			return nil, "", ErrContentTypeNotAllowed
+		}
+	}
+
+	if opts.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), opts.SHA256) {
+			return nil, "", ErrChecksumMismatch
+		}
+	}
+
+	if opts.ContentType != "" {
+		contentType = opts.ContentType
+	}
+	return data, contentType, nil
+}
+
+func typeAllowed(mediaType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, prefix := range allowed {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,13 +3,15 @@ package file
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
+	"crypto/md5"
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -30,6 +32,15 @@ type IFile interface {
 	GetContainer() (azblob.ContainerURL, error)
 	GenerateSharedAccessSignature(expiryTime string, fileName string) string
 	GetListBlob(ctx context.Context, prefix string) (list []string, err error)
+	PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error)
+	ListDir(ctx context.Context, prefix string) (DirList, error)
+	List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error)
+	ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem
+	Usage(ctx context.Context, prefix string) (UsageReport, error)
+	Download(ctx context.Context, filePath string) ([]byte, error)
+	DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error)
+	Copy(ctx context.Context, srcPath, dstPath string) (string, error)
+	SetHeaders(ctx context.Context, filePath string, h Headers) error
 }
 
 type File struct {
@@ -38,40 +49,91 @@ type File struct {
 	RootURL       string
 	ContainerName string
 	APIVersion    string
+	HTTPClient    *http.Client
+
+	// BlockSize and Parallelism split uploads and downloads into blocks transferred in parallel,
+	// files are transferred in a single request when BlockSize is zero
+	BlockSize   int64
+	Parallelism uint16
+
+	// TempDir is where DownloadToTemp create files, os.TempDir when empty
+	TempDir string
+
+	// MaxUploadSize reject uploads larger than this many bytes with ErrTooLarge, unlimited when zero
+	MaxUploadSize int64
+
+	// Decompress decode downloads stored with a Content-Encoding, see WithDecompression
+	Decompress bool
+	// Compression is the Content-Encoding uploads are compressed with, see WithCompression
+	Compression string
+
+	// NormalizeKey rewrite every key and list prefix before use, e.g. to Unicode NFC, see WithKeyNormalization. Nil keep keys as given
+	NormalizeKey func(string) string
+
+	// Anonymous read a public container without credentials, writes fail with ErrReadOnly
+	Anonymous bool
+
+	// DryRun skip Upload, Delete and Copy, reporting what they would do to OnDryRun instead
+	DryRun   bool
+	OnDryRun func(Event)
+
+	// WarmUp establish the storage connection in the background at construction, see Ready
+	WarmUp    bool
+	readyOnce sync.Once
+	warmOnce  sync.Once
+	ready     chan struct{}
+	warmErr   error
+
+	// urls cache the base url and decoded key, see urlCache
+	urls atomic.Value
 }
 
 //New set account, access key, root url, container name, api version before using this library
-func New(account, accessKey, rootURL, containerName, apiVersion string) IFile {
-	return &File{
+func New(account, accessKey, rootURL, containerName, apiVersion string, opts ...Option) IFile {
+	c := &File{
 		Account:       account,
 		AccessKey:     accessKey,
 		RootURL:       rootURL,
 		ContainerName: containerName,
 		APIVersion:    apiVersion,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.startWarmUp()
+	return c
+}
+
+// NewPublic create read-only client of a public container, no key is needed.
+// Download, listing and urls work, the container access level must be "container" for listing.
+// Uploads, deletes and copies fail with ErrReadOnly, urls are never signed.
+//
+//	Example:
+//	f := file.NewPublic(account, "https://%s.blob.core.windows.net/%s", containerName)
+func NewPublic(account, rootURL, containerName string, opts ...Option) IFile {
+	c := &File{
+		Account:       account,
+		RootURL:       rootURL,
+		ContainerName: containerName,
+		Anonymous:     true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.startWarmUp()
+	return c
 }
 
 // GetURL return string with blob_url, account and container name
 func (c *File) GetURL() string {
-	return fmt.Sprintf(c.RootURL, c.Account, c.ContainerName)
+	return c.urlCache().base
 }
 
-//GetContainer return container URL
+//GetContainer return container URL.
+// The client is built once and shared, it is rebuilt when the account, key, url or HTTPClient fields change
 func (c *File) GetContainer() (azblob.ContainerURL, error) {
-	credential, err := azblob.NewSharedKeyCredential(c.Account, c.AccessKey)
-	if err != nil {
-		return azblob.ContainerURL{}, err
-	}
-
-	p := azblob.NewPipeline(credential, azblob.PipelineOptions{})
-	URL, err := url.Parse(c.GetURL())
-	if err != nil {
-		return azblob.ContainerURL{}, err
-	}
-
-	containerURL := azblob.NewContainerURL(*URL, p)
-
-	return containerURL, nil
+	_, containerURL, err := c.storagePipeline()
+	return containerURL, err
 }
 
 // GetBlobURL convert file name and return as file url.
@@ -89,23 +151,84 @@ func (c *File) GetBlobURL(fileName string, withSignature bool) string {
 		return fileName
 	}
 
-	if !withSignature {
-		return fmt.Sprintf("%s/%s", c.GetURL(), fileName)
+	fileName = c.normalizedKey(fileName)
+	uc := c.urlCache()
+	if !withSignature || c.Anonymous {
+		var b strings.Builder
+		b.Grow(len(uc.base) + 1 + escapedKeyLen(fileName))
+		b.WriteString(uc.base)
+		b.WriteByte('/')
+		writeKeyEscaped(&b, fileName)
+		return b.String()
 	}
 
-	timeIn := time.Now().Add(time.Second * ExpireTime)
+	timeIn := time.Now().Add(time.Second * ExpireTime).UTC()
 	expiryTime := timeIn.Format("2006-01-02T15:04:05Z")
-	sig := c.GenerateSharedAccessSignature(expiryTime, fileName)
 
-	queryParams := []string{
-		"se=" + url.QueryEscape(expiryTime),
-		"sr=" + ResourceType,
-		"sp=" + Permission,
-		"sig=" + url.QueryEscape(sig),
-		"sv=" + url.QueryEscape(c.APIVersion),
+	return c.signedURL(uc.key, expiryTime, fileName)
+}
+
+// PresignMany return signed url for every key, keyed by file name.
+// Signatures are computed locally so no request is sent to storage.
+// if expiry is zero, ExpireTime is used
+//
+//	Example:
+//	urls, err := file.PresignMany(ctx, []string{"file/a.img", "file/b.img"}, time.Hour)
+func (c *File) PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+	if c.Anonymous {
+		urls := make(map[string]string, len(keys))
+		for _, key := range keys {
+			if key != "" {
+				urls[key] = c.GetBlobURL(key, false)
+			}
+		}
+		return urls, nil
+	}
+
+	if expiry <= 0 {
+		expiry = time.Second * ExpireTime
+	}
+
+	decodeAccessKey, err := base64.StdEncoding.DecodeString(c.AccessKey)
+	if err != nil {
+		return nil, err
+	}
+	expiryTime := time.Now().Add(expiry).UTC().Format("2006-01-02T15:04:05Z")
+
+	urls := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if key == "" {
+			continue
+		}
+		urls[key] = c.signedURL(decodeAccessKey, expiryTime, c.normalizedKey(key))
 	}
 
-	return fmt.Sprintf("%s/%s?%s", c.GetURL(), fileName, strings.Join(queryParams, "&"))
+	return urls, nil
+}
+
+func (c *File) signedURL(accessKey []byte, expiryTime, fileName string) string {
+	var buf [sasSignatureSize]byte
+	sig := c.appendSignature(buf[:0], accessKey, expiryTime, fileName)
+	base := c.urlCache().base
+
+	// escaping at most triple the size of the escaped values
+	var b strings.Builder
+	b.Grow(len(base) + 1 + escapedKeyLen(fileName) + 3*(len(expiryTime)+len(sig)+len(c.APIVersion)) + 32)
+	b.WriteString(base)
+	b.WriteByte('/')
+	writeKeyEscaped(&b, fileName)
+	b.WriteString("?se=")
+	writeQueryEscaped(&b, expiryTime)
+	b.WriteString("&sr=" + ResourceType + "&sp=" + Permission + "&sig=")
+	for _, ch := range sig {
+		writeQueryByte(&b, ch)
+	}
+	b.WriteString("&sv=")
+	writeQueryEscaped(&b, c.APIVersion)
+	return b.String()
 }
 
 // GetFileName convert file url and return as file name.
@@ -124,56 +247,141 @@ func (c *File) GetFileName(blobUrl string) string {
 
 //GenerateSharedAccessSignature return access signature key
 func (c *File) GenerateSharedAccessSignature(expiryTime string, fileName string) string {
-	blob := fmt.Sprintf("/%s/%s/%s", c.Account, c.ContainerName, fileName)
-
-	queryParams := []string{
-		Permission, // permissions
-		"",
-		expiryTime, // expiry
-		blob,
-		"",
-		c.APIVersion, // API version
-		"", "", "", "", ""}
-	toSign := strings.Join(queryParams, "\n")
+	if c.Anonymous {
+		return ""
+	}
 	decodeAccessKey, _ := base64.StdEncoding.DecodeString(c.AccessKey)
+	return c.sign(decodeAccessKey, expiryTime, c.normalizedKey(fileName))
+}
 
-	h := hmac.New(sha256.New, []byte(decodeAccessKey))
-	h.Write([]byte(toSign))
-
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+func (c *File) sign(accessKey []byte, expiryTime string, fileName string) string {
+	return string(c.appendSignature(nil, accessKey, expiryTime, fileName))
 }
 
-// Upload file to storage
+// Upload file to storage.
+// Actor and purpose set on ctx with WithActor and WithPurpose are stored as file metadata
 //
 //	Example:
 //	file := file.Upload(ctx, "/file/image.img", buffBytes)
 func (c *File) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
-	containerURL, err := c.GetContainer()
+	blob, err := c.UploadBlob(ctx, filePath, contentType, buffBytes)
 	if err != nil {
 		return "", err
 	}
+	return blob.URL, nil
+}
+
+// UploadBlob upload like Upload and return the ETag and MD5 of the uploaded content, the MD5 is also stored as its Content-MD5.
+// Compressed with a Content-Encoding, the Content-MD5 is the one of the stored compressed bytes
+// and the MD5 of buffBytes is stored as the MetadataContentMD5 metadata.
+//
+//	Example:
+//	blob, err := file.UploadBlob(ctx, "/file/image.img", "", buffBytes)
+func (c *File) UploadBlob(ctx context.Context, filePath, contentType string, buffBytes []byte) (Blob, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return Blob{}, err
+	}
+	if c.Anonymous {
+		return Blob{}, ErrReadOnly
+	}
+	if c.DryRun {
+		if contentType == "" {
+			contentType = http.DetectContentType(buffBytes)
+		}
+		c.dryRun(ctx, Event{Type: EventCreated, Key: filePath, ContentType: contentType, Size: int64(len(buffBytes))})
+		return newBlob(c.GetBlobURL(filePath, false), filePath, buffBytes), nil
+	}
+	size := int64(len(buffBytes))
+	if c.MaxUploadSize > 0 && size > c.MaxUploadSize {
+		return Blob{}, ErrTooLarge
+	}
+	blockSize, err := uploadBlockSize(size, c.BlockSize)
+	if err != nil {
+		return Blob{}, err
+	}
+	if err := validateTags(TagsFromContext(ctx)); err != nil {
+		return Blob{}, err
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return Blob{}, err
+	}
 	blobURL := containerURL.NewBlockBlobURL(filePath)
 	if contentType == "" {
 		contentType = http.DetectContentType(buffBytes)
 	}
+	blob := newBlob(c.GetBlobURL(filePath, false), filePath, buffBytes)
+	headers := azblob.BlobHTTPHeaders{ContentType: contentType, ContentMD5: blob.ContentMD5}
+	metadata := contextMetadata(ctx)
+	if encoding := c.uploadEncoding(ctx); encoding != "" {
+		buffBytes, err = EncodeContent(encoding, buffBytes)
+		if err != nil {
+			return Blob{}, err
+		}
+		sum := md5.Sum(buffBytes)
+		headers.ContentEncoding, headers.ContentMD5 = encoding, sum[:]
+		metadata[MetadataContentMD5] = blob.MD5()
+		size = int64(len(buffBytes))
+		if blockSize, err = uploadBlockSize(size, c.BlockSize); err != nil {
+			return Blob{}, err
+		}
+	}
 
-	_, err = blobURL.Upload(ctx,
-		bytes.NewReader(buffBytes),
-		azblob.BlobHTTPHeaders{ContentType: contentType},
-		azblob.Metadata{}, azblob.BlobAccessConditions{})
+	// a single request can't carry more than BlockBlobMaxUploadBlobBytes
+	if c.BlockSize > 0 || size > azblob.BlockBlobMaxUploadBlobBytes {
+		var resp azblob.CommonResponse
+		resp, err = azblob.UploadBufferToBlockBlob(ctx, buffBytes, blobURL, azblob.UploadToBlockBlobOptions{
+			BlockSize:       blockSize,
+			Parallelism:     c.Parallelism,
+			BlobHTTPHeaders: headers,
+			Metadata:        metadata,
+		})
+		if err == nil {
+			blob.ETag, blob.LastModified = string(resp.ETag()), resp.LastModified()
+		}
+	} else {
+		var resp *azblob.BlockBlobUploadResponse
+		resp, err = blobURL.Upload(ctx,
+			bytes.NewReader(buffBytes),
+			headers,
+			metadata, azblob.BlobAccessConditions{})
+		if err == nil {
+			blob.ETag, blob.LastModified = string(resp.ETag()), resp.LastModified()
+		}
+	}
 
 	if err != nil {
-		return "", err
+		return Blob{}, err
 	}
 
-	return c.GetBlobURL(filePath, false), nil
+	if tags := TagsFromContext(ctx); len(tags) > 0 {
+		if err := c.SetTags(ctx, filePath, tags); err != nil {
+			return Blob{}, err
+		}
+	}
+
+	return blob, nil
 }
 
-// Delete file from storage
+// Delete file from storage, a file kept by a legal hold or a retention policy fail with a *HoldError
 //
 //	Example:
 //	file := file.Delete(ctx, "/file/image.img")
 func (c *File) Delete(ctx context.Context, filePath string) (string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return "", err
+	}
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventDeleted, Key: filePath})
+		return c.GetBlobURL(filePath, false), nil
+	}
+
 	containerURL, err := c.GetContainer()
 	if err != nil {
 		return "", err
@@ -185,13 +393,150 @@ func (c *File) Delete(ctx context.Context, filePath string) (string, error) {
 		azblob.BlobAccessConditions{})
 
 	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNotFound
+		}
+		if reason, ok := holdReason(err); ok {
+			return "", &HoldError{Key: filePath, Reason: reason}
+		}
 		return "", err
 	}
 
 	return c.GetBlobURL(filePath, false), nil
 }
 
+// Download file content from storage, return ErrNotFound if file does not exist
+//
+//	Example:
+//	buffBytes, err := file.Download(ctx, "file/image.img")
+func (c *File) Download(ctx context.Context, filePath string) ([]byte, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize > 0 {
+		return c.downloadBlocks(ctx, filePath)
+	}
+
+	resp, err := c.openBlob(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: 3})
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.decodeContent(ctx, resp.ContentEncoding(), data)
+}
+
+func (c *File) downloadBlocks(ctx context.Context, filePath string) ([]byte, error) {
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil, err
+	}
+	blobURL := containerURL.NewBlobURL(filePath)
+
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	size := props.ContentLength()
+	if size == 0 {
+		return []byte{}, nil
+	}
+	if size > maxInt {
+		return nil, ErrTooLarge
+	}
+	buff := make([]byte, size)
+	err = azblob.DownloadBlobToBuffer(ctx, blobURL, 0, 0, buff, azblob.DownloadFromBlobOptions{
+		BlockSize:   c.BlockSize,
+		Parallelism: c.Parallelism,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decodeContent(ctx, props.ContentEncoding(), buff)
+}
+
+// Copy file to another path inside the container, the copy is done by storage without downloading the file
+//
+//	Example:
+//	url, err := file.Copy(ctx, "file/image.img", "archive/image.img")
+func (c *File) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	srcPath, err := c.key(srcPath)
+	if err != nil {
+		return "", err
+	}
+	dstPath, err = c.key(dstPath)
+	if err != nil {
+		return "", err
+	}
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventCreated, Key: dstPath, Source: srcPath})
+		return c.GetBlobURL(dstPath, false), nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", err
+	}
+	// the copy source is escaped like GetBlobURL, azblob keep "+" unescaped
+	srcURL := containerURL.URL()
+	srcURL.RawPath = strings.TrimSuffix(srcURL.EscapedPath(), "/") + "/" + EscapeKey(srcPath)
+	srcURL.Path = strings.TrimSuffix(srcURL.Path, "/") + "/" + srcPath
+	dstURL := containerURL.NewBlobURL(dstPath)
+
+	resp, err := dstURL.StartCopyFromURL(ctx, srcURL, azblob.Metadata{}, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{})
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	status, err := waitCopy(ctx, dstURL, resp.CopyStatus())
+	if err != nil {
+		return "", err
+	}
+	if status != azblob.CopyStatusSuccess {
+		return "", fmt.Errorf("file: copy %s to %s %s", srcPath, dstPath, status)
+	}
+
+	return c.GetBlobURL(dstPath, false), nil
+}
+
+// waitCopy return the final status of the copy into dstURL,
+// copy inside an account usually completes synchronously, otherwise wait for it
+func waitCopy(ctx context.Context, dstURL azblob.BlobURL, status azblob.CopyStatusType) (azblob.CopyStatusType, error) {
+	for status == azblob.CopyStatusPending {
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(time.Second):
+		}
+
+		props, err := dstURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+		if err != nil {
+			return status, err
+		}
+		status = props.CopyStatus()
+	}
+	return status, nil
+}
+
 func (c *File) GetListBlob(ctx context.Context, prefix string) (list []string, err error) {
+	prefix = c.normalizedKey(prefix)
 	containerURL, err := c.GetContainer()
 	if err != nil {
 		return
@@ -199,7 +544,8 @@ func (c *File) GetListBlob(ctx context.Context, prefix string) (list []string, e
 
 	// List the blob(s) in our container; since a container may hold millions of blobs, this is done 1 segment at a time.
 	for marker := (azblob.Marker{}); marker.NotDone(); { // The parens around Marker{} are required to avoid compiler error.
-		listBlob, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
+		var listBlob *azblob.ListBlobsFlatSegmentResponse
+		listBlob, err = containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
 		if err != nil {
 			return
 		}
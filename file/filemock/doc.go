@@ -0,0 +1,12 @@
+// Package filemock provide generated mocks of the file package interfaces.
+// Mocks are kept in sync with the interfaces by go generate, run it after changing an interface:
+//
+//	go generate ./file/...
+//
+//	Example:
+//	store := &filemock.IFileMock{
+//		UploadFunc: func(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+//			return "https://storage.blob.core.windows.net/container/" + filePath, nil
+//		},
+//	}
+package filemock
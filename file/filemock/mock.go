@@ -0,0 +1,3061 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package filemock
+
+import (
+	"context"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/ndv6/assets-sdk/file"
+	"io"
+	"sync"
+	"time"
+)
+
+// Ensure, that IFileMock does implement file.IFile.
+// If this is not the case, regenerate this file with moq.
+var _ file.IFile = &IFileMock{}
+
+// IFileMock is a mock implementation of file.IFile.
+//
+//	func TestSomethingThatUsesIFile(t *testing.T) {
+//
+//		// make and configure a mocked file.IFile
+//		mockedIFile := &IFileMock{
+//			CopyFunc: func(ctx context.Context, srcPath string, dstPath string) (string, error) {
+//				panic("mock out the Copy method")
+//			},
+//			DeleteFunc: func(ctx context.Context, filePath string) (string, error) {
+//				panic("mock out the Delete method")
+//			},
+//			DownloadFunc: func(ctx context.Context, filePath string) ([]byte, error) {
+//				panic("mock out the Download method")
+//			},
+//			DownloadIfModifiedFunc: func(ctx context.Context, filePath string, knownETag string) ([]byte, string, error) {
+//				panic("mock out the DownloadIfModified method")
+//			},
+//			GenerateSharedAccessSignatureFunc: func(expiryTime string, fileName string) string {
+//				panic("mock out the GenerateSharedAccessSignature method")
+//			},
+//			GetBlobURLFunc: func(fileName string, withSignature bool) string {
+//				panic("mock out the GetBlobURL method")
+//			},
+//			GetContainerFunc: func() (azblob.ContainerURL, error) {
+//				panic("mock out the GetContainer method")
+//			},
+//			GetFileNameFunc: func(blobUrl string) string {
+//				panic("mock out the GetFileName method")
+//			},
+//			GetListBlobFunc: func(ctx context.Context, prefix string) ([]string, error) {
+//				panic("mock out the GetListBlob method")
+//			},
+//			GetURLFunc: func() string {
+//				panic("mock out the GetURL method")
+//			},
+//			ListFunc: func(ctx context.Context, prefix string, opts ...file.ListOption) ([]file.ObjectInfo, error) {
+//				panic("mock out the List method")
+//			},
+//			ListDirFunc: func(ctx context.Context, prefix string) (file.DirList, error) {
+//				panic("mock out the ListDir method")
+//			},
+//			ListStreamFunc: func(ctx context.Context, prefix string, opts ...file.ListOption) <-chan file.ListItem {
+//				panic("mock out the ListStream method")
+//			},
+//			PresignManyFunc: func(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+//				panic("mock out the PresignMany method")
+//			},
+//			SetHeadersFunc: func(ctx context.Context, filePath string, h file.Headers) error {
+//				panic("mock out the SetHeaders method")
+//			},
+//			UploadFunc: func(ctx context.Context, filePath string, contentType string, buffBytes []byte) (string, error) {
+//				panic("mock out the Upload method")
+//			},
+//			UsageFunc: func(ctx context.Context, prefix string) (file.UsageReport, error) {
+//				panic("mock out the Usage method")
+//			},
+//		}
+//
+//		// use mockedIFile in code that requires file.IFile
+//		// and then make assertions.
+//
+//	}
+type IFileMock struct {
+	// CopyFunc mocks the Copy method.
+	CopyFunc func(ctx context.Context, srcPath string, dstPath string) (string, error)
+
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, filePath string) (string, error)
+
+	// DownloadFunc mocks the Download method.
+	DownloadFunc func(ctx context.Context, filePath string) ([]byte, error)
+
+	// DownloadIfModifiedFunc mocks the DownloadIfModified method.
+	DownloadIfModifiedFunc func(ctx context.Context, filePath string, knownETag string) ([]byte, string, error)
+
+	// GenerateSharedAccessSignatureFunc mocks the GenerateSharedAccessSignature method.
+	GenerateSharedAccessSignatureFunc func(expiryTime string, fileName string) string
+
+	// GetBlobURLFunc mocks the GetBlobURL method.
+	GetBlobURLFunc func(fileName string, withSignature bool) string
+
+	// GetContainerFunc mocks the GetContainer method.
+	GetContainerFunc func() (azblob.ContainerURL, error)
+
+	// GetFileNameFunc mocks the GetFileName method.
+	GetFileNameFunc func(blobUrl string) string
+
+	// GetListBlobFunc mocks the GetListBlob method.
+	GetListBlobFunc func(ctx context.Context, prefix string) ([]string, error)
+
+	// GetURLFunc mocks the GetURL method.
+	GetURLFunc func() string
+
+	// ListFunc mocks the List method.
+	ListFunc func(ctx context.Context, prefix string, opts ...file.ListOption) ([]file.ObjectInfo, error)
+
+	// ListDirFunc mocks the ListDir method.
+	ListDirFunc func(ctx context.Context, prefix string) (file.DirList, error)
+
+	// ListStreamFunc mocks the ListStream method.
+	ListStreamFunc func(ctx context.Context, prefix string, opts ...file.ListOption) <-chan file.ListItem
+
+	// PresignManyFunc mocks the PresignMany method.
+	PresignManyFunc func(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error)
+
+	// SetHeadersFunc mocks the SetHeaders method.
+	SetHeadersFunc func(ctx context.Context, filePath string, h file.Headers) error
+
+	// UploadFunc mocks the Upload method.
+	UploadFunc func(ctx context.Context, filePath string, contentType string, buffBytes []byte) (string, error)
+
+	// UsageFunc mocks the Usage method.
+	UsageFunc func(ctx context.Context, prefix string) (file.UsageReport, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Copy holds details about calls to the Copy method.
+		Copy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SrcPath is the srcPath argument value.
+			SrcPath string
+			// DstPath is the dstPath argument value.
+			DstPath string
+		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+		}
+		// Download holds details about calls to the Download method.
+		Download []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+		}
+		// DownloadIfModified holds details about calls to the DownloadIfModified method.
+		DownloadIfModified []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// KnownETag is the knownETag argument value.
+			KnownETag string
+		}
+		// GenerateSharedAccessSignature holds details about calls to the GenerateSharedAccessSignature method.
+		GenerateSharedAccessSignature []struct {
+			// ExpiryTime is the expiryTime argument value.
+			ExpiryTime string
+			// FileName is the fileName argument value.
+			FileName string
+		}
+		// GetBlobURL holds details about calls to the GetBlobURL method.
+		GetBlobURL []struct {
+			// FileName is the fileName argument value.
+			FileName string
+			// WithSignature is the withSignature argument value.
+			WithSignature bool
+		}
+		// GetContainer holds details about calls to the GetContainer method.
+		GetContainer []struct {
+		}
+		// GetFileName holds details about calls to the GetFileName method.
+		GetFileName []struct {
+			// BlobUrl is the blobUrl argument value.
+			BlobUrl string
+		}
+		// GetListBlob holds details about calls to the GetListBlob method.
+		GetListBlob []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+		}
+		// GetURL holds details about calls to the GetURL method.
+		GetURL []struct {
+		}
+		// List holds details about calls to the List method.
+		List []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+			// Opts is the opts argument value.
+			Opts []file.ListOption
+		}
+		// ListDir holds details about calls to the ListDir method.
+		ListDir []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+		}
+		// ListStream holds details about calls to the ListStream method.
+		ListStream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+			// Opts is the opts argument value.
+			Opts []file.ListOption
+		}
+		// PresignMany holds details about calls to the PresignMany method.
+		PresignMany []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Keys is the keys argument value.
+			Keys []string
+			// Expiry is the expiry argument value.
+			Expiry time.Duration
+		}
+		// SetHeaders holds details about calls to the SetHeaders method.
+		SetHeaders []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// H is the h argument value.
+			H file.Headers
+		}
+		// Upload holds details about calls to the Upload method.
+		Upload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// ContentType is the contentType argument value.
+			ContentType string
+			// BuffBytes is the buffBytes argument value.
+			BuffBytes []byte
+		}
+		// Usage holds details about calls to the Usage method.
+		Usage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+		}
+	}
+	lockCopy                          sync.RWMutex
+	lockDelete                        sync.RWMutex
+	lockDownload                      sync.RWMutex
+	lockDownloadIfModified            sync.RWMutex
+	lockGenerateSharedAccessSignature sync.RWMutex
+	lockGetBlobURL                    sync.RWMutex
+	lockGetContainer                  sync.RWMutex
+	lockGetFileName                   sync.RWMutex
+	lockGetListBlob                   sync.RWMutex
+	lockGetURL                        sync.RWMutex
+	lockList                          sync.RWMutex
+	lockListDir                       sync.RWMutex
+	lockListStream                    sync.RWMutex
+	lockPresignMany                   sync.RWMutex
+	lockSetHeaders                    sync.RWMutex
+	lockUpload                        sync.RWMutex
+	lockUsage                         sync.RWMutex
+}
+
+// Copy calls CopyFunc.
+func (mock *IFileMock) Copy(ctx context.Context, srcPath string, dstPath string) (string, error) {
+	if mock.CopyFunc == nil {
+		panic("IFileMock.CopyFunc: method is nil but IFile.Copy was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		SrcPath string
+		DstPath string
+	}{
+		Ctx:     ctx,
+		SrcPath: srcPath,
+		DstPath: dstPath,
+	}
+	mock.lockCopy.Lock()
+	mock.calls.Copy = append(mock.calls.Copy, callInfo)
+	mock.lockCopy.Unlock()
+	return mock.CopyFunc(ctx, srcPath, dstPath)
+}
+
+// CopyCalls gets all the calls that were made to Copy.
+// Check the length with:
+//
+//	len(mockedIFile.CopyCalls())
+func (mock *IFileMock) CopyCalls() []struct {
+	Ctx     context.Context
+	SrcPath string
+	DstPath string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		SrcPath string
+		DstPath string
+	}
+	mock.lockCopy.RLock()
+	calls = mock.calls.Copy
+	mock.lockCopy.RUnlock()
+	return calls
+}
+
+// Delete calls DeleteFunc.
+func (mock *IFileMock) Delete(ctx context.Context, filePath string) (string, error) {
+	if mock.DeleteFunc == nil {
+		panic("IFileMock.DeleteFunc: method is nil but IFile.Delete was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(ctx, filePath)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedIFile.DeleteCalls())
+func (mock *IFileMock) DeleteCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Download calls DownloadFunc.
+func (mock *IFileMock) Download(ctx context.Context, filePath string) ([]byte, error) {
+	if mock.DownloadFunc == nil {
+		panic("IFileMock.DownloadFunc: method is nil but IFile.Download was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+	}
+	mock.lockDownload.Lock()
+	mock.calls.Download = append(mock.calls.Download, callInfo)
+	mock.lockDownload.Unlock()
+	return mock.DownloadFunc(ctx, filePath)
+}
+
+// DownloadCalls gets all the calls that were made to Download.
+// Check the length with:
+//
+//	len(mockedIFile.DownloadCalls())
+func (mock *IFileMock) DownloadCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+	}
+	mock.lockDownload.RLock()
+	calls = mock.calls.Download
+	mock.lockDownload.RUnlock()
+	return calls
+}
+
+// DownloadIfModified calls DownloadIfModifiedFunc.
+func (mock *IFileMock) DownloadIfModified(ctx context.Context, filePath string, knownETag string) ([]byte, string, error) {
+	if mock.DownloadIfModifiedFunc == nil {
+		panic("IFileMock.DownloadIfModifiedFunc: method is nil but IFile.DownloadIfModified was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		FilePath  string
+		KnownETag string
+	}{
+		Ctx:       ctx,
+		FilePath:  filePath,
+		KnownETag: knownETag,
+	}
+	mock.lockDownloadIfModified.Lock()
+	mock.calls.DownloadIfModified = append(mock.calls.DownloadIfModified, callInfo)
+	mock.lockDownloadIfModified.Unlock()
+	return mock.DownloadIfModifiedFunc(ctx, filePath, knownETag)
+}
+
+// DownloadIfModifiedCalls gets all the calls that were made to DownloadIfModified.
+// Check the length with:
+//
+//	len(mockedIFile.DownloadIfModifiedCalls())
+func (mock *IFileMock) DownloadIfModifiedCalls() []struct {
+	Ctx       context.Context
+	FilePath  string
+	KnownETag string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		FilePath  string
+		KnownETag string
+	}
+	mock.lockDownloadIfModified.RLock()
+	calls = mock.calls.DownloadIfModified
+	mock.lockDownloadIfModified.RUnlock()
+	return calls
+}
+
+// GenerateSharedAccessSignature calls GenerateSharedAccessSignatureFunc.
+func (mock *IFileMock) GenerateSharedAccessSignature(expiryTime string, fileName string) string {
+	if mock.GenerateSharedAccessSignatureFunc == nil {
+		panic("IFileMock.GenerateSharedAccessSignatureFunc: method is nil but IFile.GenerateSharedAccessSignature was just called")
+	}
+	callInfo := struct {
+		ExpiryTime string
+		FileName   string
+	}{
+		ExpiryTime: expiryTime,
+		FileName:   fileName,
+	}
+	mock.lockGenerateSharedAccessSignature.Lock()
+	mock.calls.GenerateSharedAccessSignature = append(mock.calls.GenerateSharedAccessSignature, callInfo)
+	mock.lockGenerateSharedAccessSignature.Unlock()
+	return mock.GenerateSharedAccessSignatureFunc(expiryTime, fileName)
+}
+
+// GenerateSharedAccessSignatureCalls gets all the calls that were made to GenerateSharedAccessSignature.
+// Check the length with:
+//
+//	len(mockedIFile.GenerateSharedAccessSignatureCalls())
+func (mock *IFileMock) GenerateSharedAccessSignatureCalls() []struct {
+	ExpiryTime string
+	FileName   string
+} {
+	var calls []struct {
+		ExpiryTime string
+		FileName   string
+	}
+	mock.lockGenerateSharedAccessSignature.RLock()
+	calls = mock.calls.GenerateSharedAccessSignature
+	mock.lockGenerateSharedAccessSignature.RUnlock()
+	return calls
+}
+
+// GetBlobURL calls GetBlobURLFunc.
+func (mock *IFileMock) GetBlobURL(fileName string, withSignature bool) string {
+	if mock.GetBlobURLFunc == nil {
+		panic("IFileMock.GetBlobURLFunc: method is nil but IFile.GetBlobURL was just called")
+	}
+	callInfo := struct {
+		FileName      string
+		WithSignature bool
+	}{
+		FileName:      fileName,
+		WithSignature: withSignature,
+	}
+	mock.lockGetBlobURL.Lock()
+	mock.calls.GetBlobURL = append(mock.calls.GetBlobURL, callInfo)
+	mock.lockGetBlobURL.Unlock()
+	return mock.GetBlobURLFunc(fileName, withSignature)
+}
+
+// GetBlobURLCalls gets all the calls that were made to GetBlobURL.
+// Check the length with:
+//
+//	len(mockedIFile.GetBlobURLCalls())
+func (mock *IFileMock) GetBlobURLCalls() []struct {
+	FileName      string
+	WithSignature bool
+} {
+	var calls []struct {
+		FileName      string
+		WithSignature bool
+	}
+	mock.lockGetBlobURL.RLock()
+	calls = mock.calls.GetBlobURL
+	mock.lockGetBlobURL.RUnlock()
+	return calls
+}
+
+// GetContainer calls GetContainerFunc.
+func (mock *IFileMock) GetContainer() (azblob.ContainerURL, error) {
+	if mock.GetContainerFunc == nil {
+		panic("IFileMock.GetContainerFunc: method is nil but IFile.GetContainer was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetContainer.Lock()
+	mock.calls.GetContainer = append(mock.calls.GetContainer, callInfo)
+	mock.lockGetContainer.Unlock()
+	return mock.GetContainerFunc()
+}
+
+// GetContainerCalls gets all the calls that were made to GetContainer.
+// Check the length with:
+//
+//	len(mockedIFile.GetContainerCalls())
+func (mock *IFileMock) GetContainerCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetContainer.RLock()
+	calls = mock.calls.GetContainer
+	mock.lockGetContainer.RUnlock()
+	return calls
+}
+
+// GetFileName calls GetFileNameFunc.
+func (mock *IFileMock) GetFileName(blobUrl string) string {
+	if mock.GetFileNameFunc == nil {
+		panic("IFileMock.GetFileNameFunc: method is nil but IFile.GetFileName was just called")
+	}
+	callInfo := struct {
+		BlobUrl string
+	}{
+		BlobUrl: blobUrl,
+	}
+	mock.lockGetFileName.Lock()
+	mock.calls.GetFileName = append(mock.calls.GetFileName, callInfo)
+	mock.lockGetFileName.Unlock()
+	return mock.GetFileNameFunc(blobUrl)
+}
+
+// GetFileNameCalls gets all the calls that were made to GetFileName.
+// Check the length with:
+//
+//	len(mockedIFile.GetFileNameCalls())
+func (mock *IFileMock) GetFileNameCalls() []struct {
+	BlobUrl string
+} {
+	var calls []struct {
+		BlobUrl string
+	}
+	mock.lockGetFileName.RLock()
+	calls = mock.calls.GetFileName
+	mock.lockGetFileName.RUnlock()
+	return calls
+}
+
+// GetListBlob calls GetListBlobFunc.
+func (mock *IFileMock) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	if mock.GetListBlobFunc == nil {
+		panic("IFileMock.GetListBlobFunc: method is nil but IFile.GetListBlob was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+	}
+	mock.lockGetListBlob.Lock()
+	mock.calls.GetListBlob = append(mock.calls.GetListBlob, callInfo)
+	mock.lockGetListBlob.Unlock()
+	return mock.GetListBlobFunc(ctx, prefix)
+}
+
+// GetListBlobCalls gets all the calls that were made to GetListBlob.
+// Check the length with:
+//
+//	len(mockedIFile.GetListBlobCalls())
+func (mock *IFileMock) GetListBlobCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+	}
+	mock.lockGetListBlob.RLock()
+	calls = mock.calls.GetListBlob
+	mock.lockGetListBlob.RUnlock()
+	return calls
+}
+
+// GetURL calls GetURLFunc.
+func (mock *IFileMock) GetURL() string {
+	if mock.GetURLFunc == nil {
+		panic("IFileMock.GetURLFunc: method is nil but IFile.GetURL was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetURL.Lock()
+	mock.calls.GetURL = append(mock.calls.GetURL, callInfo)
+	mock.lockGetURL.Unlock()
+	return mock.GetURLFunc()
+}
+
+// GetURLCalls gets all the calls that were made to GetURL.
+// Check the length with:
+//
+//	len(mockedIFile.GetURLCalls())
+func (mock *IFileMock) GetURLCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetURL.RLock()
+	calls = mock.calls.GetURL
+	mock.lockGetURL.RUnlock()
+	return calls
+}
+
+// List calls ListFunc.
+func (mock *IFileMock) List(ctx context.Context, prefix string, opts ...file.ListOption) ([]file.ObjectInfo, error) {
+	if mock.ListFunc == nil {
+		panic("IFileMock.ListFunc: method is nil but IFile.List was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+		Opts   []file.ListOption
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+		Opts:   opts,
+	}
+	mock.lockList.Lock()
+	mock.calls.List = append(mock.calls.List, callInfo)
+	mock.lockList.Unlock()
+	return mock.ListFunc(ctx, prefix, opts...)
+}
+
+// ListCalls gets all the calls that were made to List.
+// Check the length with:
+//
+//	len(mockedIFile.ListCalls())
+func (mock *IFileMock) ListCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+	Opts   []file.ListOption
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+		Opts   []file.ListOption
+	}
+	mock.lockList.RLock()
+	calls = mock.calls.List
+	mock.lockList.RUnlock()
+	return calls
+}
+
+// ListDir calls ListDirFunc.
+func (mock *IFileMock) ListDir(ctx context.Context, prefix string) (file.DirList, error) {
+	if mock.ListDirFunc == nil {
+		panic("IFileMock.ListDirFunc: method is nil but IFile.ListDir was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+	}
+	mock.lockListDir.Lock()
+	mock.calls.ListDir = append(mock.calls.ListDir, callInfo)
+	mock.lockListDir.Unlock()
+	return mock.ListDirFunc(ctx, prefix)
+}
+
+// ListDirCalls gets all the calls that were made to ListDir.
+// Check the length with:
+//
+//	len(mockedIFile.ListDirCalls())
+func (mock *IFileMock) ListDirCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+	}
+	mock.lockListDir.RLock()
+	calls = mock.calls.ListDir
+	mock.lockListDir.RUnlock()
+	return calls
+}
+
+// ListStream calls ListStreamFunc.
+func (mock *IFileMock) ListStream(ctx context.Context, prefix string, opts ...file.ListOption) <-chan file.ListItem {
+	if mock.ListStreamFunc == nil {
+		panic("IFileMock.ListStreamFunc: method is nil but IFile.ListStream was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+		Opts   []file.ListOption
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+		Opts:   opts,
+	}
+	mock.lockListStream.Lock()
+	mock.calls.ListStream = append(mock.calls.ListStream, callInfo)
+	mock.lockListStream.Unlock()
+	return mock.ListStreamFunc(ctx, prefix, opts...)
+}
+
+// ListStreamCalls gets all the calls that were made to ListStream.
+// Check the length with:
+//
+//	len(mockedIFile.ListStreamCalls())
+func (mock *IFileMock) ListStreamCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+	Opts   []file.ListOption
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+		Opts   []file.ListOption
+	}
+	mock.lockListStream.RLock()
+	calls = mock.calls.ListStream
+	mock.lockListStream.RUnlock()
+	return calls
+}
+
+// PresignMany calls PresignManyFunc.
+func (mock *IFileMock) PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+	if mock.PresignManyFunc == nil {
+		panic("IFileMock.PresignManyFunc: method is nil but IFile.PresignMany was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Keys   []string
+		Expiry time.Duration
+	}{
+		Ctx:    ctx,
+		Keys:   keys,
+		Expiry: expiry,
+	}
+	mock.lockPresignMany.Lock()
+	mock.calls.PresignMany = append(mock.calls.PresignMany, callInfo)
+	mock.lockPresignMany.Unlock()
+	return mock.PresignManyFunc(ctx, keys, expiry)
+}
+
+// PresignManyCalls gets all the calls that were made to PresignMany.
+// Check the length with:
+//
+//	len(mockedIFile.PresignManyCalls())
+func (mock *IFileMock) PresignManyCalls() []struct {
+	Ctx    context.Context
+	Keys   []string
+	Expiry time.Duration
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Keys   []string
+		Expiry time.Duration
+	}
+	mock.lockPresignMany.RLock()
+	calls = mock.calls.PresignMany
+	mock.lockPresignMany.RUnlock()
+	return calls
+}
+
+// SetHeaders calls SetHeadersFunc.
+func (mock *IFileMock) SetHeaders(ctx context.Context, filePath string, h file.Headers) error {
+	if mock.SetHeadersFunc == nil {
+		panic("IFileMock.SetHeadersFunc: method is nil but IFile.SetHeaders was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+		H        file.Headers
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+		H:        h,
+	}
+	mock.lockSetHeaders.Lock()
+	mock.calls.SetHeaders = append(mock.calls.SetHeaders, callInfo)
+	mock.lockSetHeaders.Unlock()
+	return mock.SetHeadersFunc(ctx, filePath, h)
+}
+
+// SetHeadersCalls gets all the calls that were made to SetHeaders.
+// Check the length with:
+//
+//	len(mockedIFile.SetHeadersCalls())
+func (mock *IFileMock) SetHeadersCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+	H        file.Headers
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+		H        file.Headers
+	}
+	mock.lockSetHeaders.RLock()
+	calls = mock.calls.SetHeaders
+	mock.lockSetHeaders.RUnlock()
+	return calls
+}
+
+// Upload calls UploadFunc.
+func (mock *IFileMock) Upload(ctx context.Context, filePath string, contentType string, buffBytes []byte) (string, error) {
+	if mock.UploadFunc == nil {
+		panic("IFileMock.UploadFunc: method is nil but IFile.Upload was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		BuffBytes   []byte
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		ContentType: contentType,
+		BuffBytes:   buffBytes,
+	}
+	mock.lockUpload.Lock()
+	mock.calls.Upload = append(mock.calls.Upload, callInfo)
+	mock.lockUpload.Unlock()
+	return mock.UploadFunc(ctx, filePath, contentType, buffBytes)
+}
+
+// UploadCalls gets all the calls that were made to Upload.
+// Check the length with:
+//
+//	len(mockedIFile.UploadCalls())
+func (mock *IFileMock) UploadCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	ContentType string
+	BuffBytes   []byte
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		BuffBytes   []byte
+	}
+	mock.lockUpload.RLock()
+	calls = mock.calls.Upload
+	mock.lockUpload.RUnlock()
+	return calls
+}
+
+// Usage calls UsageFunc.
+func (mock *IFileMock) Usage(ctx context.Context, prefix string) (file.UsageReport, error) {
+	if mock.UsageFunc == nil {
+		panic("IFileMock.UsageFunc: method is nil but IFile.Usage was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+	}
+	mock.lockUsage.Lock()
+	mock.calls.Usage = append(mock.calls.Usage, callInfo)
+	mock.lockUsage.Unlock()
+	return mock.UsageFunc(ctx, prefix)
+}
+
+// UsageCalls gets all the calls that were made to Usage.
+// Check the length with:
+//
+//	len(mockedIFile.UsageCalls())
+func (mock *IFileMock) UsageCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+	}
+	mock.lockUsage.RLock()
+	calls = mock.calls.Usage
+	mock.lockUsage.RUnlock()
+	return calls
+}
+
+// Ensure, that OpenerMock does implement file.Opener.
+// If this is not the case, regenerate this file with moq.
+var _ file.Opener = &OpenerMock{}
+
+// OpenerMock is a mock implementation of file.Opener.
+//
+//	func TestSomethingThatUsesOpener(t *testing.T) {
+//
+//		// make and configure a mocked file.Opener
+//		mockedOpener := &OpenerMock{
+//			OpenFunc: func(ctx context.Context, filePath string) (io.ReadCloser, error) {
+//				panic("mock out the Open method")
+//			},
+//		}
+//
+//		// use mockedOpener in code that requires file.Opener
+//		// and then make assertions.
+//
+//	}
+type OpenerMock struct {
+	// OpenFunc mocks the Open method.
+	OpenFunc func(ctx context.Context, filePath string) (io.ReadCloser, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Open holds details about calls to the Open method.
+		Open []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+		}
+	}
+	lockOpen sync.RWMutex
+}
+
+// Open calls OpenFunc.
+func (mock *OpenerMock) Open(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	if mock.OpenFunc == nil {
+		panic("OpenerMock.OpenFunc: method is nil but Opener.Open was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+	}
+	mock.lockOpen.Lock()
+	mock.calls.Open = append(mock.calls.Open, callInfo)
+	mock.lockOpen.Unlock()
+	return mock.OpenFunc(ctx, filePath)
+}
+
+// OpenCalls gets all the calls that were made to Open.
+// Check the length with:
+//
+//	len(mockedOpener.OpenCalls())
+func (mock *OpenerMock) OpenCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+	}
+	mock.lockOpen.RLock()
+	calls = mock.calls.Open
+	mock.lockOpen.RUnlock()
+	return calls
+}
+
+// Ensure, that QueueMock does implement file.Queue.
+// If this is not the case, regenerate this file with moq.
+var _ file.Queue = &QueueMock{}
+
+// QueueMock is a mock implementation of file.Queue.
+//
+//	func TestSomethingThatUsesQueue(t *testing.T) {
+//
+//		// make and configure a mocked file.Queue
+//		mockedQueue := &QueueMock{
+//			AckFunc: func(ctx context.Context, msg file.Message) error {
+//				panic("mock out the Ack method")
+//			},
+//			ReceiveFunc: func(ctx context.Context, max int) ([]file.Message, error) {
+//				panic("mock out the Receive method")
+//			},
+//		}
+//
+//		// use mockedQueue in code that requires file.Queue
+//		// and then make assertions.
+//
+//	}
+type QueueMock struct {
+	// AckFunc mocks the Ack method.
+	AckFunc func(ctx context.Context, msg file.Message) error
+
+	// ReceiveFunc mocks the Receive method.
+	ReceiveFunc func(ctx context.Context, max int) ([]file.Message, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Ack holds details about calls to the Ack method.
+		Ack []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Msg is the msg argument value.
+			Msg file.Message
+		}
+		// Receive holds details about calls to the Receive method.
+		Receive []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Max is the max argument value.
+			Max int
+		}
+	}
+	lockAck     sync.RWMutex
+	lockReceive sync.RWMutex
+}
+
+// Ack calls AckFunc.
+func (mock *QueueMock) Ack(ctx context.Context, msg file.Message) error {
+	if mock.AckFunc == nil {
+		panic("QueueMock.AckFunc: method is nil but Queue.Ack was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Msg file.Message
+	}{
+		Ctx: ctx,
+		Msg: msg,
+	}
+	mock.lockAck.Lock()
+	mock.calls.Ack = append(mock.calls.Ack, callInfo)
+	mock.lockAck.Unlock()
+	return mock.AckFunc(ctx, msg)
+}
+
+// AckCalls gets all the calls that were made to Ack.
+// Check the length with:
+//
+//	len(mockedQueue.AckCalls())
+func (mock *QueueMock) AckCalls() []struct {
+	Ctx context.Context
+	Msg file.Message
+} {
+	var calls []struct {
+		Ctx context.Context
+		Msg file.Message
+	}
+	mock.lockAck.RLock()
+	calls = mock.calls.Ack
+	mock.lockAck.RUnlock()
+	return calls
+}
+
+// Receive calls ReceiveFunc.
+func (mock *QueueMock) Receive(ctx context.Context, max int) ([]file.Message, error) {
+	if mock.ReceiveFunc == nil {
+		panic("QueueMock.ReceiveFunc: method is nil but Queue.Receive was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Max int
+	}{
+		Ctx: ctx,
+		Max: max,
+	}
+	mock.lockReceive.Lock()
+	mock.calls.Receive = append(mock.calls.Receive, callInfo)
+	mock.lockReceive.Unlock()
+	return mock.ReceiveFunc(ctx, max)
+}
+
+// ReceiveCalls gets all the calls that were made to Receive.
+// Check the length with:
+//
+//	len(mockedQueue.ReceiveCalls())
+func (mock *QueueMock) ReceiveCalls() []struct {
+	Ctx context.Context
+	Max int
+} {
+	var calls []struct {
+		Ctx context.Context
+		Max int
+	}
+	mock.lockReceive.RLock()
+	calls = mock.calls.Receive
+	mock.lockReceive.RUnlock()
+	return calls
+}
+
+// Ensure, that DeadLetterMock does implement file.DeadLetter.
+// If this is not the case, regenerate this file with moq.
+var _ file.DeadLetter = &DeadLetterMock{}
+
+// DeadLetterMock is a mock implementation of file.DeadLetter.
+//
+//	func TestSomethingThatUsesDeadLetter(t *testing.T) {
+//
+//		// make and configure a mocked file.DeadLetter
+//		mockedDeadLetter := &DeadLetterMock{
+//			SendFunc: func(ctx context.Context, msg file.Message, cause error) error {
+//				panic("mock out the Send method")
+//			},
+//		}
+//
+//		// use mockedDeadLetter in code that requires file.DeadLetter
+//		// and then make assertions.
+//
+//	}
+type DeadLetterMock struct {
+	// SendFunc mocks the Send method.
+	SendFunc func(ctx context.Context, msg file.Message, cause error) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Send holds details about calls to the Send method.
+		Send []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Msg is the msg argument value.
+			Msg file.Message
+			// Cause is the cause argument value.
+			Cause error
+		}
+	}
+	lockSend sync.RWMutex
+}
+
+// Send calls SendFunc.
+func (mock *DeadLetterMock) Send(ctx context.Context, msg file.Message, cause error) error {
+	if mock.SendFunc == nil {
+		panic("DeadLetterMock.SendFunc: method is nil but DeadLetter.Send was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Msg   file.Message
+		Cause error
+	}{
+		Ctx:   ctx,
+		Msg:   msg,
+		Cause: cause,
+	}
+	mock.lockSend.Lock()
+	mock.calls.Send = append(mock.calls.Send, callInfo)
+	mock.lockSend.Unlock()
+	return mock.SendFunc(ctx, msg, cause)
+}
+
+// SendCalls gets all the calls that were made to Send.
+// Check the length with:
+//
+//	len(mockedDeadLetter.SendCalls())
+func (mock *DeadLetterMock) SendCalls() []struct {
+	Ctx   context.Context
+	Msg   file.Message
+	Cause error
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Msg   file.Message
+		Cause error
+	}
+	mock.lockSend.RLock()
+	calls = mock.calls.Send
+	mock.lockSend.RUnlock()
+	return calls
+}
+
+// Ensure, that StreamUploaderMock does implement file.StreamUploader.
+// If this is not the case, regenerate this file with moq.
+var _ file.StreamUploader = &StreamUploaderMock{}
+
+// StreamUploaderMock is a mock implementation of file.StreamUploader.
+//
+//	func TestSomethingThatUsesStreamUploader(t *testing.T) {
+//
+//		// make and configure a mocked file.StreamUploader
+//		mockedStreamUploader := &StreamUploaderMock{
+//			UploadStreamFunc: func(ctx context.Context, filePath string, contentType string, r io.Reader, size int64) (string, error) {
+//				panic("mock out the UploadStream method")
+//			},
+//		}
+//
+//		// use mockedStreamUploader in code that requires file.StreamUploader
+//		// and then make assertions.
+//
+//	}
+type StreamUploaderMock struct {
+	// UploadStreamFunc mocks the UploadStream method.
+	UploadStreamFunc func(ctx context.Context, filePath string, contentType string, r io.Reader, size int64) (string, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// UploadStream holds details about calls to the UploadStream method.
+		UploadStream []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// ContentType is the contentType argument value.
+			ContentType string
+			// R is the r argument value.
+			R io.Reader
+			// Size is the size argument value.
+			Size int64
+		}
+	}
+	lockUploadStream sync.RWMutex
+}
+
+// UploadStream calls UploadStreamFunc.
+func (mock *StreamUploaderMock) UploadStream(ctx context.Context, filePath string, contentType string, r io.Reader, size int64) (string, error) {
+	if mock.UploadStreamFunc == nil {
+		panic("StreamUploaderMock.UploadStreamFunc: method is nil but StreamUploader.UploadStream was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		R           io.Reader
+		Size        int64
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		ContentType: contentType,
+		R:           r,
+		Size:        size,
+	}
+	mock.lockUploadStream.Lock()
+	mock.calls.UploadStream = append(mock.calls.UploadStream, callInfo)
+	mock.lockUploadStream.Unlock()
+	return mock.UploadStreamFunc(ctx, filePath, contentType, r, size)
+}
+
+// UploadStreamCalls gets all the calls that were made to UploadStream.
+// Check the length with:
+//
+//	len(mockedStreamUploader.UploadStreamCalls())
+func (mock *StreamUploaderMock) UploadStreamCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	ContentType string
+	R           io.Reader
+	Size        int64
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		R           io.Reader
+		Size        int64
+	}
+	mock.lockUploadStream.RLock()
+	calls = mock.calls.UploadStream
+	mock.lockUploadStream.RUnlock()
+	return calls
+}
+
+// Ensure, that MultipartUploaderMock does implement file.MultipartUploader.
+// If this is not the case, regenerate this file with moq.
+var _ file.MultipartUploader = &MultipartUploaderMock{}
+
+// MultipartUploaderMock is a mock implementation of file.MultipartUploader.
+//
+//	func TestSomethingThatUsesMultipartUploader(t *testing.T) {
+//
+//		// make and configure a mocked file.MultipartUploader
+//		mockedMultipartUploader := &MultipartUploaderMock{
+//			CompleteUploadFunc: func(ctx context.Context, filePath string, uploadID string, contentType string, parts int) (string, error) {
+//				panic("mock out the CompleteUpload method")
+//			},
+//			UploadPartFunc: func(ctx context.Context, filePath string, uploadID string, n int, data []byte) error {
+//				panic("mock out the UploadPart method")
+//			},
+//		}
+//
+//		// use mockedMultipartUploader in code that requires file.MultipartUploader
+//		// and then make assertions.
+//
+//	}
+type MultipartUploaderMock struct {
+	// CompleteUploadFunc mocks the CompleteUpload method.
+	CompleteUploadFunc func(ctx context.Context, filePath string, uploadID string, contentType string, parts int) (string, error)
+
+	// UploadPartFunc mocks the UploadPart method.
+	UploadPartFunc func(ctx context.Context, filePath string, uploadID string, n int, data []byte) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CompleteUpload holds details about calls to the CompleteUpload method.
+		CompleteUpload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// UploadID is the uploadID argument value.
+			UploadID string
+			// ContentType is the contentType argument value.
+			ContentType string
+			// Parts is the parts argument value.
+			Parts int
+		}
+		// UploadPart holds details about calls to the UploadPart method.
+		UploadPart []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// UploadID is the uploadID argument value.
+			UploadID string
+			// N is the n argument value.
+			N int
+			// Data is the data argument value.
+			Data []byte
+		}
+	}
+	lockCompleteUpload sync.RWMutex
+	lockUploadPart     sync.RWMutex
+}
+
+// CompleteUpload calls CompleteUploadFunc.
+func (mock *MultipartUploaderMock) CompleteUpload(ctx context.Context, filePath string, uploadID string, contentType string, parts int) (string, error) {
+	if mock.CompleteUploadFunc == nil {
+		panic("MultipartUploaderMock.CompleteUploadFunc: method is nil but MultipartUploader.CompleteUpload was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		UploadID    string
+		ContentType string
+		Parts       int
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		UploadID:    uploadID,
+		ContentType: contentType,
+		Parts:       parts,
+	}
+	mock.lockCompleteUpload.Lock()
+	mock.calls.CompleteUpload = append(mock.calls.CompleteUpload, callInfo)
+	mock.lockCompleteUpload.Unlock()
+	return mock.CompleteUploadFunc(ctx, filePath, uploadID, contentType, parts)
+}
+
+// CompleteUploadCalls gets all the calls that were made to CompleteUpload.
+// Check the length with:
+//
+//	len(mockedMultipartUploader.CompleteUploadCalls())
+func (mock *MultipartUploaderMock) CompleteUploadCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	UploadID    string
+	ContentType string
+	Parts       int
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		UploadID    string
+		ContentType string
+		Parts       int
+	}
+	mock.lockCompleteUpload.RLock()
+	calls = mock.calls.CompleteUpload
+	mock.lockCompleteUpload.RUnlock()
+	return calls
+}
+
+// UploadPart calls UploadPartFunc.
+func (mock *MultipartUploaderMock) UploadPart(ctx context.Context, filePath string, uploadID string, n int, data []byte) error {
+	if mock.UploadPartFunc == nil {
+		panic("MultipartUploaderMock.UploadPartFunc: method is nil but MultipartUploader.UploadPart was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+		UploadID string
+		N        int
+		Data     []byte
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+		UploadID: uploadID,
+		N:        n,
+		Data:     data,
+	}
+	mock.lockUploadPart.Lock()
+	mock.calls.UploadPart = append(mock.calls.UploadPart, callInfo)
+	mock.lockUploadPart.Unlock()
+	return mock.UploadPartFunc(ctx, filePath, uploadID, n, data)
+}
+
+// UploadPartCalls gets all the calls that were made to UploadPart.
+// Check the length with:
+//
+//	len(mockedMultipartUploader.UploadPartCalls())
+func (mock *MultipartUploaderMock) UploadPartCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+	UploadID string
+	N        int
+	Data     []byte
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+		UploadID string
+		N        int
+		Data     []byte
+	}
+	mock.lockUploadPart.RLock()
+	calls = mock.calls.UploadPart
+	mock.lockUploadPart.RUnlock()
+	return calls
+}
+
+// Ensure, that BlobUploaderMock does implement file.BlobUploader.
+// If this is not the case, regenerate this file with moq.
+var _ file.BlobUploader = &BlobUploaderMock{}
+
+// BlobUploaderMock is a mock implementation of file.BlobUploader.
+//
+//	func TestSomethingThatUsesBlobUploader(t *testing.T) {
+//
+//		// make and configure a mocked file.BlobUploader
+//		mockedBlobUploader := &BlobUploaderMock{
+//			UploadBlobFunc: func(ctx context.Context, filePath string, contentType string, buffBytes []byte) (file.Blob, error) {
+//				panic("mock out the UploadBlob method")
+//			},
+//		}
+//
+//		// use mockedBlobUploader in code that requires file.BlobUploader
+//		// and then make assertions.
+//
+//	}
+type BlobUploaderMock struct {
+	// UploadBlobFunc mocks the UploadBlob method.
+	UploadBlobFunc func(ctx context.Context, filePath string, contentType string, buffBytes []byte) (file.Blob, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// UploadBlob holds details about calls to the UploadBlob method.
+		UploadBlob []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// ContentType is the contentType argument value.
+			ContentType string
+			// BuffBytes is the buffBytes argument value.
+			BuffBytes []byte
+		}
+	}
+	lockUploadBlob sync.RWMutex
+}
+
+// UploadBlob calls UploadBlobFunc.
+func (mock *BlobUploaderMock) UploadBlob(ctx context.Context, filePath string, contentType string, buffBytes []byte) (file.Blob, error) {
+	if mock.UploadBlobFunc == nil {
+		panic("BlobUploaderMock.UploadBlobFunc: method is nil but BlobUploader.UploadBlob was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		BuffBytes   []byte
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		ContentType: contentType,
+		BuffBytes:   buffBytes,
+	}
+	mock.lockUploadBlob.Lock()
+	mock.calls.UploadBlob = append(mock.calls.UploadBlob, callInfo)
+	mock.lockUploadBlob.Unlock()
+	return mock.UploadBlobFunc(ctx, filePath, contentType, buffBytes)
+}
+
+// UploadBlobCalls gets all the calls that were made to UploadBlob.
+// Check the length with:
+//
+//	len(mockedBlobUploader.UploadBlobCalls())
+func (mock *BlobUploaderMock) UploadBlobCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	ContentType string
+	BuffBytes   []byte
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		BuffBytes   []byte
+	}
+	mock.lockUploadBlob.RLock()
+	calls = mock.calls.UploadBlob
+	mock.lockUploadBlob.RUnlock()
+	return calls
+}
+
+// Ensure, that RangeOpenerMock does implement file.RangeOpener.
+// If this is not the case, regenerate this file with moq.
+var _ file.RangeOpener = &RangeOpenerMock{}
+
+// RangeOpenerMock is a mock implementation of file.RangeOpener.
+//
+//	func TestSomethingThatUsesRangeOpener(t *testing.T) {
+//
+//		// make and configure a mocked file.RangeOpener
+//		mockedRangeOpener := &RangeOpenerMock{
+//			OpenRangeFunc: func(ctx context.Context, filePath string, offset int64, etag string) (io.ReadCloser, string, error) {
+//				panic("mock out the OpenRange method")
+//			},
+//		}
+//
+//		// use mockedRangeOpener in code that requires file.RangeOpener
+//		// and then make assertions.
+//
+//	}
+type RangeOpenerMock struct {
+	// OpenRangeFunc mocks the OpenRange method.
+	OpenRangeFunc func(ctx context.Context, filePath string, offset int64, etag string) (io.ReadCloser, string, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// OpenRange holds details about calls to the OpenRange method.
+		OpenRange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// Offset is the offset argument value.
+			Offset int64
+			// Etag is the etag argument value.
+			Etag string
+		}
+	}
+	lockOpenRange sync.RWMutex
+}
+
+// OpenRange calls OpenRangeFunc.
+func (mock *RangeOpenerMock) OpenRange(ctx context.Context, filePath string, offset int64, etag string) (io.ReadCloser, string, error) {
+	if mock.OpenRangeFunc == nil {
+		panic("RangeOpenerMock.OpenRangeFunc: method is nil but RangeOpener.OpenRange was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+		Offset   int64
+		Etag     string
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+		Offset:   offset,
+		Etag:     etag,
+	}
+	mock.lockOpenRange.Lock()
+	mock.calls.OpenRange = append(mock.calls.OpenRange, callInfo)
+	mock.lockOpenRange.Unlock()
+	return mock.OpenRangeFunc(ctx, filePath, offset, etag)
+}
+
+// OpenRangeCalls gets all the calls that were made to OpenRange.
+// Check the length with:
+//
+//	len(mockedRangeOpener.OpenRangeCalls())
+func (mock *RangeOpenerMock) OpenRangeCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+	Offset   int64
+	Etag     string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+		Offset   int64
+		Etag     string
+	}
+	mock.lockOpenRange.RLock()
+	calls = mock.calls.OpenRange
+	mock.lockOpenRange.RUnlock()
+	return calls
+}
+
+// Ensure, that VersionListerMock does implement file.VersionLister.
+// If this is not the case, regenerate this file with moq.
+var _ file.VersionLister = &VersionListerMock{}
+
+// VersionListerMock is a mock implementation of file.VersionLister.
+//
+//	func TestSomethingThatUsesVersionLister(t *testing.T) {
+//
+//		// make and configure a mocked file.VersionLister
+//		mockedVersionLister := &VersionListerMock{
+//			ListVersionsFunc: func(ctx context.Context, prefix string) ([]file.ObjectVersion, error) {
+//				panic("mock out the ListVersions method")
+//			},
+//		}
+//
+//		// use mockedVersionLister in code that requires file.VersionLister
+//		// and then make assertions.
+//
+//	}
+type VersionListerMock struct {
+	// ListVersionsFunc mocks the ListVersions method.
+	ListVersionsFunc func(ctx context.Context, prefix string) ([]file.ObjectVersion, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ListVersions holds details about calls to the ListVersions method.
+		ListVersions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+		}
+	}
+	lockListVersions sync.RWMutex
+}
+
+// ListVersions calls ListVersionsFunc.
+func (mock *VersionListerMock) ListVersions(ctx context.Context, prefix string) ([]file.ObjectVersion, error) {
+	if mock.ListVersionsFunc == nil {
+		panic("VersionListerMock.ListVersionsFunc: method is nil but VersionLister.ListVersions was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+	}
+	mock.lockListVersions.Lock()
+	mock.calls.ListVersions = append(mock.calls.ListVersions, callInfo)
+	mock.lockListVersions.Unlock()
+	return mock.ListVersionsFunc(ctx, prefix)
+}
+
+// ListVersionsCalls gets all the calls that were made to ListVersions.
+// Check the length with:
+//
+//	len(mockedVersionLister.ListVersionsCalls())
+func (mock *VersionListerMock) ListVersionsCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+	}
+	mock.lockListVersions.RLock()
+	calls = mock.calls.ListVersions
+	mock.lockListVersions.RUnlock()
+	return calls
+}
+
+// Ensure, that CapablerMock does implement file.Capabler.
+// If this is not the case, regenerate this file with moq.
+var _ file.Capabler = &CapablerMock{}
+
+// CapablerMock is a mock implementation of file.Capabler.
+//
+//	func TestSomethingThatUsesCapabler(t *testing.T) {
+//
+//		// make and configure a mocked file.Capabler
+//		mockedCapabler := &CapablerMock{
+//			CapabilitiesFunc: func() file.CapabilitySet {
+//				panic("mock out the Capabilities method")
+//			},
+//		}
+//
+//		// use mockedCapabler in code that requires file.Capabler
+//		// and then make assertions.
+//
+//	}
+type CapablerMock struct {
+	// CapabilitiesFunc mocks the Capabilities method.
+	CapabilitiesFunc func() file.CapabilitySet
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Capabilities holds details about calls to the Capabilities method.
+		Capabilities []struct {
+		}
+	}
+	lockCapabilities sync.RWMutex
+}
+
+// Capabilities calls CapabilitiesFunc.
+func (mock *CapablerMock) Capabilities() file.CapabilitySet {
+	if mock.CapabilitiesFunc == nil {
+		panic("CapablerMock.CapabilitiesFunc: method is nil but Capabler.Capabilities was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockCapabilities.Lock()
+	mock.calls.Capabilities = append(mock.calls.Capabilities, callInfo)
+	mock.lockCapabilities.Unlock()
+	return mock.CapabilitiesFunc()
+}
+
+// CapabilitiesCalls gets all the calls that were made to Capabilities.
+// Check the length with:
+//
+//	len(mockedCapabler.CapabilitiesCalls())
+func (mock *CapablerMock) CapabilitiesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockCapabilities.RLock()
+	calls = mock.calls.Capabilities
+	mock.lockCapabilities.RUnlock()
+	return calls
+}
+
+// Ensure, that CacheMock does implement file.Cache.
+// If this is not the case, regenerate this file with moq.
+var _ file.Cache = &CacheMock{}
+
+// CacheMock is a mock implementation of file.Cache.
+//
+//	func TestSomethingThatUsesCache(t *testing.T) {
+//
+//		// make and configure a mocked file.Cache
+//		mockedCache := &CacheMock{
+//			DeleteFunc: func(key string)  {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(key string) ([]byte, bool) {
+//				panic("mock out the Get method")
+//			},
+//			SetFunc: func(key string, data []byte)  {
+//				panic("mock out the Set method")
+//			},
+//		}
+//
+//		// use mockedCache in code that requires file.Cache
+//		// and then make assertions.
+//
+//	}
+type CacheMock struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(key string)
+
+	// GetFunc mocks the Get method.
+	GetFunc func(key string) ([]byte, bool)
+
+	// SetFunc mocks the Set method.
+	SetFunc func(key string, data []byte)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// Set holds details about calls to the Set method.
+		Set []struct {
+			// Key is the key argument value.
+			Key string
+			// Data is the data argument value.
+			Data []byte
+		}
+	}
+	lockDelete sync.RWMutex
+	lockGet    sync.RWMutex
+	lockSet    sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *CacheMock) Delete(key string) {
+	if mock.DeleteFunc == nil {
+		panic("CacheMock.DeleteFunc: method is nil but Cache.Delete was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	mock.DeleteFunc(key)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedCache.DeleteCalls())
+func (mock *CacheMock) DeleteCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *CacheMock) Get(key string) ([]byte, bool) {
+	if mock.GetFunc == nil {
+		panic("CacheMock.GetFunc: method is nil but Cache.Get was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(key)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedCache.GetCalls())
+func (mock *CacheMock) GetCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// Set calls SetFunc.
+func (mock *CacheMock) Set(key string, data []byte) {
+	if mock.SetFunc == nil {
+		panic("CacheMock.SetFunc: method is nil but Cache.Set was just called")
+	}
+	callInfo := struct {
+		Key  string
+		Data []byte
+	}{
+		Key:  key,
+		Data: data,
+	}
+	mock.lockSet.Lock()
+	mock.calls.Set = append(mock.calls.Set, callInfo)
+	mock.lockSet.Unlock()
+	mock.SetFunc(key, data)
+}
+
+// SetCalls gets all the calls that were made to Set.
+// Check the length with:
+//
+//	len(mockedCache.SetCalls())
+func (mock *CacheMock) SetCalls() []struct {
+	Key  string
+	Data []byte
+} {
+	var calls []struct {
+		Key  string
+		Data []byte
+	}
+	mock.lockSet.RLock()
+	calls = mock.calls.Set
+	mock.lockSet.RUnlock()
+	return calls
+}
+
+// Ensure, that KeyStoreMock does implement file.KeyStore.
+// If this is not the case, regenerate this file with moq.
+var _ file.KeyStore = &KeyStoreMock{}
+
+// KeyStoreMock is a mock implementation of file.KeyStore.
+//
+//	func TestSomethingThatUsesKeyStore(t *testing.T) {
+//
+//		// make and configure a mocked file.KeyStore
+//		mockedKeyStore := &KeyStoreMock{
+//			CreateFunc: func(ctx context.Context, userID string) (file.DataKey, error) {
+//				panic("mock out the Create method")
+//			},
+//			DeleteFunc: func(ctx context.Context, userID string) error {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(ctx context.Context, userID string) (file.DataKey, error) {
+//				panic("mock out the Get method")
+//			},
+//		}
+//
+//		// use mockedKeyStore in code that requires file.KeyStore
+//		// and then make assertions.
+//
+//	}
+type KeyStoreMock struct {
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, userID string) (file.DataKey, error)
+
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, userID string) error
+
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, userID string) (file.DataKey, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID string
+		}
+	}
+	lockCreate sync.RWMutex
+	lockDelete sync.RWMutex
+	lockGet    sync.RWMutex
+}
+
+// Create calls CreateFunc.
+func (mock *KeyStoreMock) Create(ctx context.Context, userID string) (file.DataKey, error) {
+	if mock.CreateFunc == nil {
+		panic("KeyStoreMock.CreateFunc: method is nil but KeyStore.Create was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID string
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, userID)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedKeyStore.CreateCalls())
+func (mock *KeyStoreMock) CreateCalls() []struct {
+	Ctx    context.Context
+	UserID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID string
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// Delete calls DeleteFunc.
+func (mock *KeyStoreMock) Delete(ctx context.Context, userID string) error {
+	if mock.DeleteFunc == nil {
+		panic("KeyStoreMock.DeleteFunc: method is nil but KeyStore.Delete was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID string
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(ctx, userID)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedKeyStore.DeleteCalls())
+func (mock *KeyStoreMock) DeleteCalls() []struct {
+	Ctx    context.Context
+	UserID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *KeyStoreMock) Get(ctx context.Context, userID string) (file.DataKey, error) {
+	if mock.GetFunc == nil {
+		panic("KeyStoreMock.GetFunc: method is nil but KeyStore.Get was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID string
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(ctx, userID)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedKeyStore.GetCalls())
+func (mock *KeyStoreMock) GetCalls() []struct {
+	Ctx    context.Context
+	UserID string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// Ensure, that CatalogMock does implement file.Catalog.
+// If this is not the case, regenerate this file with moq.
+var _ file.Catalog = &CatalogMock{}
+
+// CatalogMock is a mock implementation of file.Catalog.
+//
+//	func TestSomethingThatUsesCatalog(t *testing.T) {
+//
+//		// make and configure a mocked file.Catalog
+//		mockedCatalog := &CatalogMock{
+//			GetFunc: func(ctx context.Context, key string) (file.CatalogRecord, error) {
+//				panic("mock out the Get method")
+//			},
+//			PutFunc: func(ctx context.Context, rec file.CatalogRecord) error {
+//				panic("mock out the Put method")
+//			},
+//			RemoveFunc: func(ctx context.Context, key string) error {
+//				panic("mock out the Remove method")
+//			},
+//			StaleFunc: func(ctx context.Context, before time.Time) ([]file.CatalogRecord, error) {
+//				panic("mock out the Stale method")
+//			},
+//		}
+//
+//		// use mockedCatalog in code that requires file.Catalog
+//		// and then make assertions.
+//
+//	}
+type CatalogMock struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, key string) (file.CatalogRecord, error)
+
+	// PutFunc mocks the Put method.
+	PutFunc func(ctx context.Context, rec file.CatalogRecord) error
+
+	// RemoveFunc mocks the Remove method.
+	RemoveFunc func(ctx context.Context, key string) error
+
+	// StaleFunc mocks the Stale method.
+	StaleFunc func(ctx context.Context, before time.Time) ([]file.CatalogRecord, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+		}
+		// Put holds details about calls to the Put method.
+		Put []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Rec is the rec argument value.
+			Rec file.CatalogRecord
+		}
+		// Remove holds details about calls to the Remove method.
+		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+		}
+		// Stale holds details about calls to the Stale method.
+		Stale []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+	}
+	lockGet    sync.RWMutex
+	lockPut    sync.RWMutex
+	lockRemove sync.RWMutex
+	lockStale  sync.RWMutex
+}
+
+// Get calls GetFunc.
+func (mock *CatalogMock) Get(ctx context.Context, key string) (file.CatalogRecord, error) {
+	if mock.GetFunc == nil {
+		panic("CatalogMock.GetFunc: method is nil but Catalog.Get was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(ctx, key)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedCatalog.GetCalls())
+func (mock *CatalogMock) GetCalls() []struct {
+	Ctx context.Context
+	Key string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// Put calls PutFunc.
+func (mock *CatalogMock) Put(ctx context.Context, rec file.CatalogRecord) error {
+	if mock.PutFunc == nil {
+		panic("CatalogMock.PutFunc: method is nil but Catalog.Put was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Rec file.CatalogRecord
+	}{
+		Ctx: ctx,
+		Rec: rec,
+	}
+	mock.lockPut.Lock()
+	mock.calls.Put = append(mock.calls.Put, callInfo)
+	mock.lockPut.Unlock()
+	return mock.PutFunc(ctx, rec)
+}
+
+// PutCalls gets all the calls that were made to Put.
+// Check the length with:
+//
+//	len(mockedCatalog.PutCalls())
+func (mock *CatalogMock) PutCalls() []struct {
+	Ctx context.Context
+	Rec file.CatalogRecord
+} {
+	var calls []struct {
+		Ctx context.Context
+		Rec file.CatalogRecord
+	}
+	mock.lockPut.RLock()
+	calls = mock.calls.Put
+	mock.lockPut.RUnlock()
+	return calls
+}
+
+// Remove calls RemoveFunc.
+func (mock *CatalogMock) Remove(ctx context.Context, key string) error {
+	if mock.RemoveFunc == nil {
+		panic("CatalogMock.RemoveFunc: method is nil but Catalog.Remove was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key string
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	mock.lockRemove.Lock()
+	mock.calls.Remove = append(mock.calls.Remove, callInfo)
+	mock.lockRemove.Unlock()
+	return mock.RemoveFunc(ctx, key)
+}
+
+// RemoveCalls gets all the calls that were made to Remove.
+// Check the length with:
+//
+//	len(mockedCatalog.RemoveCalls())
+func (mock *CatalogMock) RemoveCalls() []struct {
+	Ctx context.Context
+	Key string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key string
+	}
+	mock.lockRemove.RLock()
+	calls = mock.calls.Remove
+	mock.lockRemove.RUnlock()
+	return calls
+}
+
+// Stale calls StaleFunc.
+func (mock *CatalogMock) Stale(ctx context.Context, before time.Time) ([]file.CatalogRecord, error) {
+	if mock.StaleFunc == nil {
+		panic("CatalogMock.StaleFunc: method is nil but Catalog.Stale was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockStale.Lock()
+	mock.calls.Stale = append(mock.calls.Stale, callInfo)
+	mock.lockStale.Unlock()
+	return mock.StaleFunc(ctx, before)
+}
+
+// StaleCalls gets all the calls that were made to Stale.
+// Check the length with:
+//
+//	len(mockedCatalog.StaleCalls())
+func (mock *CatalogMock) StaleCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockStale.RLock()
+	calls = mock.calls.Stale
+	mock.lockStale.RUnlock()
+	return calls
+}
+
+// Ensure, that InspectorMock does implement file.Inspector.
+// If this is not the case, regenerate this file with moq.
+var _ file.Inspector = &InspectorMock{}
+
+// InspectorMock is a mock implementation of file.Inspector.
+//
+//	func TestSomethingThatUsesInspector(t *testing.T) {
+//
+//		// make and configure a mocked file.Inspector
+//		mockedInspector := &InspectorMock{
+//			InspectFunc: func(ctx context.Context, filePath string, contentType string, data []byte) ([]file.Finding, error) {
+//				panic("mock out the Inspect method")
+//			},
+//		}
+//
+//		// use mockedInspector in code that requires file.Inspector
+//		// and then make assertions.
+//
+//	}
+type InspectorMock struct {
+	// InspectFunc mocks the Inspect method.
+	InspectFunc func(ctx context.Context, filePath string, contentType string, data []byte) ([]file.Finding, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Inspect holds details about calls to the Inspect method.
+		Inspect []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// ContentType is the contentType argument value.
+			ContentType string
+			// Data is the data argument value.
+			Data []byte
+		}
+	}
+	lockInspect sync.RWMutex
+}
+
+// Inspect calls InspectFunc.
+func (mock *InspectorMock) Inspect(ctx context.Context, filePath string, contentType string, data []byte) ([]file.Finding, error) {
+	if mock.InspectFunc == nil {
+		panic("InspectorMock.InspectFunc: method is nil but Inspector.Inspect was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		Data        []byte
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		ContentType: contentType,
+		Data:        data,
+	}
+	mock.lockInspect.Lock()
+	mock.calls.Inspect = append(mock.calls.Inspect, callInfo)
+	mock.lockInspect.Unlock()
+	return mock.InspectFunc(ctx, filePath, contentType, data)
+}
+
+// InspectCalls gets all the calls that were made to Inspect.
+// Check the length with:
+//
+//	len(mockedInspector.InspectCalls())
+func (mock *InspectorMock) InspectCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	ContentType string
+	Data        []byte
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+		Data        []byte
+	}
+	mock.lockInspect.RLock()
+	calls = mock.calls.Inspect
+	mock.lockInspect.RUnlock()
+	return calls
+}
+
+// Ensure, that DownloadCounterMock does implement file.DownloadCounter.
+// If this is not the case, regenerate this file with moq.
+var _ file.DownloadCounter = &DownloadCounterMock{}
+
+// DownloadCounterMock is a mock implementation of file.DownloadCounter.
+//
+//	func TestSomethingThatUsesDownloadCounter(t *testing.T) {
+//
+//		// make and configure a mocked file.DownloadCounter
+//		mockedDownloadCounter := &DownloadCounterMock{
+//			CountDownloadFunc: func(ctx context.Context, key string, bytes int64, at time.Time) error {
+//				panic("mock out the CountDownload method")
+//			},
+//		}
+//
+//		// use mockedDownloadCounter in code that requires file.DownloadCounter
+//		// and then make assertions.
+//
+//	}
+type DownloadCounterMock struct {
+	// CountDownloadFunc mocks the CountDownload method.
+	CountDownloadFunc func(ctx context.Context, key string, bytes int64, at time.Time) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CountDownload holds details about calls to the CountDownload method.
+		CountDownload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Bytes is the bytes argument value.
+			Bytes int64
+			// At is the at argument value.
+			At time.Time
+		}
+	}
+	lockCountDownload sync.RWMutex
+}
+
+// CountDownload calls CountDownloadFunc.
+func (mock *DownloadCounterMock) CountDownload(ctx context.Context, key string, bytes int64, at time.Time) error {
+	if mock.CountDownloadFunc == nil {
+		panic("DownloadCounterMock.CountDownloadFunc: method is nil but DownloadCounter.CountDownload was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Key   string
+		Bytes int64
+		At    time.Time
+	}{
+		Ctx:   ctx,
+		Key:   key,
+		Bytes: bytes,
+		At:    at,
+	}
+	mock.lockCountDownload.Lock()
+	mock.calls.CountDownload = append(mock.calls.CountDownload, callInfo)
+	mock.lockCountDownload.Unlock()
+	return mock.CountDownloadFunc(ctx, key, bytes, at)
+}
+
+// CountDownloadCalls gets all the calls that were made to CountDownload.
+// Check the length with:
+//
+//	len(mockedDownloadCounter.CountDownloadCalls())
+func (mock *DownloadCounterMock) CountDownloadCalls() []struct {
+	Ctx   context.Context
+	Key   string
+	Bytes int64
+	At    time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Key   string
+		Bytes int64
+		At    time.Time
+	}
+	mock.lockCountDownload.RLock()
+	calls = mock.calls.CountDownload
+	mock.lockCountDownload.RUnlock()
+	return calls
+}
+
+// Ensure, that CloserMock does implement file.Closer.
+// If this is not the case, regenerate this file with moq.
+var _ file.Closer = &CloserMock{}
+
+// CloserMock is a mock implementation of file.Closer.
+//
+//	func TestSomethingThatUsesCloser(t *testing.T) {
+//
+//		// make and configure a mocked file.Closer
+//		mockedCloser := &CloserMock{
+//			CloseFunc: func(ctx context.Context) error {
+//				panic("mock out the Close method")
+//			},
+//		}
+//
+//		// use mockedCloser in code that requires file.Closer
+//		// and then make assertions.
+//
+//	}
+type CloserMock struct {
+	// CloseFunc mocks the Close method.
+	CloseFunc func(ctx context.Context) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Close holds details about calls to the Close method.
+		Close []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+	}
+	lockClose sync.RWMutex
+}
+
+// Close calls CloseFunc.
+func (mock *CloserMock) Close(ctx context.Context) error {
+	if mock.CloseFunc == nil {
+		panic("CloserMock.CloseFunc: method is nil but Closer.Close was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockClose.Lock()
+	mock.calls.Close = append(mock.calls.Close, callInfo)
+	mock.lockClose.Unlock()
+	return mock.CloseFunc(ctx)
+}
+
+// CloseCalls gets all the calls that were made to Close.
+// Check the length with:
+//
+//	len(mockedCloser.CloseCalls())
+func (mock *CloserMock) CloseCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockClose.RLock()
+	calls = mock.calls.Close
+	mock.lockClose.RUnlock()
+	return calls
+}
+
+// Ensure, that RangeReaderMock does implement file.RangeReader.
+// If this is not the case, regenerate this file with moq.
+var _ file.RangeReader = &RangeReaderMock{}
+
+// RangeReaderMock is a mock implementation of file.RangeReader.
+//
+//	func TestSomethingThatUsesRangeReader(t *testing.T) {
+//
+//		// make and configure a mocked file.RangeReader
+//		mockedRangeReader := &RangeReaderMock{
+//			ReadRangeFunc: func(ctx context.Context, filePath string, offset int64, count int64, etag string) ([]byte, error) {
+//				panic("mock out the ReadRange method")
+//			},
+//		}
+//
+//		// use mockedRangeReader in code that requires file.RangeReader
+//		// and then make assertions.
+//
+//	}
+type RangeReaderMock struct {
+	// ReadRangeFunc mocks the ReadRange method.
+	ReadRangeFunc func(ctx context.Context, filePath string, offset int64, count int64, etag string) ([]byte, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ReadRange holds details about calls to the ReadRange method.
+		ReadRange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// Offset is the offset argument value.
+			Offset int64
+			// Count is the count argument value.
+			Count int64
+			// Etag is the etag argument value.
+			Etag string
+		}
+	}
+	lockReadRange sync.RWMutex
+}
+
+// ReadRange calls ReadRangeFunc.
+func (mock *RangeReaderMock) ReadRange(ctx context.Context, filePath string, offset int64, count int64, etag string) ([]byte, error) {
+	if mock.ReadRangeFunc == nil {
+		panic("RangeReaderMock.ReadRangeFunc: method is nil but RangeReader.ReadRange was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		FilePath string
+		Offset   int64
+		Count    int64
+		Etag     string
+	}{
+		Ctx:      ctx,
+		FilePath: filePath,
+		Offset:   offset,
+		Count:    count,
+		Etag:     etag,
+	}
+	mock.lockReadRange.Lock()
+	mock.calls.ReadRange = append(mock.calls.ReadRange, callInfo)
+	mock.lockReadRange.Unlock()
+	return mock.ReadRangeFunc(ctx, filePath, offset, count, etag)
+}
+
+// ReadRangeCalls gets all the calls that were made to ReadRange.
+// Check the length with:
+//
+//	len(mockedRangeReader.ReadRangeCalls())
+func (mock *RangeReaderMock) ReadRangeCalls() []struct {
+	Ctx      context.Context
+	FilePath string
+	Offset   int64
+	Count    int64
+	Etag     string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		FilePath string
+		Offset   int64
+		Count    int64
+		Etag     string
+	}
+	mock.lockReadRange.RLock()
+	calls = mock.calls.ReadRange
+	mock.lockReadRange.RUnlock()
+	return calls
+}
+
+// Ensure, that ObjectWriterCreatorMock does implement file.ObjectWriterCreator.
+// If this is not the case, regenerate this file with moq.
+var _ file.ObjectWriterCreator = &ObjectWriterCreatorMock{}
+
+// ObjectWriterCreatorMock is a mock implementation of file.ObjectWriterCreator.
+//
+//	func TestSomethingThatUsesObjectWriterCreator(t *testing.T) {
+//
+//		// make and configure a mocked file.ObjectWriterCreator
+//		mockedObjectWriterCreator := &ObjectWriterCreatorMock{
+//			NewObjectWriterFunc: func(ctx context.Context, filePath string, contentType string) (io.WriteCloser, error) {
+//				panic("mock out the NewObjectWriter method")
+//			},
+//		}
+//
+//		// use mockedObjectWriterCreator in code that requires file.ObjectWriterCreator
+//		// and then make assertions.
+//
+//	}
+type ObjectWriterCreatorMock struct {
+	// NewObjectWriterFunc mocks the NewObjectWriter method.
+	NewObjectWriterFunc func(ctx context.Context, filePath string, contentType string) (io.WriteCloser, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// NewObjectWriter holds details about calls to the NewObjectWriter method.
+		NewObjectWriter []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FilePath is the filePath argument value.
+			FilePath string
+			// ContentType is the contentType argument value.
+			ContentType string
+		}
+	}
+	lockNewObjectWriter sync.RWMutex
+}
+
+// NewObjectWriter calls NewObjectWriterFunc.
+func (mock *ObjectWriterCreatorMock) NewObjectWriter(ctx context.Context, filePath string, contentType string) (io.WriteCloser, error) {
+	if mock.NewObjectWriterFunc == nil {
+		panic("ObjectWriterCreatorMock.NewObjectWriterFunc: method is nil but ObjectWriterCreator.NewObjectWriter was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+	}{
+		Ctx:         ctx,
+		FilePath:    filePath,
+		ContentType: contentType,
+	}
+	mock.lockNewObjectWriter.Lock()
+	mock.calls.NewObjectWriter = append(mock.calls.NewObjectWriter, callInfo)
+	mock.lockNewObjectWriter.Unlock()
+	return mock.NewObjectWriterFunc(ctx, filePath, contentType)
+}
+
+// NewObjectWriterCalls gets all the calls that were made to NewObjectWriter.
+// Check the length with:
+//
+//	len(mockedObjectWriterCreator.NewObjectWriterCalls())
+func (mock *ObjectWriterCreatorMock) NewObjectWriterCalls() []struct {
+	Ctx         context.Context
+	FilePath    string
+	ContentType string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		FilePath    string
+		ContentType string
+	}
+	mock.lockNewObjectWriter.RLock()
+	calls = mock.calls.NewObjectWriter
+	mock.lockNewObjectWriter.RUnlock()
+	return calls
+}
+
+// Ensure, that ClassifierMock does implement file.Classifier.
+// If this is not the case, regenerate this file with moq.
+var _ file.Classifier = &ClassifierMock{}
+
+// ClassifierMock is a mock implementation of file.Classifier.
+//
+//	func TestSomethingThatUsesClassifier(t *testing.T) {
+//
+//		// make and configure a mocked file.Classifier
+//		mockedClassifier := &ClassifierMock{
+//			ErrorClassFunc: func() file.ErrorClass {
+//				panic("mock out the ErrorClass method")
+//			},
+//		}
+//
+//		// use mockedClassifier in code that requires file.Classifier
+//		// and then make assertions.
+//
+//	}
+type ClassifierMock struct {
+	// ErrorClassFunc mocks the ErrorClass method.
+	ErrorClassFunc func() file.ErrorClass
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ErrorClass holds details about calls to the ErrorClass method.
+		ErrorClass []struct {
+		}
+	}
+	lockErrorClass sync.RWMutex
+}
+
+// ErrorClass calls ErrorClassFunc.
+func (mock *ClassifierMock) ErrorClass() file.ErrorClass {
+	if mock.ErrorClassFunc == nil {
+		panic("ClassifierMock.ErrorClassFunc: method is nil but Classifier.ErrorClass was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockErrorClass.Lock()
+	mock.calls.ErrorClass = append(mock.calls.ErrorClass, callInfo)
+	mock.lockErrorClass.Unlock()
+	return mock.ErrorClassFunc()
+}
+
+// ErrorClassCalls gets all the calls that were made to ErrorClass.
+// Check the length with:
+//
+//	len(mockedClassifier.ErrorClassCalls())
+func (mock *ClassifierMock) ErrorClassCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockErrorClass.RLock()
+	calls = mock.calls.ErrorClass
+	mock.lockErrorClass.RUnlock()
+	return calls
+}
+
+// Ensure, that EntryCacheMock does implement file.EntryCache.
+// If this is not the case, regenerate this file with moq.
+var _ file.EntryCache = &EntryCacheMock{}
+
+// EntryCacheMock is a mock implementation of file.EntryCache.
+//
+//	func TestSomethingThatUsesEntryCache(t *testing.T) {
+//
+//		// make and configure a mocked file.EntryCache
+//		mockedEntryCache := &EntryCacheMock{
+//			DeleteFunc: func(key string)  {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(key string) ([]byte, bool) {
+//				panic("mock out the Get method")
+//			},
+//			GetEntryFunc: func(key string) (file.CacheEntry, bool) {
+//				panic("mock out the GetEntry method")
+//			},
+//			SetFunc: func(key string, data []byte)  {
+//				panic("mock out the Set method")
+//			},
+//			SetEntryFunc: func(key string, e file.CacheEntry)  {
+//				panic("mock out the SetEntry method")
+//			},
+//		}
+//
+//		// use mockedEntryCache in code that requires file.EntryCache
+//		// and then make assertions.
+//
+//	}
+type EntryCacheMock struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(key string)
+
+	// GetFunc mocks the Get method.
+	GetFunc func(key string) ([]byte, bool)
+
+	// GetEntryFunc mocks the GetEntry method.
+	GetEntryFunc func(key string) (file.CacheEntry, bool)
+
+	// SetFunc mocks the Set method.
+	SetFunc func(key string, data []byte)
+
+	// SetEntryFunc mocks the SetEntry method.
+	SetEntryFunc func(key string, e file.CacheEntry)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// GetEntry holds details about calls to the GetEntry method.
+		GetEntry []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// Set holds details about calls to the Set method.
+		Set []struct {
+			// Key is the key argument value.
+			Key string
+			// Data is the data argument value.
+			Data []byte
+		}
+		// SetEntry holds details about calls to the SetEntry method.
+		SetEntry []struct {
+			// Key is the key argument value.
+			Key string
+			// E is the e argument value.
+			E file.CacheEntry
+		}
+	}
+	lockDelete   sync.RWMutex
+	lockGet      sync.RWMutex
+	lockGetEntry sync.RWMutex
+	lockSet      sync.RWMutex
+	lockSetEntry sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *EntryCacheMock) Delete(key string) {
+	if mock.DeleteFunc == nil {
+		panic("EntryCacheMock.DeleteFunc: method is nil but EntryCache.Delete was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	mock.DeleteFunc(key)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedEntryCache.DeleteCalls())
+func (mock *EntryCacheMock) DeleteCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *EntryCacheMock) Get(key string) ([]byte, bool) {
+	if mock.GetFunc == nil {
+		panic("EntryCacheMock.GetFunc: method is nil but EntryCache.Get was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(key)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedEntryCache.GetCalls())
+func (mock *EntryCacheMock) GetCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetEntry calls GetEntryFunc.
+func (mock *EntryCacheMock) GetEntry(key string) (file.CacheEntry, bool) {
+	if mock.GetEntryFunc == nil {
+		panic("EntryCacheMock.GetEntryFunc: method is nil but EntryCache.GetEntry was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockGetEntry.Lock()
+	mock.calls.GetEntry = append(mock.calls.GetEntry, callInfo)
+	mock.lockGetEntry.Unlock()
+	return mock.GetEntryFunc(key)
+}
+
+// GetEntryCalls gets all the calls that were made to GetEntry.
+// Check the length with:
+//
+//	len(mockedEntryCache.GetEntryCalls())
+func (mock *EntryCacheMock) GetEntryCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockGetEntry.RLock()
+	calls = mock.calls.GetEntry
+	mock.lockGetEntry.RUnlock()
+	return calls
+}
+
+// Set calls SetFunc.
+func (mock *EntryCacheMock) Set(key string, data []byte) {
+	if mock.SetFunc == nil {
+		panic("EntryCacheMock.SetFunc: method is nil but EntryCache.Set was just called")
+	}
+	callInfo := struct {
+		Key  string
+		Data []byte
+	}{
+		Key:  key,
+		Data: data,
+	}
+	mock.lockSet.Lock()
+	mock.calls.Set = append(mock.calls.Set, callInfo)
+	mock.lockSet.Unlock()
+	mock.SetFunc(key, data)
+}
+
+// SetCalls gets all the calls that were made to Set.
+// Check the length with:
+//
+//	len(mockedEntryCache.SetCalls())
+func (mock *EntryCacheMock) SetCalls() []struct {
+	Key  string
+	Data []byte
+} {
+	var calls []struct {
+		Key  string
+		Data []byte
+	}
+	mock.lockSet.RLock()
+	calls = mock.calls.Set
+	mock.lockSet.RUnlock()
+	return calls
+}
+
+// SetEntry calls SetEntryFunc.
+func (mock *EntryCacheMock) SetEntry(key string, e file.CacheEntry) {
+	if mock.SetEntryFunc == nil {
+		panic("EntryCacheMock.SetEntryFunc: method is nil but EntryCache.SetEntry was just called")
+	}
+	callInfo := struct {
+		Key string
+		E   file.CacheEntry
+	}{
+		Key: key,
+		E:   e,
+	}
+	mock.lockSetEntry.Lock()
+	mock.calls.SetEntry = append(mock.calls.SetEntry, callInfo)
+	mock.lockSetEntry.Unlock()
+	mock.SetEntryFunc(key, e)
+}
+
+// SetEntryCalls gets all the calls that were made to SetEntry.
+// Check the length with:
+//
+//	len(mockedEntryCache.SetEntryCalls())
+func (mock *EntryCacheMock) SetEntryCalls() []struct {
+	Key string
+	E   file.CacheEntry
+} {
+	var calls []struct {
+		Key string
+		E   file.CacheEntry
+	}
+	mock.lockSetEntry.RLock()
+	calls = mock.calls.SetEntry
+	mock.lockSetEntry.RUnlock()
+	return calls
+}
+
+// Ensure, that PrefetcherMock does implement file.Prefetcher.
+// If this is not the case, regenerate this file with moq.
+var _ file.Prefetcher = &PrefetcherMock{}
+
+// PrefetcherMock is a mock implementation of file.Prefetcher.
+//
+//	func TestSomethingThatUsesPrefetcher(t *testing.T) {
+//
+//		// make and configure a mocked file.Prefetcher
+//		mockedPrefetcher := &PrefetcherMock{
+//			PrefetchFunc: func(ctx context.Context, urls []string) error {
+//				panic("mock out the Prefetch method")
+//			},
+//		}
+//
+//		// use mockedPrefetcher in code that requires file.Prefetcher
+//		// and then make assertions.
+//
+//	}
+type PrefetcherMock struct {
+	// PrefetchFunc mocks the Prefetch method.
+	PrefetchFunc func(ctx context.Context, urls []string) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Prefetch holds details about calls to the Prefetch method.
+		Prefetch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Urls is the urls argument value.
+			Urls []string
+		}
+	}
+	lockPrefetch sync.RWMutex
+}
+
+// Prefetch calls PrefetchFunc.
+func (mock *PrefetcherMock) Prefetch(ctx context.Context, urls []string) error {
+	if mock.PrefetchFunc == nil {
+		panic("PrefetcherMock.PrefetchFunc: method is nil but Prefetcher.Prefetch was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Urls []string
+	}{
+		Ctx:  ctx,
+		Urls: urls,
+	}
+	mock.lockPrefetch.Lock()
+	mock.calls.Prefetch = append(mock.calls.Prefetch, callInfo)
+	mock.lockPrefetch.Unlock()
+	return mock.PrefetchFunc(ctx, urls)
+}
+
+// PrefetchCalls gets all the calls that were made to Prefetch.
+// Check the length with:
+//
+//	len(mockedPrefetcher.PrefetchCalls())
+func (mock *PrefetcherMock) PrefetchCalls() []struct {
+	Ctx  context.Context
+	Urls []string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Urls []string
+	}
+	mock.lockPrefetch.RLock()
+	calls = mock.calls.Prefetch
+	mock.lockPrefetch.RUnlock()
+	return calls
+}
@@ -0,0 +1,278 @@
+// Package filerecord record storage HTTP exchanges once and replay them offline,
+// so integration tests run in CI without cloud credentials.
+//
+//	Example:
+//	rec := filerecord.New("testdata/upload.json", filerecord.ModeFromEnv())
+//	defer rec.Save()
+//	f := file.New(account, accessKey, rootURL, container, apiVersion, file.WithHTTPClient(rec.Client()))
+package filerecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mode tells whether Recorder talk to the real service or replay a cassette
+type Mode int
+
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+)
+
+// Redacted replace secret values in recorded exchanges
+const Redacted = "REDACTED"
+
+// EnvRecord set to "1" switch ModeFromEnv to recording
+const EnvRecord = "ASSETS_SDK_RECORD"
+
+// ErrNoInteraction returned in replay mode when no recorded exchange match the request
+var ErrNoInteraction = errors.New("filerecord: no recorded interaction")
+
+// secret headers are redacted before saving
+var secretHeaders = []string{"Authorization", "X-Ms-Copy-Source-Authorization", "X-Amz-Security-Token"}
+
+// secret query parameters are redacted and ignored when matching, "se" and "st" change on every run
+var secretParams = []string{"sig", "se", "st", "X-Amz-Signature", "X-Amz-Credential", "X-Amz-Date", "X-Amz-Security-Token"}
+
+// Request is a recorded HTTP request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response is a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a request and the response it received
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying interactions
+type Recorder struct {
+	Path string
+	Mode Mode
+	// Transport used in record mode, http.DefaultTransport if nil
+	Transport http.RoundTripper
+	// Scrub is called on every interaction before it is saved, after default scrubbing
+	Scrub func(*Interaction)
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	loaded       bool
+}
+
+// New create recorder saving to or replaying from cassette at path
+func New(path string, mode Mode) *Recorder {
+	return &Recorder{Path: path, Mode: mode}
+}
+
+// ModeFromEnv return ModeRecord when EnvRecord is "1", ModeReplay otherwise
+func ModeFromEnv() Mode {
+	if os.Getenv(EnvRecord) == "1" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Client return http client using the recorder as transport
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implement http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   string(body),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       string(respBody),
+		},
+	}
+	scrub(&in)
+	if r.Scrub != nil {
+		r.Scrub(&in)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.loaded {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	key := matchKey(req.Method, req.URL)
+	for i, in := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		u, err := url.Parse(in.Request.URL)
+		if err != nil || matchKey(in.Request.Method, u) != key {
+			continue
+		}
+		r.used[i] = true
+
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoInteraction, key)
+}
+
+func (r *Recorder) load() error {
+	data, err := ioutil.ReadFile(r.Path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return err
+	}
+	r.used = make([]bool, len(r.interactions))
+	r.loaded = true
+	return nil
+}
+
+// Save write recorded interactions to the cassette, it does nothing in replay mode
+func (r *Recorder) Save() error {
+	if r.Mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, data, 0644)
+}
+
+// matchKey identify a request by method and url without volatile or secret parameters
+func matchKey(method string, u *url.URL) string {
+	query := u.Query()
+	for _, p := range secretParams {
+		query.Del(p)
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(method)
+	b.WriteString(" ")
+	b.WriteString(u.Scheme + "://" + u.Host + u.EscapedPath())
+	for i, k := range keys {
+		if i == 0 {
+			b.WriteString("?")
+		} else {
+			b.WriteString("&")
+		}
+		b.WriteString(k + "=" + strings.Join(query[k], ","))
+	}
+	return b.String()
+}
+
+func scrub(in *Interaction) {
+	for _, h := range secretHeaders {
+		if in.Request.Header.Get(h) != "" {
+			in.Request.Header.Set(h, Redacted)
+		}
+	}
+
+	u, err := url.Parse(in.Request.URL)
+	if err != nil {
+		return
+	}
+	query := u.Query()
+	for _, p := range secretParams {
+		if query.Get(p) != "" {
+			query.Set(p, Redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	in.Request.URL = u.String()
+}
@@ -0,0 +1,115 @@
+package filetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// ConcurrencyWorkers is the number of goroutines RunConcurrencyTests share one store between
+var ConcurrencyWorkers = 16
+
+// RunConcurrencyTests share one store returned by newStore between goroutines uploading, downloading,
+// listing, copying and deleting at once, checking no operation see another file content or a partial write.
+// Run it with the race detector so unsynchronized client state is reported:
+//
+//	go test -race -run Concurrency ./...
+//
+//	func TestConcurrency(t *testing.T) {
+//		filetest.RunConcurrencyTests(t, func() file.IFile {
+//			return filetest.NewMemory()
+//		})
+//	}
+func RunConcurrencyTests(t *testing.T, newStore func() file.IFile) {
+	ctx := context.Background()
+	store := newStore()
+	prefix := fmt.Sprintf("concurrency/%d/", time.Now().UnixNano())
+	defer cleanup(t, ctx, store, prefix)
+
+	const rounds = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, ConcurrencyWorkers*rounds)
+	for w := 0; w < ConcurrencyWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := concurrentRound(ctx, store, prefix, w, i); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// every worker keep its last file and its copy
+	list, err := store.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if want := 2 * ConcurrencyWorkers; len(list) != want {
+		t.Errorf("List = %d files, want %d", len(list), want)
+	}
+}
+
+// concurrentRound overwrite the file of worker w, read it back, copy it, list and read the files of the others
+func concurrentRound(ctx context.Context, store file.IFile, prefix string, w, i int) error {
+	name := fmt.Sprintf("%sw%d/data.txt", prefix, w)
+	data := bytes.Repeat([]byte(fmt.Sprintf("worker %d round %d\n", w, i)), 64*(w+1))
+
+	if _, err := store.Upload(ctx, name, "text/plain", data); err != nil {
+		return fmt.Errorf("Upload(%q): %v", name, err)
+	}
+	got, err := store.Download(ctx, name)
+	if err != nil {
+		return fmt.Errorf("Download(%q): %v", name, err)
+	}
+	if !bytes.Equal(got, data) {
+		return fmt.Errorf("Download(%q) round %d = %d bytes, not the content uploaded", name, i, len(got))
+	}
+
+	copyName := fmt.Sprintf("%sw%d/copy.txt", prefix, w)
+	if _, err := store.Copy(ctx, name, copyName); err != nil {
+		return fmt.Errorf("Copy(%q): %v", name, err)
+	}
+	if u := store.GetBlobURL(copyName, true); u == "" {
+		return fmt.Errorf("GetBlobURL(%q) is empty", copyName)
+	}
+
+	list, err := store.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("List: %v", err)
+	}
+	for _, info := range list {
+		other, err := store.Download(ctx, info.Name)
+		if err == file.ErrNotFound {
+			// deleted by its worker since listed
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Download(%q): %v", info.Name, err)
+		}
+		if len(other) == 0 || other[len(other)-1] != '\n' {
+			return fmt.Errorf("Download(%q) = partial content of %d bytes", info.Name, len(other))
+		}
+	}
+
+	// temporary files appear and disappear while the others list
+	tmp := fmt.Sprintf("%sw%d/tmp-%d.txt", prefix, w, i)
+	if _, err := store.Upload(ctx, tmp, "text/plain", data); err != nil {
+		return fmt.Errorf("Upload(%q): %v", tmp, err)
+	}
+	if _, err := store.Delete(ctx, tmp); err != nil {
+		return fmt.Errorf("Delete(%q): %v", tmp, err)
+	}
+	return nil
+}
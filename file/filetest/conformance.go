@@ -0,0 +1,430 @@
+// Package filetest provide helpers to test IFile implementations and code using them.
+//
+// RunConformanceTests check that a backend behaves like the Azure implementation,
+// so third party backends can prove they are interchangeable.
+//
+//	func TestConformance(t *testing.T) {
+//		filetest.RunConformanceTests(t, func() file.IFile {
+//			return filetest.NewMemory()
+//		})
+//	}
+package filetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// pngHeader is enough for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// RunConformanceTests exercise upload, download, list, copy, delete and url semantics of the store
+// returned by newStore. Every test use its own prefix and delete the files it created,
+// so it is safe to run against a real container.
+func RunConformanceTests(t *testing.T, newStore func() file.IFile) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, store file.IFile, prefix string)
+	}{
+		{"UploadDownload", testUploadDownload},
+		{"ContentType", testContentType},
+		{"Overwrite", testOverwrite},
+		{"DownloadNotFound", testDownloadNotFound},
+		{"DownloadIfModified", testDownloadIfModified},
+		{"List", testList},
+		{"ListOptions", testListOptions},
+		{"ListDir", testListDir},
+		{"ListStream", testListStream},
+		{"Copy", testCopy},
+		{"SetHeaders", testSetHeaders},
+		{"Delete", testDelete},
+		{"URL", testURL},
+		{"PresignMany", testPresignMany},
+		{"Usage", testUsage},
+		{"ContextMetadata", testContextMetadata},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore()
+			prefix := fmt.Sprintf("conformance/%d/%s/", time.Now().UnixNano(), tt.name)
+			defer cleanup(t, ctx, store, prefix)
+
+			tt.fn(t, ctx, store, prefix)
+		})
+	}
+}
+
+func cleanup(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	names, err := store.GetListBlob(ctx, prefix)
+	if err != nil {
+		t.Logf("cleanup %s: %v", prefix, err)
+		return
+	}
+	for _, name := range names {
+		if _, err := store.Delete(ctx, name); err != nil {
+			t.Logf("cleanup %s: %v", name, err)
+		}
+	}
+}
+
+func upload(t *testing.T, ctx context.Context, store file.IFile, name, contentType string, data []byte) string {
+	t.Helper()
+
+	u, err := store.Upload(ctx, name, contentType, data)
+	if err != nil {
+		t.Fatalf("Upload(%q): %v", name, err)
+	}
+	return u
+}
+
+func names(list []file.ObjectInfo) []string {
+	out := []string{}
+	for _, info := range list {
+		out = append(out, info.Name)
+	}
+	return out
+}
+
+func testUploadDownload(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "a.txt"
+	data := []byte("hello conformance")
+
+	u := upload(t, ctx, store, name, "text/plain", data)
+	if want := store.GetBlobURL(name, false); u != want {
+		t.Errorf("Upload url = %q, want %q", u, want)
+	}
+
+	got, err := store.Download(ctx, name)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Download = %q, want %q", got, data)
+	}
+
+	upload(t, ctx, store, prefix+"empty", "text/plain", []byte{})
+	got, err = store.Download(ctx, prefix+"empty")
+	if err != nil {
+		t.Fatalf("Download empty: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Download empty = %d bytes, want 0", len(got))
+	}
+}
+
+func testContentType(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	upload(t, ctx, store, prefix+"detected", "", pngHeader)
+	upload(t, ctx, store, prefix+"explicit", "application/pdf", []byte("%PDF-1.4"))
+
+	list, err := store.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := map[string]string{prefix + "detected": "image/png", prefix + "explicit": "application/pdf"}
+	for _, info := range list {
+		if info.ContentType != want[info.Name] {
+			t.Errorf("%s content type = %q, want %q", info.Name, info.ContentType, want[info.Name])
+		}
+	}
+}
+
+func testOverwrite(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "a.txt"
+	upload(t, ctx, store, name, "text/plain", []byte("first"))
+	upload(t, ctx, store, name, "text/plain", []byte("second"))
+
+	got, err := store.Download(ctx, name)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if string(got) != "second" {
+		t.Errorf("Download = %q, want %q", got, "second")
+	}
+}
+
+func testDownloadNotFound(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	_, err := store.Download(ctx, prefix+"missing")
+	if err != file.ErrNotFound {
+		t.Errorf("Download missing = %v, want %v", err, file.ErrNotFound)
+	}
+}
+
+func testList(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	for _, name := range []string{"b/2.txt", "a/1.txt", "b/1.txt", "c.txt"} {
+		upload(t, ctx, store, prefix+name, "text/plain", []byte(name))
+	}
+
+	list, err := store.List(ctx, prefix+"b/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []string{prefix + "b/1.txt", prefix + "b/2.txt"}
+	if got := names(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+	for _, info := range list {
+		if info.Size != int64(len(strings.TrimPrefix(info.Name, prefix))) {
+			t.Errorf("%s size = %d", info.Name, info.Size)
+		}
+	}
+
+	blobs, err := store.GetListBlob(ctx, prefix)
+	if err != nil {
+		t.Fatalf("GetListBlob: %v", err)
+	}
+	want = []string{prefix + "a/1.txt", prefix + "b/1.txt", prefix + "b/2.txt", prefix + "c.txt"}
+	if !reflect.DeepEqual(blobs, want) {
+		t.Errorf("GetListBlob = %v, want %v", blobs, want)
+	}
+}
+
+func testListOptions(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	sizes := map[string]int{"a.pdf": 3, "b.txt": 1, "c.pdf": 2, "d.pdf": 5}
+	for name, size := range sizes {
+		upload(t, ctx, store, prefix+name, "application/octet-stream", bytes.Repeat([]byte("x"), size))
+	}
+
+	cases := []struct {
+		name string
+		opts []file.ListOption
+		want []string
+	}{
+		{"glob", []file.ListOption{file.WithGlob("*.pdf")}, []string{"a.pdf", "c.pdf", "d.pdf"}},
+		{"max", []file.ListOption{file.WithMaxResults(2)}, []string{"a.pdf", "b.txt"}},
+		{"start after", []file.ListOption{file.WithStartAfter(prefix + "b.txt")}, []string{"c.pdf", "d.pdf"}},
+		{"size desc", []file.ListOption{file.WithSort(file.SortBySize, true), file.WithMaxResults(2)}, []string{"d.pdf", "a.pdf"}},
+		{"name desc", []file.ListOption{file.WithGlob("*.pdf"), file.WithSort(file.SortByName, true)}, []string{"d.pdf", "c.pdf", "a.pdf"}},
+	}
+	for _, c := range cases {
+		list, err := store.List(ctx, prefix, c.opts...)
+		if err != nil {
+			t.Fatalf("%s: List: %v", c.name, err)
+		}
+		want := []string{}
+		for _, name := range c.want {
+			want = append(want, prefix+name)
+		}
+		if got := names(list); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: List = %v, want %v", c.name, got, want)
+		}
+	}
+}
+
+func testListDir(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	for _, name := range []string{"dir/a/1.txt", "dir/a/2.txt", "dir/b/1.txt", "dir/root.txt"} {
+		upload(t, ctx, store, prefix+name, "text/plain", []byte(name))
+	}
+
+	dir, err := store.ListDir(ctx, prefix+"dir")
+	if err != nil {
+		t.Fatalf("ListDir: %v", err)
+	}
+	if want := []string{prefix + "dir/a/", prefix + "dir/b/"}; !reflect.DeepEqual(dir.Dirs, want) {
+		t.Errorf("ListDir dirs = %v, want %v", dir.Dirs, want)
+	}
+	if want := []string{prefix + "dir/root.txt"}; !reflect.DeepEqual(names(dir.Files), want) {
+		t.Errorf("ListDir files = %v, want %v", names(dir.Files), want)
+	}
+}
+
+func testDownloadIfModified(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "etag.txt"
+	upload(t, ctx, store, name, "text/plain", []byte("v1"))
+
+	data, etag, err := store.DownloadIfModified(ctx, name, "")
+	if err != nil || string(data) != "v1" || etag == "" {
+		t.Fatalf("DownloadIfModified = %q, %q, %v", data, etag, err)
+	}
+	if _, _, err := store.DownloadIfModified(ctx, name, etag); err != file.ErrNotModified {
+		t.Errorf("DownloadIfModified same etag = %v, want ErrNotModified", err)
+	}
+
+	upload(t, ctx, store, name, "text/plain", []byte("v2"))
+	data, changed, err := store.DownloadIfModified(ctx, name, etag)
+	if err != nil || string(data) != "v2" || changed == etag {
+		t.Errorf("DownloadIfModified after change = %q, %q, %v", data, changed, err)
+	}
+	if _, _, err := store.DownloadIfModified(ctx, prefix+"missing.txt", etag); err != file.ErrNotFound {
+		t.Errorf("DownloadIfModified missing = %v, want ErrNotFound", err)
+	}
+}
+
+func testListStream(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	for _, name := range []string{"c.pdf", "a.pdf", "b.txt", "d.pdf"} {
+		upload(t, ctx, store, prefix+name, "text/plain", []byte(name))
+	}
+
+	var got []string
+	for item := range store.ListStream(ctx, prefix, file.WithGlob("*.pdf"), file.WithMaxResults(2)) {
+		if item.Err != nil {
+			t.Fatalf("ListStream: %v", item.Err)
+		}
+		got = append(got, item.Info.Name)
+	}
+	if want := []string{prefix + "a.pdf", prefix + "c.pdf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListStream = %v, want %v", got, want)
+	}
+
+	// stopping early must not block the lister
+	stopCtx, cancel := context.WithCancel(ctx)
+	items := store.ListStream(stopCtx, prefix)
+	<-items
+	cancel()
+	for range items {
+	}
+}
+
+func testSetHeaders(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "headers.txt"
+	upload(t, ctx, store, name, "text/plain", []byte("headers"))
+
+	if err := store.SetHeaders(ctx, name, file.Headers{CacheControl: "public, max-age=60"}); err != nil {
+		t.Fatalf("SetHeaders: %v", err)
+	}
+	list, err := store.List(ctx, name)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	if list[0].CacheControl != "public, max-age=60" || list[0].ContentType != "text/plain" {
+		t.Errorf("headers = %q, %q", list[0].CacheControl, list[0].ContentType)
+	}
+
+	if err := store.SetHeaders(ctx, prefix+"missing.txt", file.Headers{CacheControl: "no-cache"}); err != file.ErrNotFound {
+		t.Errorf("SetHeaders missing = %v, want ErrNotFound", err)
+	}
+}
+
+func testCopy(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	src, dst := prefix+"src.txt", prefix+"copy/dst.txt"
+	upload(t, ctx, store, src, "text/plain", []byte("copy me"))
+
+	u, err := store.Copy(ctx, src, dst)
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if want := store.GetBlobURL(dst, false); u != want {
+		t.Errorf("Copy url = %q, want %q", u, want)
+	}
+
+	for _, name := range []string{src, dst} {
+		got, err := store.Download(ctx, name)
+		if err != nil {
+			t.Fatalf("Download(%q): %v", name, err)
+		}
+		if string(got) != "copy me" {
+			t.Errorf("Download(%q) = %q", name, got)
+		}
+	}
+
+	if _, err := store.Copy(ctx, prefix+"missing", prefix+"other"); err != file.ErrNotFound {
+		t.Errorf("Copy missing = %v, want %v", err, file.ErrNotFound)
+	}
+}
+
+func testDelete(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "a.txt"
+	upload(t, ctx, store, name, "text/plain", []byte("bye"))
+
+	u, err := store.Delete(ctx, name)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if want := store.GetBlobURL(name, false); u != want {
+		t.Errorf("Delete url = %q, want %q", u, want)
+	}
+
+	if _, err := store.Download(ctx, name); err != file.ErrNotFound {
+		t.Errorf("Download deleted = %v, want %v", err, file.ErrNotFound)
+	}
+	if _, err := store.Delete(ctx, name); err != file.ErrNotFound {
+		t.Errorf("Delete missing = %v, want %v", err, file.ErrNotFound)
+	}
+}
+
+func testURL(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	name := prefix + "dir/image.img"
+
+	if got := store.GetBlobURL("", false); got != "" {
+		t.Errorf("GetBlobURL empty = %q, want empty", got)
+	}
+
+	plain := store.GetBlobURL(name, false)
+	if !strings.HasPrefix(plain, store.GetURL()+"/") {
+		t.Errorf("GetBlobURL = %q, want prefix %q", plain, store.GetURL())
+	}
+	if got := store.GetFileName(plain); got != name {
+		t.Errorf("GetFileName(%q) = %q, want %q", plain, got, name)
+	}
+
+	signed := store.GetBlobURL(name, true)
+	if !strings.HasPrefix(signed, plain+"?") || !strings.Contains(signed, "sig=") {
+		t.Errorf("signed GetBlobURL = %q, want %q with signature", signed, plain)
+	}
+	if got := store.GetFileName(signed); got != name {
+		t.Errorf("GetFileName(%q) = %q, want %q", signed, got, name)
+	}
+}
+
+func testPresignMany(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	keys := []string{prefix + "a", prefix + "b", ""}
+
+	urls, err := store.PresignMany(ctx, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignMany: %v", err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("PresignMany returned %d urls, want 2", len(urls))
+	}
+	for _, key := range keys[:2] {
+		u := urls[key]
+		if !strings.HasPrefix(u, store.GetBlobURL(key, false)+"?") || !strings.Contains(u, "sig=") {
+			t.Errorf("PresignMany[%q] = %q", key, u)
+		}
+	}
+}
+
+func testUsage(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	upload(t, ctx, store, prefix+"a.txt", "text/plain", []byte("12345"))
+	upload(t, ctx, store, prefix+"b.txt", "text/plain", []byte("123"))
+	upload(t, ctx, store, prefix+"c.png", "", pngHeader)
+
+	report, err := store.Usage(ctx, prefix)
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if report.Objects != 3 || report.Bytes != int64(8+len(pngHeader)) {
+		t.Errorf("Usage = %d objects %d bytes, want 3 objects %d bytes", report.Objects, report.Bytes, 8+len(pngHeader))
+	}
+	if got := report.ByContentType["text/plain"]; got.Objects != 2 || got.Bytes != 8 {
+		t.Errorf("Usage text/plain = %+v", got)
+	}
+}
+
+func testContextMetadata(t *testing.T, ctx context.Context, store file.IFile, prefix string) {
+	ctx = file.WithPurpose(file.WithActor(ctx, "user-1"), "export")
+	upload(t, ctx, store, prefix+"a.csv", "text/csv", []byte("a,b"))
+
+	list, err := store.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("List returned %d files, want 1", len(list))
+	}
+	if got := list[0].Metadata[file.MetadataActor]; got != "user-1" {
+		t.Errorf("actor metadata = %q, want %q", got, "user-1")
+	}
+	if got := list[0].Metadata[file.MetadataPurpose]; got != "export" {
+		t.Errorf("purpose metadata = %q, want %q", got, "export")
+	}
+}
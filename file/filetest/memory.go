@@ -0,0 +1,364 @@
+package filetest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// MemoryURL is the root url of files stored by Memory
+const MemoryURL = "https://memory.blob.local/container"
+
+// Memory is an in-memory IFile, safe for concurrent use.
+// It is the reference implementation the conformance suite is checked against
+// and a fake backend for tests of code using IFile.
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	holds   map[string]string
+}
+
+type memoryObject struct {
+	data []byte
+	info file.ObjectInfo
+}
+
+var _ file.IFile = &Memory{}
+var _ file.CreateUploader = &Memory{}
+
+// NewMemory create empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{objects: map[string]memoryObject{}}
+}
+
+// Upload store a copy of buffBytes
+func (m *Memory) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	blob, err := m.UploadBlob(ctx, filePath, contentType, buffBytes)
+	if err != nil {
+		return "", err
+	}
+	return blob.URL, nil
+}
+
+// UploadBlob store a copy of buffBytes and return its ETag and MD5
+func (m *Memory) UploadBlob(ctx context.Context, filePath, contentType string, buffBytes []byte) (file.Blob, error) {
+	return m.store(ctx, filePath, contentType, buffBytes, false)
+}
+
+// UploadIfAbsent store a copy of buffBytes unless filePath exists, file.ErrExists then
+func (m *Memory) UploadIfAbsent(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	blob, err := m.store(ctx, filePath, contentType, buffBytes, true)
+	if err != nil {
+		return "", err
+	}
+	return blob.URL, nil
+}
+
+// store a copy of buffBytes at filePath, failing with file.ErrExists when ifAbsent is set and it exists
+func (m *Memory) store(ctx context.Context, filePath, contentType string, buffBytes []byte, ifAbsent bool) (file.Blob, error) {
+	if err := file.CheckKey(filePath); err != nil {
+		return file.Blob{}, err
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(buffBytes)
+	}
+
+	metadata := file.MetadataFromContext(ctx)
+
+	sum := md5.Sum(buffBytes)
+	obj := memoryObject{
+		data: append([]byte(nil), buffBytes...),
+		info: file.ObjectInfo{
+			Name:         filePath,
+			Size:         int64(len(buffBytes)),
+			ContentType:  contentType,
+			ContentMD5:   sum[:],
+			ETag:         fmt.Sprintf("\"%x\"", sum),
+			LastModified: time.Now().UTC(),
+			Metadata:     metadata,
+		},
+	}
+
+	m.mu.Lock()
+	if _, exists := m.objects[filePath]; exists && ifAbsent {
+		m.mu.Unlock()
+		return file.Blob{}, file.ErrExists
+	}
+	m.objects[filePath] = obj
+	m.mu.Unlock()
+
+	return file.Blob{
+		URL:          m.GetBlobURL(filePath, false),
+		Key:          filePath,
+		Size:         obj.info.Size,
+		ETag:         obj.info.ETag,
+		ContentMD5:   obj.info.ContentMD5,
+		LastModified: obj.info.LastModified,
+	}, nil
+}
+
+// Delete remove file, return file.ErrNotFound if it does not exist
+func (m *Memory) Delete(ctx context.Context, filePath string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.objects[filePath]; !ok {
+		return "", file.ErrNotFound
+	}
+	if reason, ok := m.holds[filePath]; ok {
+		return "", &file.HoldError{Key: filePath, Reason: reason}
+	}
+	delete(m.objects, filePath)
+
+	return m.GetBlobURL(filePath, false), nil
+}
+
+// Capabilities return the features Memory simulate: presigned urls and holds
+func (m *Memory) Capabilities() file.CapabilitySet {
+	return file.CapPresign | file.CapLocking
+}
+
+// Hold make Delete of filePath fail with a *file.HoldError until Release, reason is file.HoldLegal or file.HoldRetention
+func (m *Memory) Hold(filePath, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holds == nil {
+		m.holds = map[string]string{}
+	}
+	m.holds[filePath] = reason
+}
+
+// Release remove the hold of filePath
+func (m *Memory) Release(filePath string) {
+	m.mu.Lock()
+	delete(m.holds, filePath)
+	m.mu.Unlock()
+}
+
+// Download return a copy of the file content
+func (m *Memory) Download(ctx context.Context, filePath string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[filePath]
+	if !ok {
+		return nil, file.ErrNotFound
+	}
+
+	return append([]byte(nil), obj.data...), nil
+}
+
+// DownloadIfModified return file.ErrNotModified when the file ETag is knownETag
+func (m *Memory) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[filePath]
+	if !ok {
+		return nil, "", file.ErrNotFound
+	}
+	if knownETag != "" && knownETag == obj.info.ETag {
+		return nil, knownETag, file.ErrNotModified
+	}
+
+	return append([]byte(nil), obj.data...), obj.info.ETag, nil
+}
+
+// ReadRange return count bytes of the file from offset, file.ErrChanged when etag is set and not the file ETag
+func (m *Memory) ReadRange(ctx context.Context, filePath string, offset, count int64, etag string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[filePath]
+	if !ok {
+		return nil, file.ErrNotFound
+	}
+	if etag != "" && etag != obj.info.ETag {
+		return nil, file.ErrChanged
+	}
+	if offset < 0 || count < 0 || offset+count > int64(len(obj.data)) {
+		return nil, fmt.Errorf("filetest: range %d-%d past the %d bytes of %s", offset, offset+count, len(obj.data), filePath)
+	}
+	return append([]byte(nil), obj.data[offset:offset+count]...), nil
+}
+
+// Copy duplicate file to dstPath
+func (m *Memory) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	if err := file.CheckKey(dstPath); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.objects[srcPath]
+	if !ok {
+		return "", file.ErrNotFound
+	}
+	obj.info.Name = dstPath
+	obj.info.LastModified = time.Now().UTC()
+	m.objects[dstPath] = obj
+
+	return m.GetBlobURL(dstPath, false), nil
+}
+
+// SetHeaders change content type, cache control and content encoding of file, other headers are not kept
+func (m *Memory) SetHeaders(ctx context.Context, filePath string, h file.Headers) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok := m.objects[filePath]
+	if !ok {
+		return file.ErrNotFound
+	}
+	if h.ContentType != "" {
+		obj.info.ContentType = h.ContentType
+	}
+	if h.CacheControl != "" {
+		obj.info.CacheControl = h.CacheControl
+	}
+	if h.ContentEncoding != "" {
+		obj.info.ContentEncoding = h.ContentEncoding
+	}
+	m.objects[filePath] = obj
+
+	return nil
+}
+
+// GetBlobURL return MemoryURL based url, signed with a fake signature if withSignature is set
+func (m *Memory) GetBlobURL(fileName string, withSignature bool) string {
+	if fileName == "" {
+		return fileName
+	}
+
+	u := MemoryURL + "/" + file.EscapeKey(fileName)
+	if !withSignature {
+		return u
+	}
+
+	expiryTime := time.Now().Add(time.Second * file.ExpireTime).UTC().Format("2006-01-02T15:04:05Z")
+	return u + "?se=" + url.QueryEscape(expiryTime) + "&sig=" + url.QueryEscape(m.GenerateSharedAccessSignature(expiryTime, fileName))
+}
+
+// GetFileName return file name of a MemoryURL based url
+func (m *Memory) GetFileName(blobUrl string) string {
+	u, err := url.Parse(blobUrl)
+	if err != nil {
+		return blobUrl
+	}
+	base, _ := url.Parse(MemoryURL)
+	return strings.TrimPrefix(u.Path, base.Path+"/")
+}
+
+// GetURL return MemoryURL
+func (m *Memory) GetURL() string {
+	return MemoryURL
+}
+
+// GetContainer is not supported
+func (m *Memory) GetContainer() (azblob.ContainerURL, error) {
+	return azblob.ContainerURL{}, file.ErrNotSupported
+}
+
+// GenerateSharedAccessSignature return deterministic fake signature
+func (m *Memory) GenerateSharedAccessSignature(expiryTime string, fileName string) string {
+	h := hmac.New(sha256.New, []byte("memory"))
+	h.Write([]byte(expiryTime + "\n" + fileName))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// GetListBlob return names of files under prefix
+func (m *Memory) GetListBlob(ctx context.Context, prefix string) (list []string, err error) {
+	for _, info := range m.snapshot(prefix) {
+		list = append(list, info.Name)
+	}
+	return
+}
+
+// PresignMany return signed url of every key
+func (m *Memory) PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+	urls := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			urls[key] = m.GetBlobURL(key, true)
+		}
+	}
+	return urls, nil
+}
+
+// ListDir return immediate child directories and files of prefix
+func (m *Memory) ListDir(ctx context.Context, prefix string) (file.DirList, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, file.Delimiter) {
+		prefix += file.Delimiter
+	}
+	dir := file.DirList{Prefix: prefix}
+
+	seen := map[string]bool{}
+	for _, info := range m.snapshot(prefix) {
+		rest := strings.TrimPrefix(info.Name, prefix)
+		if i := strings.Index(rest, file.Delimiter); i >= 0 {
+			sub := prefix + rest[:i+1]
+			if !seen[sub] {
+				seen[sub] = true
+				dir.Dirs = append(dir.Dirs, sub)
+			}
+			continue
+		}
+		dir.Files = append(dir.Files, info)
+	}
+
+	return dir, nil
+}
+
+// List return files under prefix, see file.ApplyListOptions
+func (m *Memory) List(ctx context.Context, prefix string, opts ...file.ListOption) ([]file.ObjectInfo, error) {
+	return file.ApplyListOptions(m.snapshot(prefix), opts...), nil
+}
+
+// ListStream stream files under prefix in name order, see file.StreamList
+func (m *Memory) ListStream(ctx context.Context, prefix string, opts ...file.ListOption) <-chan file.ListItem {
+	files := file.ApplyListOptions(m.snapshot(prefix), append(opts, file.WithSort(file.SortByName, false))...)
+	return file.StreamList(ctx, func(fn func(file.ObjectInfo) error) error {
+		for _, info := range files {
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// Usage aggregate files under prefix
+func (m *Memory) Usage(ctx context.Context, prefix string) (file.UsageReport, error) {
+	report := file.UsageReport{Prefix: prefix}
+	for _, info := range m.snapshot(prefix) {
+		report.Add(info)
+	}
+	return report, nil
+}
+
+// snapshot return info of files under prefix ordered by name
+func (m *Memory) snapshot(prefix string) []file.ObjectInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []file.ObjectInfo
+	for name, obj := range m.objects {
+		if strings.HasPrefix(name, prefix) {
+			list = append(list, obj.info)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,16 @@
+package filetest_test
+
+import (
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	filetest.RunConformanceTests(t, func() file.IFile { return filetest.NewMemory() })
+}
+
+func TestMemoryConcurrency(t *testing.T) {
+	filetest.RunConcurrencyTests(t, func() file.IFile { return filetest.NewMemory() })
+}
@@ -0,0 +1,3 @@
+package file
+
+//go:generate go run github.com/matryer/moq@v0.6.0 -out filemock/mock.go -pkg filemock . IFile Opener Queue DeadLetter StreamUploader MultipartUploader BlobUploader RangeOpener VersionLister Capabler Cache KeyStore Catalog Inspector DownloadCounter Closer RangeReader ObjectWriterCreator Classifier EntryCache Prefetcher
@@ -0,0 +1,100 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TempPrefix is the area for temporary files managed by the SDK
+const TempPrefix = ".tmp/"
+
+// DefaultReservedPrefixes are managed by infrastructure, applications must not write or delete under them
+var DefaultReservedPrefixes = []string{".trash/", "system/", TempPrefix}
+
+// PolicyError returned when an operation violates a storage policy
+type PolicyError struct {
+	Op   string
+	Path string
+	Rule string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("file: %s %s denied by policy: %s", e.Op, e.Path, e.Rule)
+}
+
+// Guard wrap an IFile rejecting uploads, copies, deletes and header changes on reserved prefixes
+// or on the container root with *PolicyError, so application bugs can't overwrite
+// or delete infrastructure managed files.
+type Guard struct {
+	IFile
+	Reserved []string
+	// DenyRoot reject files outside any directory, e.g. "image.img"
+	DenyRoot bool
+}
+
+// NewGuard wrap next protecting reserved prefixes, DefaultReservedPrefixes when none given
+//
+//	Example:
+//	store := file.NewGuard(store)
+//	_, err := store.Delete(ctx, "system/config.json") // *file.PolicyError
+func NewGuard(next IFile, reserved ...string) *Guard {
+	if len(reserved) == 0 {
+		reserved = DefaultReservedPrefixes
+	}
+	return &Guard{IFile: next, Reserved: reserved, DenyRoot: true}
+}
+
+// Unwrap return the wrapped store
+func (g *Guard) Unwrap() IFile {
+	return g.IFile
+}
+
+// Check return *PolicyError if op is not allowed on filePath
+func (g *Guard) Check(op, filePath string) error {
+	name := strings.TrimPrefix(filePath, Delimiter)
+	if name == "" {
+		return &PolicyError{Op: op, Path: filePath, Rule: "empty path"}
+	}
+	if g.DenyRoot && !strings.Contains(name, Delimiter) {
+		return &PolicyError{Op: op, Path: filePath, Rule: "container root"}
+	}
+	for _, prefix := range g.Reserved {
+		if strings.HasPrefix(name, prefix) {
+			return &PolicyError{Op: op, Path: filePath, Rule: "reserved prefix " + prefix}
+		}
+	}
+	return nil
+}
+
+// Upload file unless filePath is protected
+func (g *Guard) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	if err := g.Check("upload", filePath); err != nil {
+		return "", err
+	}
+	return g.IFile.Upload(ctx, filePath, contentType, buffBytes)
+}
+
+// Delete file unless filePath is protected
+func (g *Guard) Delete(ctx context.Context, filePath string) (string, error) {
+	if err := g.Check("delete", filePath); err != nil {
+		return "", err
+	}
+	return g.IFile.Delete(ctx, filePath)
+}
+
+// Copy file unless dstPath is protected, reading from protected prefixes is allowed
+func (g *Guard) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	if err := g.Check("copy", dstPath); err != nil {
+		return "", err
+	}
+	return g.IFile.Copy(ctx, srcPath, dstPath)
+}
+
+// SetHeaders change file headers unless filePath is protected
+func (g *Guard) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	if err := g.Check("set headers", filePath); err != nil {
+		return err
+	}
+	return g.IFile.SetHeaders(ctx, filePath, h)
+}
@@ -0,0 +1,183 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// EncryptionAPIVersion is the storage API version used for encryption scope requests,
+// copying blob index tags with Put Blob From URL need 2021-04-10 or later
+const EncryptionAPIVersion = "2021-04-10"
+
+// Headers are the HTTP headers served with a file, empty fields are left unchanged by SetHeaders
+type Headers struct {
+	ContentType        string
+	CacheControl       string
+	ContentEncoding    string
+	ContentDisposition string
+	ContentLanguage    string
+}
+
+// SetHeaders change the HTTP headers of a stored file without rewriting its content
+//
+//	Example:
+//	err := file.SetHeaders(ctx, "file/image.img", file.Headers{CacheControl: "public, max-age=31536000"})
+func (c *File) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return err
+	}
+	if c.Anonymous {
+		return ErrReadOnly
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventUpdated, Key: filePath, ContentType: h.ContentType})
+		return nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return err
+	}
+	blobURL := containerURL.NewBlobURL(filePath)
+
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	// storage replace every header, keep the ones not being changed
+	current := props.NewHTTPHeaders()
+	merged := azblob.BlobHTTPHeaders{
+		ContentType:        firstNonEmpty(h.ContentType, current.ContentType),
+		CacheControl:       firstNonEmpty(h.CacheControl, current.CacheControl),
+		ContentEncoding:    firstNonEmpty(h.ContentEncoding, current.ContentEncoding),
+		ContentDisposition: firstNonEmpty(h.ContentDisposition, current.ContentDisposition),
+		ContentLanguage:    firstNonEmpty(h.ContentLanguage, current.ContentLanguage),
+		ContentMD5:         current.ContentMD5,
+	}
+
+	_, err = blobURL.SetHTTPHeaders(ctx, merged, azblob.BlobAccessConditions{
+		ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfMatch: props.ETag()},
+	})
+	return err
+}
+
+// SetEncryptionScope rewrite a file encrypted with the given encryption scope, e.g. one backed by a new key vault key.
+// The file is copied onto itself by storage, headers, metadata and blob index tags are kept,
+// files over 5000 MiB are rejected by storage.
+func (c *File) SetEncryptionScope(ctx context.Context, filePath, scope string) error {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return err
+	}
+	if c.Anonymous {
+		return ErrReadOnly
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventUpdated, Key: filePath})
+		return nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return err
+	}
+	props, err := containerURL.NewBlobURL(filePath).GetProperties(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	p, _, err := c.storagePipeline()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(c.GetBlobURL(filePath, false))
+	if err != nil {
+		return err
+	}
+
+	// Put Blob From URL, the source is read with a short lived signature
+	req, err := pipeline.NewRequest(http.MethodPut, *u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", EncryptionAPIVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-copy-source", c.GetBlobURL(filePath, true))
+	req.Header.Set("x-ms-copy-source-blob-properties", "true")
+	req.Header.Set("x-ms-copy-source-tag-option", "COPY")
+	req.Header.Set("x-ms-encryption-scope", scope)
+	req.Header.Set("If-Match", string(props.ETag()))
+	for k, v := range props.NewMetadata() {
+		req.Header.Set("x-ms-meta-"+k, v)
+	}
+	req.ContentLength = 0
+
+	resp, err := p.Do(ctx, nil, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Response().Body.Close()
+
+	if resp.Response().StatusCode != http.StatusCreated {
+		return fmt.Errorf("file: set encryption scope %s: %s", filePath, resp.Response().Status)
+	}
+	return nil
+}
+
+// EncryptionScope return the encryption scope a file is encrypted with, empty for the account key
+func (c *File) EncryptionScope(ctx context.Context, filePath string) (string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return "", err
+	}
+	p, _, err := c.storagePipeline()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(c.GetBlobURL(filePath, false))
+	if err != nil {
+		return "", err
+	}
+	req, err := pipeline.NewRequest(http.MethodHead, *u, nil)
+	if err != nil {
+		return "", err
+	}
+	// older versions don't report the scope
+	req.Header.Set("x-ms-version", EncryptionAPIVersion)
+
+	resp, err := p.Do(ctx, nil, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Response().Body.Close()
+
+	switch resp.Response().StatusCode {
+	case http.StatusOK:
+		return resp.Response().Header.Get("x-ms-encryption-scope"), nil
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	}
+	return "", fmt.Errorf("file: encryption scope %s: %s", filePath, resp.Response().Status)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetEncryptionScopeCopyTags(t *testing.T) {
+	var put http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("ETag", `"0x8D8"`)
+			w.Header().Set("x-ms-meta-owner", "42")
+			return
+		}
+		put = r.Header
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12").(*File)
+	if err := c.SetEncryptionScope(context.Background(), "docs/contract.pdf", "scope-2021"); err != nil {
+		t.Fatal(err)
+	}
+	if put == nil {
+		t.Fatal("no Put Blob From URL request")
+	}
+
+	want := map[string]string{
+		"x-ms-version":                     EncryptionAPIVersion,
+		"x-ms-copy-source-tag-option":      "COPY",
+		"x-ms-copy-source-blob-properties": "true",
+		"x-ms-encryption-scope":            "scope-2021",
+		"If-Match":                         `"0x8D8"`,
+		"x-ms-meta-owner":                  "42",
+	}
+	for name, v := range want {
+		if got := put.Get(name); got != v {
+			t.Errorf("%s = %q, want %q", name, got, v)
+		}
+	}
+	if put.Get("x-ms-copy-source") == "" {
+		t.Error("x-ms-copy-source missing")
+	}
+	if EncryptionAPIVersion < "2021-04-10" {
+		t.Errorf("EncryptionAPIVersion %s doesn't support x-ms-copy-source-tag-option", EncryptionAPIVersion)
+	}
+}
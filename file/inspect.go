@@ -0,0 +1,237 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Metadata keys set on files flagged by Inspected
+const (
+	MetadataPIIReview = "pii_review"
+	MetadataPIIRules  = "pii_rules"
+)
+
+// InspectAction is what Inspected do with files containing findings
+type InspectAction int
+
+const (
+	// InspectFlag store the file tagged for review
+	InspectFlag InspectAction = iota
+	// InspectBlock reject the upload with *PIIError
+	InspectBlock
+)
+
+// Finding is sensitive data found in a file
+type Finding struct {
+	Rule   string
+	Offset int
+	// Match is the masked matched text, e.g. "************1111"
+	Match string
+}
+
+// Inspector scan file content for sensitive data, implement it to plug external DLP services
+type Inspector interface {
+	Inspect(ctx context.Context, filePath, contentType string, data []byte) ([]Finding, error)
+}
+
+// PIIError returned when an upload is blocked by Inspected
+type PIIError struct {
+	Path     string
+	Findings []Finding
+}
+
+func (e *PIIError) Error() string {
+	return fmt.Sprintf("file: %s blocked, contains %s", e.Path, strings.Join(findingRules(e.Findings), ", "))
+}
+
+func findingRules(findings []Finding) []string {
+	seen := map[string]bool{}
+	var rules []string
+	for _, f := range findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			rules = append(rules, f.Rule)
+		}
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// Rule is a regular expression for sensitive data, Validate filter false positives when set
+type Rule struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// DefaultRules detect credit card numbers, Indonesian national ID (NIK) and US social security numbers
+var DefaultRules = []Rule{
+	{
+		Name:     "credit_card",
+		Pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Validate: luhn,
+	},
+	{
+		Name:     "national_id",
+		Pattern:  regexp.MustCompile(`\b\d{6}[0-7]\d[01]\d{3}\d{4}\b`),
+		Validate: validNIK,
+	},
+	{
+		Name:    "ssn",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+}
+
+// RegexInspector find sensitive data with regular expression rules
+type RegexInspector struct {
+	Rules []Rule
+}
+
+// NewRegexInspector create inspector using rules, DefaultRules when none given
+func NewRegexInspector(rules ...Rule) *RegexInspector {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	return &RegexInspector{Rules: rules}
+}
+
+// Inspect return every rule match in data
+func (r *RegexInspector) Inspect(ctx context.Context, filePath, contentType string, data []byte) ([]Finding, error) {
+	var findings []Finding
+	for _, rule := range r.Rules {
+		for _, loc := range rule.Pattern.FindAllIndex(data, -1) {
+			match := string(data[loc[0]:loc[1]])
+			if rule.Validate != nil && !rule.Validate(match) {
+				continue
+			}
+			findings = append(findings, Finding{Rule: rule.Name, Offset: loc[0], Match: mask(match)})
+		}
+	}
+	return findings, nil
+}
+
+// mask hide all but the last 4 digits
+func mask(s string) string {
+	digits := []rune(s)
+	shown := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digits[i] < '0' || digits[i] > '9' {
+			continue
+		}
+		if shown < 4 {
+			shown++
+			continue
+		}
+		digits[i] = '*'
+	}
+	return string(digits)
+}
+
+// luhn report whether the digits of s pass the Luhn checksum used by card numbers
+func luhn(s string) bool {
+	sum, n, double := 0, 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// validNIK check the province and birth date parts of an Indonesian NIK, women have 40 added to the day
+func validNIK(s string) bool {
+	// province codes are 11 to 94, none of them start with 4
+	if s[0] == '0' || s[0] == '4' {
+		return false
+	}
+	day := int(s[6]-'0')*10 + int(s[7]-'0')
+	if day > 40 {
+		day -= 40
+	}
+	month := int(s[8]-'0')*10 + int(s[9]-'0')
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+// DefaultInspectTypes are the content type prefixes inspected by default
+var DefaultInspectTypes = []string{"text/", "application/json", "application/xml", "application/csv"}
+
+// Inspected wrap an IFile scanning uploads for sensitive data before they are stored.
+// Depending on Action, files with findings are stored with MetadataPIIReview metadata or rejected with *PIIError.
+type Inspected struct {
+	IFile
+	Inspector Inspector
+	Action    InspectAction
+	// ContentTypes prefixes of inspected content types, DefaultInspectTypes when nil
+	ContentTypes []string
+	// OnFinding is called for every file with findings, e.g. to queue it for review
+	OnFinding func(ctx context.Context, filePath string, findings []Finding)
+}
+
+// NewInspected wrap next inspecting uploads with inspector
+//
+//	Example:
+//	store := file.NewInspected(store, file.NewRegexInspector(), file.InspectBlock)
+func NewInspected(next IFile, inspector Inspector, action InspectAction) *Inspected {
+	return &Inspected{IFile: next, Inspector: inspector, Action: action}
+}
+
+// Unwrap return the wrapped store
+func (i *Inspected) Unwrap() IFile {
+	return i.IFile
+}
+
+// Upload inspect buffBytes before uploading it
+func (i *Inspected) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	detected := contentType
+	if detected == "" {
+		detected = http.DetectContentType(buffBytes)
+	}
+
+	if i.inspects(detected) {
+		findings, err := i.Inspector.Inspect(ctx, filePath, detected, buffBytes)
+		if err != nil {
+			return "", err
+		}
+
+		if len(findings) > 0 {
+			if i.OnFinding != nil {
+				i.OnFinding(ctx, filePath, findings)
+			}
+			if i.Action == InspectBlock {
+				return "", &PIIError{Path: filePath, Findings: findings}
+			}
+			ctx = WithMetadata(ctx, MetadataPIIReview, "true")
+			ctx = WithMetadata(ctx, MetadataPIIRules, strings.Join(findingRules(findings), ","))
+		}
+	}
+
+	return i.IFile.Upload(ctx, filePath, contentType, buffBytes)
+}
+
+func (i *Inspected) inspects(contentType string) bool {
+	types := i.ContentTypes
+	if types == nil {
+		types = DefaultInspectTypes
+	}
+	for _, t := range types {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ErrUnsupportedInventoryFormat returned for inventory formats other than CSV, ORC and Parquet,
+// and for ORC and Parquet files using a compression, encoding or nested column the reader doesn't support
+var ErrUnsupportedInventoryFormat = errors.New("file: unsupported inventory format")
+
+// inventory column names of each ObjectInfo field: S3 CSV, Azure, then S3 ORC and Parquet
+var (
+	inventoryKeyColumns          = []string{"Key", "Name", "key"}
+	inventorySizeColumns         = []string{"Size", "Content-Length", "size"}
+	inventoryLastModifiedColumns = []string{"LastModifiedDate", "Last-Modified", "last_modified_date"}
+	inventoryETagColumns         = []string{"ETag", "Etag", "e_tag"}
+	inventoryContentTypeColumns  = []string{"Content-Type"}
+	inventoryContentMD5Columns   = []string{"Content-MD5"}
+)
+
+// inventoryColumn report whether name is a column ObjectInfo is built from, ORC and Parquet readers decode only those
+func inventoryColumn(name string) bool {
+	for _, names := range [][]string{inventoryKeyColumns, inventorySizeColumns, inventoryLastModifiedColumns,
+		inventoryETagColumns, inventoryContentTypeColumns, inventoryContentMD5Columns} {
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inventoryObject build the ObjectInfo of an inventory record, field return the value of the first column found
+func inventoryObject(field func(names ...string) string) ObjectInfo {
+	info := ObjectInfo{
+		Name:        field(inventoryKeyColumns...),
+		ContentType: field(inventoryContentTypeColumns...),
+		ETag:        field(inventoryETagColumns...),
+	}
+	info.Size, _ = strconv.ParseInt(field(inventorySizeColumns...), 10, 64)
+	info.LastModified, _ = time.Parse(time.RFC3339Nano, field(inventoryLastModifiedColumns...))
+	info.ContentMD5, _ = base64.StdEncoding.DecodeString(field(inventoryContentMD5Columns...))
+	return info
+}
+
+// Opener open a stored file for reading
+type Opener interface {
+	Open(ctx context.Context, filePath string) (io.ReadCloser, error)
+}
+
+// DirOpener open inventory files downloaded to a local directory
+type DirOpener string
+
+// Open file relative to the directory
+func (d DirOpener) Open(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(filePath)))
+}
+
+// Open return a reader of the file content, caller must close it.
+// A transfer failing midway is resumed from the last received byte, see NewResumeReader.
+// Return ErrNotFound if file does not exist
+func (c *File) Open(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	return NewResumeReader(ctx, c, filePath, ResumeOptions{})
+}
+
+func (c *File) openBlob(ctx context.Context, filePath string) (*azblob.DownloadResponse, error) {
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := containerURL.NewBlobURL(filePath).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// inventoryManifest cover both S3 Inventory manifest.json and Azure Blob Inventory manifest
+type inventoryManifest struct {
+	// S3
+	FileFormat string `json:"fileFormat"`
+	FileSchema string `json:"fileSchema"`
+
+	// Azure
+	RuleDefinition struct {
+		Format string `json:"format"`
+	} `json:"ruleDefinition"`
+
+	Files []struct {
+		Key  string `json:"key"`
+		Blob string `json:"blob"`
+	} `json:"files"`
+}
+
+// ReadInventory call fn for every object listed by an S3 Inventory or Azure Blob Inventory run in CSV, ORC or Parquet.
+// manifestKey is the run manifest, e.g. "bucket/config/2020-03-01T00-00Z/manifest.json"
+// for S3 or "2020/03/01/00-00-00/rule/rule-manifest.json" for Azure, data files are opened relative to store.
+// ORC files compressed with zlib or snappy and Parquet files compressed with snappy or gzip are supported,
+// they are read from a temporary file unless store open them as *os.File, e.g. DirOpener.
+// Use it instead of live listing for containers holding hundreds of millions of files.
+func ReadInventory(ctx context.Context, store Opener, manifestKey string, fn func(ObjectInfo) error) error {
+	r, err := store.Open(ctx, manifestKey)
+	if err != nil {
+		return err
+	}
+	var m inventoryManifest
+	err = json.NewDecoder(r).Decode(&m)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	format := m.FileFormat
+	if format == "" {
+		format = m.RuleDefinition.Format
+	}
+	read := readInventoryFile
+	switch strings.ToLower(format) {
+	case "csv":
+	case "orc":
+		read = readColumnarInventoryFile(readORC)
+	case "parquet":
+		read = readColumnarInventoryFile(readParquet)
+	default:
+		return ErrUnsupportedInventoryFormat
+	}
+
+	// S3 data files have no header row, columns are given by the manifest
+	var columns []string
+	if m.FileSchema != "" {
+		for _, col := range strings.Split(m.FileSchema, ",") {
+			columns = append(columns, strings.TrimSpace(col))
+		}
+	}
+
+	for _, f := range m.Files {
+		name := f.Key
+		if name == "" {
+			name = f.Blob
+		}
+		if err := read(ctx, store, name, columns, fn); err != nil {
+			return fmt.Errorf("file: inventory %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func readInventoryFile(ctx context.Context, store Opener, name string, columns []string, fn func(ObjectInfo) error) error {
+	rc, err := store.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	s3 := columns != nil
+	if !s3 {
+		header, err := cr.Read()
+		if err != nil {
+			return err
+		}
+		columns = append([]string(nil), header...)
+	}
+
+	index := map[string]int{}
+	for i, col := range columns {
+		index[col] = i
+	}
+	field := func(record []string, names ...string) string {
+		for _, n := range names {
+			if i, ok := index[n]; ok && i < len(record) {
+				return record[i]
+			}
+		}
+		return ""
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		info := inventoryObject(func(names ...string) string {
+			return field(record, names...)
+		})
+		if s3 {
+			// S3 inventory keys are url encoded
+			if key, err := url.QueryUnescape(info.Name); err == nil {
+				info.Name = key
+			}
+		}
+
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+}
+
+// columnarReader call fn with the values of every row of an ORC or Parquet file, by column name.
+// Only columns accepted by inventoryColumn are decoded, values are strings with times in RFC 3339 and null as empty.
+type columnarReader func(ctx context.Context, r io.ReaderAt, size int64, fn func(row map[string]string) error) error
+
+// readColumnarInventoryFile return a data file reader for a columnar format, the manifest columns are not used
+// as ORC and Parquet files carry their schema
+func readColumnarInventoryFile(read columnarReader) func(context.Context, Opener, string, []string, func(ObjectInfo) error) error {
+	return func(ctx context.Context, store Opener, name string, _ []string, fn func(ObjectInfo) error) error {
+		r, size, cleanup, err := openReaderAt(ctx, store, name)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		return read(ctx, r, size, func(row map[string]string) error {
+			return fn(inventoryObject(func(names ...string) string {
+				for _, n := range names {
+					if v, ok := row[n]; ok {
+						return v
+					}
+				}
+				return ""
+			}))
+		})
+	}
+}
+
+// openReaderAt open name for random access, columnar formats are read from their footer.
+// Files not opened as *os.File are copied to a temporary file removed by cleanup.
+func openReaderAt(ctx context.Context, store Opener, name string) (r io.ReaderAt, size int64, cleanup func(), err error) {
+	rc, err := store.Open(ctx, name)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if f, ok := rc.(*os.File); ok {
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, nil, err
+		}
+		return f, stat.Size(), func() { f.Close() }, nil
+	}
+	defer rc.Close()
+
+	tmp, err := ioutil.TempFile("", "inventory-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup = func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	if size, err = io.Copy(tmp, rc); err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return tmp, size, cleanup, nil
+}
+
+// readFullAt fill p from offset off of r, a full read at the end of r may report io.EOF
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// UsageFromInventory aggregate usage under prefix from an inventory run instead of live listing
+func UsageFromInventory(ctx context.Context, store Opener, manifestKey, prefix string) (UsageReport, error) {
+	report := UsageReport{Prefix: prefix}
+	err := ReadInventory(ctx, store, manifestKey, func(info ObjectInfo) error {
+		if strings.HasPrefix(info.Name, prefix) {
+			report.Add(info)
+		}
+		return nil
+	})
+	if err != nil {
+		return UsageReport{}, err
+	}
+
+	return report, nil
+}
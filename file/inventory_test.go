@@ -0,0 +1,549 @@
+package file
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnappyDecode(t *testing.T) {
+	// blocks encoded by github.com/golang/snappy
+	vectors := []struct{ src, encoded string }{
+		{
+			"inventory/" + strings.Repeat("abcabcabc", 20) + "tenant/42/" + strings.Repeat("x", 100) + "end",
+			"af0230696e76656e746f72792f616263fe0300fe0300c203002874656e616e742f34322f78fe01008a010008656e64",
+		},
+		{
+			"xvlbzgbaicmrajwwhthctcuaxhxkqfdafplsjfbcxoeffrswxpldnjobcsnvlgtemapezqsize,etag,size,etag,key1,key2,key1,key2",
+			"6df06c78766c627a67626169636d72616a777768746863746375617868786b7166646166706c736a666263786f65666672737778706c646e6a6f6263736e766c6774656d6170657a7173697a652c657461672c73697a652c657461672c6b6579312c6b6579322c6b6579312c6b657932",
+		},
+		// 1 and 4 bytes offset copies
+		{"abcdabcd", "080c61626364" + "0104"},
+		{"abcdabc", "070c61626364" + "0b04000000"},
+	}
+	for _, v := range vectors {
+		encoded, _ := hex.DecodeString(v.encoded)
+		got, err := snappyDecode(encoded)
+		if err != nil || string(got) != v.src {
+			t.Errorf("snappyDecode(%s) = %q, %v, want %q", v.encoded, got, err, v.src)
+		}
+		for i := range encoded {
+			if _, err := snappyDecode(encoded[:i]); err == nil {
+				t.Errorf("snappyDecode of %d of %d bytes succeeded", i, len(encoded))
+			}
+		}
+	}
+}
+
+func TestORCIntegersV2(t *testing.T) {
+	// examples of the ORC specification
+	vectors := []struct {
+		encoded string
+		want    []int64
+	}{
+		{"0a2710", []int64{10000, 10000, 10000, 10000, 10000}},
+		{"5e035ca1ab1edeadbeef", []int64{23713, 43806, 57005, 48879}},
+		{
+			"8e132b2107d01e00147028323c46505a646e78828c96a0aab4befce8",
+			[]int64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190},
+		},
+		{"c609020222424246", []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+	}
+	for _, v := range vectors {
+		encoded, _ := hex.DecodeString(v.encoded)
+		got, err := orcIntegers(encoded, false, true, len(v.want))
+		if err != nil || !reflect.DeepEqual(got, v.want) {
+			t.Errorf("orcIntegers(%s) = %v, %v, want %v", v.encoded, got, err, v.want)
+		}
+	}
+
+	// signed fixed delta and v1 runs
+	if got, err := orcIntegers([]byte{0xc0, 0x04, 0x13, 0x03}, true, true, 5); err != nil || !reflect.DeepEqual(got, []int64{-10, -12, -14, -16, -18}) {
+		t.Errorf("orcIntegers of a signed fixed delta = %v, %v", got, err)
+	}
+	if got, err := orcIntegers([]byte{0x00, 0xff, 0x07, 0xff, 0x05}, true, false, 4); err != nil || !reflect.DeepEqual(got, []int64{-4, -5, -6, -3}) {
+		t.Errorf("orcIntegers v1 = %v, %v", got, err)
+	}
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	// run of 3 fives then a bit-packed group of 0 to 7, bit width 3
+	data := []byte{0x06, 0x05, 0x03, 0x88, 0xc6, 0xfa}
+	got, err := decodeHybrid(data, 3, 11)
+	want := []uint32{5, 5, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeHybrid = %v, %v, want %v", got, err, want)
+	}
+}
+
+var inventoryRows = []ObjectInfo{
+	{Name: "tenant/42/a b.pdf", Size: 10, ETag: "e1", LastModified: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+	{Name: "tenant/42/c.pdf", ETag: "e1", LastModified: time.Date(2020, 3, 1, 0, 0, 0, 123000000, time.UTC)},
+	{Name: "tenant/7/d.pdf", Size: 30, ETag: "e2"},
+}
+
+// memoryOpener open files from memory, not as *os.File
+type memoryOpener map[string][]byte
+
+func (m memoryOpener) Open(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	data, ok := m[filePath]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendBigEndian64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// sameObjects compare the fields inventories fill
+func sameObjects(got, want []ObjectInfo) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Size != w.Size || g.ETag != w.ETag || !g.LastModified.Equal(w.LastModified) {
+			return false
+		}
+	}
+	return true
+}
+
+func readAllInventory(t *testing.T, store Opener, manifest string) []ObjectInfo {
+	t.Helper()
+	var got []ObjectInfo
+	err := ReadInventory(context.Background(), store, manifest, func(info ObjectInfo) error {
+		got = append(got, info)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestReadInventoryParquet(t *testing.T) {
+	store := memoryOpener{
+		"rule-manifest.json":       []byte(`{"ruleDefinition": {"format": "parquet"}, "files": [{"blob": "inventory/part-0.parquet"}]}`),
+		"inventory/part-0.parquet": parquetFixture(),
+	}
+	if got := readAllInventory(t, store, "rule-manifest.json"); !sameObjects(got, inventoryRows) {
+		t.Errorf("ReadInventory = %+v, want %+v", got, inventoryRows)
+	}
+
+	fixture := parquetFixture()
+	for i := range fixture {
+		readParquet(context.Background(), bytes.NewReader(fixture[:i]), int64(i), func(map[string]string) error { return nil })
+	}
+}
+
+func TestReadInventoryORC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := `{"fileFormat": "ORC", "fileSchema": "struct<bucket:string,key:string>", "files": [{"key": "data/0.orc"}]}`
+	os.MkdirAll(filepath.Join(dir, "data"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "data", "0.orc"), orcFixture(1), 0644)
+
+	// S3 ORC keys are not url encoded
+	if got := readAllInventory(t, DirOpener(dir), "manifest.json"); !sameObjects(got, inventoryRows) {
+		t.Errorf("ReadInventory = %+v, want %+v", got, inventoryRows)
+	}
+
+	plain := memoryOpener{"manifest.json": []byte(manifest), "data/0.orc": orcFixture(0)}
+	if got := readAllInventory(t, plain, "manifest.json"); !sameObjects(got, inventoryRows) {
+		t.Errorf("ReadInventory of an uncompressed file = %+v, want %+v", got, inventoryRows)
+	}
+
+	fixture := orcFixture(1)
+	for i := range fixture {
+		readORC(context.Background(), bytes.NewReader(fixture[:i]), int64(i), func(map[string]string) error { return nil })
+	}
+
+	lzo := memoryOpener{"manifest.json": []byte(manifest), "data/0.orc": orcFixture(3)}
+	err = ReadInventory(context.Background(), lzo, "manifest.json", func(ObjectInfo) error { return nil })
+	if !errors.Is(err, ErrUnsupportedInventoryFormat) {
+		t.Errorf("ReadInventory of a LZO compressed ORC file = %v, want ErrUnsupportedInventoryFormat", err)
+	}
+}
+
+// thriftField is a field of a struct encoded by encodeThrift. Values are bool, int32, int64, string,
+// []thriftField for structs, [][]thriftField for lists of structs, []int32 and []string
+type thriftField struct {
+	id int16
+	v  interface{}
+}
+
+func encodeThrift(fields []thriftField) []byte {
+	var b bytes.Buffer
+	var last int16
+	for _, f := range fields {
+		typ, body := thriftValue(f.v)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b.WriteByte(byte(delta)<<4 | typ)
+		} else {
+			b.WriteByte(typ)
+			b.Write(appendUvarint(nil, uint64(int64(f.id)<<1^int64(f.id)>>15)))
+		}
+		b.Write(body)
+		last = f.id
+	}
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+func thriftValue(v interface{}) (byte, []byte) {
+	zigzag := func(n int64) []byte { return appendUvarint(nil, uint64(n<<1^n>>63)) }
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 2, nil
+	case int32:
+		return 5, zigzag(int64(v))
+	case int64:
+		return 6, zigzag(v)
+	case string:
+		return 8, append(appendUvarint(nil, uint64(len(v))), v...)
+	case []thriftField:
+		return 12, encodeThrift(v)
+	case [][]thriftField:
+		b := []byte{byte(len(v))<<4 | 12}
+		for _, s := range v {
+			b = append(b, encodeThrift(s)...)
+		}
+		return 9, b
+	case []int32:
+		b := []byte{byte(len(v))<<4 | 5}
+		for _, n := range v {
+			b = append(b, zigzag(int64(n))...)
+		}
+		return 9, b
+	case []string:
+		b := []byte{byte(len(v))<<4 | 8}
+		for _, s := range v {
+			_, e := thriftValue(s)
+			b = append(b, e...)
+		}
+		return 9, b
+	}
+	panic("thrift type")
+}
+
+// snappyLiterals encode data as a snappy block of literals only
+func snappyLiterals(data []byte) []byte {
+	b := appendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := len(data)
+		if n > 60 {
+			n = 60
+		}
+		b = append(b, byte(n-1)<<2)
+		b = append(b, data[:n]...)
+		data = data[n:]
+	}
+	return b
+}
+
+func plainStrings(values ...string) []byte {
+	var b []byte
+	for _, v := range values {
+		b = appendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+func plainInt64s(values ...int64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = appendUint64(b, uint64(v))
+	}
+	return b
+}
+
+// bitPacked encode values of width bits as one bit-packed run of the RLE hybrid encoding
+func bitPacked(width int, values ...uint32) []byte {
+	groups := (len(values) + 7) / 8
+	packed := make([]byte, groups*width)
+	for i, v := range values {
+		for b := 0; b < width; b++ {
+			bit := i*width + b
+			packed[bit/8] |= byte(v>>uint(b)&1) << uint(bit%8)
+		}
+	}
+	return append(appendUvarint(nil, uint64(groups<<1|1)), packed...)
+}
+
+// parquetFixture is a snappy compressed Parquet file of inventoryRows with the S3 schema, the e_tag
+// column dictionary encoded in a data page v2 and the key column split in two pages
+func parquetFixture() []byte {
+	file := bytes.NewBuffer(append([]byte(nil), parquetMagic...))
+	page := func(typ int32, header thriftField, body []byte, compressed bool) {
+		data := body
+		if compressed {
+			data = snappyLiterals(body)
+		}
+		file.Write(encodeThrift([]thriftField{{1, typ}, {2, int32(len(body))}, {3, int32(len(data))}, header}))
+		file.Write(data)
+	}
+	dataPage := func(n int32, encoding int32, body []byte) {
+		page(0, thriftField{5, []thriftField{{1, n}, {2, encoding}, {3, int32(3)}, {4, int32(3)}}}, body, true)
+	}
+	levels := func(defs ...uint32) []byte {
+		b := bitPacked(1, defs...)
+		return append(appendUint32(nil, uint32(len(b))), b...)
+	}
+
+	var chunks [][]thriftField
+	chunk := func(typ int32, name string, dictionary bool, write func()) {
+		start := int64(file.Len())
+		write()
+		md := []thriftField{{1, typ}, {2, []int32{0}}, {3, []string{name}}, {4, int32(1)}, {5, int64(3)},
+			{6, int64(file.Len()) - start}, {7, int64(file.Len()) - start}, {9, start}}
+		if dictionary {
+			md = append(md, thriftField{11, start})
+		}
+		chunks = append(chunks, []thriftField{{2, start}, {3, md}})
+	}
+
+	chunk(parquetByteArray, "bucket", false, func() { dataPage(3, 0, plainStrings("assets", "assets", "assets")) })
+	chunk(parquetByteArray, "key", false, func() {
+		dataPage(2, 0, plainStrings(inventoryRows[0].Name, inventoryRows[1].Name))
+		dataPage(1, 0, plainStrings(inventoryRows[2].Name))
+	})
+	chunk(parquetInt64, "size", false, func() { dataPage(3, 0, append(levels(1, 0, 1), plainInt64s(10, 30)...)) })
+	chunk(parquetInt64, "last_modified_date", false, func() {
+		dataPage(3, 0, append(levels(1, 1, 0), plainInt64s(1583020800000, 1583020800123)...))
+	})
+	chunk(parquetByteArray, "e_tag", true, func() {
+		page(2, thriftField{7, []thriftField{{1, int32(2)}, {2, int32(0)}}}, plainStrings("e1", "e2"), true)
+		defs := bitPacked(1, 1, 1, 1)
+		page(3, thriftField{8, []thriftField{{1, int32(3)}, {2, int32(0)}, {3, int32(3)}, {4, int32(8)},
+			{5, int32(len(defs))}, {6, int32(0)}, {7, false}}}, append(defs, append([]byte{1}, bitPacked(1, 0, 0, 1)...)...), false)
+	})
+
+	optional := func(name string, typ int32, fields ...thriftField) []thriftField {
+		return append([]thriftField{{1, typ}, {3, int32(1)}, {4, name}}, fields...)
+	}
+	meta := encodeThrift([]thriftField{
+		{1, int32(1)},
+		{2, [][]thriftField{
+			{{4, "s3"}, {5, int32(5)}},
+			{{1, int32(parquetByteArray)}, {3, int32(0)}, {4, "bucket"}, {6, int32(0)}},
+			{{1, int32(parquetByteArray)}, {3, int32(0)}, {4, "key"}, {6, int32(0)}},
+			optional("size", parquetInt64),
+			optional("last_modified_date", parquetInt64, thriftField{6, int32(9)}),
+			optional("e_tag", parquetByteArray, thriftField{6, int32(0)}),
+		}},
+		{3, int64(3)},
+		{4, [][]thriftField{{{1, chunks}, {2, int64(file.Len())}, {3, int64(3)}}}},
+	})
+	file.Write(meta)
+	file.Write(appendUint32(nil, uint32(len(meta))))
+	file.Write(parquetMagic)
+	return file.Bytes()
+}
+
+// protoField is a field of a message encoded by encodeProto. Values are uint64, string, []byte and []uint64 packed
+type protoField struct {
+	n uint64
+	v interface{}
+}
+
+func encodeProto(fields ...protoField) []byte {
+	var b []byte
+	for _, f := range fields {
+		switch v := f.v.(type) {
+		case uint64:
+			b = appendUvarint(b, f.n<<3)
+			b = appendUvarint(b, v)
+		case []uint64:
+			var packed []byte
+			for _, n := range v {
+				packed = appendUvarint(packed, n)
+			}
+			f.v = packed
+			b = append(b, encodeProto(f)...)
+		case string:
+			f.v = []byte(v)
+			b = append(b, encodeProto(f)...)
+		case []byte:
+			b = appendUvarint(b, f.n<<3|2)
+			b = appendUvarint(b, uint64(len(v)))
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// orcDirect encode values as one run length encoding v2 direct block of 64 bits values
+func orcDirect(signed bool, values ...int64) []byte {
+	b := []byte{0x40 | 31<<1 | byte((len(values)-1)>>8), byte(len(values) - 1)}
+	for _, v := range values {
+		u := uint64(v)
+		if signed {
+			u = uint64(v<<1 ^ v>>63)
+		}
+		b = appendBigEndian64(b, u)
+	}
+	return b
+}
+
+// orcLiterals encode values as run length encoding v1 literals
+func orcLiterals(signed bool, values ...int64) []byte {
+	b := []byte{byte(-len(values))}
+	for _, v := range values {
+		u := uint64(v)
+		if signed {
+			u = uint64(v<<1 ^ v>>63)
+		}
+		b = appendUvarint(b, u)
+	}
+	return b
+}
+
+// orcPresentStream encode a present bitmap as byte run length encoding literals
+func orcPresentStream(present ...bool) []byte {
+	bits := make([]byte, (len(present)+7)/8)
+	for i, p := range present {
+		if p {
+			bits[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return append([]byte{byte(-len(bits))}, bits...)
+}
+
+// orcFixture is an ORC file of inventoryRows with the S3 schema in two stripes, compressed with compression,
+// streams and footers are zlib compressed when compression is 1
+func orcFixture(compression uint64) []byte {
+	compress := func(data []byte, original bool) []byte {
+		if compression != 1 {
+			return data
+		}
+		chunk, flag := data, 1
+		if !original {
+			var b bytes.Buffer
+			w, _ := flate.NewWriter(&b, flate.BestCompression)
+			w.Write(data)
+			w.Close()
+			chunk, flag = b.Bytes(), 0
+		}
+		header := len(chunk)<<1 | flag
+		return append([]byte{byte(header), byte(header >> 8), byte(header >> 16)}, chunk...)
+	}
+
+	file := bytes.NewBufferString("ORC")
+	var stripes [][]byte
+	epoch := func(t time.Time) int64 { return int64(t.Sub(orcEpoch) / time.Second) }
+
+	type stream struct {
+		column, kind uint64
+		data         []byte
+	}
+	stripe := func(rows int, streams []stream, encodings ...[]byte) {
+		offset := file.Len()
+		var footer []byte
+		for i, s := range streams {
+			data := compress(s.data, i%2 == 1)
+			file.Write(data)
+			footer = append(footer, encodeProto(protoField{1, encodeProto(protoField{1, s.kind}, protoField{2, s.column}, protoField{3, uint64(len(data))})})...)
+		}
+		for _, e := range encodings {
+			footer = append(footer, encodeProto(protoField{2, e})...)
+		}
+		footer = compress(append(footer, encodeProto(protoField{3, "UTC"})...), false)
+		dataLen := file.Len() - offset
+		file.Write(footer)
+		stripes = append(stripes, encodeProto(protoField{1, uint64(offset)}, protoField{2, uint64(0)},
+			protoField{3, uint64(dataLen)}, protoField{4, uint64(len(footer))}, protoField{5, uint64(rows)}))
+	}
+	direct := encodeProto(protoField{1, uint64(0)})
+	directV2 := encodeProto(protoField{1, uint64(2)})
+	dictionaryV2 := func(size uint64) []byte { return encodeProto(protoField{1, uint64(3)}, protoField{2, size}) }
+
+	stripe(2, []stream{
+		{1, orcData, []byte("assetsassets")},
+		{1, orcLength, orcDirect(false, 6, 6)},
+		{2, orcData, []byte(inventoryRows[0].Name + inventoryRows[1].Name)},
+		{2, orcLength, orcDirect(false, int64(len(inventoryRows[0].Name)), int64(len(inventoryRows[1].Name)))},
+		{3, orcPresent, orcPresentStream(true, false)},
+		{3, orcData, orcLiterals(true, 10)},
+		{4, orcData, orcDirect(true, epoch(inventoryRows[0].LastModified), epoch(inventoryRows[1].LastModified))},
+		// 123000000 nanoseconds, 6 zeros removed
+		{4, orcSecondary, orcDirect(false, 0, 123<<3|5)},
+		{5, orcData, orcDirect(false, 0, 0)},
+		{5, orcLength, orcDirect(false, 2)},
+		{5, orcDictionaryData, []byte("e1")},
+		{6, orcData, orcPresentStream(true, true)},
+	}, direct, directV2, directV2, direct, directV2, dictionaryV2(1), direct)
+	stripe(1, []stream{
+		{1, orcData, []byte("assets")},
+		{1, orcLength, orcDirect(false, 6)},
+		{2, orcData, []byte(inventoryRows[2].Name)},
+		{2, orcLength, orcDirect(false, int64(len(inventoryRows[2].Name)))},
+		{3, orcData, orcLiterals(true, 30)},
+		{4, orcPresent, orcPresentStream(false)},
+		{5, orcData, orcDirect(false, 0)},
+		{5, orcLength, orcDirect(false, 2)},
+		{5, orcDictionaryData, []byte("e2")},
+		{6, orcData, orcPresentStream(false)},
+	}, direct, directV2, directV2, direct, directV2, dictionaryV2(1), direct)
+
+	var fields []protoField
+	for _, s := range stripes {
+		fields = append(fields, protoField{3, s})
+	}
+	root := encodeProto(protoField{1, uint64(orcStruct)}, protoField{2, []uint64{1, 2, 3, 4, 5, 6}},
+		protoField{3, "bucket"}, protoField{3, "key"}, protoField{3, "size"},
+		protoField{3, "last_modified_date"}, protoField{3, "e_tag"}, protoField{3, "is_latest"})
+	fields = append(fields, protoField{4, root})
+	for _, kind := range []uint64{orcString, orcString, orcLong, orcTimestamp, orcString, orcBoolean} {
+		fields = append(fields, protoField{4, encodeProto(protoField{1, kind})})
+	}
+	fields = append(fields, protoField{6, uint64(3)})
+	footer := compress(encodeProto(fields...), false)
+	file.Write(footer)
+
+	ps := encodeProto(protoField{1, uint64(len(footer))}, protoField{2, compression}, protoField{3, uint64(256 << 10)},
+		protoField{4, []uint64{0, 12}}, protoField{8000, "ORC"})
+	file.Write(ps)
+	file.WriteByte(byte(len(ps)))
+	return file.Bytes()
+}
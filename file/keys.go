@@ -0,0 +1,100 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxKeyLength is the longest key in bytes storage accept
+const MaxKeyLength = 1024
+
+// ErrInvalidKey returned for keys that are empty, too long, not valid UTF-8 or holding control characters
+var ErrInvalidKey = errors.New("file: invalid key")
+
+// CheckKey return ErrInvalidKey with the reason when key can't be stored and addressed by url the same way everywhere
+func CheckKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidKey)
+	}
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("%w: longer than %d bytes", ErrInvalidKey, MaxKeyLength)
+	}
+	for i, r := range key {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(key[i:]); size == 1 {
+				return fmt.Errorf("%w: invalid utf-8 at byte %d", ErrInvalidKey, i)
+			}
+		}
+		// C0 and C1 control characters and DEL
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return fmt.Errorf("%w: control character %U at byte %d", ErrInvalidKey, r, i)
+		}
+	}
+	return nil
+}
+
+// EscapeKey percent-encode key for url paths. Only letters, digits, "-._~" and "/" are kept,
+// everything else is escaped as UTF-8 bytes, "+" as %2B and space as %20,
+// so no proxy, CDN or provider decode the path to another key.
+//
+//	Example:
+//	file.EscapeKey("docs/a b+c.pdf") // docs/a%20b%2Bc.pdf
+func EscapeKey(key string) string {
+	n := escapedKeyLen(key)
+	if n == len(key) {
+		return key
+	}
+	var b strings.Builder
+	b.Grow(n)
+	writeKeyEscaped(&b, key)
+	return b.String()
+}
+
+// escapedKeyLen return the length of key escaped like EscapeKey
+func escapedKeyLen(key string) int {
+	n := len(key)
+	for i := 0; i < len(key); i++ {
+		if !keyPathByte(key[i]) {
+			n += 2
+		}
+	}
+	return n
+}
+
+func keyPathByte(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' ||
+		ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/'
+}
+
+// writeKeyEscaped write key escaped like EscapeKey
+func writeKeyEscaped(b *strings.Builder, key string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		if keyPathByte(ch) {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&15])
+	}
+}
+
+// key return name normalized by NormalizeKey, ErrInvalidKey when it can't be stored
+func (c *File) key(name string) (string, error) {
+	if c.NormalizeKey != nil {
+		name = c.NormalizeKey(name)
+	}
+	return name, CheckKey(name)
+}
+
+// normalizedKey return name normalized by NormalizeKey
+func (c *File) normalizedKey(name string) string {
+	if c.NormalizeKey != nil {
+		return c.NormalizeKey(name)
+	}
+	return name
+}
@@ -0,0 +1,88 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeyNormalization(t *testing.T) {
+	var paths, prefixes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("comp") == "list" {
+			prefixes = append(prefixes, q.Get("prefix"))
+			w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs></Blobs><NextMarker/></EnumerationResults>`))
+			return
+		}
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && q.Get("comp") == "tags":
+			w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Tags><TagSet></TagSet></Tags>`))
+		case r.Method == http.MethodHead:
+			w.Header().Set("ETag", `"0x8D8"`)
+		case r.Method == http.MethodPut && q.Get("comp") == "":
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12", WithKeyNormalization(strings.ToLower)).(*File)
+	ctx := context.Background()
+	const want = "/account/container/reports/2020.txt"
+
+	calls := map[string]func(string) error{
+		"SetHeaders":         func(k string) error { return c.SetHeaders(ctx, k, Headers{CacheControl: "no-cache"}) },
+		"SetEncryptionScope": func(k string) error { return c.SetEncryptionScope(ctx, k, "scope") },
+		"EncryptionScope": func(k string) error {
+			_, err := c.EncryptionScope(ctx, k)
+			return err
+		},
+		"SetTags": func(k string) error { return c.SetTags(ctx, k, Tags{"status": "clean"}) },
+		"GetTags": func(k string) error {
+			_, err := c.GetTags(ctx, k)
+			return err
+		},
+		"ReplicationStatus": func(k string) error {
+			_, err := c.ReplicationStatus(ctx, k)
+			return err
+		},
+	}
+	for name, call := range calls {
+		paths = nil
+		if err := call("Reports/2020.TXT"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		for _, p := range paths {
+			if !strings.HasSuffix(p, " "+want) {
+				t.Errorf("%s requested %s, want the normalized key", name, p)
+			}
+		}
+		paths = nil
+		if err := call("bad\x00key"); err == nil || len(paths) != 0 {
+			t.Errorf("%s of an invalid key = %v, %d requests", name, err, len(paths))
+		}
+	}
+
+	if _, err := c.List(ctx, "Reports/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListDir(ctx, "Reports"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Usage(ctx, "Reports/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range prefixes {
+		if p != "reports/" {
+			t.Errorf("listed prefix %q, want reports/", p)
+		}
+	}
+	if len(prefixes) != 3 {
+		t.Errorf("%d list requests, want 3", len(prefixes))
+	}
+}
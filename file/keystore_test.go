@@ -0,0 +1,46 @@
+package file_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+// two instances creating the key of the same owner end up sealing with the same stored key
+func TestStoredKeyStoreCreateRace(t *testing.T) {
+	store := filetest.NewMemory()
+	master := []byte("0123456789abcdef0123456789abcdef")
+	ctx := context.Background()
+
+	const instances = 8
+	ids := make([]string, instances)
+	errs := make([]error, instances)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		keys, err := file.NewStoredKeyStore(store, "keys/", master)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := keys.Create(ctx, "42")
+			ids[i], errs[i] = key.ID, err
+		}(i)
+	}
+	wg.Wait()
+
+	keys, _ := file.NewStoredKeyStore(store, "keys/", master)
+	stored, err := keys.Get(ctx, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ids {
+		if errs[i] != nil || ids[i] != stored.ID {
+			t.Errorf("instance %d created key %q, %v, stored key is %q", i, ids[i], errs[i], stored.ID)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package file
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// Delimiter separate virtual directories in file names
+const Delimiter = "/"
+
+// ObjectInfo describe a stored file
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	ContentType  string
+	CacheControl string
+	// ContentEncoding is the encoding of the stored content, e.g. "gzip"
+	ContentEncoding string
+	ContentMD5      []byte
+	ETag            string
+	LastModified    time.Time
+	Metadata        map[string]string
+}
+
+// DirList is the content of a virtual directory
+type DirList struct {
+	Prefix string
+	Dirs   []string
+	Files  []ObjectInfo
+}
+
+func objectInfo(item azblob.BlobItem) ObjectInfo {
+	info := ObjectInfo{
+		Name:         item.Name,
+		ContentMD5:   item.Properties.ContentMD5,
+		ETag:         string(item.Properties.Etag),
+		LastModified: item.Properties.LastModified,
+		Metadata:     item.Metadata,
+	}
+	if item.Properties.ContentLength != nil {
+		info.Size = *item.Properties.ContentLength
+	}
+	if item.Properties.ContentType != nil {
+		info.ContentType = *item.Properties.ContentType
+	}
+	if item.Properties.CacheControl != nil {
+		info.CacheControl = *item.Properties.CacheControl
+	}
+	if item.Properties.ContentEncoding != nil {
+		info.ContentEncoding = *item.Properties.ContentEncoding
+	}
+	return info
+}
+
+// ListDir return immediate child directories and files of prefix.
+// Prefix is treated as directory, "file/image" list the content of "file/image/".
+// Dirs are returned with trailing delimiter, e.g. "file/image/2020/"
+//
+//	Example:
+//	dir, err := file.ListDir(ctx, "file/image")
+func (c *File) ListDir(ctx context.Context, prefix string) (dir DirList, err error) {
+	prefix = c.normalizedKey(prefix)
+	if prefix != "" && !strings.HasSuffix(prefix, Delimiter) {
+		prefix += Delimiter
+	}
+	dir.Prefix = prefix
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return
+	}
+
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		var listBlob *azblob.ListBlobsHierarchySegmentResponse
+		listBlob, err = containerURL.ListBlobsHierarchySegment(ctx, marker, Delimiter, listSegmentOptions(prefix))
+		if err != nil {
+			return
+		}
+		marker = listBlob.NextMarker
+
+		for _, p := range listBlob.Segment.BlobPrefixes {
+			dir.Dirs = append(dir.Dirs, p.Name)
+		}
+		for _, blobInfo := range listBlob.Segment.BlobItems {
+			dir.Files = append(dir.Files, objectInfo(blobInfo))
+		}
+	}
+
+	return
+}
+
+func listSegmentOptions(prefix string) azblob.ListBlobsSegmentOptions {
+	return azblob.ListBlobsSegmentOptions{
+		Prefix:  prefix,
+		Details: azblob.BlobListingDetails{Metadata: true},
+	}
+}
+
+// ListOption configure List
+type ListOption func(*listOptions)
+
+type listOptions struct {
+	filters    []func(name string) bool
+	sortBy     SortField
+	desc       bool
+	max        int
+	startAfter string
+}
+
+// SortField is the ObjectInfo field List result is ordered by
+type SortField int
+
+const (
+	SortByName SortField = iota
+	SortBySize
+	SortByLastModified
+)
+
+// errStopList stop listing early without reporting an error
+var errStopList = errors.New("file: stop listing")
+
+// WithGlob keep files matching shell pattern, see path.Match.
+// Pattern without delimiter is matched against base name, "*.pdf" match "docs/a.pdf",
+// otherwise against full name, "docs/*/a.pdf"
+func WithGlob(pattern string) ListOption {
+	return func(o *listOptions) {
+		o.filters = append(o.filters, func(name string) bool {
+			if !strings.Contains(pattern, Delimiter) {
+				name = path.Base(name)
+			}
+			ok, _ := path.Match(pattern, name)
+			return ok
+		})
+	}
+}
+
+// WithRegex keep files whose full name match re
+func WithRegex(re *regexp.Regexp) ListOption {
+	return func(o *listOptions) {
+		o.filters = append(o.filters, re.MatchString)
+	}
+}
+
+// WithSort order List result by field, ascending unless desc is set
+func WithSort(field SortField, desc bool) ListOption {
+	return func(o *listOptions) {
+		o.sortBy = field
+		o.desc = desc
+	}
+}
+
+// WithMaxResults limit List result to the first n files after sorting
+func WithMaxResults(n int) ListOption {
+	return func(o *listOptions) {
+		o.max = n
+	}
+}
+
+// WithStartAfter list only files whose name sorts after key.
+// Azure listing can't start at a key with azblob v0.8, *File list from the start of the prefix and skip
+// earlier names on the client: the cost is O(n) in the files under the prefix, narrow it when possible.
+// Listing stops without any request when key sorts after every name under the prefix.
+func WithStartAfter(key string) ListOption {
+	return func(o *listOptions) {
+		o.startAfter = key
+	}
+}
+
+func (o *listOptions) match(name string) bool {
+	if o.startAfter != "" && name <= o.startAfter {
+		return false
+	}
+	for _, f := range o.filters {
+		if !f(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// List return files under prefix, filtered and ordered by options.
+// Prefix is applied by storage, filters are evaluated page by page as listing progress.
+// Files are ordered by name unless WithSort is given; with WithMaxResults only the
+// top n files are kept in memory, and listing by name ascending stops as soon as n files are found.
+//
+//	Example:
+//	files, err := file.List(ctx, "report/", file.WithGlob("*.pdf"))
+//	latest, err := file.List(ctx, "report/", file.WithSort(file.SortByLastModified, true), file.WithMaxResults(20))
+func (c *File) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	col := newListCollector(opts)
+	err := c.list(ctx, prefix, opts, col.add)
+	if err != nil && err != errStopList {
+		return nil, err
+	}
+
+	return col.result(), nil
+}
+
+// ListItem is a file or the error ending a ListStream
+type ListItem struct {
+	Info ObjectInfo
+	Err  error
+}
+
+// listStreamBuffer is the number of files listed ahead of a ListStream consumer
+const listStreamBuffer = 1000
+
+// ListStream list files under prefix lazily page by page, so millions of files can be processed with constant memory.
+// Files are sent in name order, filter options and WithMaxResults apply, WithSort is ignored.
+// The channel is closed when listing ends, after an item with Err set if listing failed.
+// Cancel ctx to stop listing before reading every item.
+//
+//	Example:
+//	for item := range f.ListStream(ctx, "report/") {
+//		if item.Err != nil {
+//			return item.Err
+//		}
+//		fmt.Println(item.Info.Name)
+//	}
+func (c *File) ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem {
+	return StreamList(ctx, func(fn func(ObjectInfo) error) error {
+		return c.list(ctx, prefix, opts, fn)
+	}, opts...)
+}
+
+// StreamList run list in a goroutine sending every file it produce to the returned channel the way ListStream does,
+// list must call fn in name order for files matching the filter options and stop when fn return an error
+func StreamList(ctx context.Context, list func(fn func(ObjectInfo) error) error, opts ...ListOption) <-chan ListItem {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ch := make(chan ListItem, listStreamBuffer)
+	go func() {
+		defer close(ch)
+
+		n := 0
+		err := list(func(info ObjectInfo) error {
+			select {
+			case ch <- ListItem{Info: info}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			n++
+			if o.max > 0 && n >= o.max {
+				return errStopList
+			}
+			return nil
+		})
+		if err != nil && err != errStopList {
+			select {
+			case ch <- ListItem{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
+
+// ApplyListOptions filter, sort and limit files the way List does,
+// for IFile implementations holding their listing in memory
+func ApplyListOptions(files []ObjectInfo, opts ...ListOption) []ObjectInfo {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sorted := append([]ObjectInfo(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	col := newListCollector(opts)
+	for _, info := range sorted {
+		if !o.match(info.Name) {
+			continue
+		}
+		if col.add(info) == errStopList {
+			break
+		}
+	}
+
+	return col.result()
+}
+
+// listCollector keep the files List return, fed in ascending name order
+type listCollector struct {
+	o    listOptions
+	less func(a, b ObjectInfo) bool
+	h    *objectHeap
+}
+
+func newListCollector(opts []ListOption) *listCollector {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	less := o.less()
+	return &listCollector{o: o, less: less, h: &objectHeap{less: less}}
+}
+
+func (col *listCollector) add(info ObjectInfo) error {
+	h := col.h
+	if col.o.max <= 0 {
+		h.items = append(h.items, info)
+		return nil
+	}
+
+	// storage return names in ascending order, nothing after the n-th file can rank higher
+	if col.o.sortBy == SortByName && !col.o.desc {
+		h.items = append(h.items, info)
+		if len(h.items) >= col.o.max {
+			return errStopList
+		}
+		return nil
+	}
+
+	// keep the n best files, heap root is the worst of them
+	if len(h.items) < col.o.max {
+		heap.Push(h, info)
+	} else if col.less(info, h.items[0]) {
+		h.items[0] = info
+		heap.Fix(h, 0)
+	}
+	return nil
+}
+
+func (col *listCollector) result() []ObjectInfo {
+	list := col.h.items
+	sort.SliceStable(list, func(i, j int) bool { return col.less(list[i], list[j]) })
+	return list
+}
+
+func (o *listOptions) less() func(a, b ObjectInfo) bool {
+	var less func(a, b ObjectInfo) bool
+	switch o.sortBy {
+	case SortBySize:
+		less = func(a, b ObjectInfo) bool {
+			if a.Size == b.Size {
+				return a.Name < b.Name
+			}
+			return a.Size < b.Size
+		}
+	case SortByLastModified:
+		less = func(a, b ObjectInfo) bool {
+			if a.LastModified.Equal(b.LastModified) {
+				return a.Name < b.Name
+			}
+			return a.LastModified.Before(b.LastModified)
+		}
+	default:
+		less = func(a, b ObjectInfo) bool { return a.Name < b.Name }
+	}
+
+	if o.desc {
+		return func(a, b ObjectInfo) bool { return less(b, a) }
+	}
+	return less
+}
+
+// objectHeap is a max heap by less, so the root is the file to evict first
+type objectHeap struct {
+	items []ObjectInfo
+	less  func(a, b ObjectInfo) bool
+}
+
+func (h *objectHeap) Len() int           { return len(h.items) }
+func (h *objectHeap) Less(i, j int) bool { return h.less(h.items[j], h.items[i]) }
+func (h *objectHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *objectHeap) Push(x interface{}) { h.items = append(h.items, x.(ObjectInfo)) }
+func (h *objectHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// list call fn for every file under prefix matching options, stopping at first error
+func (c *File) list(ctx context.Context, prefix string, opts []ListOption, fn func(ObjectInfo) error) error {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	prefix = c.normalizedKey(prefix)
+	if o.startAfter != "" {
+		o.startAfter = c.normalizedKey(o.startAfter)
+	}
+	if o.startAfter > prefix && !strings.HasPrefix(o.startAfter, prefix) {
+		// every name under prefix sort before startAfter
+		return nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return err
+	}
+
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		listBlob, err := containerURL.ListBlobsFlatSegment(ctx, marker, listSegmentOptions(prefix))
+		if err != nil {
+			return err
+		}
+		marker = listBlob.NextMarker
+
+		for _, blobInfo := range listBlob.Segment.BlobItems {
+			if !o.match(blobInfo.Name) {
+				continue
+			}
+			if err := fn(objectInfo(blobInfo)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
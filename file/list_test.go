@@ -0,0 +1,57 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const listResponse = `<?xml version="1.0" encoding="utf-8"?>
+<EnumerationResults ContainerName="container"><Prefix>docs/</Prefix><Blobs>
+<Blob><Name>docs/a.txt</Name><Properties><Content-Length>1</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>
+<Blob><Name>docs/b.txt</Name><Properties><Content-Length>1</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>
+<Blob><Name>docs/c.txt</Name><Properties><Content-Length>1</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>
+</Blobs><NextMarker /></EnumerationResults>`
+
+func TestListStartAfter(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(listResponse))
+	}))
+	defer srv.Close()
+	c := NewPublic("account", srv.URL+"/%s/%s", "container")
+
+	tests := []struct {
+		after    string
+		want     []string
+		requests int32
+	}{
+		{"", []string{"docs/a.txt", "docs/b.txt", "docs/c.txt"}, 1},
+		{"docs/a.txt", []string{"docs/b.txt", "docs/c.txt"}, 1},
+		{"a", []string{"docs/a.txt", "docs/b.txt", "docs/c.txt"}, 1},
+		// after every name under docs/, nothing is listed
+		{"e", nil, 0},
+		{"docs0", nil, 0},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt32(&requests, 0)
+		files, err := c.List(context.Background(), "docs/", WithStartAfter(tt.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		if len(names) != len(tt.want) || (len(names) > 0 && names[0] != tt.want[0]) {
+			t.Errorf("List after %q = %v, want %v", tt.after, names, tt.want)
+		}
+		if n := atomic.LoadInt32(&requests); n != tt.requests {
+			t.Errorf("List after %q sent %d requests, want %d", tt.after, n, tt.requests)
+		}
+	}
+}
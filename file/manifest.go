@@ -0,0 +1,173 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultManifestPrefix is where ManifestExporter write manifests by default
+const DefaultManifestPrefix = "system/manifests/"
+
+// ManifestEntry is one asset of a manifest
+type ManifestEntry struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	// Status is the catalog record status, empty for listings
+	Status RecordStatus `json:"status,omitempty"`
+	URL    string       `json:"url,omitempty"`
+}
+
+// ManifestEncoder write the entries of a manifest
+type ManifestEncoder interface {
+	Encode(e ManifestEntry) error
+	// Close finish the manifest, without closing the underlying writer
+	Close() error
+}
+
+// ManifestFormat is a manifest file format. NDJSONManifest is built in, Parquet is written by a format whose
+// encoder is built on a parquet library, e.g. with the fields of ManifestEntry as columns.
+type ManifestFormat struct {
+	Ext         string
+	ContentType string
+	NewEncoder  func(w io.Writer) ManifestEncoder
+}
+
+// NDJSONManifest write one JSON object per line
+var NDJSONManifest = ManifestFormat{
+	Ext:         ".ndjson",
+	ContentType: "application/x-ndjson",
+	NewEncoder: func(w io.Writer) ManifestEncoder {
+		return ndjsonEncoder{json.NewEncoder(w)}
+	},
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (n ndjsonEncoder) Encode(e ManifestEntry) error {
+	return n.enc.Encode(e)
+}
+
+func (n ndjsonEncoder) Close() error {
+	return nil
+}
+
+// CatalogWalker is implemented by catalogs able to enumerate their records, e.g. MemoryCatalog
+type CatalogWalker interface {
+	// Walk call fn with every record, stopping at the first error
+	Walk(ctx context.Context, fn func(rec CatalogRecord) error) error
+}
+
+// ManifestReport describe an exported manifest
+type ManifestReport struct {
+	Key     string
+	Entries int
+}
+
+// ManifestExporter write the catalog or the listing of a prefix as a manifest file, so the data warehouse
+// load it to analyse asset growth. Manifests are keyed <Prefix>dt=<yyyy-mm-dd>/manifest-<hhmmss><ext> in UTC,
+// partitioned by date the way warehouse external tables expect. A failed export store nothing.
+type ManifestExporter struct {
+	// Source is listed under SourcePrefix when Catalog is nil
+	Source       IFile
+	SourcePrefix string
+	// Catalog is exported instead of the listing when set
+	Catalog CatalogWalker
+	// Dest store the manifests
+	Dest IFile
+	// Prefix of the manifest keys, DefaultManifestPrefix when empty
+	Prefix string
+	// Format of the manifests, NDJSONManifest when zero
+	Format ManifestFormat
+}
+
+// NewManifestExporter create exporter writing the listing of prefix of source as NDJSON manifests into dest
+//
+//	Example:
+//	exporter := file.NewManifestExporter(store, "products/", store)
+//	go exporter.Run(ctx, 24*time.Hour, func(err error) { log.Println("manifest export:", err) })
+func NewManifestExporter(source IFile, prefix string, dest IFile) *ManifestExporter {
+	return &ManifestExporter{Source: source, SourcePrefix: prefix, Dest: dest}
+}
+
+// Export write a manifest now
+func (m *ManifestExporter) Export(ctx context.Context) (ManifestReport, error) {
+	format := m.Format
+	if format.NewEncoder == nil {
+		format = NDJSONManifest
+	}
+	prefix := m.Prefix
+	if prefix == "" {
+		prefix = DefaultManifestPrefix
+	}
+	now := time.Now().UTC()
+	report := ManifestReport{Key: fmt.Sprintf("%sdt=%s/manifest-%s%s", prefix, now.Format("2006-01-02"), now.Format("150405"), format.Ext)}
+
+	// cancelled on failure so the partial manifest is not committed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w, err := newObjectWriter(ctx, m.Dest, report.Key, format.ContentType)
+	if err != nil {
+		return report, err
+	}
+	enc := format.NewEncoder(w)
+	encode := func(e ManifestEntry) error {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		report.Entries++
+		return nil
+	}
+
+	if m.Catalog != nil {
+		err = m.Catalog.Walk(ctx, func(rec CatalogRecord) error {
+			return encode(ManifestEntry{Key: rec.Key, Size: rec.Size, ContentType: rec.ContentType, ETag: rec.ETag,
+				LastModified: rec.UpdatedAt, Status: rec.Status, URL: rec.URL})
+		})
+	} else {
+		for item := range m.Source.ListStream(ctx, m.SourcePrefix) {
+			if item.Err != nil {
+				err = item.Err
+				break
+			}
+			info := item.Info
+			if err = encode(ManifestEntry{Key: info.Name, Size: info.Size, ContentType: info.ContentType, ETag: info.ETag,
+				LastModified: info.LastModified}); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		cancel()
+		w.Close()
+		return report, err
+	}
+	return report, w.Close()
+}
+
+// Run export a manifest every interval until ctx is done, onError is called with failed exports
+func (m *ManifestExporter) Run(ctx context.Context, interval time.Duration, onError func(err error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Export(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
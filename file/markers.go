@@ -0,0 +1,202 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// MarkerPolicy decide what happen to directory marker keys, zero byte files whose name end with Delimiter
+type MarkerPolicy int
+
+const (
+	// MarkersAllow store markers uploaded by the application and create none
+	MarkersAllow MarkerPolicy = iota
+	// MarkersReject fail marker uploads with ErrDirectoryMarker
+	MarkersReject
+	// MarkersCreate store a marker for every parent directory of uploaded files,
+	// so directories stay listed once their files are deleted, like S3 console folders
+	MarkersCreate
+)
+
+var (
+	// ErrDirectoryMarker returned for uploads of marker keys rejected by MarkersReject
+	ErrDirectoryMarker = errors.New("file: directory markers not allowed")
+	// ErrInvalidMarker returned for uploads of marker keys with content
+	ErrInvalidMarker = errors.New("file: directory marker must be empty")
+	// ErrEmptyFile returned for zero byte uploads when RejectEmpty is set
+	ErrEmptyFile = errors.New("file: empty file")
+	// ErrDirectoryNotEmpty returned when deleting the marker of a directory still holding files
+	ErrDirectoryNotEmpty = errors.New("file: directory not empty")
+)
+
+// IsMarker report whether key is a directory marker key
+func IsMarker(key string) bool {
+	return strings.HasSuffix(key, Delimiter)
+}
+
+// Directories wrap an IFile applying one policy to empty files and directory markers whatever the provider.
+// Markers never appear in List, ListStream and GetListBlob, ListDir show marked directories as Dirs,
+// and a marker can only be deleted once its directory is empty.
+type Directories struct {
+	IFile
+	Markers MarkerPolicy
+	// RejectEmpty fail zero byte uploads of files with ErrEmptyFile
+	RejectEmpty bool
+
+	created sync.Map
+}
+
+// NewDirectories wrap next with the marker policy
+//
+//	Example:
+//	store := file.NewDirectories(store, file.MarkersCreate)
+//	store.RejectEmpty = true
+func NewDirectories(next IFile, markers MarkerPolicy) *Directories {
+	return &Directories{IFile: next, Markers: markers}
+}
+
+// Unwrap return the wrapped store
+func (d *Directories) Unwrap() IFile {
+	return d.IFile
+}
+
+// Upload file after checking the policy, creating the markers of its parent directories with MarkersCreate
+func (d *Directories) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	if IsMarker(filePath) {
+		if d.Markers == MarkersReject {
+			return "", ErrDirectoryMarker
+		}
+		if len(buffBytes) > 0 {
+			return "", ErrInvalidMarker
+		}
+	} else if len(buffBytes) == 0 && d.RejectEmpty {
+		return "", ErrEmptyFile
+	}
+
+	url, err := d.IFile.Upload(ctx, filePath, contentType, buffBytes)
+	if err != nil || d.Markers != MarkersCreate {
+		return url, err
+	}
+	return url, d.createMarkers(ctx, filePath)
+}
+
+// Copy file, creating the markers of the destination parent directories with MarkersCreate
+func (d *Directories) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	if IsMarker(dstPath) && d.Markers == MarkersReject {
+		return "", ErrDirectoryMarker
+	}
+	url, err := d.IFile.Copy(ctx, srcPath, dstPath)
+	if err != nil || d.Markers != MarkersCreate {
+		return url, err
+	}
+	return url, d.createMarkers(ctx, dstPath)
+}
+
+// createMarkers upload an empty marker for every parent directory of key not marked yet
+func (d *Directories) createMarkers(ctx context.Context, key string) error {
+	parts := strings.Split(strings.TrimSuffix(key, Delimiter), Delimiter)
+	dir := ""
+	for _, p := range parts[:len(parts)-1] {
+		dir += p + Delimiter
+		if _, ok := d.created.Load(dir); ok {
+			continue
+		}
+		if _, err := d.IFile.Upload(ctx, dir, "application/x-directory", []byte{}); err != nil {
+			return err
+		}
+		d.created.Store(dir, true)
+	}
+	return nil
+}
+
+// Delete file, or the marker of an empty directory
+func (d *Directories) Delete(ctx context.Context, filePath string) (string, error) {
+	if IsMarker(filePath) {
+		files, err := d.IFile.List(ctx, filePath, WithMaxResults(2))
+		if err != nil {
+			return "", err
+		}
+		for _, f := range files {
+			if f.Name != filePath {
+				return "", ErrDirectoryNotEmpty
+			}
+		}
+		d.created.Delete(filePath)
+	}
+	return d.IFile.Delete(ctx, filePath)
+}
+
+// List files under prefix without markers
+func (d *Directories) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	files, err := d.IFile.List(ctx, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	kept := files[:0]
+	for _, f := range files {
+		if !IsMarker(f.Name) {
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+// ListStream stream files under prefix without markers
+func (d *Directories) ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem {
+	items := d.IFile.ListStream(ctx, prefix, opts...)
+	out := make(chan ListItem)
+	go func() {
+		defer close(out)
+		for item := range items {
+			if item.Err == nil && IsMarker(item.Info.Name) {
+				continue
+			}
+			out <- item
+		}
+	}()
+	return out
+}
+
+// GetListBlob return file names under prefix without markers
+func (d *Directories) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	names, err := d.IFile.GetListBlob(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	kept := names[:0]
+	for _, name := range names {
+		if !IsMarker(name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept, nil
+}
+
+// ListDir list prefix, its own marker is not a file and marked directories are Dirs
+func (d *Directories) ListDir(ctx context.Context, prefix string) (DirList, error) {
+	list, err := d.IFile.ListDir(ctx, prefix)
+	if err != nil {
+		return list, err
+	}
+	files := list.Files[:0]
+	for _, f := range list.Files {
+		if !IsMarker(f.Name) {
+			files = append(files, f)
+		} else if f.Name != prefix && !containsString(list.Dirs, f.Name) {
+			list.Dirs = append(list.Dirs, f.Name)
+		}
+	}
+	list.Files = files
+	return list, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
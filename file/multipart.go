@@ -0,0 +1,98 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// MultipartUploader is implemented by stores uploading a file as parts sent separately, possibly by
+// different processes, and committed at once, e.g. *File with block blobs. Parts are numbered from 0,
+// the file is not visible until CompleteUpload. Uncommitted parts are discarded by the store after a while.
+type MultipartUploader interface {
+	// UploadPart store part n of the upload uploadID of filePath, replacing a part n sent before
+	UploadPart(ctx context.Context, filePath, uploadID string, n int, data []byte) error
+	// CompleteUpload commit parts 0 to parts-1 as filePath
+	CompleteUpload(ctx context.Context, filePath, uploadID, contentType string, parts int) (string, error)
+}
+
+// blockID return the block id of part n of an upload, every block id of a blob must have the same length
+func blockID(uploadID string, n int) string {
+	sum := sha256.Sum256([]byte(uploadID))
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%06d", hex.EncodeToString(sum[:8]), n)))
+}
+
+// UploadPart stage part n of filePath as an uncommitted block, storage discard uncommitted blocks after 7 days
+//
+//	Example:
+//	err := file.UploadPart(ctx, "video/intro.mp4", uploadID, 0, chunk)
+//	url, err := file.CompleteUpload(ctx, "video/intro.mp4", uploadID, "video/mp4", 1)
+func (c *File) UploadPart(ctx context.Context, filePath, uploadID string, n int, data []byte) error {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return err
+	}
+	if c.Anonymous {
+		return ErrReadOnly
+	}
+	if n < 0 || n >= azblob.BlockBlobMaxBlocks {
+		return fmt.Errorf("file: part %d out of range", n)
+	}
+	if int64(len(data)) > azblob.BlockBlobMaxStageBlockBytes {
+		return ErrTooLarge
+	}
+	if c.DryRun {
+		return nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return err
+	}
+	_, err = containerURL.NewBlockBlobURL(filePath).StageBlock(ctx, blockID(uploadID, n), bytes.NewReader(data), azblob.LeaseAccessConditions{}, nil)
+	return err
+}
+
+// CompleteUpload commit the parts staged by UploadPart as filePath, with the metadata of ctx
+func (c *File) CompleteUpload(ctx context.Context, filePath, uploadID, contentType string, parts int) (string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return "", err
+	}
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+	if parts < 0 {
+		return "", fmt.Errorf("file: %d parts", parts)
+	}
+	if parts > azblob.BlockBlobMaxBlocks {
+		return "", ErrTooLarge
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventCreated, Key: filePath, ContentType: contentType})
+		return c.GetBlobURL(filePath, false), nil
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", err
+	}
+	ids := make([]string, parts)
+	for i := range ids {
+		ids[i] = blockID(uploadID, i)
+	}
+	_, err = containerURL.NewBlockBlobURL(filePath).CommitBlockList(ctx, ids, azblob.BlobHTTPHeaders{ContentType: contentType},
+		contextMetadata(ctx), azblob.BlobAccessConditions{})
+	if err != nil {
+		return "", err
+	}
+	return c.GetBlobURL(filePath, false), nil
+}
@@ -0,0 +1,27 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompleteUploadNegativeParts(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12").(*File)
+	if _, err := c.CompleteUpload(context.Background(), "video/intro.mp4", "upload-1", "video/mp4", -1); err == nil {
+		t.Error("CompleteUpload of -1 parts succeeded")
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent for -1 parts", requests)
+	}
+}
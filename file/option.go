@@ -0,0 +1,149 @@
+package file
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// Option configure File created by New
+type Option func(*File)
+
+// WithHTTPClient send storage requests through client instead of the default azure http client,
+// e.g. a client whose transport record and replay requests in tests
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *File) {
+		c.HTTPClient = client
+	}
+}
+
+// WithTransfer split uploads and downloads into blocks of blockSize bytes,
+// transferring up to parallelism blocks at once
+func WithTransfer(blockSize int64, parallelism uint16) Option {
+	return func(c *File) {
+		c.BlockSize = blockSize
+		c.Parallelism = parallelism
+	}
+}
+
+// WithTempDir set the directory DownloadToTemp create files in
+func WithTempDir(dir string) Option {
+	return func(c *File) {
+		c.TempDir = dir
+	}
+}
+
+// WithMaxUploadSize reject Upload and UploadStream content larger than n bytes with ErrTooLarge,
+// streams are aborted as soon as they exceed it
+func WithMaxUploadSize(n int64) Option {
+	return func(c *File) {
+		c.MaxUploadSize = n
+	}
+}
+
+// WithDecompression decode downloads according to their Content-Encoding with the codecs of RegisterCodec,
+// use WithRawContent to get the stored bytes
+func WithDecompression() Option {
+	return func(c *File) {
+		c.Decompress = true
+	}
+}
+
+// WithCompression compress uploads with the codec of encoding, e.g. "gzip" or a "zstd" codec registered with RegisterCodec,
+// and decode downloads like WithDecompression. Upload fail with ErrUnsupportedEncoding when no codec encode it.
+func WithCompression(encoding string) Option {
+	return func(c *File) {
+		c.Compression = strings.ToLower(encoding)
+		c.Decompress = true
+	}
+}
+
+// WithKeyNormalization rewrite every key with normalize before upload, download, copy, delete and url generation,
+// e.g. norm.NFC.String of golang.org/x/text/unicode/norm so "é" typed on macOS and on Windows is the same file.
+// Keys are not normalized without it, the SDK doesn't depend on golang.org/x/text. List prefixes are normalized too.
+func WithKeyNormalization(normalize func(string) string) Option {
+	return func(c *File) {
+		c.NormalizeKey = normalize
+	}
+}
+
+// ServerlessTransport is the small connection pool used by WithServerless
+var ServerlessTransport = TransportConfig{
+	DialTimeout:         5 * time.Second,
+	IdleConnTimeout:     30 * time.Second,
+	MaxIdleConns:        4,
+	MaxIdleConnsPerHost: 4,
+	MaxConnsPerHost:     16,
+}
+
+// WithServerless tune the client for short lived function instances: a small connection pool
+// with short timeouts unless WithHTTPClient is given, and no warm-up. Clients are already created lazily
+// per request and no goroutine outlive a call, except ListStream until its channel is drained.
+func WithServerless() Option {
+	return func(c *File) {
+		c.WarmUp = false
+		if c.HTTPClient == nil {
+			if client, err := NewHTTPClient(ServerlessTransport); err == nil {
+				c.HTTPClient = client
+			}
+		}
+	}
+}
+
+// WithDryRun skip mutating operations, reporting each one as an event to fn.
+// Events are logged with the standard logger when fn is nil.
+//
+//	Example:
+//	f := file.New(account, accessKey, rootURL, containerName, apiVersion, file.WithDryRun(nil))
+func WithDryRun(fn func(Event)) Option {
+	return func(c *File) {
+		c.DryRun = true
+		c.OnDryRun = fn
+	}
+}
+
+func (c *File) dryRun(ctx context.Context, e Event) {
+	e.DryRun = true
+	e.Container = c.ContainerName
+	e.URL = c.GetBlobURL(e.Key, false)
+	e.Time = time.Now()
+	e.Actor = ActorFromContext(ctx)
+	e.Purpose = PurposeFromContext(ctx)
+
+	if c.OnDryRun != nil {
+		c.OnDryRun(e)
+		return
+	}
+
+	if e.Source != "" {
+		log.Printf("file: dry run copy %s to %s", e.Source, e.Key)
+		return
+	}
+	log.Printf("file: dry run %s %s %s %d bytes", e.Type, e.Key, e.ContentType, e.Size)
+}
+
+// pipelineOptions return azure pipeline options honouring File options
+func (c *File) pipelineOptions() azblob.PipelineOptions {
+	o := azblob.PipelineOptions{}
+	if c.HTTPClient != nil {
+		o.HTTPSender = httpSender(c.HTTPClient)
+	}
+	return o
+}
+
+func httpSender(client *http.Client) pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			resp, err := client.Do(request.WithContext(ctx))
+			if err != nil {
+				err = pipeline.NewError(err, "HTTP request failed")
+			}
+			return pipeline.NewHTTPResponse(resp), err
+		}
+	})
+}
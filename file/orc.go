@@ -0,0 +1,890 @@
+package file
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+)
+
+// errORCCorrupt returned for ORC files whose metadata or streams can't be decoded
+var errORCCorrupt = errors.New("file: corrupt orc file")
+
+// maxORCStream is the largest footer, stripe footer or stream read in memory
+const maxORCStream = 1 << 30
+
+// orcEpoch is the time ORC timestamp seconds are counted from, in the writer timezone
+var orcEpoch = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ORC type kinds
+const (
+	orcBoolean   = 0
+	orcByte      = 1
+	orcShort     = 2
+	orcInt       = 3
+	orcLong      = 4
+	orcFloat     = 5
+	orcDouble    = 6
+	orcString    = 7
+	orcBinary    = 8
+	orcTimestamp = 9
+	orcStruct    = 12
+	orcDate      = 15
+	orcVarchar   = 16
+	orcChar      = 17
+	// orcTimestampInstant is a timestamp in UTC whatever the writer timezone
+	orcTimestampInstant = 18
+)
+
+// ORC stream kinds
+const (
+	orcPresent        = 0
+	orcData           = 1
+	orcLength         = 2
+	orcDictionaryData = 3
+	orcSecondary      = 5
+)
+
+// orcFile is an ORC file opened from its postscript and footer
+type orcFile struct {
+	r           io.ReaderAt
+	compression uint64
+}
+
+// readORC call fn with every row of an ORC file whose root is a struct of primitive columns, see columnarReader
+func readORC(ctx context.Context, r io.ReaderAt, size int64, fn func(row map[string]string) error) error {
+	if size < 4 {
+		return errORCCorrupt
+	}
+	last := make([]byte, 1)
+	if err := readFullAt(r, last, size-1); err != nil {
+		return err
+	}
+	psLen := int64(last[0])
+	if psLen+1 > size {
+		return errORCCorrupt
+	}
+	psBytes := make([]byte, psLen)
+	if err := readFullAt(r, psBytes, size-1-psLen); err != nil {
+		return err
+	}
+	ps, err := parseProto(psBytes)
+	if err != nil {
+		return err
+	}
+	if string(ps.bytes(8000)) != "ORC" {
+		return errORCCorrupt
+	}
+
+	o := &orcFile{r: r, compression: ps.uint(2)}
+	switch o.compression {
+	case 0, 1, 2:
+	default:
+		return fmt.Errorf("%w: orc compression %d", ErrUnsupportedInventoryFormat, o.compression)
+	}
+
+	footerLen := ps.uint(1)
+	if footerLen > uint64(size-1-psLen) {
+		return errORCCorrupt
+	}
+	footer, err := o.readMessage(size-1-psLen-int64(footerLen), footerLen)
+	if err != nil {
+		return err
+	}
+
+	var types []protoMessage
+	for _, b := range footer.repeated(4) {
+		t, err := parseProto(b)
+		if err != nil {
+			return err
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 || types[0].uint(1) != orcStruct {
+		return fmt.Errorf("%w: orc root is not a struct", ErrUnsupportedInventoryFormat)
+	}
+
+	// column ids of the root fields decoded
+	columns := map[string]int{}
+	ids, names := types[0].packed(2), types[0].repeated(3)
+	if len(ids) != len(names) {
+		return errORCCorrupt
+	}
+	for i, name := range names {
+		if !inventoryColumn(string(name)) {
+			continue
+		}
+		if ids[i] >= uint64(len(types)) {
+			return errORCCorrupt
+		}
+		columns[string(name)] = int(ids[i])
+	}
+
+	for _, b := range footer.repeated(3) {
+		stripe, err := parseProto(b)
+		if err != nil {
+			return err
+		}
+		rows := stripe.uint(5)
+		if rows > maxORCStream {
+			return errORCCorrupt
+		}
+
+		values := map[string][]string{}
+		if len(columns) > 0 {
+			streams, err := o.readStripe(stripe, size)
+			if err != nil {
+				return err
+			}
+			for name, id := range columns {
+				if values[name], err = streams.column(id, types[id].uint(1), int(rows)); err != nil {
+					return fmt.Errorf("column %s: %w", name, err)
+				}
+			}
+		}
+
+		for i := 0; i < int(rows); i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			row := make(map[string]string, len(values))
+			for name, v := range values {
+				row[name] = v[i]
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// orcStreams are the streams of a stripe by column and kind
+type orcStreams struct {
+	o         *orcFile
+	offsets   map[[2]uint64][2]int64
+	encodings []protoMessage
+	location  *time.Location
+}
+
+// readStripe read the stripe footer locating the stripe streams
+func (o *orcFile) readStripe(stripe protoMessage, size int64) (*orcStreams, error) {
+	offset, index, data, footerLen := stripe.uint(1), stripe.uint(2), stripe.uint(3), stripe.uint(4)
+	end := offset + index + data
+	if end < offset || end > uint64(size) || footerLen > uint64(size)-end {
+		return nil, errORCCorrupt
+	}
+	footer, err := o.readMessage(int64(end), footerLen)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &orcStreams{o: o, offsets: map[[2]uint64][2]int64{}, location: time.UTC}
+	// streams follow each other from the stripe start, index streams first
+	pos := offset
+	for _, b := range footer.repeated(1) {
+		stream, err := parseProto(b)
+		if err != nil {
+			return nil, err
+		}
+		length := stream.uint(3)
+		if pos+length < pos || pos+length > end {
+			return nil, errORCCorrupt
+		}
+		s.offsets[[2]uint64{stream.uint(2), stream.uint(1)}] = [2]int64{int64(pos), int64(length)}
+		pos += length
+	}
+	for _, b := range footer.repeated(2) {
+		encoding, err := parseProto(b)
+		if err != nil {
+			return nil, err
+		}
+		s.encodings = append(s.encodings, encoding)
+	}
+	if tz := string(footer.bytes(3)); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			s.location = loc
+		}
+	}
+	return s, nil
+}
+
+// stream return the decompressed stream of column, nil if the stripe has none
+func (s *orcStreams) stream(column int, kind uint64) ([]byte, error) {
+	at, ok := s.offsets[[2]uint64{uint64(column), kind}]
+	if !ok {
+		return nil, nil
+	}
+	return s.o.read(at[0], uint64(at[1]))
+}
+
+// column decode the rows values of column, empty for nulls
+func (s *orcStreams) column(column int, kind uint64, rows int) ([]string, error) {
+	var encoding uint64
+	if column < len(s.encodings) {
+		encoding = s.encodings[column].uint(1)
+	}
+	// DIRECT_V2 and DICTIONARY_V2 use integer run length encoding v2
+	v2 := encoding == 2 || encoding == 3
+	dictionary := encoding == 1 || encoding == 3
+
+	present := make([]bool, rows)
+	defined := rows
+	if b, err := s.stream(column, orcPresent); err != nil {
+		return nil, err
+	} else if b != nil {
+		if present, err = orcBooleans(b, rows); err != nil {
+			return nil, err
+		}
+		defined = 0
+		for _, p := range present {
+			if p {
+				defined++
+			}
+		}
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+
+	data, err := s.stream(column, orcData)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	switch kind {
+	case orcBoolean:
+		bools, err := orcBooleans(data, defined)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bools {
+			values = append(values, strconv.FormatBool(b))
+		}
+	case orcByte:
+		b, err := orcByteRLE(data, defined)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range b {
+			values = append(values, strconv.Itoa(int(int8(v))))
+		}
+	case orcShort, orcInt, orcLong, orcDate:
+		ints, err := orcIntegers(data, true, v2, defined)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range ints {
+			if kind == orcDate {
+				values = append(values, time.Unix(v*86400, 0).UTC().Format("2006-01-02"))
+			} else {
+				values = append(values, strconv.FormatInt(v, 10))
+			}
+		}
+	case orcFloat, orcDouble:
+		width := 8
+		if kind == orcFloat {
+			width = 4
+		}
+		if len(data) < defined*width {
+			return nil, errORCCorrupt
+		}
+		for i := 0; i < defined; i++ {
+			if kind == orcFloat {
+				f := math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+				values = append(values, strconv.FormatFloat(float64(f), 'g', -1, 32))
+			} else {
+				f := math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+				values = append(values, strconv.FormatFloat(f, 'g', -1, 64))
+			}
+		}
+	case orcString, orcBinary, orcVarchar, orcChar:
+		if values, err = s.strings(column, data, v2, dictionary, defined); err != nil {
+			return nil, err
+		}
+	case orcTimestamp, orcTimestampInstant:
+		seconds, err := orcIntegers(data, true, v2, defined)
+		if err != nil {
+			return nil, err
+		}
+		secondary, err := s.stream(column, orcSecondary)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := orcIntegers(secondary, false, v2, defined)
+		if err != nil {
+			return nil, err
+		}
+		epoch := orcEpoch
+		if kind == orcTimestamp {
+			epoch = time.Date(2015, 1, 1, 0, 0, 0, 0, s.location)
+		}
+		for i, sec := range seconds {
+			values = append(values, epoch.Add(time.Duration(sec)*time.Second+time.Duration(orcNanos(nanos[i]))).UTC().Format(time.RFC3339Nano))
+		}
+	default:
+		return nil, fmt.Errorf("%w: orc type %d", ErrUnsupportedInventoryFormat, kind)
+	}
+
+	if len(values) != defined {
+		return nil, errORCCorrupt
+	}
+	out := make([]string, rows)
+	for i, p := range present {
+		if p {
+			out[i], values = values[0], values[1:]
+		}
+	}
+	return out, nil
+}
+
+// strings decode n values of a string column, directly stored or as indexes of the stripe dictionary
+func (s *orcStreams) strings(column int, data []byte, v2, dictionary bool, n int) ([]string, error) {
+	lengthData, err := s.stream(column, orcLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dictionary {
+		lengths, err := orcIntegers(lengthData, false, v2, n)
+		if err != nil {
+			return nil, err
+		}
+		return orcSplit(data, lengths)
+	}
+
+	size := s.encodings[column].uint(2)
+	if size > maxORCStream {
+		return nil, errORCCorrupt
+	}
+	lengths, err := orcIntegers(lengthData, false, v2, int(size))
+	if err != nil {
+		return nil, err
+	}
+	dictData, err := s.stream(column, orcDictionaryData)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := orcSplit(dictData, lengths)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := orcIntegers(data, false, v2, n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, n)
+	for i, index := range indexes {
+		if index < 0 || index >= int64(len(dict)) {
+			return nil, errORCCorrupt
+		}
+		values[i] = dict[index]
+	}
+	return values, nil
+}
+
+// orcSplit cut data in strings of the given lengths
+func orcSplit(data []byte, lengths []int64) ([]string, error) {
+	values := make([]string, len(lengths))
+	for i, l := range lengths {
+		if l < 0 || l > int64(len(data)) {
+			return nil, errORCCorrupt
+		}
+		values[i], data = string(data[:l]), data[l:]
+	}
+	return values, nil
+}
+
+// orcNanos decode the nanoseconds of a timestamp, the low 3 bits are the count of trailing decimal zeros removed less one
+func orcNanos(v int64) int64 {
+	zeros := v & 7
+	v >>= 3
+	if zeros != 0 {
+		for i := int64(0); i <= zeros; i++ {
+			v *= 10
+		}
+	}
+	return v
+}
+
+// readMessage read and decompress the protobuf message at offset
+func (o *orcFile) readMessage(offset int64, length uint64) (protoMessage, error) {
+	b, err := o.read(offset, length)
+	if err != nil {
+		return protoMessage{}, err
+	}
+	return parseProto(b)
+}
+
+// read read and decompress length bytes at offset
+func (o *orcFile) read(offset int64, length uint64) ([]byte, error) {
+	if length > maxORCStream {
+		return nil, errORCCorrupt
+	}
+	b := make([]byte, length)
+	if err := readFullAt(o.r, b, offset); err != nil {
+		return nil, err
+	}
+	return o.decompress(b)
+}
+
+// decompress the chunks of a compressed ORC stream, each behind a 3 bytes header holding its length and
+// whether it is stored uncompressed
+func (o *orcFile) decompress(b []byte) ([]byte, error) {
+	if o.compression == 0 {
+		return b, nil
+	}
+	var out []byte
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, errORCCorrupt
+		}
+		header := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		original, length := header&1 == 1, header>>1
+		b = b[3:]
+		if length > len(b) {
+			return nil, errORCCorrupt
+		}
+		chunk := b[:length]
+		b = b[length:]
+
+		switch {
+		case original:
+			out = append(out, chunk...)
+		case o.compression == 1:
+			// zlib streams are raw deflate without header
+			inflated, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(chunk)), maxORCStream))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inflated...)
+		case o.compression == 2:
+			decoded, err := snappyDecode(chunk)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, decoded...)
+		}
+		if len(out) > maxORCStream {
+			return nil, errORCCorrupt
+		}
+	}
+	return out, nil
+}
+
+// orcByteRLE decode n bytes of a byte run length encoded stream
+func orcByteRLE(b []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if len(b) < 2 {
+			return nil, errORCCorrupt
+		}
+		control := int8(b[0])
+		if control >= 0 {
+			// run of control+3 copies
+			for i := 0; i < int(control)+3; i++ {
+				out = append(out, b[1])
+			}
+			b = b[2:]
+			continue
+		}
+		literals := -int(control)
+		if len(b) < 1+literals {
+			return nil, errORCCorrupt
+		}
+		out = append(out, b[1:1+literals]...)
+		b = b[1+literals:]
+	}
+	return out[:n], nil
+}
+
+// orcBooleans decode n booleans stored as a byte run length encoded bitmap, most significant bit first
+func orcBooleans(b []byte, n int) ([]bool, error) {
+	bits, err := orcByteRLE(b, (n+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = bits[i/8]>>(7-uint(i)%8)&1 == 1
+	}
+	return out, nil
+}
+
+// orcIntegers decode n integers of a run length encoded stream, version 2 when v2 is set.
+// Signed streams are zigzag encoded.
+func orcIntegers(b []byte, signed, v2 bool, n int) ([]int64, error) {
+	d := &orcIntDecoder{b: b, signed: signed}
+	out := make([]int64, 0, n)
+	for len(out) < n {
+		var err error
+		if v2 {
+			out, err = d.nextV2(out)
+		} else {
+			out, err = d.nextV1(out)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > n {
+			return nil, errORCCorrupt
+		}
+	}
+	return out, nil
+}
+
+type orcIntDecoder struct {
+	b      []byte
+	signed bool
+}
+
+func (d *orcIntDecoder) byte() (byte, error) {
+	if len(d.b) == 0 {
+		return 0, errORCCorrupt
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c, nil
+}
+
+// varint read a base 128 varint, zigzag decoded for signed streams when zigzag is set
+func (d *orcIntDecoder) varint(zigzag bool) (int64, error) {
+	v, l := binary.Uvarint(d.b)
+	if l <= 0 {
+		return 0, errORCCorrupt
+	}
+	d.b = d.b[l:]
+	if zigzag {
+		return unzigzag(v), nil
+	}
+	return int64(v), nil
+}
+
+// bigEndian read an unsigned integer of width bytes
+func (d *orcIntDecoder) bigEndian(width int) (uint64, error) {
+	if len(d.b) < width {
+		return 0, errORCCorrupt
+	}
+	var v uint64
+	for _, c := range d.b[:width] {
+		v = v<<8 | uint64(c)
+	}
+	d.b = d.b[width:]
+	return v, nil
+}
+
+// packed read n integers of width bits packed most significant bit first, padded to a whole byte
+func (d *orcIntDecoder) packed(n, width int) ([]uint64, error) {
+	total := n * width
+	if width > 64 || (total+7)/8 > len(d.b) {
+		return nil, errORCCorrupt
+	}
+	out := make([]uint64, n)
+	for i := range out {
+		var v uint64
+		for bit := i * width; bit < (i+1)*width; bit++ {
+			v = v<<1 | uint64(d.b[bit/8]>>(7-uint(bit)%8)&1)
+		}
+		out[i] = v
+	}
+	d.b = d.b[(total+7)/8:]
+	return out, nil
+}
+
+func (d *orcIntDecoder) value(v uint64) int64 {
+	if d.signed {
+		return unzigzag(v)
+	}
+	return int64(v)
+}
+
+// nextV1 append the next run or literals of run length encoding v1
+func (d *orcIntDecoder) nextV1(out []int64) ([]int64, error) {
+	c, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	control := int8(c)
+	if control >= 0 {
+		delta, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		base, err := d.varint(d.signed)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(control)+3; i++ {
+			out = append(out, base+int64(i)*int64(int8(delta)))
+		}
+		return out, nil
+	}
+	for i := 0; i < -int(control); i++ {
+		v, err := d.varint(d.signed)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// nextV2 append the values of the next run length encoding v2 block: short repeat, direct, patched base or delta
+func (d *orcIntDecoder) nextV2(out []int64) ([]int64, error) {
+	first, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if first>>6 == 0 {
+		// short repeat: 3 to 10 copies of a value of 1 to 8 bytes
+		v, err := d.bigEndian(int(first>>3&7) + 1)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(first&7)+3; i++ {
+			out = append(out, d.value(v))
+		}
+		return out, nil
+	}
+
+	second, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	length := (int(first&1)<<8 | int(second)) + 1
+	width := orcBitWidth(first >> 1 & 0x1f)
+
+	switch first >> 6 {
+	case 1:
+		// direct
+		values, err := d.packed(length, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			out = append(out, d.value(v))
+		}
+		return out, nil
+
+	case 2:
+		// patched base: values over a base, the few large ones patched with their high bits
+		third, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		fourth, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		baseWidth := int(third>>5&7) + 1
+		patchWidth := orcBitWidth(third & 0x1f)
+		gapWidth := int(fourth>>5&7) + 1
+		patches := int(fourth & 0x1f)
+
+		b, err := d.bigEndian(baseWidth)
+		if err != nil {
+			return nil, err
+		}
+		// the most significant bit of the base is its sign
+		sign := uint64(1) << uint(baseWidth*8-1)
+		base := int64(b &^ sign)
+		if b&sign != 0 {
+			base = -base
+		}
+
+		values, err := d.packed(length, width)
+		if err != nil {
+			return nil, err
+		}
+		list, err := d.packed(patches, orcClosestFixedBits(gapWidth+patchWidth))
+		if err != nil {
+			return nil, err
+		}
+		i := 0
+		for _, entry := range list {
+			i += int(entry >> uint(patchWidth))
+			if i >= length {
+				return nil, errORCCorrupt
+			}
+			values[i] |= (entry & (1<<uint(patchWidth) - 1)) << uint(width)
+		}
+		for _, v := range values {
+			out = append(out, base+int64(v))
+		}
+		return out, nil
+
+	default:
+		// delta: a base, a delta and the variations of the following deltas
+		if first>>1&0x1f == 0 {
+			width = 0
+		}
+		base, err := d.varint(d.signed)
+		if err != nil {
+			return nil, err
+		}
+		delta, err := d.varint(true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, base)
+		if length == 1 {
+			return out, nil
+		}
+		v := base + delta
+		out = append(out, v)
+		if width == 0 {
+			for i := 2; i < length; i++ {
+				v += delta
+				out = append(out, v)
+			}
+			return out, nil
+		}
+		deltas, err := d.packed(length-2, width)
+		if err != nil {
+			return nil, err
+		}
+		for _, dv := range deltas {
+			if delta < 0 {
+				v -= int64(dv)
+			} else {
+				v += int64(dv)
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+}
+
+// orcBitWidth decode the 5 bits width of run length encoding v2
+func orcBitWidth(encoded byte) int {
+	if encoded < 24 {
+		return int(encoded) + 1
+	}
+	return [...]int{26, 28, 30, 32, 40, 48, 56, 64}[encoded-24]
+}
+
+// orcClosestFixedBits round a patch list entry width up to a width of orcBitWidth
+func orcClosestFixedBits(n int) int {
+	switch {
+	case n <= 24:
+		if n == 0 {
+			return 1
+		}
+		return n
+	case n <= 26:
+		return 26
+	case n <= 28:
+		return 28
+	case n <= 30:
+		return 30
+	case n <= 32:
+		return 32
+	case n <= 40:
+		return 40
+	case n <= 48:
+		return 48
+	case n <= 56:
+		return 56
+	}
+	return 64
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// protoMessage is a decoded protobuf message, varints and length delimited fields by field number.
+// Fixed width fields are skipped.
+type protoMessage struct {
+	varints map[uint64][]uint64
+	fields  map[uint64][][]byte
+}
+
+// parseProto decode a protobuf message
+func parseProto(b []byte) (protoMessage, error) {
+	m := protoMessage{varints: map[uint64][]uint64{}, fields: map[uint64][][]byte{}}
+	for len(b) > 0 {
+		key, l := binary.Uvarint(b)
+		if l <= 0 {
+			return m, errORCCorrupt
+		}
+		b = b[l:]
+		field := key >> 3
+		switch key & 7 {
+		case 0:
+			v, l := binary.Uvarint(b)
+			if l <= 0 {
+				return m, errORCCorrupt
+			}
+			b = b[l:]
+			m.varints[field] = append(m.varints[field], v)
+		case 1, 5:
+			width := 8
+			if key&7 == 5 {
+				width = 4
+			}
+			if len(b) < width {
+				return m, errORCCorrupt
+			}
+			b = b[width:]
+		case 2:
+			n, l := binary.Uvarint(b)
+			if l <= 0 || n > uint64(len(b)-l) {
+				return m, errORCCorrupt
+			}
+			m.fields[field] = append(m.fields[field], b[l:l+int(n)])
+			b = b[l+int(n):]
+		default:
+			return m, errORCCorrupt
+		}
+	}
+	return m, nil
+}
+
+// uint return the last value of a varint field, zero when absent
+func (m protoMessage) uint(field uint64) uint64 {
+	v := m.varints[field]
+	if len(v) == 0 {
+		return 0
+	}
+	return v[len(v)-1]
+}
+
+// bytes return the last value of a length delimited field
+func (m protoMessage) bytes(field uint64) []byte {
+	v := m.fields[field]
+	if len(v) == 0 {
+		return nil
+	}
+	return v[len(v)-1]
+}
+
+// repeated return every value of a repeated length delimited field
+func (m protoMessage) repeated(field uint64) [][]byte {
+	return m.fields[field]
+}
+
+// packed return every value of a repeated varint field, packed or not
+func (m protoMessage) packed(field uint64) []uint64 {
+	values := append([]uint64(nil), m.varints[field]...)
+	for _, b := range m.fields[field] {
+		for len(b) > 0 {
+			v, l := binary.Uvarint(b)
+			if l <= 0 {
+				break
+			}
+			values = append(values, v)
+			b = b[l:]
+		}
+	}
+	return values
+}
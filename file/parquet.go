@@ -0,0 +1,638 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+)
+
+// errParquetCorrupt returned for Parquet files whose metadata or pages can't be decoded
+var errParquetCorrupt = errors.New("file: corrupt parquet file")
+
+var parquetMagic = []byte("PAR1")
+
+// maxParquetChunk is the largest column chunk or footer read in memory
+const maxParquetChunk = 1 << 30
+
+// parquet physical types
+const (
+	parquetBoolean           = 0
+	parquetInt32             = 1
+	parquetInt64             = 2
+	parquetInt96             = 3
+	parquetFloat             = 4
+	parquetDouble            = 5
+	parquetByteArray         = 6
+	parquetFixedLenByteArray = 7
+)
+
+// parquetColumn is a leaf of a flat Parquet schema
+type parquetColumn struct {
+	name       string
+	typ        int64
+	typeLength int
+	optional   bool
+	// timeUnit is the duration of one unit of a timestamp column, zero for other columns
+	timeUnit time.Duration
+}
+
+// readParquet call fn with every row of a Parquet file with a flat schema, see columnarReader
+func readParquet(ctx context.Context, r io.ReaderAt, size int64, fn func(row map[string]string) error) error {
+	if size < int64(2*len(parquetMagic)+4) {
+		return errParquetCorrupt
+	}
+	tail := make([]byte, 8)
+	if err := readFullAt(r, tail, size-8); err != nil {
+		return err
+	}
+	if !bytes.Equal(tail[4:], parquetMagic) {
+		return errParquetCorrupt
+	}
+	metaLen := int64(binary.LittleEndian.Uint32(tail))
+	if metaLen > size-12 || metaLen > maxParquetChunk {
+		return errParquetCorrupt
+	}
+	metaBytes := make([]byte, metaLen)
+	if err := readFullAt(r, metaBytes, size-8-metaLen); err != nil {
+		return err
+	}
+	tr := &thriftReader{b: metaBytes}
+	meta := tr.readStruct()
+	if tr.err != nil {
+		return tr.err
+	}
+
+	columns, err := parquetSchema(meta.list(2))
+	if err != nil {
+		return err
+	}
+
+	for _, v := range meta.list(4) {
+		rowGroup, _ := v.(thriftStruct)
+		chunks := rowGroup.list(1)
+		if len(chunks) != len(columns) {
+			return errParquetCorrupt
+		}
+		rows := rowGroup.i64(3)
+		if rows < 0 || rows > maxParquetChunk {
+			return errParquetCorrupt
+		}
+
+		values := map[string][]string{}
+		for i, col := range columns {
+			if !inventoryColumn(col.name) {
+				continue
+			}
+			chunk, _ := chunks[i].(thriftStruct)
+			if values[col.name], err = readParquetChunk(r, size, col, chunk, int(rows)); err != nil {
+				return fmt.Errorf("column %s: %w", col.name, err)
+			}
+		}
+
+		for i := 0; i < int(rows); i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			row := make(map[string]string, len(values))
+			for name, v := range values {
+				row[name] = v[i]
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parquetSchema return the columns of a flat schema: a root holding primitive required or optional fields
+func parquetSchema(schema []interface{}) ([]parquetColumn, error) {
+	if len(schema) == 0 {
+		return nil, errParquetCorrupt
+	}
+	root, _ := schema[0].(thriftStruct)
+	if int(root.i64(5)) != len(schema)-1 {
+		return nil, fmt.Errorf("%w: nested parquet schema", ErrUnsupportedInventoryFormat)
+	}
+
+	columns := make([]parquetColumn, 0, len(schema)-1)
+	for _, v := range schema[1:] {
+		el, _ := v.(thriftStruct)
+		if _, group := el[5]; group {
+			return nil, fmt.Errorf("%w: nested parquet schema", ErrUnsupportedInventoryFormat)
+		}
+		col := parquetColumn{
+			name:       el.str(4),
+			typ:        el.i64(1),
+			typeLength: int(el.i64(2)),
+		}
+		switch el.i64(3) {
+		case 0:
+		case 1:
+			col.optional = true
+		default:
+			return nil, fmt.Errorf("%w: repeated parquet column %s", ErrUnsupportedInventoryFormat, col.name)
+		}
+
+		// converted type TIMESTAMP_MILLIS and TIMESTAMP_MICROS, or the logical type TIMESTAMP
+		switch el.i64(6) {
+		case 9:
+			col.timeUnit = time.Millisecond
+		case 10:
+			col.timeUnit = time.Microsecond
+		}
+		if ts, ok := el.strct(10)[8].(thriftStruct); ok {
+			unit := ts.strct(2)
+			switch {
+			case unit[1] != nil:
+				col.timeUnit = time.Millisecond
+			case unit[2] != nil:
+				col.timeUnit = time.Microsecond
+			case unit[3] != nil:
+				col.timeUnit = time.Nanosecond
+			}
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// readParquetChunk decode the rows values of a column chunk
+func readParquetChunk(r io.ReaderAt, size int64, col parquetColumn, chunk thriftStruct, rows int) ([]string, error) {
+	if chunk.str(1) != "" {
+		return nil, fmt.Errorf("%w: parquet column chunk in another file", ErrUnsupportedInventoryFormat)
+	}
+	md := chunk.strct(3)
+	codec := md.i64(4)
+	start, length := md.i64(9), md.i64(7)
+	if dict := md.i64(11); dict > 0 && dict < start {
+		start = dict
+	}
+	if start < 0 || length < 0 || length > maxParquetChunk || start+length > size {
+		return nil, errParquetCorrupt
+	}
+	buf := make([]byte, length)
+	if err := readFullAt(r, buf, start); err != nil {
+		return nil, err
+	}
+
+	var dict []string
+	values := make([]string, 0, rows)
+	for len(values) < rows {
+		tr := &thriftReader{b: buf}
+		header := tr.readStruct()
+		if tr.err != nil {
+			return nil, tr.err
+		}
+		buf = buf[tr.pos:]
+		compressed, uncompressed := header.i64(3), header.i64(2)
+		if compressed < 0 || compressed > int64(len(buf)) || uncompressed < 0 || uncompressed > maxParquetChunk {
+			return nil, errParquetCorrupt
+		}
+		page := buf[:compressed]
+		buf = buf[compressed:]
+
+		switch header.i64(1) {
+		case 2:
+			// dictionary page, PLAIN encoded
+			data, err := parquetDecompress(codec, page, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			n := header.strct(7).i64(1)
+			if n < 0 || n > uncompressed {
+				return nil, errParquetCorrupt
+			}
+			if dict, err = parquetPlain(col, data, int(n)); err != nil {
+				return nil, err
+			}
+		case 0:
+			dh := header.strct(5)
+			data, err := parquetDecompress(codec, page, uncompressed)
+			if err != nil {
+				return nil, err
+			}
+			var levels []byte
+			if col.optional {
+				if len(data) < 4 {
+					return nil, errParquetCorrupt
+				}
+				n := binary.LittleEndian.Uint32(data)
+				if uint64(n) > uint64(len(data)-4) {
+					return nil, errParquetCorrupt
+				}
+				levels, data = data[4:4+n], data[4+n:]
+			}
+			if values, err = appendParquetPage(values, col, dict, dh.i64(1), dh.i64(2), levels, data); err != nil {
+				return nil, err
+			}
+		case 3:
+			// data page v2, levels are never compressed
+			dh := header.strct(8)
+			defLen, repLen := dh.i64(5), dh.i64(6)
+			if defLen < 0 || repLen != 0 || defLen > int64(len(page)) {
+				return nil, errParquetCorrupt
+			}
+			levels, data := page[:defLen], page[defLen:]
+			if compressed, ok := dh[7].(bool); !ok || compressed {
+				var err error
+				if data, err = parquetDecompress(codec, data, uncompressed-defLen); err != nil {
+					return nil, err
+				}
+			}
+			if !col.optional {
+				levels = nil
+			}
+			var err error
+			if values, err = appendParquetPage(values, col, dict, dh.i64(1), dh.i64(4), levels, data); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(buf) == 0 && len(values) < rows {
+			return nil, errParquetCorrupt
+		}
+	}
+	if len(values) != rows {
+		return nil, errParquetCorrupt
+	}
+	return values, nil
+}
+
+// appendParquetPage append the n values of a data page to values, empty for nulls.
+// levels are the definition levels of an optional column, RLE encoded with bit width 1.
+func appendParquetPage(values []string, col parquetColumn, dict []string, n, encoding int64, levels, data []byte) ([]string, error) {
+	if n < 0 || n > maxParquetChunk {
+		return nil, errParquetCorrupt
+	}
+	defined := int(n)
+	var defs []uint32
+	if col.optional {
+		var err error
+		if defs, err = decodeHybrid(levels, 1, int(n)); err != nil {
+			return nil, err
+		}
+		defined = 0
+		for _, d := range defs {
+			defined += int(d)
+		}
+	}
+
+	var page []string
+	switch encoding {
+	case 0:
+		var err error
+		if page, err = parquetPlain(col, data, defined); err != nil {
+			return nil, err
+		}
+	case 2, 8:
+		// PLAIN_DICTIONARY and RLE_DICTIONARY, indexes in the dictionary page
+		if len(data) == 0 {
+			return nil, errParquetCorrupt
+		}
+		indexes, err := decodeHybrid(data[1:], int(data[0]), defined)
+		if err != nil {
+			return nil, err
+		}
+		page = make([]string, defined)
+		for i, index := range indexes {
+			if int(index) >= len(dict) {
+				return nil, errParquetCorrupt
+			}
+			page[i] = dict[index]
+		}
+	default:
+		return nil, fmt.Errorf("%w: parquet encoding %d", ErrUnsupportedInventoryFormat, encoding)
+	}
+
+	if defs == nil {
+		return append(values, page...), nil
+	}
+	for _, d := range defs {
+		if d == 0 {
+			values = append(values, "")
+			continue
+		}
+		values = append(values, page[0])
+		page = page[1:]
+	}
+	return values, nil
+}
+
+// parquetPlain decode n PLAIN encoded values
+func parquetPlain(col parquetColumn, data []byte, n int) ([]string, error) {
+	width := map[int64]int{parquetInt32: 4, parquetInt64: 8, parquetInt96: 12, parquetFloat: 4, parquetDouble: 8,
+		parquetFixedLenByteArray: col.typeLength}[col.typ]
+	switch {
+	case col.typ == parquetBoolean:
+		if len(data)*8 < n {
+			return nil, errParquetCorrupt
+		}
+	case col.typ == parquetByteArray:
+	case col.typ < 0 || col.typ > parquetFixedLenByteArray || width < 0 || len(data) < n*width:
+		return nil, errParquetCorrupt
+	}
+
+	values := make([]string, n)
+	for i := range values {
+		switch col.typ {
+		case parquetBoolean:
+			values[i] = strconv.FormatBool(data[i/8]>>(uint(i)%8)&1 == 1)
+		case parquetInt32:
+			values[i] = strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(data[i*4:]))), 10)
+		case parquetInt64:
+			v := int64(binary.LittleEndian.Uint64(data[i*8:]))
+			if col.timeUnit != 0 {
+				values[i] = unixTime(v, col.timeUnit).Format(time.RFC3339Nano)
+			} else {
+				values[i] = strconv.FormatInt(v, 10)
+			}
+		case parquetInt96:
+			// nanoseconds of the day then julian day
+			v := data[i*12:]
+			nanos := int64(binary.LittleEndian.Uint64(v))
+			days := int64(binary.LittleEndian.Uint32(v[8:])) - 2440588
+			values[i] = time.Unix(days*86400, nanos).UTC().Format(time.RFC3339Nano)
+		case parquetFloat:
+			values[i] = strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))), 'g', -1, 32)
+		case parquetDouble:
+			values[i] = strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:])), 'g', -1, 64)
+		case parquetByteArray:
+			if len(data) < 4 {
+				return nil, errParquetCorrupt
+			}
+			l := binary.LittleEndian.Uint32(data)
+			if uint64(l) > uint64(len(data)-4) {
+				return nil, errParquetCorrupt
+			}
+			values[i] = string(data[4 : 4+l])
+			data = data[4+l:]
+		case parquetFixedLenByteArray:
+			values[i] = string(data[i*width : (i+1)*width])
+		}
+	}
+	return values, nil
+}
+
+// unixTime return the UTC time v units after the unix epoch
+func unixTime(v int64, unit time.Duration) time.Time {
+	perSecond := int64(time.Second / unit)
+	sec, rest := v/perSecond, v%perSecond
+	if rest < 0 {
+		sec, rest = sec-1, rest+perSecond
+	}
+	return time.Unix(sec, rest*int64(unit)).UTC()
+}
+
+// parquetDecompress decompress a page compressed with codec UNCOMPRESSED, SNAPPY or GZIP
+func parquetDecompress(codec int64, data []byte, size int64) ([]byte, error) {
+	var out []byte
+	var err error
+	switch codec {
+	case 0:
+		out = data
+	case 1:
+		out, err = snappyDecode(data)
+	case 2:
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		out, err = ioutil.ReadAll(io.LimitReader(gz, size+1))
+	default:
+		return nil, fmt.Errorf("%w: parquet codec %d", ErrUnsupportedInventoryFormat, codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) != size {
+		return nil, errParquetCorrupt
+	}
+	return out, nil
+}
+
+// decodeHybrid decode n values of the Parquet RLE / bit-packing hybrid encoding
+func decodeHybrid(data []byte, bitWidth, n int) ([]uint32, error) {
+	if bitWidth > 32 {
+		return nil, errParquetCorrupt
+	}
+	values := make([]uint32, 0, n)
+	byteWidth := (bitWidth + 7) / 8
+	for len(values) < n {
+		header, l := binary.Uvarint(data)
+		if l <= 0 {
+			return nil, errParquetCorrupt
+		}
+		data = data[l:]
+
+		if header&1 == 0 {
+			// run of one value
+			count := header >> 1
+			if len(data) < byteWidth || count > uint64(n-len(values)) {
+				return nil, errParquetCorrupt
+			}
+			var v uint32
+			for i := byteWidth - 1; i >= 0; i-- {
+				v = v<<8 | uint32(data[i])
+			}
+			data = data[byteWidth:]
+			for i := uint64(0); i < count; i++ {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		// groups of 8 values packed from the least significant bit, the last group may be padding
+		groups := header >> 1
+		if groups > uint64(len(data)) || int(groups)*bitWidth > len(data) {
+			return nil, errParquetCorrupt
+		}
+		packed := data[:int(groups)*bitWidth]
+		data = data[len(packed):]
+		for i := 0; i < int(groups)*8 && len(values) < n; i++ {
+			var v uint32
+			for b := 0; b < bitWidth; b++ {
+				bit := i*bitWidth + b
+				v |= uint32(packed[bit/8]>>(uint(bit)%8)&1) << uint(b)
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// thriftStruct is a struct of the thrift compact protocol by field id. Values are int64, bool, float64, []byte,
+// thriftStruct and []interface{} for lists and sets, maps are skipped
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) i64(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStruct) strct(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// maxThriftDepth is the deepest nesting of structs and lists accepted, Parquet metadata use a few levels
+const maxThriftDepth = 32
+
+// thriftReader decode the thrift compact protocol Parquet metadata and page headers are written with
+type thriftReader struct {
+	b     []byte
+	pos   int
+	depth int
+	err   error
+}
+
+func (r *thriftReader) fail() {
+	if r.err == nil {
+		r.err = errParquetCorrupt
+	}
+}
+
+func (r *thriftReader) byte() byte {
+	if r.err != nil || r.pos >= len(r.b) {
+		r.fail()
+		return 0
+	}
+	r.pos++
+	return r.b[r.pos-1]
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, l := binary.Uvarint(r.b[r.pos:])
+	if l <= 0 {
+		r.fail()
+		return 0
+	}
+	r.pos += l
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) bytes(n uint64) []byte {
+	if r.err != nil || n > uint64(len(r.b)-r.pos) {
+		r.fail()
+		return nil
+	}
+	r.pos += int(n)
+	return r.b[r.pos-int(n) : r.pos]
+}
+
+func (r *thriftReader) readStruct() thriftStruct {
+	s := thriftStruct{}
+	if r.depth++; r.depth > maxThriftDepth {
+		r.fail()
+	}
+	defer func() { r.depth-- }()
+
+	var id int16
+	for r.err == nil {
+		header := r.byte()
+		if header == 0 {
+			break
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			id = int16(r.zigzag())
+		}
+		s[id] = r.value(header & 0x0f)
+	}
+	return s
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		b := r.bytes(8)
+		if b == nil {
+			return nil
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case 8:
+		return r.bytes(r.uvarint())
+	case 9, 10:
+		header := r.byte()
+		n, elem := uint64(header>>4), header&0x0f
+		if n == 15 {
+			n = r.uvarint()
+		}
+		// every element take at least one byte
+		if n > uint64(len(r.b)-r.pos) {
+			r.fail()
+			return nil
+		}
+		if r.depth++; r.depth > maxThriftDepth {
+			r.fail()
+		}
+		defer func() { r.depth-- }()
+		list := make([]interface{}, 0, n)
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			list = append(list, r.element(elem))
+		}
+		return list
+	case 11:
+		n := r.uvarint()
+		if n == 0 {
+			return nil
+		}
+		if n > uint64(len(r.b)-r.pos) {
+			r.fail()
+			return nil
+		}
+		types := r.byte()
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			r.element(types >> 4)
+			r.element(types & 0x0f)
+		}
+		return nil
+	case 12:
+		return r.readStruct()
+	}
+	r.fail()
+	return nil
+}
+
+// element read a list, set or map element, booleans are a byte there instead of the type
+func (r *thriftReader) element(typ byte) interface{} {
+	if typ == 1 || typ == 2 {
+		return r.byte() == 1
+	}
+	return r.value(typ)
+}
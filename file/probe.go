@@ -0,0 +1,101 @@
+package file
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultProbeTimeout bound a replica probe by default
+	DefaultProbeTimeout = 5 * time.Second
+	// ProbePrefix is listed to measure a store latency
+	ProbePrefix = "system/probe/"
+)
+
+// ReplicaHealth is the last probe of a store
+type ReplicaHealth struct {
+	Store    IFile
+	Primary  bool
+	Latency  time.Duration
+	Err      error
+	ProbedAt time.Time
+}
+
+// Healthy report whether the last probe succeeded
+func (h ReplicaHealth) Healthy() bool {
+	return h.Err == nil
+}
+
+// Probe measure the latency of every store with a one file listing and reorder reads:
+// healthy stores by ascending latency, Primary always last resort when it failed.
+// When every store failed reads go back to the configured order.
+func (r *Replicated) Probe(ctx context.Context) []ReplicaHealth {
+	timeout := r.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	stores := append([]IFile{r.IFile}, r.Replicas...)
+	health := make([]ReplicaHealth, len(stores))
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		wg.Add(1)
+		go func(i int, store IFile) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			_, err := store.List(pctx, ProbePrefix, WithMaxResults(1))
+			health[i] = ReplicaHealth{Store: store, Primary: i == 0, Latency: time.Since(start), Err: err, ProbedAt: time.Now()}
+		}(i, store)
+	}
+	wg.Wait()
+
+	sorted := append([]ReplicaHealth(nil), health...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Latency < sorted[j].Latency })
+
+	var order []IFile
+	for _, h := range sorted {
+		if h.Healthy() {
+			order = append(order, h.Store)
+		}
+	}
+	if len(order) > 0 && !health[0].Healthy() {
+		order = append(order, r.IFile)
+	}
+
+	r.mu.Lock()
+	r.probed = order
+	r.health = health
+	r.mu.Unlock()
+	return health
+}
+
+// Health return the last probe of every store, Primary first
+func (r *Replicated) Health() []ReplicaHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]ReplicaHealth(nil), r.health...)
+}
+
+// RunProbes probe stores every interval until ctx is done
+//
+//	Example:
+//	store := file.NewReplicated(jakarta, singapore, tokyo)
+//	go store.RunProbes(ctx, 30*time.Second)
+func (r *Replicated) RunProbes(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
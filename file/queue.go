@@ -0,0 +1,197 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const (
+	QueueAPIVersion          = "2018-03-28"
+	DefaultVisibilityTimeout = 30 * time.Second
+
+	// azure queue returns at most 32 messages per request
+	maxQueueBatch = 32
+)
+
+// AzureQueue read messages from Azure Storage Queue, e.g. one subscribed to Event Grid blob events.
+// It can be used as both Queue and DeadLetter of a Consumer.
+type AzureQueue struct {
+	QueueURL string
+	// VisibilityTimeout hide received messages until acknowledged, DefaultVisibilityTimeout when 0.
+	// Azure count it in seconds, shorter timeouts are rounded up to one second
+	VisibilityTimeout time.Duration
+
+	p pipeline.Pipeline
+}
+
+type queueMessage struct {
+	MessageID    string `xml:"MessageId"`
+	PopReceipt   string `xml:"PopReceipt"`
+	DequeueCount int    `xml:"DequeueCount"`
+	MessageText  string `xml:"MessageText"`
+}
+
+type queueMessagesList struct {
+	Messages []queueMessage `xml:"QueueMessage"`
+}
+
+// DeadLetterMessage is the text AzureQueue.Send enqueue, the failed message with the cause of its failure
+type DeadLetterMessage struct {
+	ID           string `json:"id"`
+	DequeueCount int    `json:"dequeueCount"`
+	Cause        string `json:"cause,omitempty"`
+	// Body is the original message body
+	Body []byte `json:"body"`
+}
+
+// ParseDeadLetter decode the body of a message sent to a dead letter AzureQueue, e.g. to inspect or replay it
+func ParseDeadLetter(body []byte) (DeadLetterMessage, error) {
+	var m DeadLetterMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return DeadLetterMessage{}, fmt.Errorf("file: dead letter message: %w", err)
+	}
+	return m, nil
+}
+
+// NewAzureQueue create queue client using account shared key.
+// queueURL is the full queue url, e.g. "https://account.queue.core.windows.net/blob-events"
+func NewAzureQueue(account, accessKey, queueURL string, opts ...Option) (*AzureQueue, error) {
+	credential, err := azblob.NewSharedKeyCredential(account, accessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &File{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return &AzureQueue{
+		QueueURL:          queueURL,
+		VisibilityTimeout: DefaultVisibilityTimeout,
+		p:                 azblob.NewPipeline(credential, c.pipelineOptions()),
+	}, nil
+}
+
+// visibilityTimeout return VisibilityTimeout, a zero timeout would redeliver messages at once to other consumers
+func (q *AzureQueue) visibilityTimeout() time.Duration {
+	switch {
+	case q.VisibilityTimeout <= 0:
+		return DefaultVisibilityTimeout
+	case q.VisibilityTimeout < time.Second:
+		return time.Second
+	}
+	return q.VisibilityTimeout
+}
+
+// Receive dequeue up to max messages, hiding them for VisibilityTimeout
+func (q *AzureQueue) Receive(ctx context.Context, max int) ([]Message, error) {
+	if max <= 0 || max > maxQueueBatch {
+		max = maxQueueBatch
+	}
+
+	query := url.Values{}
+	query.Set("numofmessages", strconv.Itoa(max))
+	query.Set("visibilitytimeout", strconv.Itoa(int(q.visibilityTimeout()/time.Second)))
+
+	body, err := q.do(ctx, http.MethodGet, "/messages", query, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var list queueMessagesList
+	if err := xml.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(list.Messages))
+	for _, m := range list.Messages {
+		msgs = append(msgs, Message{
+			ID:           m.MessageID,
+			Receipt:      m.PopReceipt,
+			Body:         []byte(m.MessageText),
+			DequeueCount: m.DequeueCount,
+		})
+	}
+
+	return msgs, nil
+}
+
+// Ack delete message from the queue
+func (q *AzureQueue) Ack(ctx context.Context, msg Message) error {
+	query := url.Values{}
+	query.Set("popreceipt", msg.Receipt)
+
+	_, err := q.do(ctx, http.MethodDelete, "/messages/"+url.PathEscape(msg.ID), query, nil, http.StatusNoContent)
+	return err
+}
+
+// Send enqueue message as DeadLetterMessage, used when the queue acts as dead letter queue.
+// Azure queue messages have no metadata so cause is kept in the message text next to the original body
+func (q *AzureQueue) Send(ctx context.Context, msg Message, cause error) error {
+	dead := DeadLetterMessage{
+		ID:           msg.ID,
+		DequeueCount: msg.DequeueCount,
+		Body:         msg.Body,
+	}
+	if cause != nil {
+		dead.Cause = cause.Error()
+	}
+	text, err := json.Marshal(dead)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<QueueMessage><MessageText>")
+	if err := xml.EscapeText(&buf, text); err != nil {
+		return err
+	}
+	buf.WriteString("</MessageText></QueueMessage>")
+
+	_, err = q.do(ctx, http.MethodPost, "/messages", nil, bytes.NewReader(buf.Bytes()), http.StatusCreated)
+	return err
+}
+
+func (q *AzureQueue) do(ctx context.Context, method, path string, query url.Values, body io.ReadSeeker, expect int) ([]byte, error) {
+	u, err := url.Parse(q.QueueURL + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := pipeline.NewRequest(method, *u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", QueueAPIVersion)
+
+	resp, err := q.p.Do(ctx, nil, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Response().Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Response().Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Response().StatusCode != expect {
+		return nil, fmt.Errorf("file: queue %s %s: %s", method, path, resp.Response().Status)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// Unwrapper is implemented by decorators wrapping another store, e.g. Guard or Cached
+type Unwrapper interface {
+	Unwrap() IFile
+}
+
+// Raw is implemented by stores exposing the client of their provider,
+// so provider features the SDK doesn't wrap can be used directly
+type Raw interface {
+	// Raw return the provider client, an azblob.ContainerURL for File
+	Raw() interface{}
+}
+
+// AzureClient is implemented by stores backed by Azure blob storage
+type AzureClient interface {
+	// AzureContainerClient return the container url sharing the store credential and connection pool
+	AzureContainerClient() (azblob.ContainerURL, error)
+	// AzurePipeline return the request pipeline of the store, to send requests azblob has no method for
+	AzurePipeline() (pipeline.Pipeline, error)
+}
+
+// Raw return the container url of the client, nil when the client configuration is invalid
+func (c *File) Raw() interface{} {
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil
+	}
+	return containerURL
+}
+
+// AzureContainerClient return the container url of the client
+func (c *File) AzureContainerClient() (azblob.ContainerURL, error) {
+	return c.GetContainer()
+}
+
+// AzurePipeline return the request pipeline of the client, requests are signed with the account key
+func (c *File) AzurePipeline() (pipeline.Pipeline, error) {
+	p, _, err := c.storagePipeline()
+	return p, err
+}
+
+// RawClient return the provider client of store, looking through decorators implementing Unwrapper.
+// False when no store in the chain expose one, e.g. filetest.Memory.
+//
+//	Example:
+//	if client, ok := file.RawClient(store); ok {
+//		containerURL := client.(azblob.ContainerURL)
+//	}
+func RawClient(store IFile) (interface{}, bool) {
+	for store != nil {
+		if r, ok := store.(Raw); ok {
+			if client := r.Raw(); client != nil {
+				return client, true
+			}
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, false
+}
+
+// AsAzure return the Azure client of store, looking through decorators implementing Unwrapper
+//
+//	Example:
+//	if az, ok := file.AsAzure(store); ok {
+//		containerURL, err := az.AzureContainerClient()
+//		_, err = containerURL.SetAccessPolicy(ctx, azblob.PublicAccessBlob, nil, azblob.ContainerAccessConditions{})
+//	}
+func AsAzure(store IFile) (AzureClient, bool) {
+	for store != nil {
+		if az, ok := store.(AzureClient); ok {
+			return az, true
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, false
+}
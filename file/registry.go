@@ -0,0 +1,162 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// URIScheme is the scheme of logical file uris, "assets://<name>/<key>"
+const URIScheme = "assets"
+
+// maxAliasDepth bound alias chains
+const maxAliasDepth = 8
+
+var (
+	// ErrInvalidURI returned for uris not of the form assets://<name>/<key>
+	ErrInvalidURI = errors.New("file: invalid assets uri")
+	// ErrUnknownName returned when a uri name is neither registered nor an alias
+	ErrUnknownName = errors.New("file: unknown assets name")
+)
+
+// Mount is the physical location of a logical name: a store and a key prefix inside it
+type Mount struct {
+	Store  IFile
+	Prefix string
+}
+
+// Registry resolve logical uris such as "assets://avatars/42.jpg" to a store and key at runtime,
+// so applications persist uris while the physical container, or provider, is changed by configuration.
+// Names may be aliases of other names, like DNS CNAME records. Safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	mounts  map[string]Mount
+	aliases map[string]string
+}
+
+// NewRegistry create an empty registry
+//
+//	Example:
+//	registry := file.NewRegistry()
+//	registry.Register("avatars-2020", avatars, "users/")
+//	registry.Alias("avatars", "avatars-2020")
+//	uri := file.URI("avatars", "42.jpg")
+//	data, err := registry.Download(ctx, uri)
+func NewRegistry() *Registry {
+	return &Registry{mounts: map[string]Mount{}, aliases: map[string]string{}}
+}
+
+// Register mount name on store under prefix, replacing a previous mount or alias of name
+func (r *Registry) Register(name string, store IFile, prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.aliases, name)
+	r.mounts[name] = Mount{Store: store, Prefix: prefix}
+}
+
+// Alias resolve name as target, replacing a previous mount or alias of name
+func (r *Registry) Alias(name, target string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.mounts, name)
+	r.aliases[name] = target
+}
+
+// SetAliases replace every alias, e.g. from configuration reloaded at runtime
+func (r *Registry) SetAliases(aliases map[string]string) {
+	copied := make(map[string]string, len(aliases))
+	for name, target := range aliases {
+		copied[name] = target
+	}
+	r.mu.Lock()
+	r.aliases = copied
+	r.mu.Unlock()
+}
+
+// URI return the logical uri of key under name
+func URI(name, key string) string {
+	return URIScheme + "://" + name + "/" + key
+}
+
+// ParseURI return the name and key of a logical uri
+func ParseURI(uri string) (string, string, error) {
+	rest := strings.TrimPrefix(uri, URIScheme+"://")
+	if rest == uri {
+		return "", "", ErrInvalidURI
+	}
+	i := strings.IndexByte(rest, '/')
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", ErrInvalidURI
+	}
+	return rest[:i], rest[i+1:], nil
+}
+
+// Mount return the mount of name, following aliases
+func (r *Registry) Mount(name string) (Mount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	original := name
+	for i := 0; i < maxAliasDepth; i++ {
+		if m, ok := r.mounts[name]; ok {
+			return m, nil
+		}
+		target, ok := r.aliases[name]
+		if !ok {
+			return Mount{}, fmt.Errorf("%w: %s", ErrUnknownName, name)
+		}
+		name = target
+	}
+	return Mount{}, fmt.Errorf("file: alias chain of %s too long or looping", original)
+}
+
+// Resolve return the store and physical key of uri
+func (r *Registry) Resolve(uri string) (IFile, string, error) {
+	name, key, err := ParseURI(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	m, err := r.Mount(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return m.Store, m.Prefix + key, nil
+}
+
+// URL return the current physical url of uri
+func (r *Registry) URL(uri string, withSignature bool) (string, error) {
+	store, key, err := r.Resolve(uri)
+	if err != nil {
+		return "", err
+	}
+	return store.GetBlobURL(key, withSignature), nil
+}
+
+// Upload file at uri, return the physical url
+func (r *Registry) Upload(ctx context.Context, uri, contentType string, buffBytes []byte) (string, error) {
+	store, key, err := r.Resolve(uri)
+	if err != nil {
+		return "", err
+	}
+	return store.Upload(ctx, key, contentType, buffBytes)
+}
+
+// Download file at uri
+func (r *Registry) Download(ctx context.Context, uri string) ([]byte, error) {
+	store, key, err := r.Resolve(uri)
+	if err != nil {
+		return nil, err
+	}
+	return store.Download(ctx, key)
+}
+
+// Delete file at uri
+func (r *Registry) Delete(ctx context.Context, uri string) error {
+	store, key, err := r.Resolve(uri)
+	if err != nil {
+		return err
+	}
+	_, err = store.Delete(ctx, key)
+	return err
+}
@@ -0,0 +1,221 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// ReplicationAPIVersion is the storage API version reporting object replication headers
+const ReplicationAPIVersion = "2019-12-12"
+
+// ReplicationState is the replication state of a file for one replication rule
+type ReplicationState string
+
+const (
+	ReplicationComplete ReplicationState = "complete"
+	ReplicationFailed   ReplicationState = "failed"
+)
+
+// Replication is the object replication status of a file.
+// On a source file Rules hold the state per "policy_rule", rules not listed are still pending.
+// On a destination file PolicyID is the policy that replicated it.
+type Replication struct {
+	PolicyID string
+	Rules    map[string]ReplicationState
+}
+
+// ReplicationStatus return the object replication status of file
+//
+//	Example:
+//	status, err := file.ReplicationStatus(ctx, "products/42/original.jpg")
+func (c *File) ReplicationStatus(ctx context.Context, filePath string) (Replication, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return Replication{}, err
+	}
+	p, _, err := c.storagePipeline()
+	if err != nil {
+		return Replication{}, err
+	}
+
+	u, err := url.Parse(c.GetBlobURL(filePath, false))
+	if err != nil {
+		return Replication{}, err
+	}
+	req, err := pipeline.NewRequest(http.MethodHead, *u, nil)
+	if err != nil {
+		return Replication{}, err
+	}
+	req.Header.Set("x-ms-version", ReplicationAPIVersion)
+
+	resp, err := p.Do(ctx, nil, req)
+	if err != nil {
+		return Replication{}, err
+	}
+	defer resp.Response().Body.Close()
+
+	switch resp.Response().StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return Replication{}, ErrNotFound
+	default:
+		return Replication{}, fmt.Errorf("file: replication status %s: %s", filePath, resp.Response().Status)
+	}
+
+	status := Replication{PolicyID: resp.Response().Header.Get("x-ms-or-policy-id"), Rules: map[string]ReplicationState{}}
+	for name, values := range resp.Response().Header {
+		// x-ms-or-{policy id}_{rule id}: Complete | Failed
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-ms-or-") || name == "x-ms-or-policy-id" || len(values) == 0 {
+			continue
+		}
+		status.Rules[strings.TrimPrefix(name, "x-ms-or-")] = ReplicationState(strings.ToLower(values[0]))
+	}
+	return status, nil
+}
+
+// ReplicationPolicy is an Azure object replication policy copying containers of a source account to a destination account.
+// Policies are management operations, apply the JSON document with
+// "az storage account or-policy create --account-name <destination> --policy @policy.json"
+// then on the source account with the policy id returned.
+type ReplicationPolicy struct {
+	PolicyID           string
+	SourceAccount      string
+	DestinationAccount string
+	Rules              []ReplicationRule
+}
+
+// ReplicationRule replicate files of SourceContainer, optionally only those under PrefixMatch
+// or created after MinCreationTime
+type ReplicationRule struct {
+	RuleID               string
+	SourceContainer      string
+	DestinationContainer string
+	PrefixMatch          []string
+	MinCreationTime      time.Time
+}
+
+type policyDocument struct {
+	Properties struct {
+		PolicyID           string         `json:"policyId,omitempty"`
+		SourceAccount      string         `json:"sourceAccount"`
+		DestinationAccount string         `json:"destinationAccount"`
+		Rules              []ruleDocument `json:"rules"`
+	} `json:"properties"`
+}
+
+type ruleDocument struct {
+	RuleID               string `json:"ruleId,omitempty"`
+	SourceContainer      string `json:"sourceContainer"`
+	DestinationContainer string `json:"destinationContainer"`
+	Filters              *struct {
+		PrefixMatch     []string `json:"prefixMatch,omitempty"`
+		MinCreationTime string   `json:"minCreationTime,omitempty"`
+	} `json:"filters,omitempty"`
+}
+
+// MarshalJSON encode policy as the Azure Resource Manager object replication policy document
+func (p ReplicationPolicy) MarshalJSON() ([]byte, error) {
+	var doc policyDocument
+	doc.Properties.PolicyID = p.PolicyID
+	doc.Properties.SourceAccount = p.SourceAccount
+	doc.Properties.DestinationAccount = p.DestinationAccount
+	doc.Properties.Rules = []ruleDocument{}
+
+	for _, r := range p.Rules {
+		rule := ruleDocument{RuleID: r.RuleID, SourceContainer: r.SourceContainer, DestinationContainer: r.DestinationContainer}
+		if len(r.PrefixMatch) > 0 || !r.MinCreationTime.IsZero() {
+			rule.Filters = &struct {
+				PrefixMatch     []string `json:"prefixMatch,omitempty"`
+				MinCreationTime string   `json:"minCreationTime,omitempty"`
+			}{PrefixMatch: r.PrefixMatch}
+			if !r.MinCreationTime.IsZero() {
+				rule.Filters.MinCreationTime = r.MinCreationTime.UTC().Format("2006-01-02T15:04:05Z")
+			}
+		}
+		doc.Properties.Rules = append(doc.Properties.Rules, rule)
+	}
+
+	return json.Marshal(doc)
+}
+
+// Replicated is an IFile writing to Primary and reading from the nearest replica holding the file.
+// Replicas are tried in order, files not replicated yet are read from Primary.
+// Once probed, reads go to healthy stores by ascending latency instead, see Probe.
+// Listings, urls and writes use Primary.
+type Replicated struct {
+	IFile
+	Replicas []IFile
+	// ProbeTimeout bound each probe, DefaultProbeTimeout when 0
+	ProbeTimeout time.Duration
+
+	mu     sync.RWMutex
+	probed []IFile
+	health []ReplicaHealth
+}
+
+// NewReplicated read from replicas ordered nearest first, falling back to primary
+//
+//	Example:
+//	store := file.NewReplicated(jakarta, singapore)
+func NewReplicated(primary IFile, replicas ...IFile) *Replicated {
+	return &Replicated{IFile: primary, Replicas: replicas}
+}
+
+// Unwrap return the wrapped store
+func (r *Replicated) Unwrap() IFile {
+	return r.IFile
+}
+
+// readOrder return the stores reads are tried on, by latency once probed, Primary last otherwise
+func (r *Replicated) readOrder() []IFile {
+	r.mu.RLock()
+	probed := r.probed
+	r.mu.RUnlock()
+	if len(probed) > 0 {
+		return probed
+	}
+	return append(append([]IFile(nil), r.Replicas...), r.IFile)
+}
+
+// Download file from the nearest store holding it
+func (r *Replicated) Download(ctx context.Context, filePath string) ([]byte, error) {
+	var err error
+	for _, store := range r.readOrder() {
+		var data []byte
+		if data, err = store.Download(ctx, filePath); err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// DownloadIfModified download file from the nearest store holding it
+func (r *Replicated) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	var err error
+	for _, store := range r.readOrder() {
+		var (
+			data []byte
+			etag string
+		)
+		data, etag, err = store.DownloadIfModified(ctx, filePath, knownETag)
+		if err == nil || err == ErrNotModified {
+			return data, etag, err
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+	}
+	return nil, "", err
+}
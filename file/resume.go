@@ -0,0 +1,157 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const (
+	// DefaultResumeAttempts is how many times in a row a download is resumed by default
+	DefaultResumeAttempts = 5
+	// DefaultResumeBackoff is the wait before the first resume by default, doubled on every failed attempt
+	DefaultResumeBackoff = 500 * time.Millisecond
+)
+
+// ErrChanged returned when a file was replaced while being read, the content read so far is from the old version
+var ErrChanged = errors.New("file: changed while being read")
+
+// RangeOpener is implemented by stores reading a file from an offset, e.g. *File
+type RangeOpener interface {
+	// OpenRange return a reader of the content from offset to the end and the ETag of the file.
+	// With a non empty etag it fail with ErrChanged when the file has another one.
+	OpenRange(ctx context.Context, filePath string, offset int64, etag string) (io.ReadCloser, string, error)
+}
+
+// ResumeOptions configure NewResumeReader
+type ResumeOptions struct {
+	// Attempts is how many times in a row the download is resumed without receiving anything, DefaultResumeAttempts when 0
+	Attempts int
+	// Backoff is the wait before the first resume, DefaultResumeBackoff when 0
+	Backoff time.Duration
+	// OnResume is called before resuming from offset after err
+	OnResume func(offset int64, err error)
+}
+
+// NewResumeReader return a reader of filePath that resume from the last received byte with a range request when the
+// transfer fail with a retryable error, see Classify, instead of restarting a multi-GB download from zero.
+// Resumed ranges must have the ETag of the first response, a file replaced meanwhile fail with ErrChanged.
+//
+//	Example:
+//	r, err := file.NewResumeReader(ctx, f, "exports/2020.tar", file.ResumeOptions{Attempts: 10})
+//	defer r.Close()
+//	_, err = io.Copy(out, r)
+func NewResumeReader(ctx context.Context, opener RangeOpener, filePath string, opts ResumeOptions) (io.ReadCloser, error) {
+	body, etag, err := opener.OpenRange(ctx, filePath, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = DefaultResumeAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultResumeBackoff
+	}
+	return &resumeReader{ctx: ctx, opener: opener, filePath: filePath, opts: opts, body: body, etag: etag}, nil
+}
+
+type resumeReader struct {
+	ctx      context.Context
+	opener   RangeOpener
+	filePath string
+	opts     ResumeOptions
+	body     io.ReadCloser
+	etag     string
+	offset   int64
+}
+
+func (r *resumeReader) Read(p []byte) (int, error) {
+	backoff := r.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		if r.body == nil {
+			body, _, err := r.opener.OpenRange(r.ctx, r.filePath, r.offset, r.etag)
+			if err != nil {
+				if attempt < r.opts.Attempts && Classify(err).Retryable() && r.wait(&backoff) {
+					continue
+				}
+				return 0, err
+			}
+			r.body = body
+		}
+
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == nil || err == io.EOF || !Classify(err).Retryable() {
+			return n, err
+		}
+		r.body.Close()
+		r.body = nil
+		if n > 0 {
+			// resume on the next read, the attempts start over as the transfer progressed
+			return n, nil
+		}
+		if attempt >= r.opts.Attempts || !r.wait(&backoff) {
+			return 0, err
+		}
+		if r.opts.OnResume != nil {
+			r.opts.OnResume(r.offset, err)
+		}
+	}
+}
+
+// wait sleep for backoff and double it, it report false when ctx is done first
+func (r *resumeReader) wait(backoff *time.Duration) bool {
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-time.After(*backoff):
+	}
+	*backoff *= 2
+	return true
+}
+
+func (r *resumeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// OpenRange return a reader of the file content from offset, see RangeOpener
+func (c *File) OpenRange(ctx context.Context, filePath string, offset int64, etag string) (io.ReadCloser, string, error) {
+	return c.openRange(ctx, filePath, offset, azblob.CountToEnd, etag)
+}
+
+// openRange return a reader of count bytes of the file content from offset and the file ETag
+func (c *File) openRange(ctx context.Context, filePath string, offset, count int64, etag string) (io.ReadCloser, string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil, "", err
+	}
+
+	conditions := azblob.BlobAccessConditions{}
+	if etag != "" {
+		conditions.ModifiedAccessConditions.IfMatch = azblob.ETag(etag)
+	}
+	resp, err := containerURL.NewBlobURL(filePath).Download(ctx, offset, count, conditions, false)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, "", ErrNotFound
+		}
+		if serr, ok := err.(azblob.StorageError); ok && serr.Response() != nil && serr.Response().StatusCode == http.StatusPreconditionFailed {
+			return nil, "", ErrChanged
+		}
+		return nil, "", err
+	}
+	return resp.Response().Body, string(resp.ETag()), nil
+}
@@ -0,0 +1,131 @@
+package file
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Redirect map formats written by WriteRedirects
+const (
+	RedirectCSV    = "csv"
+	RedirectNginx  = "nginx"
+	RedirectApache = "apache"
+)
+
+// URLMapping is the new url of a file url
+type URLMapping struct {
+	Key string
+	Old string
+	New string
+}
+
+// URLRewriter move file urls from a storage domain to another one, e.g. a CDN in front of the container.
+// The path after OldBase is kept, signatures and other query parameters are dropped.
+type URLRewriter struct {
+	OldBase string
+	NewBase string
+}
+
+// NewURLRewriter rewrite the urls of store to newBase, e.g. "https://cdn.example.com/assets"
+//
+//	Example:
+//	rw := file.NewURLRewriter(store, "https://cdn.example.com")
+//	mappings, err := rw.List(ctx, store, "products/")
+//	err = file.WriteRedirects(w, mappings, file.RedirectNginx)
+func NewURLRewriter(store IFile, newBase string) *URLRewriter {
+	return &URLRewriter{OldBase: strings.TrimSuffix(store.GetURL(), "/"), NewBase: strings.TrimSuffix(newBase, "/")}
+}
+
+// Rewrite return the new url of u, false when u is not under OldBase
+func (r *URLRewriter) Rewrite(u string) (string, bool) {
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		u = u[:i]
+	}
+	if !strings.HasPrefix(u, r.OldBase+"/") {
+		return "", false
+	}
+	return r.NewBase + strings.TrimPrefix(u, r.OldBase), true
+}
+
+// List return the mapping of every file of store under prefix
+func (r *URLRewriter) List(ctx context.Context, store IFile, prefix string) ([]URLMapping, error) {
+	var mappings []URLMapping
+	for item := range store.ListStream(ctx, prefix) {
+		if item.Err != nil {
+			return nil, item.Err
+		}
+		old := store.GetBlobURL(item.Info.Name, false)
+		if u, ok := r.Rewrite(old); ok {
+			mappings = append(mappings, URLMapping{Key: item.Info.Name, Old: old, New: u})
+		}
+	}
+	return mappings, nil
+}
+
+// Records return the mapping of every catalog record url under OldBase
+func (r *URLRewriter) Records(recs []CatalogRecord) []URLMapping {
+	var mappings []URLMapping
+	for _, rec := range recs {
+		if u, ok := r.Rewrite(rec.URL); ok {
+			mappings = append(mappings, URLMapping{Key: rec.Key, Old: rec.URL, New: u})
+		}
+	}
+	return mappings
+}
+
+// UpdateCatalog save the new url of recs under OldBase, return the number of records updated
+func (r *URLRewriter) UpdateCatalog(ctx context.Context, catalog Catalog, recs []CatalogRecord) (int, error) {
+	updated := 0
+	for _, rec := range recs {
+		u, ok := r.Rewrite(rec.URL)
+		if !ok {
+			continue
+		}
+		rec.URL = u
+		if err := catalog.Put(ctx, rec); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// WriteRedirects write mappings as a CSV of old and new urls, or as 301 redirects of the old url paths
+// for an nginx map on $request_uri or an apache configuration
+func WriteRedirects(w io.Writer, mappings []URLMapping, format string) error {
+	switch format {
+	case RedirectCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"old", "new"})
+		for _, m := range mappings {
+			cw.Write([]string{m.Old, m.New})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case RedirectNginx, RedirectApache:
+		for _, m := range mappings {
+			from, err := url.Parse(m.Old)
+			if err != nil {
+				return err
+			}
+			to, err := url.Parse(m.New)
+			if err != nil {
+				return err
+			}
+			line := fmt.Sprintf("%q %q;\n", from.EscapedPath(), to.String())
+			if format == RedirectApache {
+				line = fmt.Sprintf("Redirect 301 %q %q\n", from.EscapedPath(), to.String())
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("file: unknown redirect format %q", format)
+}
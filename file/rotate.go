@@ -0,0 +1,272 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRotateSize is the uncompressed size rolling a log part by default
+	DefaultRotateSize = 64 << 20
+	// DefaultRotateAge is the age rolling a log part by default, it bound the events lost by a crash
+	DefaultRotateAge = time.Minute
+	// DefaultRotateExt is the extension of log parts by default
+	DefaultRotateExt = ".ndjson.gz"
+)
+
+// ErrWriterClosed returned when writing to a closed RotatingWriter
+var ErrWriterClosed = errors.New("file: writer closed")
+
+// ObjectWriterCreator is implemented by stores streaming writes into a file, e.g. *File
+type ObjectWriterCreator interface {
+	NewObjectWriter(ctx context.Context, filePath, contentType string) (io.WriteCloser, error)
+}
+
+// RotatingWriter archive an append-only log, e.g. audit events as NDJSON, into parts keyed
+// <prefix><yyyy>/<mm>/<dd>/part-0001<ext> by UTC date. A part is rolled once it reach MaxSize or MaxAge,
+// and on date change. Parts are only visible once committed, every stored part is complete:
+// a crash lose at most the events of the last MaxAge, never corrupt a part.
+// Each Write is kept in one part, write whole records. Parts are numbered after the parts already stored
+// for the day, processes writing concurrently need their own Prefix, e.g. with the host name.
+type RotatingWriter struct {
+	Store  IFile
+	Prefix string
+	// MaxSize roll the part before it exceed this many uncompressed bytes, DefaultRotateSize when 0
+	MaxSize int64
+	// MaxAge roll the part this long after its first write, DefaultRotateAge when 0
+	MaxAge time.Duration
+	// Ext is the extension of the parts, compressed with gzip when it ends with ".gz", DefaultRotateExt when empty
+	Ext string
+	// ContentType of the parts, "application/gzip" for compressed parts and "application/x-ndjson" otherwise when empty
+	ContentType string
+
+	ctx    context.Context
+	mu     sync.Mutex
+	closed bool
+	day    string
+	seq    int
+	part   *logPart
+	// err is the failure of a part rolled by its age, returned by the next Flush or Close
+	err error
+}
+
+// logPart is a part being written
+type logPart struct {
+	w     io.WriteCloser
+	gz    *gzip.Writer
+	size  int64
+	timer *time.Timer
+}
+
+// NewRotatingWriter create a writer archiving the log into parts under prefix of store. Parts are uploaded with ctx,
+// cancelling it discard the part being written. Stores implementing ObjectWriterCreator receive the part as it is written,
+// other stores receive it at once when rolled.
+//
+//	Example:
+//	w := file.NewRotatingWriter(ctx, store, "logs/audit/"+hostname+"/")
+//	defer w.Close()
+//	json.NewEncoder(w).Encode(event)
+func NewRotatingWriter(ctx context.Context, store IFile, prefix string) *RotatingWriter {
+	return &RotatingWriter{Store: store, Prefix: prefix, ctx: ctx}
+}
+
+// Write append p to the current part, rolling it first when p would exceed MaxSize or the date changed
+func (r *RotatingWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, ErrWriterClosed
+	}
+
+	day := time.Now().UTC().Format("2006/01/02")
+	if r.part != nil && (day != r.day || r.part.size > 0 && r.part.size+int64(len(p)) > r.maxSize()) {
+		if err := r.roll(); err != nil {
+			return 0, err
+		}
+	}
+	if r.part == nil {
+		if err := r.open(day); err != nil {
+			return 0, err
+		}
+	}
+
+	var w io.Writer = r.part.w
+	if r.part.gz != nil {
+		w = r.part.gz
+	}
+	n, err := w.Write(p)
+	r.part.size += int64(n)
+	if err != nil {
+		// the part failed, the next write start another one
+		r.part.timer.Stop()
+		r.part.w.Close()
+		r.part = nil
+	}
+	return n, err
+}
+
+// Flush commit the current part now, e.g. before a deploy. It also return the failure of a part rolled by its age.
+func (r *RotatingWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush()
+}
+
+// Close commit the current part, later writes fail with ErrWriterClosed
+func (r *RotatingWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.flush()
+}
+
+// flush roll the current part and return the first failure since the last flush, caller must hold mu
+func (r *RotatingWriter) flush() error {
+	err := r.roll()
+	if err == nil {
+		err = r.err
+	}
+	r.err = nil
+	return err
+}
+
+func (r *RotatingWriter) maxSize() int64 {
+	if r.MaxSize > 0 {
+		return r.MaxSize
+	}
+	return DefaultRotateSize
+}
+
+func (r *RotatingWriter) ext() string {
+	if r.Ext != "" {
+		return r.Ext
+	}
+	return DefaultRotateExt
+}
+
+// open start the next part of day, caller must hold mu
+func (r *RotatingWriter) open(day string) error {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if day != r.day {
+		seq, err := r.lastPart(ctx, day)
+		if err != nil {
+			return err
+		}
+		r.day, r.seq = day, seq
+	}
+
+	key := r.Prefix + path.Join(day, fmt.Sprintf("part-%04d%s", r.seq+1, r.ext()))
+	compress := strings.HasSuffix(r.ext(), ".gz")
+	contentType := r.ContentType
+	switch {
+	case contentType != "":
+	case compress:
+		// archives, stored as gzip files rather than gzip encoded ndjson
+		contentType = "application/gzip"
+	default:
+		contentType = "application/x-ndjson"
+	}
+
+	w, err := newObjectWriter(ctx, r.Store, key, contentType)
+	if err != nil {
+		return err
+	}
+	r.seq++
+
+	part := &logPart{w: w}
+	if compress {
+		part.gz = gzip.NewWriter(w)
+	}
+	maxAge := r.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultRotateAge
+	}
+	part.timer = time.AfterFunc(maxAge, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// the part may have been rolled already
+		if r.part == part {
+			if err := r.roll(); err != nil && r.err == nil {
+				r.err = err
+			}
+		}
+	})
+	r.part = part
+	return nil
+}
+
+// lastPart return the number of the last part stored for day
+func (r *RotatingWriter) lastPart(ctx context.Context, day string) (int, error) {
+	dir := r.Prefix + day + "/"
+	files, err := r.Store.List(ctx, dir)
+	if err != nil {
+		return 0, err
+	}
+	last := 0
+	for _, f := range files {
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(f.Name, dir), "part-%04d", &n); err == nil && n > last {
+			last = n
+		}
+	}
+	return last, nil
+}
+
+// roll commit the current part, caller must hold mu
+func (r *RotatingWriter) roll() error {
+	part := r.part
+	if part == nil {
+		return nil
+	}
+	r.part = nil
+	part.timer.Stop()
+	if part.gz != nil {
+		if err := part.gz.Close(); err != nil {
+			part.w.Close()
+			return err
+		}
+	}
+	return part.w.Close()
+}
+
+// newObjectWriter return a writer streaming into key when store implement ObjectWriterCreator,
+// buffering the content until Close otherwise
+func newObjectWriter(ctx context.Context, store IFile, key, contentType string) (io.WriteCloser, error) {
+	if creator, ok := store.(ObjectWriterCreator); ok {
+		return creator.NewObjectWriter(ctx, key, contentType)
+	}
+	return &bufferedObject{ctx: ctx, store: store, key: key, contentType: contentType}, nil
+}
+
+// bufferedObject upload its writes at once on Close, for stores unable to stream
+type bufferedObject struct {
+	ctx         context.Context
+	store       IFile
+	key         string
+	contentType string
+	buf         bytes.Buffer
+}
+
+func (b *bufferedObject) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+// Close upload the content unless ctx is done, like a stream cancelled before its commit
+func (b *bufferedObject) Close() error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	_, err := b.store.Upload(b.ctx, b.key, b.contentType, b.buf.Bytes())
+	return err
+}
@@ -0,0 +1,434 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// DefaultMaxLocations is the number of file locations a Router remember by default
+const DefaultMaxLocations = 10000
+
+// Route send uploads matching its content types and size range to Store
+type Route struct {
+	Name  string
+	Store IFile
+	// Prefixes are key prefixes held by Store whatever the content type and size, e.g. "videos/".
+	// Their files are found without a request, and their urls built from these rules only.
+	Prefixes []string
+	// ContentTypes are content type prefixes, e.g. "video/", any type when empty
+	ContentTypes []string
+	// MinSize and MaxSize bound the file size in bytes, MaxSize 0 is unbounded
+	MinSize int64
+	MaxSize int64
+}
+
+func (r Route) match(contentType string, size int64) bool {
+	if size < r.MinSize || (r.MaxSize > 0 && size > r.MaxSize) {
+		return false
+	}
+	return typeAllowed(contentType, r.ContentTypes)
+}
+
+func (r Route) hold(filePath string) bool {
+	for _, prefix := range r.Prefixes {
+		if strings.HasPrefix(filePath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Router is an IFile dispatching uploads to different stores by content type and size,
+// e.g. videos to a cheap container and images to the CDN container.
+// Routes are evaluated in order, keys under the Prefixes of a route go to its store first,
+// uploads matching none go to Default.
+//
+// Other operations go to the store holding the file: the one of its prefix rule, or else found by listing
+// each store once and remembered, up to MaxLocations files. GetBlobURL send no request, files outside the
+// prefix rules get the url of Default. Listings merge every store ordered by name, GetURL and GetContainer
+// are those of Default. Copy is done inside the store holding the source file.
+type Router struct {
+	Default IFile
+	Routes  []Route
+	// MaxLocations bound the remembered file locations, DefaultMaxLocations when 0
+	MaxLocations int
+
+	mu        sync.RWMutex
+	locations map[string]IFile
+}
+
+var _ IFile = &Router{}
+
+// NewRouter create router sending uploads to the first matching route, defaultStore otherwise
+//
+//	Example:
+//	store := file.NewRouter(cdn, file.Route{Name: "video", Store: archive, ContentTypes: []string{"video/"}})
+func NewRouter(defaultStore IFile, routes ...Route) *Router {
+	return &Router{Default: defaultStore, Routes: routes, locations: map[string]IFile{}}
+}
+
+// Route return the store an upload of contentType and size goes to, outside the prefix rules
+func (r *Router) Route(contentType string, size int64) IFile {
+	for _, route := range r.Routes {
+		if route.match(contentType, size) {
+			return route.Store
+		}
+	}
+	return r.Default
+}
+
+// routeKey return the store of the prefix rule holding filePath
+func (r *Router) routeKey(filePath string) (IFile, bool) {
+	for _, route := range r.Routes {
+		if route.hold(filePath) {
+			return route.Store, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Router) stores() []IFile {
+	stores := []IFile{r.Default}
+	for _, route := range r.Routes {
+		if !containsStore(stores, route.Store) {
+			stores = append(stores, route.Store)
+		}
+	}
+	return stores
+}
+
+func containsStore(stores []IFile, store IFile) bool {
+	for _, s := range stores {
+		if s == store {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) remember(filePath string, store IFile) {
+	if _, ok := r.routeKey(filePath); ok {
+		return
+	}
+	max := r.MaxLocations
+	if max <= 0 {
+		max = DefaultMaxLocations
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.locations == nil {
+		r.locations = map[string]IFile{}
+	}
+	if store == nil {
+		delete(r.locations, filePath)
+		return
+	}
+	if _, ok := r.locations[filePath]; !ok {
+		// forget arbitrary locations once full, they are found again by listing
+		for k := range r.locations {
+			if len(r.locations) < max {
+				break
+			}
+			delete(r.locations, k)
+		}
+	}
+	r.locations[filePath] = store
+}
+
+// locate return the store holding filePath, ErrNotFound when no store has it
+func (r *Router) locate(ctx context.Context, filePath string) (IFile, error) {
+	if store, ok := r.routeKey(filePath); ok {
+		return store, nil
+	}
+	r.mu.RLock()
+	store, ok := r.locations[filePath]
+	r.mu.RUnlock()
+	if ok {
+		return store, nil
+	}
+
+	for _, store := range r.stores() {
+		_, err := stat(ctx, store, filePath)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.remember(filePath, store)
+		return store, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Upload file to the store routed by content type and size
+func (r *Router) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	detected := contentType
+	if detected == "" {
+		detected = http.DetectContentType(buffBytes)
+	}
+	store, ok := r.routeKey(filePath)
+	if !ok {
+		store = r.Route(detected, int64(len(buffBytes)))
+	}
+
+	// an overwrite may move the file to another store
+	if previous, err := r.locate(ctx, filePath); err == nil && previous != store {
+		if _, err := previous.Delete(ctx, filePath); err != nil && err != ErrNotFound {
+			return "", err
+		}
+	}
+
+	url, err := store.Upload(ctx, filePath, contentType, buffBytes)
+	if err != nil {
+		return "", err
+	}
+	r.remember(filePath, store)
+	return url, nil
+}
+
+// Delete file from the store holding it
+func (r *Router) Delete(ctx context.Context, filePath string) (string, error) {
+	store, err := r.locate(ctx, filePath)
+	if err != nil {
+		return "", err
+	}
+	url, err := store.Delete(ctx, filePath)
+	r.remember(filePath, nil)
+	return url, err
+}
+
+// Download file from the store holding it
+func (r *Router) Download(ctx context.Context, filePath string) ([]byte, error) {
+	store, err := r.locate(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return store.Download(ctx, filePath)
+}
+
+// DownloadIfModified download file from the store holding it unless its ETag is knownETag
+func (r *Router) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	store, err := r.locate(ctx, filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return store.DownloadIfModified(ctx, filePath, knownETag)
+}
+
+// Copy file inside the store holding srcPath
+func (r *Router) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	store, err := r.locate(ctx, srcPath)
+	if err != nil {
+		return "", err
+	}
+	if previous, err := r.locate(ctx, dstPath); err == nil && previous != store {
+		if _, err := previous.Delete(ctx, dstPath); err != nil && err != ErrNotFound {
+			return "", err
+		}
+	}
+
+	url, err := store.Copy(ctx, srcPath, dstPath)
+	if err != nil {
+		return "", err
+	}
+	r.remember(dstPath, store)
+	return url, nil
+}
+
+// SetHeaders change headers of file in the store holding it
+func (r *Router) SetHeaders(ctx context.Context, filePath string, h Headers) error {
+	store, err := r.locate(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	return store.SetHeaders(ctx, filePath, h)
+}
+
+// GetBlobURL return file url in the store of its prefix rule, the Default store url outside the prefix rules.
+// No request is sent, see Route.Prefixes.
+func (r *Router) GetBlobURL(fileName string, withSignature bool) string {
+	store, ok := r.routeKey(fileName)
+	if !ok {
+		store = r.Default
+	}
+	return store.GetBlobURL(fileName, withSignature)
+}
+
+// GetFileName return file name of a url of any routed store
+func (r *Router) GetFileName(blobUrl string) string {
+	for _, store := range r.stores() {
+		if strings.HasPrefix(blobUrl, store.GetURL()+"/") {
+			return store.GetFileName(blobUrl)
+		}
+	}
+	return r.Default.GetFileName(blobUrl)
+}
+
+// GetURL return Default store url
+func (r *Router) GetURL() string {
+	return r.Default.GetURL()
+}
+
+// Unwrap return Default store, whose provider client the router expose
+func (r *Router) Unwrap() IFile {
+	return r.Default
+}
+
+// GetContainer return Default store container
+func (r *Router) GetContainer() (azblob.ContainerURL, error) {
+	return r.Default.GetContainer()
+}
+
+// GenerateSharedAccessSignature sign fileName with the Default store key
+func (r *Router) GenerateSharedAccessSignature(expiryTime string, fileName string) string {
+	return r.Default.GenerateSharedAccessSignature(expiryTime, fileName)
+}
+
+// PresignMany sign every key with the store holding it, keys not found are signed by Default
+func (r *Router) PresignMany(ctx context.Context, keys []string, expiry time.Duration) (map[string]string, error) {
+	byStore := map[IFile][]string{}
+	for _, key := range keys {
+		store, err := r.locate(ctx, key)
+		if err == ErrNotFound {
+			store = r.Default
+		} else if err != nil {
+			return nil, err
+		}
+		byStore[store] = append(byStore[store], key)
+	}
+
+	urls := make(map[string]string, len(keys))
+	for store, keys := range byStore {
+		signed, err := store.PresignMany(ctx, keys, expiry)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range signed {
+			urls[k] = v
+		}
+	}
+	return urls, nil
+}
+
+// GetListBlob return file names of every store ordered by name
+func (r *Router) GetListBlob(ctx context.Context, prefix string) ([]string, error) {
+	var list []string
+	for _, store := range r.stores() {
+		names, err := store.GetListBlob(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, names...)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// ListDir merge directory listing of every store
+func (r *Router) ListDir(ctx context.Context, prefix string) (DirList, error) {
+	var merged DirList
+	seen := map[string]bool{}
+	for _, store := range r.stores() {
+		dir, err := store.ListDir(ctx, prefix)
+		if err != nil {
+			return DirList{}, err
+		}
+		merged.Prefix = dir.Prefix
+		for _, d := range dir.Dirs {
+			if !seen[d] {
+				seen[d] = true
+				merged.Dirs = append(merged.Dirs, d)
+			}
+		}
+		merged.Files = append(merged.Files, dir.Files...)
+	}
+	sort.Strings(merged.Dirs)
+	sort.Slice(merged.Files, func(i, j int) bool { return merged.Files[i].Name < merged.Files[j].Name })
+	return merged, nil
+}
+
+// List merge listing of every store, options apply to the merged result
+func (r *Router) List(ctx context.Context, prefix string, opts ...ListOption) ([]ObjectInfo, error) {
+	var files []ObjectInfo
+	for _, store := range r.stores() {
+		// each store top n contain the merged top n
+		list, err := store.List(ctx, prefix, opts...)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, list...)
+	}
+	return ApplyListOptions(files, opts...), nil
+}
+
+// ListStream merge the streams of every store in name order
+func (r *Router) ListStream(ctx context.Context, prefix string, opts ...ListOption) <-chan ListItem {
+	return StreamList(ctx, func(fn func(ObjectInfo) error) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var (
+			streams []<-chan ListItem
+			heads   []*ObjectInfo
+		)
+		next := func(i int) error {
+			item, ok := <-streams[i]
+			if !ok {
+				heads[i] = nil
+				return nil
+			}
+			if item.Err != nil {
+				return item.Err
+			}
+			heads[i] = &item.Info
+			return nil
+		}
+
+		for i, store := range r.stores() {
+			streams = append(streams, store.ListStream(ctx, prefix, opts...))
+			heads = append(heads, nil)
+			if err := next(i); err != nil {
+				return err
+			}
+		}
+
+		for {
+			min := -1
+			for i, head := range heads {
+				if head != nil && (min < 0 || head.Name < heads[min].Name) {
+					min = i
+				}
+			}
+			if min < 0 {
+				return nil
+			}
+			if err := fn(*heads[min]); err != nil {
+				return err
+			}
+			if err := next(min); err != nil {
+				return err
+			}
+		}
+	}, opts...)
+}
+
+// Usage sum usage of every store
+func (r *Router) Usage(ctx context.Context, prefix string) (UsageReport, error) {
+	report := UsageReport{Prefix: prefix}
+	for _, store := range r.stores() {
+		usage, err := store.Usage(ctx, prefix)
+		if err != nil {
+			return UsageReport{}, err
+		}
+		report.Merge(usage)
+	}
+	return report, nil
+}
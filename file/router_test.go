@@ -0,0 +1,96 @@
+package file_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+// namedStore is a Memory with urls of its own, counting the listings locating files
+type namedStore struct {
+	*filetest.Memory
+	name  string
+	lists int32
+}
+
+func newNamedStore(name string) *namedStore {
+	return &namedStore{Memory: filetest.NewMemory(), name: name}
+}
+
+func (s *namedStore) List(ctx context.Context, prefix string, opts ...file.ListOption) ([]file.ObjectInfo, error) {
+	atomic.AddInt32(&s.lists, 1)
+	return s.Memory.List(ctx, prefix, opts...)
+}
+
+func (s *namedStore) GetBlobURL(fileName string, withSignature bool) string {
+	return "https://" + s.name + "/" + fileName
+}
+
+func TestRouterGetBlobURLFromPrefixRules(t *testing.T) {
+	ctx := context.Background()
+	cdn, archive := newNamedStore("cdn"), newNamedStore("archive")
+	r := file.NewRouter(cdn,
+		file.Route{Name: "video", Store: archive, ContentTypes: []string{"video/"}},
+		file.Route{Name: "exports", Store: archive, Prefixes: []string{"exports/"}},
+	)
+
+	// under a prefix rule whatever the content type
+	if _, err := r.Upload(ctx, "exports/report.png", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archive.Download(ctx, "exports/report.png"); err != nil {
+		t.Errorf("upload under exports/ not stored in archive: %v", err)
+	}
+	if _, err := r.Upload(ctx, "clips/intro.mp4", "video/mp4", []byte("mp4")); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&cdn.lists, 0)
+	atomic.StoreInt32(&archive.lists, 0)
+
+	tests := map[string]string{
+		"exports/report.png": "https://archive/exports/report.png",
+		// routed by content type, outside the prefix rules
+		"clips/intro.mp4": "https://cdn/clips/intro.mp4",
+		"missing.txt":     "https://cdn/missing.txt",
+	}
+	for key, want := range tests {
+		if got := r.GetBlobURL(key, false); got != want {
+			t.Errorf("GetBlobURL(%q) = %s, want %s", key, got, want)
+		}
+	}
+	if _, err := r.Download(ctx, "exports/report.png"); err != nil {
+		t.Errorf("Download under a prefix rule: %v", err)
+	}
+	if n := atomic.LoadInt32(&cdn.lists) + atomic.LoadInt32(&archive.lists); n != 0 {
+		t.Errorf("%d listings to resolve prefix rules and urls, want 0", n)
+	}
+}
+
+func TestRouterMaxLocations(t *testing.T) {
+	ctx := context.Background()
+	cdn, archive := newNamedStore("cdn"), newNamedStore("archive")
+	r := file.NewRouter(cdn, file.Route{Name: "video", Store: archive, ContentTypes: []string{"video/"}})
+	r.MaxLocations = 2
+
+	keys := []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4", "e.mp4"}
+	for _, key := range keys {
+		if _, err := r.Upload(ctx, key, "video/mp4", []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	atomic.StoreInt32(&cdn.lists, 0)
+	atomic.StoreInt32(&archive.lists, 0)
+	for _, key := range keys {
+		data, err := r.Download(ctx, key)
+		if err != nil || string(data) != key {
+			t.Errorf("Download(%q) = %q, %v", key, data, err)
+		}
+	}
+	// forgotten locations are found again by listing
+	if n := atomic.LoadInt32(&cdn.lists) + atomic.LoadInt32(&archive.lists); n == 0 {
+		t.Error("no listing after uploading more than MaxLocations files")
+	}
+}
@@ -0,0 +1,393 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+)
+
+var (
+	// ErrKeyNotFound returned by KeyStore when user has no data key
+	ErrKeyNotFound = errors.New("file: data key not found")
+	// ErrErased returned when downloading a file whose owner data key was erased
+	ErrErased = errors.New("file: data key erased")
+	// ErrNoDataOwner returned when the owner of an uploaded file is unknown
+	ErrNoDataOwner = errors.New("file: no data owner")
+	// ErrNotEncrypted returned when downloading a file not written by CryptoShredder
+	ErrNotEncrypted = errors.New("file: file is not encrypted")
+)
+
+// shredMagic prefix files encrypted by CryptoShredder
+var shredMagic = []byte("ASH1")
+
+const dataKeySize = 32
+
+// DataKey is a user encryption key, ID change whenever the key is recreated
+type DataKey struct {
+	ID  string
+	Key []byte
+}
+
+// KeyStore keep one data key per user
+type KeyStore interface {
+	// Get return the user data key, ErrKeyNotFound if there is none
+	Get(ctx context.Context, userID string) (DataKey, error)
+	// Create generate and store a new data key for the user. When another process created one first,
+	// the stored key is returned, files must only be sealed with it.
+	Create(ctx context.Context, userID string) (DataKey, error)
+	// Delete destroy the user data key
+	Delete(ctx context.Context, userID string) error
+}
+
+// NewDataKey generate random data key
+func NewDataKey() (DataKey, error) {
+	id := make([]byte, 8)
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(id); err != nil {
+		return DataKey{}, err
+	}
+	if _, err := rand.Read(key); err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{ID: hex.EncodeToString(id), Key: key}, nil
+}
+
+// CryptoShredder encrypt every file with a data key dedicated to its owner.
+// Erasing the owner key make all of the owner files unreadable without enumerating and deleting them.
+//
+// Listing and urls are passed through, they expose encrypted content and sizes.
+type CryptoShredder struct {
+	IFile
+	Keys KeyStore
+	// Owner return the user owning the file, the actor set by WithActor by default
+	Owner func(ctx context.Context, filePath string) string
+
+	mu sync.Mutex
+}
+
+// NewCryptoShredder wrap next encrypting files with per user data keys from keys
+//
+//	Example:
+//	store := file.NewCryptoShredder(store, file.NewMemoryKeyStore())
+//	url, err := store.Upload(file.WithActor(ctx, userID), "users/42/id-card.jpg", "", buffBytes)
+//	err = store.Erase(ctx, userID)
+func NewCryptoShredder(next IFile, keys KeyStore) *CryptoShredder {
+	return &CryptoShredder{
+		IFile: next,
+		Keys:  keys,
+		Owner: func(ctx context.Context, filePath string) string {
+			return ActorFromContext(ctx)
+		},
+	}
+}
+
+// Unwrap return the wrapped store
+func (s *CryptoShredder) Unwrap() IFile {
+	return s.IFile
+}
+
+// Upload encrypt buffBytes with the owner data key, creating the key on first upload
+func (s *CryptoShredder) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	owner := s.Owner(ctx, filePath)
+	if owner == "" {
+		return "", ErrNoDataOwner
+	}
+
+	key, err := s.ownerKey(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+
+	// detect on plain content, encrypted content is always octet-stream
+	if contentType == "" {
+		contentType = http.DetectContentType(buffBytes)
+	}
+
+	sealed, err := seal(key, owner, buffBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return s.IFile.Upload(ctx, filePath, contentType, sealed)
+}
+
+// Download decrypt file, return ErrErased if its owner key was erased
+func (s *CryptoShredder) Download(ctx context.Context, filePath string) ([]byte, error) {
+	sealed, err := s.IFile.Download(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.open(ctx, sealed)
+}
+
+// DownloadIfModified decrypt file unless its ETag is knownETag
+func (s *CryptoShredder) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	sealed, etag, err := s.IFile.DownloadIfModified(ctx, filePath, knownETag)
+	if err != nil {
+		return nil, etag, err
+	}
+
+	data, err := s.open(ctx, sealed)
+	return data, etag, err
+}
+
+// Erase destroy user data key, every file owned by the user become unreadable
+func (s *CryptoShredder) Erase(ctx context.Context, userID string) error {
+	return s.Keys.Delete(ctx, userID)
+}
+
+func (s *CryptoShredder) ownerKey(ctx context.Context, owner string) (DataKey, error) {
+	// serialize creation so concurrent first uploads share one key
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.Keys.Get(ctx, owner)
+	if err == ErrKeyNotFound {
+		return s.Keys.Create(ctx, owner)
+	}
+	return key, err
+}
+
+// seal encrypt data as magic | owner length | owner | key id length | key id | nonce | ciphertext,
+// the header is authenticated as additional data
+func seal(key DataKey, owner string, data []byte) ([]byte, error) {
+	// the header store the lengths on 2 and 1 bytes
+	if len(owner) > math.MaxUint16 {
+		return nil, fmt.Errorf("file: data owner longer than %d bytes", math.MaxUint16)
+	}
+	if len(key.ID) > math.MaxUint8 {
+		return nil, fmt.Errorf("file: data key id longer than %d bytes", math.MaxUint8)
+	}
+	gcm, err := newGCM(key.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.Write(shredMagic)
+	binary.Write(&header, binary.BigEndian, uint16(len(owner)))
+	header.WriteString(owner)
+	header.WriteByte(byte(len(key.ID)))
+	header.WriteString(key.ID)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(header.Bytes(), nonce...)
+	return gcm.Seal(out, nonce, data, header.Bytes()), nil
+}
+
+func (s *CryptoShredder) open(ctx context.Context, sealed []byte) ([]byte, error) {
+	owner, keyID, headerLen, err := parseShredHeader(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.Keys.Get(ctx, owner)
+	if err == ErrKeyNotFound || (err == nil && key.ID != keyID) {
+		return nil, ErrErased
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < headerLen+gcm.NonceSize() {
+		return nil, ErrNotEncrypted
+	}
+	nonce := sealed[headerLen : headerLen+gcm.NonceSize()]
+
+	return gcm.Open(nil, nonce, sealed[headerLen+gcm.NonceSize():], sealed[:headerLen])
+}
+
+func parseShredHeader(sealed []byte) (owner, keyID string, n int, err error) {
+	if !bytes.HasPrefix(sealed, shredMagic) || len(sealed) < len(shredMagic)+2 {
+		return "", "", 0, ErrNotEncrypted
+	}
+	n = len(shredMagic)
+
+	ownerLen := int(binary.BigEndian.Uint16(sealed[n:]))
+	n += 2
+	if len(sealed) < n+ownerLen+1 {
+		return "", "", 0, ErrNotEncrypted
+	}
+	owner = string(sealed[n : n+ownerLen])
+	n += ownerLen
+
+	idLen := int(sealed[n])
+	n++
+	if len(sealed) < n+idLen {
+		return "", "", 0, ErrNotEncrypted
+	}
+	keyID = string(sealed[n : n+idLen])
+	n += idLen
+
+	return owner, keyID, n, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// MemoryKeyStore keep data keys in memory, for tests and development
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]DataKey
+}
+
+// NewMemoryKeyStore create empty key store
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: map[string]DataKey{}}
+}
+
+// Get return user data key
+func (m *MemoryKeyStore) Get(ctx context.Context, userID string) (DataKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[userID]
+	if !ok {
+		return DataKey{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Create generate new data key for user, the existing one is returned when user has one
+func (m *MemoryKeyStore) Create(ctx context.Context, userID string) (DataKey, error) {
+	key, err := NewDataKey()
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.keys[userID]; ok {
+		return existing, nil
+	}
+	m.keys[userID] = key
+	return key, nil
+}
+
+// Delete destroy user data key
+func (m *MemoryKeyStore) Delete(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	delete(m.keys, userID)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// StoredKeyStore keep data keys as files in an IFile, each wrapped with a master key.
+// Deleting the key file erase the user data, keep the key container separate
+// from the data container and without soft delete or versioning.
+type StoredKeyStore struct {
+	Store  IFile
+	Prefix string
+
+	master []byte
+}
+
+// NewStoredKeyStore create key store writing keys under prefix of store, masterKey must be 32 bytes
+func NewStoredKeyStore(store IFile, prefix string, masterKey []byte) (*StoredKeyStore, error) {
+	if len(masterKey) != dataKeySize {
+		return nil, errors.New("file: master key must be 32 bytes")
+	}
+	return &StoredKeyStore{Store: store, Prefix: prefix, master: masterKey}, nil
+}
+
+func (k *StoredKeyStore) path(userID string) string {
+	return k.Prefix + hex.EncodeToString([]byte(userID))
+}
+
+// Get return user data key
+func (k *StoredKeyStore) Get(ctx context.Context, userID string) (DataKey, error) {
+	sealed, err := k.Store.Download(ctx, k.path(userID))
+	if err == ErrNotFound {
+		return DataKey{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	gcm, err := newGCM(k.master)
+	if err != nil {
+		return DataKey{}, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return DataKey{}, ErrNotEncrypted
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(userID))
+	if err != nil {
+		return DataKey{}, err
+	}
+	if len(plain) < dataKeySize {
+		return DataKey{}, ErrNotEncrypted
+	}
+
+	return DataKey{ID: string(plain[dataKeySize:]), Key: plain[:dataKeySize]}, nil
+}
+
+// Create generate new data key for user and store it wrapped with the master key.
+// Stores implementing CreateUploader, e.g. *File, create the key file only when absent, concurrent creations
+// by several instances all return the key stored first. Other stores are read back after the upload,
+// the last writer win.
+func (k *StoredKeyStore) Create(ctx context.Context, userID string) (DataKey, error) {
+	key, err := NewDataKey()
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	gcm, err := newGCM(k.master)
+	if err != nil {
+		return DataKey{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return DataKey{}, err
+	}
+	sealed := gcm.Seal(nonce, nonce, append(append([]byte(nil), key.Key...), key.ID...), []byte(userID))
+
+	if creator, ok := k.Store.(CreateUploader); ok {
+		_, err := creator.UploadIfAbsent(ctx, k.path(userID), "application/octet-stream", sealed)
+		if err == ErrExists {
+			return k.Get(ctx, userID)
+		}
+		if err != nil {
+			return DataKey{}, err
+		}
+		return key, nil
+	}
+	if _, err := k.Store.Upload(ctx, k.path(userID), "application/octet-stream", sealed); err != nil {
+		return DataKey{}, err
+	}
+	// seal only with the key stored, another instance may have written its own meanwhile
+	return k.Get(ctx, userID)
+}
+
+// Delete remove user data key file
+func (k *StoredKeyStore) Delete(ctx context.Context, userID string) error {
+	_, err := k.Store.Delete(ctx, k.path(userID))
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
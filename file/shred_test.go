@@ -0,0 +1,37 @@
+package file
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSealHeaderLengths(t *testing.T) {
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("id card")
+
+	owner := strings.Repeat("o", math.MaxUint16)
+	sealed, err := seal(key, owner, data)
+	if err != nil {
+		t.Fatalf("seal with a %d bytes owner: %v", len(owner), err)
+	}
+	got, keyID, _, err := parseShredHeader(sealed)
+	if err != nil || got != owner || keyID != key.ID {
+		t.Errorf("parseShredHeader = %d bytes owner, %q, %v", len(got), keyID, err)
+	}
+
+	if _, err := seal(key, owner+"o", data); err == nil {
+		t.Errorf("seal with a %d bytes owner succeeded", len(owner)+1)
+	}
+	long := DataKey{ID: strings.Repeat("k", math.MaxUint8+1), Key: key.Key}
+	if sealed, err := seal(long, "42", data); err == nil {
+		t.Errorf("seal with a %d bytes key id = %d bytes", len(long.ID), len(sealed))
+	}
+	if sealed, _ := seal(key, "42", data); !bytes.HasPrefix(sealed, shredMagic) {
+		t.Error("sealed content without magic")
+	}
+}
@@ -0,0 +1,201 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SidecarSuffix is appended to a file key to get its sidecar key by JSONSerializer
+const SidecarSuffix = ".meta.json"
+
+// AssetInfo describe a file for systems without catalog access
+type AssetInfo struct {
+	Key         string    `json:"key"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	MD5         string    `json:"md5"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// Renditions map a rendition name to its key, e.g. "thumb"
+	Renditions map[string]string `json:"renditions,omitempty"`
+	// Custom hold application fields
+	Custom map[string]interface{} `json:"custom,omitempty"`
+}
+
+// DescribeAsset return the info of content stored at key: size, checksums, and dimensions of gif, jpeg and png images
+func DescribeAsset(key, contentType string, data []byte) AssetInfo {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	sha := sha256.Sum256(data)
+	sum := md5.Sum(data)
+	info := AssetInfo{
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sha[:]),
+		MD5:         hex.EncodeToString(sum[:]),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	}
+	return info
+}
+
+// Serializer encode sidecar documents, implement it to write another schema or format
+type Serializer interface {
+	// Key return the sidecar key of file key
+	Key(key string) string
+	ContentType() string
+	Marshal(info AssetInfo) ([]byte, error)
+	Unmarshal(data []byte) (AssetInfo, error)
+}
+
+// JSONSerializer write AssetInfo as JSON at "<key>.meta.json"
+type JSONSerializer struct {
+	Indent bool
+}
+
+// Key return key + SidecarSuffix
+func (JSONSerializer) Key(key string) string {
+	return key + SidecarSuffix
+}
+
+// ContentType return application/json
+func (JSONSerializer) ContentType() string {
+	return "application/json"
+}
+
+// Marshal encode info as JSON
+func (s JSONSerializer) Marshal(info AssetInfo) ([]byte, error) {
+	if s.Indent {
+		return json.MarshalIndent(info, "", "  ")
+	}
+	return json.Marshal(info)
+}
+
+// Unmarshal decode JSON info
+func (JSONSerializer) Unmarshal(data []byte) (AssetInfo, error) {
+	var info AssetInfo
+	err := json.Unmarshal(data, &info)
+	return info, err
+}
+
+// Sidecars wrap an IFile writing a sidecar document next to every uploaded file,
+// removed with it on Delete and rewritten for the destination on Copy.
+// Sidecars are regular files and appear in listings.
+type Sidecars struct {
+	IFile
+	Serializer Serializer
+	// Describe add application fields to info before the sidecar is written, e.g. renditions or owner
+	Describe func(ctx context.Context, info *AssetInfo, data []byte) error
+}
+
+// NewSidecars wrap next writing sidecars with serializer, JSONSerializer when nil
+//
+//	Example:
+//	store := file.NewSidecars(store, nil)
+//	info, err := store.ReadSidecar(ctx, "products/42.jpg")
+func NewSidecars(next IFile, serializer Serializer) *Sidecars {
+	if serializer == nil {
+		serializer = JSONSerializer{}
+	}
+	return &Sidecars{IFile: next, Serializer: serializer}
+}
+
+// Unwrap return the wrapped store
+func (s *Sidecars) Unwrap() IFile {
+	return s.IFile
+}
+
+func (s *Sidecars) isSidecar(key string) bool {
+	return strings.HasSuffix(key, s.Serializer.Key(""))
+}
+
+// Upload file then its sidecar
+func (s *Sidecars) Upload(ctx context.Context, filePath, contentType string, buffBytes []byte) (string, error) {
+	url, err := s.IFile.Upload(ctx, filePath, contentType, buffBytes)
+	if err != nil || s.isSidecar(filePath) {
+		return url, err
+	}
+
+	info := DescribeAsset(filePath, contentType, buffBytes)
+	if s.Describe != nil {
+		if err := s.Describe(ctx, &info, buffBytes); err != nil {
+			return "", err
+		}
+	}
+	if err := s.WriteSidecar(ctx, info); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// Delete file then its sidecar
+func (s *Sidecars) Delete(ctx context.Context, filePath string) (string, error) {
+	url, err := s.IFile.Delete(ctx, filePath)
+	if err != nil || s.isSidecar(filePath) {
+		return url, err
+	}
+	if _, err := s.IFile.Delete(ctx, s.Serializer.Key(filePath)); err != nil && err != ErrNotFound {
+		return "", err
+	}
+	return url, nil
+}
+
+// Copy file, and its sidecar with the destination key
+func (s *Sidecars) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
+	url, err := s.IFile.Copy(ctx, srcPath, dstPath)
+	if err != nil || s.isSidecar(srcPath) {
+		return url, err
+	}
+
+	info, err := s.ReadSidecar(ctx, srcPath)
+	if err == ErrNotFound {
+		return url, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	info.Key = dstPath
+	info.UpdatedAt = time.Now().UTC()
+	if err := s.WriteSidecar(ctx, info); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// WriteSidecar write the sidecar of info.Key, e.g. after adding renditions
+func (s *Sidecars) WriteSidecar(ctx context.Context, info AssetInfo) error {
+	data, err := s.Serializer.Marshal(info)
+	if err != nil {
+		return err
+	}
+	_, err = s.IFile.Upload(ctx, s.Serializer.Key(info.Key), s.Serializer.ContentType(), data)
+	return err
+}
+
+// ReadSidecar return the sidecar of key, ErrNotFound when it has none
+func (s *Sidecars) ReadSidecar(ctx context.Context, key string) (AssetInfo, error) {
+	data, err := s.IFile.Download(ctx, s.Serializer.Key(key))
+	if err != nil {
+		return AssetInfo{}, err
+	}
+	return s.Serializer.Unmarshal(data)
+}
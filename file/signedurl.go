@@ -0,0 +1,222 @@
+package file
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signed url providers
+const (
+	SignedAzure = "azure-sas"
+	SignedS3    = "s3"
+)
+
+var (
+	// ErrNotSigned returned when parsing a url carrying neither a SAS nor an S3 signature
+	ErrNotSigned = errors.New("file: url is not signed")
+	// ErrSignatureMismatch returned when a url was not signed with the client key, e.g. after a key rotation
+	ErrSignatureMismatch = errors.New("file: signature does not match")
+)
+
+// SignedURL is what a SAS or S3 presigned url grant, parsed without contacting storage
+type SignedURL struct {
+	Provider string
+	Host     string
+	// Container is the container or the bucket, Key the file, empty for container or account signatures
+	Container string
+	Key       string
+	// Permissions are SAS permission letters, e.g. "r" or "racwd", or the S3 http method allowed
+	Permissions string
+	// Resource is the SAS signed resource, "b" blob, "c" container, empty for account SAS and S3
+	Resource string
+	Start    time.Time
+	Expiry   time.Time
+	// Version is the SAS storage version or the S3 signature algorithm
+	Version string
+	// KeyID identify the signing key: the stored access policy, the user delegation key object
+	// or the S3 access key id
+	KeyID string
+	// KeyVersion is the user delegation key version of the SAS or the S3 credential scope date
+	KeyVersion string
+	// IP and Protocol are SAS restrictions, e.g. "168.1.5.60-168.1.5.70" and "https"
+	IP       string
+	Protocol string
+	// Signature is the raw signature
+	Signature string
+}
+
+// ParseSignedURL parse an Azure SAS url or an S3 presigned url, signature version 2 or 4
+//
+//	Example:
+//	s, err := file.ParseSignedURL(link)
+//	for _, p := range s.Problems(time.Now(), "r") {
+//		fmt.Println(p)
+//	}
+func ParseSignedURL(raw string) (SignedURL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return SignedURL{}, err
+	}
+	q := u.Query()
+	s := SignedURL{Host: u.Host}
+
+	switch {
+	case q.Get("sig") != "":
+		s.Provider = SignedAzure
+		s.Container, s.Key = splitPath(strings.TrimPrefix(u.Path, "/"))
+		s.Permissions = q.Get("sp")
+		s.Resource = q.Get("sr")
+		s.Version = q.Get("sv")
+		s.IP = q.Get("sip")
+		s.Protocol = q.Get("spr")
+		s.Signature = q.Get("sig")
+		s.KeyID = q.Get("si")
+		if oid := q.Get("skoid"); oid != "" {
+			s.KeyID = oid
+		}
+		s.KeyVersion = q.Get("skv")
+		s.Start, _ = parseSASTime(q.Get("st"))
+		if s.Expiry, err = parseSASTime(q.Get("se")); err != nil {
+			return s, fmt.Errorf("file: invalid sas expiry %q", q.Get("se"))
+		}
+
+	case q.Get("X-Amz-Signature") != "":
+		s.Provider = SignedS3
+		s.Container, s.Key = s3Path(u)
+		s.Version = q.Get("X-Amz-Algorithm")
+		s.Signature = q.Get("X-Amz-Signature")
+		s.Permissions = "GET"
+		// credential is <access key id>/<date>/<region>/s3/aws4_request
+		credential := strings.Split(q.Get("X-Amz-Credential"), "/")
+		s.KeyID = credential[0]
+		if len(credential) > 1 {
+			s.KeyVersion = credential[1]
+		}
+		if s.Start, err = time.Parse("20060102T150405Z", q.Get("X-Amz-Date")); err != nil {
+			return s, fmt.Errorf("file: invalid X-Amz-Date %q", q.Get("X-Amz-Date"))
+		}
+		expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+		if err != nil {
+			return s, fmt.Errorf("file: invalid X-Amz-Expires %q", q.Get("X-Amz-Expires"))
+		}
+		s.Expiry = s.Start.Add(time.Duration(expires) * time.Second)
+
+	case q.Get("Signature") != "" && q.Get("AWSAccessKeyId") != "":
+		s.Provider = SignedS3
+		s.Container, s.Key = s3Path(u)
+		s.Version = "AWS"
+		s.Signature = q.Get("Signature")
+		s.Permissions = "GET"
+		s.KeyID = q.Get("AWSAccessKeyId")
+		expires, err := strconv.ParseInt(q.Get("Expires"), 10, 64)
+		if err != nil {
+			return s, fmt.Errorf("file: invalid Expires %q", q.Get("Expires"))
+		}
+		s.Expiry = time.Unix(expires, 0).UTC()
+
+	default:
+		return s, ErrNotSigned
+	}
+	return s, nil
+}
+
+// parseSASTime parse the ISO 8601 forms accepted by SAS: date, minutes, seconds and fractions
+func parseSASTime(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("file: invalid time %q", v)
+}
+
+func splitPath(p string) (string, string) {
+	parts := strings.SplitN(p, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// s3Path return bucket and key of virtual hosted style and path style urls
+func s3Path(u *url.URL) (string, string) {
+	p := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(u.Host, ".s3"); i > 0 {
+		return u.Host[:i], p
+	}
+	return splitPath(p)
+}
+
+// Expired report whether the url is past its expiry at now
+func (s SignedURL) Expired(now time.Time) bool {
+	return !s.Expiry.IsZero() && now.After(s.Expiry)
+}
+
+// Problems explain why a request at now needing the SAS permission letters want may be refused,
+// e.g. "r" for a download, with the restrictions to check on the client side such as ip and protocol.
+// Empty when nothing looks wrong, the signature itself is not checked.
+func (s SignedURL) Problems(now time.Time, want string) []string {
+	var problems []string
+	if s.Expired(now) {
+		problems = append(problems, fmt.Sprintf("expired %s ago at %s", now.Sub(s.Expiry).Round(time.Second), s.Expiry.Format(time.RFC3339)))
+	}
+	if !s.Start.IsZero() && now.Before(s.Start) && s.Provider == SignedAzure {
+		problems = append(problems, fmt.Sprintf("not valid before %s, check the clock of the signer", s.Start.Format(time.RFC3339)))
+	}
+	if s.Provider == SignedS3 && s.Version == "AWS4-HMAC-SHA256" && s.Expiry.Sub(s.Start) > 7*24*time.Hour {
+		problems = append(problems, "signature version 4 urls can't be valid for more than 7 days")
+	}
+	if s.Provider == SignedAzure {
+		for _, p := range want {
+			if !strings.ContainsRune(s.Permissions, p) {
+				problems = append(problems, fmt.Sprintf("permission %q not granted, granted %q", p, s.Permissions))
+			}
+		}
+		if s.Resource == "b" && s.Key == "" {
+			problems = append(problems, "blob signature without blob name")
+		}
+		if s.IP != "" {
+			problems = append(problems, "restricted to client ip "+s.IP)
+		}
+		if s.Protocol == "https" {
+			problems = append(problems, "restricted to https")
+		}
+		if s.KeyVersion != "" {
+			problems = append(problems, "signed with a user delegation key, refused once the key expire or is revoked")
+		}
+	}
+	if _, err := base64.StdEncoding.DecodeString(s.Signature); err != nil && s.Provider == SignedAzure {
+		problems = append(problems, "signature is not valid base64, the url may have been double decoded")
+	}
+	return problems
+}
+
+// VerifySignedURL check raw was signed by this client key, for read urls returned by GetBlobURL and PresignMany.
+// Return ErrSignatureMismatch when another key signed it, ErrNotSupported for other SAS forms.
+func (c *File) VerifySignedURL(raw string) error {
+	s, err := ParseSignedURL(raw)
+	if err != nil {
+		return err
+	}
+	if s.Provider != SignedAzure || s.Permissions != Permission || s.Resource != ResourceType || !s.Start.IsZero() || s.KeyID != "" || s.IP != "" || s.Protocol != "" {
+		return ErrNotSupported
+	}
+	if s.Container != c.ContainerName {
+		return ErrSignatureMismatch
+	}
+
+	u, _ := url.Parse(raw)
+	accessKey, err := base64.StdEncoding.DecodeString(c.AccessKey)
+	if err != nil {
+		return err
+	}
+	if c.sign(accessKey, u.Query().Get("se"), s.Key) != s.Signature || s.Version != c.APIVersion {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errSnappyCorrupt returned for snappy blocks that can't be decoded
+var errSnappyCorrupt = errors.New("file: corrupt snappy block")
+
+// maxSnappyBlock is the largest decoded snappy block accepted, ORC and Parquet compress pages and chunks far smaller
+const maxSnappyBlock = 1 << 30
+
+// snappyDecode decode a snappy block, the raw format without framing used by ORC and Parquet
+func snappyDecode(src []byte) ([]byte, error) {
+	n, l := binary.Uvarint(src)
+	if l <= 0 || n > maxSnappyBlock {
+		return nil, errSnappyCorrupt
+	}
+	src = src[l:]
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			// literal, lengths over 60 bytes follow the tag as 1 to 4 little endian bytes
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errSnappyCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if length <= 0 || len(src) < length || uint64(len(dst)+length) > n {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errSnappyCorrupt
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+
+		// copies may overlap what they produce
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, errSnappyCorrupt
+		}
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if uint64(len(dst)) != n {
+		return nil, errSnappyCorrupt
+	}
+	return dst, nil
+}
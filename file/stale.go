@@ -0,0 +1,228 @@
+package file
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultRevalidateTimeout bound the background refresh of stale content by Cached
+const DefaultRevalidateTimeout = time.Minute
+
+// CachePolicy is how long Cached serve its content, like the max-age, stale-while-revalidate and stale-if-error
+// directives of HTTP caches. The zero value serve cached content until invalidated by a write through Cached.
+type CachePolicy struct {
+	// MaxAge is how long cached content is served without asking storage, forever when 0
+	MaxAge time.Duration
+	// StaleWhileRevalidate serve content stale for less than it and refresh it in the background
+	StaleWhileRevalidate time.Duration
+	// StaleIfError serve content stale for less than it when refreshing fail with a retryable error, see Classify
+	StaleIfError time.Duration
+}
+
+// WithCachePolicy return context whose downloads through Cached follow policy instead of Cached.Policy
+//
+//	Example:
+//	ctx = file.WithCachePolicy(ctx, file.CachePolicy{MaxAge: time.Minute, StaleIfError: time.Hour})
+//	data, err := cached.Download(ctx, "thumbs/42.jpg")
+func WithCachePolicy(ctx context.Context, policy CachePolicy) context.Context {
+	return context.WithValue(ctx, cachePolicyKey, policy)
+}
+
+// CachePolicyFromContext return the policy set by WithCachePolicy
+func CachePolicyFromContext(ctx context.Context) (CachePolicy, bool) {
+	policy, ok := ctx.Value(cachePolicyKey).(CachePolicy)
+	return policy, ok
+}
+
+// CacheEntry is cached content with its ETag and when it was stored
+type CacheEntry struct {
+	Data   []byte
+	ETag   string
+	Stored time.Time
+}
+
+// EntryCache is a Cache keeping the ETag and store time of contents, e.g. DiskCache keep them across runs.
+// Cached keep them in memory for other caches.
+type EntryCache interface {
+	Cache
+	GetEntry(key string) (CacheEntry, bool)
+	SetEntry(key string, e CacheEntry)
+}
+
+// cacheMeta is when content was cached, and its ETag when known
+type cacheMeta struct {
+	stored time.Time
+	etag   string
+}
+
+func (c *Cached) policy(ctx context.Context) CachePolicy {
+	if policy, ok := CachePolicyFromContext(ctx); ok {
+		return policy
+	}
+	return c.Policy
+}
+
+// cached return the cached entry of key, whether it is cached and whether it is fresh
+func (c *Cached) cached(ctx context.Context, key string) (CacheEntry, bool, bool) {
+	var (
+		e  CacheEntry
+		ok bool
+	)
+	if ec, isEntryCache := c.Cache.(EntryCache); isEntryCache {
+		e, ok = ec.GetEntry(key)
+	} else {
+		e.Data, ok = c.Cache.Get(key)
+		c.mu.Lock()
+		meta := c.meta[key]
+		if !ok {
+			// evicted
+			delete(c.meta, key)
+		}
+		c.mu.Unlock()
+		e.ETag, e.Stored = meta.etag, meta.stored
+	}
+	policy := c.policy(ctx)
+	fresh := e.Stored.IsZero() || policy.MaxAge <= 0 || time.Since(e.Stored) <= policy.MaxAge
+	return e, ok, fresh
+}
+
+// serveStale report whether entry e is served instead of waiting for or failing a refresh with err
+func (c *Cached) serveStale(ctx context.Context, e CacheEntry, err error) bool {
+	policy := c.policy(ctx)
+	age := time.Since(e.Stored) - policy.MaxAge
+	if err == nil {
+		return age <= policy.StaleWhileRevalidate
+	}
+	return age <= policy.StaleIfError && Classify(err).Retryable()
+}
+
+// set cache data of key with its ETag
+func (c *Cached) set(key string, data []byte, etag string) {
+	e := CacheEntry{Data: data, ETag: etag, Stored: time.Now()}
+	if ec, ok := c.Cache.(EntryCache); ok {
+		ec.SetEntry(key, e)
+		return
+	}
+	c.Cache.Set(key, data)
+	c.mu.Lock()
+	if c.meta == nil {
+		c.meta = map[string]cacheMeta{}
+	}
+	c.meta[key] = cacheMeta{stored: e.Stored, etag: etag}
+	c.mu.Unlock()
+}
+
+// invalidate the cached content of key
+func (c *Cached) invalidate(key string) {
+	c.Cache.Delete(key)
+	c.mu.Lock()
+	delete(c.meta, key)
+	c.writes++
+	c.mu.Unlock()
+}
+
+// generation return the number of writes through c, refreshes started before a write don't cache their content
+func (c *Cached) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+// refresh download key unless it still has the ETag of the cached entry e, and cache it
+func (c *Cached) refresh(ctx context.Context, key string, e CacheEntry) ([]byte, string, error) {
+	gen := c.generation()
+	data, etag, err := c.IFile.DownloadIfModified(ctx, key, e.ETag)
+	if err == ErrNotModified && e.ETag != "" {
+		data, etag, err = e.Data, e.ETag, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if c.generation() == gen {
+		c.set(key, data, etag)
+	}
+	return data, etag, nil
+}
+
+// revalidate refresh key in the background, once at a time, the values of ctx are kept but not its cancellation
+func (c *Cached) revalidate(ctx context.Context, key string, e CacheEntry) {
+	c.mu.Lock()
+	if c.revalidating == nil {
+		c.revalidating = map[string]bool{}
+	}
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(detachedContext{ctx}, DefaultRevalidateTimeout)
+		defer cancel()
+		c.refresh(ctx, key, e)
+		c.mu.Lock()
+		delete(c.revalidating, key)
+		c.mu.Unlock()
+	}()
+}
+
+// load return the content of key from the cache following the policy of ctx, or from storage.
+// Misses are downloaded with Download when withETag is false, keeping the parallel block downloads of File.
+func (c *Cached) load(ctx context.Context, key string, withETag bool) ([]byte, string, error) {
+	e, ok, fresh := c.cached(ctx, key)
+	switch {
+	case !ok && withETag:
+		return c.refresh(ctx, key, CacheEntry{})
+	case !ok:
+		gen := c.generation()
+		data, err := c.IFile.Download(ctx, key)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.generation() == gen {
+			c.set(key, data, "")
+		}
+		return data, "", nil
+	case fresh:
+		return e.Data, e.ETag, nil
+	case c.serveStale(ctx, e, nil):
+		c.revalidate(ctx, key, e)
+		return e.Data, e.ETag, nil
+	}
+	latest, etag, err := c.refresh(ctx, key, e)
+	if err != nil && c.serveStale(ctx, e, err) {
+		return e.Data, e.ETag, nil
+	}
+	return latest, etag, err
+}
+
+// DownloadIfModified return the content of file from the cache unless its ETag is knownETag, see Download.
+// Content cached by Download has no known ETag and is returned whatever knownETag.
+func (c *Cached) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	data, etag, err := c.load(ctx, filePath, true)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag != "" && etag == knownETag {
+		return nil, etag, ErrNotModified
+	}
+	return data, etag, nil
+}
+
+// detachedContext keep the values of a request context without its cancellation, for work outliving the request
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,201 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultStallWindow is how long a transfer can stay below the minimum throughput by default
+const DefaultStallWindow = 30 * time.Second
+
+// ErrStalled is wrapped by StallError, test it with errors.Is(err, file.ErrStalled)
+var ErrStalled = errors.New("file: transfer stalled")
+
+// StallError returned when a transfer was aborted because its throughput stayed below the minimum for a whole window,
+// e.g. a dead TCP connection the OS would only give up after minutes. It is a timeout, retrying can succeed.
+type StallError struct {
+	// Op is "upload" or "download"
+	Op  string
+	URL string
+	// Bytes were transferred during the last Window
+	Bytes         int64
+	Window        time.Duration
+	MinThroughput int64
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("file: %s of %s stalled, %d bytes in %s below %d bytes/s", e.Op, e.URL, e.Bytes, e.Window, e.MinThroughput)
+}
+
+// Unwrap return ErrStalled
+func (e *StallError) Unwrap() error {
+	return ErrStalled
+}
+
+// Timeout report true, so retry policies handling net.Error retry stalled transfers
+func (e *StallError) Timeout() bool {
+	return true
+}
+
+// Temporary report true, see Timeout
+func (e *StallError) Temporary() bool {
+	return true
+}
+
+// NewStallTransport wrap next so request and response bodies transferred slower than minThroughput bytes/s for window
+// are aborted with a *StallError. The wait for the response headers is not measured, bound it with ResponseHeaderTimeout.
+// DefaultStallWindow is used when window is 0. NewHTTPClient add it when TransportConfig.MinThroughput is set.
+//
+//	Example:
+//	client := &http.Client{Transport: file.NewStallTransport(http.DefaultTransport, 16<<10, 20*time.Second)}
+func NewStallTransport(next http.RoundTripper, minThroughput int64, window time.Duration) http.RoundTripper {
+	if window <= 0 {
+		window = DefaultStallWindow
+	}
+	return &stallTransport{next: next, min: minThroughput, window: window}
+}
+
+type stallTransport struct {
+	next   http.RoundTripper
+	min    int64
+	window time.Duration
+}
+
+func (t *stallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	w := &stallWatch{min: t.min, window: t.window, url: req.URL.Host + req.URL.Path, cancel: cancel, done: make(chan struct{})}
+	go w.run()
+
+	req = req.WithContext(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &stallBody{ReadCloser: req.Body, w: w, upload: true}
+		w.start("upload")
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		w.stop()
+		if serr := w.stalled(); serr != nil {
+			return nil, serr
+		}
+		return nil, err
+	}
+	resp.Body = &stallBody{ReadCloser: resp.Body, w: w}
+	w.start("download")
+	return resp, nil
+}
+
+// stallWatch measure the throughput of the body being transferred and cancel the request when it stall
+type stallWatch struct {
+	min    int64
+	window time.Duration
+	url    string
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	op       string
+	since    time.Time
+	bytes    int64
+	err      *StallError
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (w *stallWatch) run() {
+	ticker := time.NewTicker(w.window / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			if w.check(now) {
+				w.cancel()
+				return
+			}
+		}
+	}
+}
+
+// check start a new window once the current one is over, it report whether the transfer stalled
+func (w *stallWatch) check(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.op == "" || now.Sub(w.since) < w.window {
+		return false
+	}
+	if float64(w.bytes) < float64(w.min)*now.Sub(w.since).Seconds() {
+		w.err = &StallError{Op: w.op, URL: w.url, Bytes: w.bytes, Window: now.Sub(w.since), MinThroughput: w.min}
+		return true
+	}
+	w.since, w.bytes = now, 0
+	return false
+}
+
+// start measuring a body transfer
+func (w *stallWatch) start(op string) {
+	w.mu.Lock()
+	w.op, w.since, w.bytes = op, time.Now(), 0
+	w.mu.Unlock()
+}
+
+// pause measuring, e.g. while the server process an uploaded body
+func (w *stallWatch) pause() {
+	w.mu.Lock()
+	w.op = ""
+	w.mu.Unlock()
+}
+
+func (w *stallWatch) add(n int) {
+	w.mu.Lock()
+	w.bytes += int64(n)
+	w.mu.Unlock()
+}
+
+func (w *stallWatch) stalled() *StallError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// stop the watch and release the request context
+func (w *stallWatch) stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.cancel()
+	})
+}
+
+// stallBody count the bytes of a body for its watch and report a stall instead of the cancellation it caused
+type stallBody struct {
+	io.ReadCloser
+	w      *stallWatch
+	upload bool
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.w.add(n)
+	switch {
+	case err == io.EOF && b.upload:
+		// the response headers are not measured
+		b.w.pause()
+	case err != nil && err != io.EOF:
+		if serr := b.w.stalled(); serr != nil {
+			err = serr
+		}
+	}
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.upload {
+		b.w.stop()
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const (
+	// defaultStreamBlockSize is the block size of UploadStream when BlockSize is not set
+	defaultStreamBlockSize = 4 << 20
+	// maxInt is the largest content held in a slice
+	maxInt = int64(^uint(0) >> 1)
+)
+
+// uploadBlockSize return want grown so that size bytes fit in BlockBlobMaxBlocks blocks,
+// 0 when want is 0 to let azblob choose. Return ErrTooLarge for content larger than a block blob can hold.
+func uploadBlockSize(size, want int64) (int64, error) {
+	if size > azblob.BlockBlobMaxStageBlockBytes*azblob.BlockBlobMaxBlocks {
+		return 0, ErrTooLarge
+	}
+	if want <= 0 {
+		return 0, nil
+	}
+	if least := (size + azblob.BlockBlobMaxBlocks - 1) / azblob.BlockBlobMaxBlocks; want < least {
+		want = least
+	}
+	if want > azblob.BlockBlobMaxStageBlockBytes {
+		want = azblob.BlockBlobMaxStageBlockBytes
+	}
+	return want, nil
+}
+
+// StreamUploader is implemented by stores uploading from a reader without holding the content in memory, e.g. *File
+type StreamUploader interface {
+	UploadStream(ctx context.Context, filePath, contentType string, r io.Reader, size int64) (string, error)
+}
+
+// errShortContent end a stream shorter than its declared size. azblob commit streams ending with io.EOF
+// or io.ErrUnexpectedEOF, UploadStream return io.ErrUnexpectedEOF once the upload is aborted.
+var errShortContent = errors.New("file: content shorter than declared size")
+
+// limitedReader fail with ErrTooLarge as soon as more than n bytes are read,
+// and with errShortContent when exact is set and r end before n bytes
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exact    bool
+	exceeded bool
+	short    bool
+}
+
+// SizeLimitReader return a reader failing with ErrTooLarge as soon as more than max bytes are read from r,
+// instead of silently truncating like io.LimitReader
+func SizeLimitReader(r io.Reader, max int64) io.Reader {
+	return &limitedReader{r: r, n: max}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrTooLarge
+	}
+	// read one byte past the limit to detect oversize content, l.n+1 would overflow for unlimited readers
+	if int64(len(p))-1 > l.n {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.exceeded = true
+		return int(l.n), ErrTooLarge
+	}
+	l.n -= int64(n)
+	if err == io.EOF && l.exact && l.n > 0 {
+		l.short = true
+		return n, errShortContent
+	}
+	return n, err
+}
+
+// UploadStream upload content read from r without holding it in memory, in blocks of BlockSize.
+// size is the declared content length, -1 when unknown. Content larger than size or MaxUploadSize
+// is rejected with ErrTooLarge: upfront when declared, otherwise as soon as the limit is crossed,
+// so the oversize content is never committed. Content ending before size return io.ErrUnexpectedEOF
+// and is not committed either. The content is compressed while streamed like Upload with WithCompression.
+//
+//	Example:
+//	url, err := file.UploadStream(ctx, "video/intro.mp4", "video/mp4", r.Body, r.ContentLength)
+func (c *File) UploadStream(ctx context.Context, filePath, contentType string, r io.Reader, size int64) (string, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return "", err
+	}
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+
+	if size < -1 {
+		return "", fmt.Errorf("file: invalid upload size %d", size)
+	}
+	limit := c.MaxUploadSize
+	if size >= 0 {
+		if limit > 0 && size > limit {
+			return "", ErrTooLarge
+		}
+		limit = size
+	}
+	var lr *limitedReader
+	if size >= 0 || limit > 0 {
+		lr = &limitedReader{r: r, n: limit, exact: size >= 0}
+		r = lr
+	}
+
+	if contentType == "" {
+		br := bufio.NewReaderSize(r, 512)
+		head, err := br.Peek(512)
+		if lr != nil && lr.short {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		contentType = http.DetectContentType(head)
+		r = br
+	}
+
+	if c.DryRun {
+		n, err := io.Copy(ioutil.Discard, r)
+		if lr != nil && lr.short {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		c.dryRun(ctx, Event{Type: EventCreated, Key: filePath, ContentType: contentType, Size: n})
+		return c.GetBlobURL(filePath, false), nil
+	}
+	if err := validateTags(TagsFromContext(ctx)); err != nil {
+		return "", err
+	}
+	headers := azblob.BlobHTTPHeaders{ContentType: contentType}
+	encoder, encoding, err := contentEncoder(c.uploadEncoding(ctx))
+	if err != nil {
+		return "", err
+	}
+	if encoder != nil {
+		er := encodeReader(r, encoder)
+		// stop the compression when the upload fail before reading everything
+		defer er.Close()
+		r, headers.ContentEncoding = er, encoding
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", err
+	}
+
+	blockSize := c.BlockSize
+	if blockSize <= 0 {
+		blockSize = defaultStreamBlockSize
+	}
+	// streams of unknown size are limited to BlockBlobMaxBlocks blocks of blockSize
+	if size >= 0 {
+		bound := size
+		if encoder != nil {
+			// incompressible content grow a little when compressed
+			bound += size/1000 + 1024
+		}
+		if blockSize, err = uploadBlockSize(bound, blockSize); err != nil {
+			return "", err
+		}
+	}
+	maxBuffers := int(c.Parallelism)
+	if maxBuffers <= 0 {
+		maxBuffers = 1
+	}
+
+	_, err = azblob.UploadStreamToBlockBlob(ctx, r, containerURL.NewBlockBlobURL(filePath), azblob.UploadStreamToBlockBlobOptions{
+		BufferSize:      int(blockSize),
+		MaxBuffers:      maxBuffers,
+		BlobHTTPHeaders: headers,
+		Metadata:        contextMetadata(ctx),
+	})
+	if lr != nil && lr.exceeded {
+		return "", ErrTooLarge
+	}
+	if lr != nil && lr.short {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if tags := TagsFromContext(ctx); len(tags) > 0 {
+		if err := c.SetTags(ctx, filePath, tags); err != nil {
+			return "", err
+		}
+	}
+
+	return c.GetBlobURL(filePath, false), nil
+}
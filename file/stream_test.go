@@ -0,0 +1,266 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const (
+	gib = int64(1) << 30
+	// maxBlockBlob is the largest content a block blob hold
+	maxBlockBlob = azblob.BlockBlobMaxStageBlockBytes * azblob.BlockBlobMaxBlocks
+)
+
+// zeroReader is a synthetic stream of n bytes that never touch the read buffer, n < 0 is endless
+type zeroReader struct {
+	n int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.n == 0 {
+		return 0, io.EOF
+	}
+	if z.n > 0 && int64(len(p)) > z.n {
+		p = p[:z.n]
+	}
+	if z.n > 0 {
+		z.n -= int64(len(p))
+	}
+	return len(p), nil
+}
+
+// readAll read r to the end with a large buffer, return the bytes read and the first error other than io.EOF
+func readAll(r io.Reader) (int64, error) {
+	buf := make([]byte, 4<<20)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func TestUploadBlockSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		size, want int64
+		expect     int64
+		err        error
+	}{
+		{"empty, azblob choose", 0, 0, 0, nil},
+		{"empty, requested", 0, 4 << 20, 4 << 20, nil},
+		{"just over 5GiB, azblob choose", 5*gib + 1, 0, 0, nil},
+		{"just over 5GiB, small blocks grown", 5*gib + 1, 64 << 10, (5*gib + 1 + azblob.BlockBlobMaxBlocks - 1) / azblob.BlockBlobMaxBlocks, nil},
+		{"just over 5GiB, large blocks kept", 5*gib + 1, 8 << 20, 8 << 20, nil},
+		{"requested over the block limit", 1 << 20, 2 * azblob.BlockBlobMaxStageBlockBytes, azblob.BlockBlobMaxStageBlockBytes, nil},
+		{"largest block blob", maxBlockBlob, 1, azblob.BlockBlobMaxStageBlockBytes, nil},
+		{"over the largest block blob", maxBlockBlob + 1, 1, 0, ErrTooLarge},
+		{"max int64", math.MaxInt64, 0, 0, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		got, err := uploadBlockSize(tt.size, tt.want)
+		if got != tt.expect || err != tt.err {
+			t.Errorf("%s: uploadBlockSize(%d, %d) = %d, %v, want %d, %v", tt.name, tt.size, tt.want, got, err, tt.expect, tt.err)
+		}
+	}
+}
+
+func TestUploadBlockSizeProperties(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		size := rnd.Int63n(maxBlockBlob + 1)
+		if i%2 == 0 {
+			// around 5GiB, the single Put limit of other providers
+			size = 5*gib + rnd.Int63n(1<<20) - 1<<19
+		}
+		want := rnd.Int63n(2*azblob.BlockBlobMaxStageBlockBytes) + 1
+
+		got, err := uploadBlockSize(size, want)
+		if err != nil {
+			t.Fatalf("uploadBlockSize(%d, %d) = %v", size, want, err)
+		}
+		if got > azblob.BlockBlobMaxStageBlockBytes {
+			t.Fatalf("uploadBlockSize(%d, %d) = %d, over the block limit", size, want, got)
+		}
+		if got*azblob.BlockBlobMaxBlocks < size {
+			t.Fatalf("uploadBlockSize(%d, %d) = %d, %d bytes don't fit in %d blocks", size, want, got, size, azblob.BlockBlobMaxBlocks)
+		}
+		if got < want && want <= azblob.BlockBlobMaxStageBlockBytes {
+			t.Fatalf("uploadBlockSize(%d, %d) = %d, smaller than requested", size, want, got)
+		}
+	}
+}
+
+func TestSizeLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content int64
+		max     int64
+		read    int64
+		err     error
+	}{
+		{"empty, no limit", 0, 0, 0, nil},
+		{"one byte over a zero limit", 1, 0, 0, ErrTooLarge},
+		{"exactly 5GiB", 5 * gib, 5 * gib, 5 * gib, nil},
+		{"one byte over 5GiB", 5*gib + 1, 5 * gib, 5 * gib, ErrTooLarge},
+		{"5GiB+1 under the limit", 5*gib + 1, 6 * gib, 5*gib + 1, nil},
+		{"unlimited", 5*gib + 1, math.MaxInt64, 5*gib + 1, nil},
+	}
+	for _, tt := range tests {
+		read, err := readAll(SizeLimitReader(&zeroReader{n: tt.content}, tt.max))
+		if read != tt.read || err != tt.err {
+			t.Errorf("%s: read %d, %v, want %d, %v", tt.name, read, err, tt.read, tt.err)
+		}
+	}
+
+	// the error stick once the limit is crossed
+	r := SizeLimitReader(&zeroReader{n: -1}, 10)
+	readAll(r)
+	if n, err := r.Read(make([]byte, 1)); n != 0 || err != ErrTooLarge {
+		t.Errorf("Read after the limit = %d, %v, want 0, ErrTooLarge", n, err)
+	}
+}
+
+func TestUploadStreamOver5GiB(t *testing.T) {
+	ctx := context.Background()
+	c := &File{Account: "account", RootURL: "https://%s.blob.core.windows.net/%s", ContainerName: "container", DryRun: true}
+
+	// declared larger than MaxUploadSize, rejected before reading
+	c.MaxUploadSize = 5 * gib
+	src := &zeroReader{n: 5*gib + 1}
+	if _, err := c.UploadStream(ctx, "big.bin", "application/octet-stream", src, 5*gib+1); err != ErrTooLarge {
+		t.Fatalf("UploadStream declared over the limit = %v, want ErrTooLarge", err)
+	}
+	if src.n != 5*gib+1 {
+		t.Errorf("UploadStream read %d bytes of content rejected upfront", 5*gib+1-src.n)
+	}
+
+	// more content than declared
+	c.MaxUploadSize = 0
+	if _, err := c.UploadStream(ctx, "big.bin", "application/octet-stream", &zeroReader{n: 5*gib + 1}, 5*gib); err != ErrTooLarge {
+		t.Errorf("UploadStream over the declared size = %v, want ErrTooLarge", err)
+	}
+
+	// unknown size under MaxUploadSize
+	c.MaxUploadSize = 6 * gib
+	if _, err := c.UploadStream(ctx, "big.bin", "application/octet-stream", &zeroReader{n: 5*gib + 1}, -1); err != nil {
+		t.Errorf("UploadStream of 5GiB+1 of unknown size = %v", err)
+	}
+}
+
+func TestDownloadEmpty(t *testing.T) {
+	var gets int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+		w.Header().Set("Content-Length", "0")
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	for _, blockSize := range []int64{0, 4 << 20} {
+		c := NewPublic("account", srv.URL+"/%s/%s", "container").(*File)
+		c.BlockSize = blockSize
+		atomic.StoreInt32(&gets, 0)
+
+		data, err := c.Download(context.Background(), "empty.txt")
+		if err != nil || data == nil || len(data) != 0 {
+			t.Fatalf("BlockSize %d: Download = %q, %v, want empty content", blockSize, data, err)
+		}
+		// block downloads stop at the properties of empty files
+		if blockSize > 0 && atomic.LoadInt32(&gets) != 0 {
+			t.Errorf("BlockSize %d: %d GET requests for an empty file, want 0", blockSize, gets)
+		}
+	}
+}
+
+func TestUploadStreamShort(t *testing.T) {
+	var commits, puts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		switch r.URL.Query().Get("comp") {
+		case "blocklist":
+			atomic.AddInt32(&commits, 1)
+		case "":
+			atomic.AddInt32(&puts, 1)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	for _, tt := range []struct {
+		contentType string
+		blockSize   int64
+		n           int64
+	}{
+		{"", 0, 100},
+		{"application/octet-stream", 0, 100},
+		// staged blocks are left uncommitted
+		{"application/octet-stream", 1 << 10, 3 << 10},
+	} {
+		c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12").(*File)
+		c.BlockSize = tt.blockSize
+		_, err := c.UploadStream(context.Background(), "scan.bin", tt.contentType, &zeroReader{n: tt.n}, tt.n+1)
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("UploadStream of %d bytes declared %d = %v, want io.ErrUnexpectedEOF", tt.n, tt.n+1, err)
+		}
+	}
+	if commits != 0 || puts != 0 {
+		t.Errorf("short streams committed: %d block lists, %d blobs", commits, puts)
+	}
+}
+
+func TestUploadStreamCompressed(t *testing.T) {
+	content := bytes.Repeat([]byte("orders,2020-06\n"), 200)
+	var header http.Header
+	var stored []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		stored, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c := New("account", key, srv.URL+"/%s/%s", "container", "2019-12-12", WithCompression("gzip")).(*File)
+	if _, err := c.UploadStream(context.Background(), "lake/orders.csv", "text/csv", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-ms-blob-content-encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := ioutil.ReadAll(gz)
+	if err != nil || !bytes.Equal(plain, content) {
+		t.Errorf("stored content decode to %d bytes, %v, want the %d uploaded", len(plain), err, len(content))
+	}
+
+	c.Compression = "br"
+	if _, err := c.UploadStream(context.Background(), "lake/orders.csv", "text/csv", bytes.NewReader(content), int64(len(content))); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("UploadStream with an unregistered encoding = %v, want ErrUnsupportedEncoding", err)
+	}
+}
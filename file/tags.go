@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// TagsAPIVersion is the storage API version used for blob index tag requests
+const TagsAPIVersion = "2019-12-12"
+
+// ErrInvalidTag returned when a tag key or value use characters blob index tags don't allow
+var ErrInvalidTag = errors.New("file: invalid tag")
+
+// ListByTag return names of files whose blob index tag key equal value, ordered by name.
+// The query is evaluated by storage across the container without listing it,
+// tags are indexed asynchronously so a file tagged just now may be missing.
+//
+//	Example:
+//	quarantined, err := file.ListByTag(ctx, "status", "quarantined")
+func (c *File) ListByTag(ctx context.Context, key, value string) ([]string, error) {
+	if err := validateTag(key, value); err != nil {
+		return nil, err
+	}
+	return c.FindByTags(ctx, fmt.Sprintf(`"%s" = '%s'`, key, value))
+}
+
+// FindByTags return names of files matching a blob index tag expression, ordered by name,
+// e.g. `"status" = 'quarantined' AND "tenant" = '42'`
+func (c *File) FindByTags(ctx context.Context, where string) ([]string, error) {
+	accountURL := strings.TrimSuffix(c.GetURL(), "/"+c.ContainerName)
+	where = fmt.Sprintf("@container = '%s' AND %s", c.ContainerName, where)
+
+	var (
+		names  []string
+		marker string
+	)
+	for {
+		u, err := url.Parse(accountURL)
+		if err != nil {
+			return nil, err
+		}
+		q := url.Values{}
+		q.Set("comp", "blobs")
+		q.Set("where", where)
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		// storage expect spaces of the expression encoded as %20
+		u.RawQuery = strings.Replace(q.Encode(), "+", "%20", -1)
+
+		req, err := pipeline.NewRequest(http.MethodGet, *u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.doTags(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range result.Blobs {
+			names = append(names, b.Name)
+		}
+		if result.NextMarker == "" {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Tags are the blob index tags of a file, up to 10 per file
+type Tags map[string]string
+
+// WithTags return context setting tags on files uploaded with it.
+// Tags are set by a second request once the content is uploaded.
+//
+//	Example:
+//	url, err := file.Upload(file.WithTags(ctx, file.Tags{"status": "quarantined"}), "upload/42.pdf", "application/pdf", buffBytes)
+func WithTags(ctx context.Context, tags Tags) context.Context {
+	return context.WithValue(ctx, tagsKey, tags)
+}
+
+// TagsFromContext return tags set by WithTags, nil if none
+func TagsFromContext(ctx context.Context) Tags {
+	tags, _ := ctx.Value(tagsKey).(Tags)
+	return tags
+}
+
+type tagSet struct {
+	XMLName xml.Name `xml:"Tags"`
+	Tags    []tag    `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// SetTags replace the blob index tags of file, tags not given are removed
+//
+//	Example:
+//	err := file.SetTags(ctx, "upload/42.pdf", file.Tags{"status": "clean"})
+func (c *File) SetTags(ctx context.Context, filePath string, tags Tags) error {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return err
+	}
+	if c.Anonymous {
+		return ErrReadOnly
+	}
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventUpdated, Key: filePath})
+		return nil
+	}
+
+	set := tagSet{Tags: []tag{}}
+	for k, v := range tags {
+		set.Tags = append(set.Tags, tag{Key: k, Value: v})
+	}
+	sort.Slice(set.Tags, func(i, j int) bool { return set.Tags[i].Key < set.Tags[j].Key })
+	body, err := xml.Marshal(set)
+	if err != nil {
+		return err
+	}
+
+	u, err := c.tagsURL(filePath)
+	if err != nil {
+		return err
+	}
+	req, err := pipeline.NewRequest(http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := c.doTags(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetTags return the blob index tags of file
+func (c *File) GetTags(ctx context.Context, filePath string) (Tags, error) {
+	filePath, err := c.key(filePath)
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.tagsURL(filePath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := pipeline.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doTags(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var set tagSet
+	if err := xml.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	tags := make(Tags, len(set.Tags))
+	for _, t := range set.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+func (c *File) tagsURL(filePath string) (url.URL, error) {
+	u, err := url.Parse(c.GetBlobURL(filePath, false))
+	if err != nil {
+		return url.URL{}, err
+	}
+	u.RawQuery = "comp=tags"
+	return *u, nil
+}
+
+// doTags send a blob index tag request, the response body must be closed by the caller when err is nil
+func (c *File) doTags(ctx context.Context, req pipeline.Request) (*http.Response, error) {
+	p, _, err := c.storagePipeline()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", TagsAPIVersion)
+
+	resp, err := p.Do(ctx, nil, req)
+	if err != nil {
+		return nil, err
+	}
+
+	r := resp.Response()
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		r.Body.Close()
+		if r.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("file: tags %s: %s", req.URL.Path, r.Status)
+	}
+	return r, nil
+}
+
+func validateTags(tags Tags) error {
+	for k, v := range tags {
+		if err := validateTag(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTag check key and value use the characters allowed by blob index tags:
+// letters, digits, space and + - . / : = _, keys up to 128 and values up to 256 characters
+func validateTag(key, value string) error {
+	if key == "" || len(key) > 128 || len(value) > 256 {
+		return ErrInvalidTag
+	}
+	for _, s := range []string{key, value} {
+		for _, r := range s {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(" +-./:=_", r)) {
+				return ErrInvalidTag
+			}
+		}
+	}
+	return nil
+}
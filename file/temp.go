@@ -0,0 +1,96 @@
+package file
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// DownloadToTemp download file into a new uniquely named temporary file of dir, os.TempDir when dir is empty.
+// The local file keep the extension of filePath, call cleanup to remove it once done.
+//
+//	Example:
+//	localPath, cleanup, err := file.DownloadToTemp(ctx, store, "report/2020.pdf", "")
+//	if err != nil {
+//		return err
+//	}
+//	defer cleanup()
+func DownloadToTemp(ctx context.Context, store IFile, filePath, dir string) (localPath string, cleanup func(), err error) {
+	data, err := store.Download(ctx, filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return writeTemp(dir, filePath, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+// DownloadToTemp stream file into a temporary file of TempDir without buffering it in memory, see DownloadToTemp.
+// Keys are checked and normalized and content decoded like Download, decoded content is streamed
+// without parallel block downloads.
+func (c *File) DownloadToTemp(ctx context.Context, filePath string) (localPath string, cleanup func(), err error) {
+	filePath, err = c.key(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.Decompress && !isRawContent(ctx) {
+		return writeTemp(c.TempDir, filePath, func(f *os.File) error {
+			resp, err := c.openBlob(ctx, filePath)
+			if err != nil {
+				return err
+			}
+			body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: 3})
+			defer body.Close()
+			r, err := decodeReader(resp.ContentEncoding(), body)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			_, err = io.Copy(f, r)
+			return err
+		})
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", nil, err
+	}
+	blobURL := containerURL.NewBlobURL(filePath)
+
+	return writeTemp(c.TempDir, filePath, func(f *os.File) error {
+		err := azblob.DownloadBlobToFile(ctx, blobURL, 0, azblob.CountToEnd, f, azblob.DownloadFromBlobOptions{
+			BlockSize:   c.BlockSize,
+			Parallelism: c.Parallelism,
+		})
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+// writeTemp create temporary file named after filePath and fill it with write, the file is removed on failure
+func writeTemp(dir, filePath string, write func(f *os.File) error) (string, func(), error) {
+	f, err := ioutil.TempFile(dir, "assets-*"+path.Ext(filePath))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return f.Name(), cleanup, nil
+}
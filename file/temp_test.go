@@ -0,0 +1,57 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileDownloadToTemp(t *testing.T) {
+	content := []byte(strings.Repeat("compressed report line\n", 100))
+	gz, err := EncodeContent("gzip", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(gz)))
+		w.Write(gz)
+	}))
+	defer srv.Close()
+
+	c := NewPublic("account", srv.URL+"/%s/%s", "container",
+		WithDecompression(), WithKeyNormalization(strings.ToLower)).(*File)
+	if c.TempDir, err = ioutil.TempDir("", "temp-test"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.TempDir)
+
+	localPath, cleanup, err := c.DownloadToTemp(context.Background(), "Reports/2020.TXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	data, err := ioutil.ReadFile(localPath)
+	if err != nil || string(data) != string(content) {
+		t.Errorf("DownloadToTemp content = %d bytes, %v, want the %d decoded bytes", len(data), err, len(content))
+	}
+	if len(paths) != 1 || paths[0] != "/account/container/reports/2020.txt" {
+		t.Errorf("requested %v, want the normalized key", paths)
+	}
+
+	if _, _, err := c.DownloadToTemp(context.Background(), "bad\x00key"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("DownloadToTemp of an invalid key = %v, want ErrInvalidKey", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("request sent for an invalid key")
+	}
+}
@@ -0,0 +1,120 @@
+package file
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Transport defaults used by NewHTTPClient for zero TransportConfig fields
+const (
+	DefaultDialTimeout         = 30 * time.Second
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConnsPerHost = 100
+	DefaultTLSMinVersion       = tls.VersionTLS12
+)
+
+// TransportConfig is the network configuration of storage requests.
+// Build a client with NewHTTPClient and pass it to WithHTTPClient, it is used the same way
+// by New, NewAzureQueue and UploadFromURL.
+type TransportConfig struct {
+	// Proxy is the proxy url, e.g. "http://proxy.corp:3128", HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used when empty
+	Proxy string
+	// CAFile and CAPEM add PEM encoded certificate authorities to the system pool, e.g. a corporate TLS inspection CA
+	CAFile string
+	CAPEM  []byte
+	// TLSMinVersion is the lowest accepted TLS version, DefaultTLSMinVersion when 0
+	TLSMinVersion uint16
+
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	// MaxConnsPerHost limit connections to storage, 0 is unlimited
+	MaxConnsPerHost int
+	// MinThroughput abort transfers slower than this many bytes/s for StallWindow with a *StallError, 0 never abort
+	MinThroughput int64
+	// StallWindow is DefaultStallWindow when 0
+	StallWindow time.Duration
+}
+
+// NewHTTPClient create http client from cfg
+//
+//	Example:
+//	client, err := file.NewHTTPClient(file.TransportConfig{Proxy: "http://proxy.corp:3128", CAFile: "/etc/ssl/corp-ca.pem"})
+//	f := file.New(account, accessKey, rootURL, containerName, apiVersion, file.WithHTTPClient(client))
+func NewHTTPClient(cfg TransportConfig) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: cfg.TLSMinVersion}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = DefaultTLSMinVersion
+	}
+
+	if cfg.CAFile != "" || len(cfg.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem := cfg.CAPEM
+		if cfg.CAFile != "" {
+			data, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			pem = append(append([]byte(nil), pem...), data...)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("file: no certificate found in CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+	idleTimeout := cfg.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleConnTimeout
+	}
+	idlePerHost := cfg.MaxIdleConnsPerHost
+	if idlePerHost <= 0 {
+		idlePerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       idleTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   idlePerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	if cfg.MinThroughput > 0 {
+		return &http.Client{Transport: NewStallTransport(transport, cfg.MinThroughput, cfg.StallWindow)}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
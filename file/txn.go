@@ -0,0 +1,198 @@
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrTxnDone returned when committing a transaction twice
+var ErrTxnDone = errors.New("file: transaction already committed")
+
+const (
+	txnUpload = "upload"
+	txnCopy   = "copy"
+	txnDelete = "delete"
+)
+
+// TxnError returned by Commit when a step fails, Rollback hold the errors of steps that could not be undone
+type TxnError struct {
+	Op       string
+	Path     string
+	Err      error
+	Rollback []error
+}
+
+func (e *TxnError) Error() string {
+	msg := fmt.Sprintf("file: transaction %s %s: %v", e.Op, e.Path, e.Err)
+	if len(e.Rollback) > 0 {
+		msg += fmt.Sprintf(" (rollback incomplete: %d errors, first: %v)", len(e.Rollback), e.Rollback[0])
+	}
+	return msg
+}
+
+// Unwrap return the error of the failed step
+func (e *TxnError) Unwrap() error {
+	return e.Err
+}
+
+type txnStep struct {
+	op          string
+	path        string
+	src         string
+	contentType string
+	data        []byte
+}
+
+// txnUndo restore path from backup, or delete path when it did not exist before the step
+type txnUndo struct {
+	path   string
+	backup string
+}
+
+// Txn group uploads, copies and deletes executed in order by Commit.
+// Before a step changes a file, the existing file is copied under TempPrefix, when a step fails
+// completed steps are rolled back best effort: created files are deleted, overwritten and deleted files restored.
+//
+// Rollback is not isolation, other clients can see intermediate state while the transaction runs.
+// Temporary copies are written under TempPrefix, begin transactions on the store beneath a Guard.
+type Txn struct {
+	ctx   context.Context
+	store IFile
+	steps []txnStep
+	done  bool
+}
+
+// NewTxn begin transaction on store
+//
+//	Example:
+//	urls, err := file.NewTxn(ctx, store).
+//		Upload("products/42/original.jpg", "image/jpeg", original).
+//		Upload("products/42/thumb.jpg", "image/jpeg", thumb).
+//		Delete("products/42/draft.jpg").
+//		Commit()
+func NewTxn(ctx context.Context, store IFile) *Txn {
+	return &Txn{ctx: ctx, store: store}
+}
+
+// Txn begin transaction on the container
+func (c *File) Txn(ctx context.Context) *Txn {
+	return NewTxn(ctx, c)
+}
+
+// Upload add file upload to the transaction
+func (t *Txn) Upload(filePath, contentType string, buffBytes []byte) *Txn {
+	t.steps = append(t.steps, txnStep{op: txnUpload, path: filePath, contentType: contentType, data: buffBytes})
+	return t
+}
+
+// Copy add file copy to the transaction
+func (t *Txn) Copy(srcPath, dstPath string) *Txn {
+	t.steps = append(t.steps, txnStep{op: txnCopy, path: dstPath, src: srcPath})
+	return t
+}
+
+// Delete add file delete to the transaction, deleting a missing file is not an error
+func (t *Txn) Delete(filePath string) *Txn {
+	t.steps = append(t.steps, txnStep{op: txnDelete, path: filePath})
+	return t
+}
+
+// Commit execute the steps in order and return the url of every step.
+// On failure completed steps are rolled back and *TxnError returned.
+func (t *Txn) Commit() ([]string, error) {
+	if t.done {
+		return nil, ErrTxnDone
+	}
+	t.done = true
+
+	id, err := txnID()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		urls  []string
+		undos []txnUndo
+	)
+	for i, step := range t.steps {
+		if err := t.ctx.Err(); err != nil {
+			return nil, t.fail(step, err, undos)
+		}
+
+		backup := TempPrefix + "txn/" + id + "/" + strconv.Itoa(i)
+		_, err := t.store.Copy(t.ctx, step.path, backup)
+		if isNotFound(err) {
+			backup = ""
+		} else if err != nil {
+			return nil, t.fail(step, err, undos)
+		}
+		// the failed step is undone too, it may have changed the file before failing
+		undos = append(undos, txnUndo{path: step.path, backup: backup})
+
+		url, err := t.run(step)
+		if err != nil {
+			return nil, t.fail(step, err, undos)
+		}
+		urls = append(urls, url)
+	}
+
+	for _, undo := range undos {
+		if undo.backup != "" {
+			t.store.Delete(t.ctx, undo.backup)
+		}
+	}
+
+	return urls, nil
+}
+
+func (t *Txn) run(step txnStep) (string, error) {
+	switch step.op {
+	case txnUpload:
+		return t.store.Upload(t.ctx, step.path, step.contentType, step.data)
+	case txnCopy:
+		return t.store.Copy(t.ctx, step.src, step.path)
+	default:
+		url, err := t.store.Delete(t.ctx, step.path)
+		if isNotFound(err) {
+			return t.store.GetBlobURL(step.path, false), nil
+		}
+		return url, err
+	}
+}
+
+func (t *Txn) fail(step txnStep, err error, undos []txnUndo) error {
+	txnErr := &TxnError{Op: step.op, Path: step.path, Err: err}
+
+	// the rollback must run even when the transaction context is canceled
+	ctx := context.Background()
+	for i := len(undos) - 1; i >= 0; i-- {
+		undo := undos[i]
+		if undo.backup == "" {
+			if _, err := t.store.Delete(ctx, undo.path); err != nil && !isNotFound(err) {
+				txnErr.Rollback = append(txnErr.Rollback, err)
+			}
+			continue
+		}
+
+		if _, err := t.store.Copy(ctx, undo.backup, undo.path); err != nil {
+			// keep the backup so the file can be restored by hand
+			txnErr.Rollback = append(txnErr.Rollback, fmt.Errorf("restore %s from %s: %v", undo.path, undo.backup, err))
+			continue
+		}
+		t.store.Delete(ctx, undo.backup)
+	}
+
+	return txnErr
+}
+
+func txnID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
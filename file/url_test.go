@@ -0,0 +1,69 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFile() *File {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	return New("account", key, "https://%s.blob.core.windows.net/%s", "container", "2019-12-12").(*File)
+}
+
+func TestGetBlobURLExpiryUTC(t *testing.T) {
+	// a local zone far from UTC, the literal Z of a local time would be hours off
+	local := time.Local
+	time.Local = time.FixedZone("UTC+7", 7*3600)
+	defer func() { time.Local = local }()
+
+	c := newTestFile()
+	url := c.GetBlobURL("products/42/thumb.jpg", true)
+	i := strings.Index(url, "se=")
+	if i < 0 {
+		t.Fatalf("GetBlobURL = %s, no expiry", url)
+	}
+	se := url[i+3:]
+	if j := strings.IndexByte(se, '&'); j >= 0 {
+		se = se[:j]
+	}
+	se = strings.Replace(se, "%3A", ":", -1)
+	expiry, err := time.Parse("2006-01-02T15:04:05Z", se)
+	if err != nil {
+		t.Fatalf("GetBlobURL expiry %q: %v", se, err)
+	}
+	if d := time.Until(expiry) - ExpireTime*time.Second; d < -time.Minute || d > time.Minute {
+		t.Errorf("GetBlobURL expiry %s is %s off ExpireTime, not UTC", se, d)
+	}
+}
+
+func BenchmarkGetBlobURL(b *testing.B) {
+	c := newTestFile()
+	for _, signed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("signed=%v", signed), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.GetBlobURL("products/42/thumb.jpg", signed)
+			}
+		})
+	}
+}
+
+func BenchmarkPresignMany(b *testing.B) {
+	c := newTestFile()
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("products/%d/thumb.jpg", i)
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.PresignMany(ctx, keys, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
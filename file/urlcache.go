@@ -0,0 +1,163 @@
+package file
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// sasSignatureSize is the length of a base64 HMAC-SHA256 signature
+const sasSignatureSize = 44
+
+// urlCache keep what url building derive from the client fields so GetBlobURL,
+// called for every file of a page render, don't format and decode them again,
+// and the container client so requests don't build a credential and pipeline each.
+// It is rebuilt when one of the fields it was built from changed.
+type urlCache struct {
+	rootURL    string
+	account    string
+	container  string
+	accessKey  string
+	anonymous  bool
+	httpClient *http.Client
+
+	base    string
+	key     []byte
+	signers sync.Pool
+
+	// pipeline share one credential and connection pool between requests, it is safe for concurrent use
+	pipelineOnce sync.Once
+	pipeline     pipeline.Pipeline
+	containerURL azblob.ContainerURL
+	pipelineErr  error
+}
+
+// signer reuse a keyed HMAC and the buffer of the string to sign
+type signer struct {
+	mac hash.Hash
+	buf []byte
+}
+
+func (c *File) urlCache() *urlCache {
+	if uc, ok := c.urls.Load().(*urlCache); ok && uc.rootURL == c.RootURL && uc.account == c.Account &&
+		uc.container == c.ContainerName && uc.accessKey == c.AccessKey &&
+		uc.anonymous == c.Anonymous && uc.httpClient == c.HTTPClient {
+		return uc
+	}
+
+	uc := &urlCache{
+		rootURL:    c.RootURL,
+		account:    c.Account,
+		container:  c.ContainerName,
+		accessKey:  c.AccessKey,
+		anonymous:  c.Anonymous,
+		httpClient: c.HTTPClient,
+		base:       fmt.Sprintf(c.RootURL, c.Account, c.ContainerName),
+	}
+	uc.key, _ = base64.StdEncoding.DecodeString(c.AccessKey)
+	key := uc.key
+	uc.signers.New = func() interface{} {
+		return &signer{mac: hmac.New(sha256.New, key), buf: make([]byte, 0, 256)}
+	}
+	c.urls.Store(uc)
+	return uc
+}
+
+// storagePipeline return the request pipeline and container url built once per urlCache
+func (c *File) storagePipeline() (pipeline.Pipeline, azblob.ContainerURL, error) {
+	uc := c.urlCache()
+	uc.pipelineOnce.Do(func() {
+		var credential azblob.Credential = azblob.NewAnonymousCredential()
+		if !c.Anonymous {
+			sharedKey, err := azblob.NewSharedKeyCredential(c.Account, c.AccessKey)
+			if err != nil {
+				uc.pipelineErr = err
+				return
+			}
+			credential = sharedKey
+		}
+
+		URL, err := url.Parse(uc.base)
+		if err != nil {
+			uc.pipelineErr = err
+			return
+		}
+		uc.pipeline = azblob.NewPipeline(credential, c.pipelineOptions())
+		uc.containerURL = azblob.NewContainerURL(*URL, uc.pipeline)
+	})
+	return uc.pipeline, uc.containerURL, uc.pipelineErr
+}
+
+// appendSignature append the base64 SAS signature granting read on fileName until expiryTime to dst
+func (c *File) appendSignature(dst, accessKey []byte, expiryTime, fileName string) []byte {
+	uc := c.urlCache()
+	var s *signer
+	if bytes.Equal(accessKey, uc.key) {
+		s = uc.signers.Get().(*signer)
+		defer uc.signers.Put(s)
+	} else {
+		s = &signer{mac: hmac.New(sha256.New, accessKey)}
+	}
+
+	// permissions, start, expiry, resource, identifier, version and the empty response headers
+	b := s.buf[:0]
+	b = append(b, Permission...)
+	b = append(b, "\n\n"...)
+	b = append(b, expiryTime...)
+	b = append(b, "\n/"...)
+	b = append(b, c.Account...)
+	b = append(b, '/')
+	b = append(b, c.ContainerName...)
+	b = append(b, '/')
+	b = append(b, fileName...)
+	b = append(b, "\n\n"...)
+	b = append(b, c.APIVersion...)
+	b = append(b, "\n\n\n\n\n"...)
+
+	s.mac.Reset()
+	s.mac.Write(b)
+	sum := s.mac.Sum(b[:0])
+	s.buf = b
+
+	n := len(dst)
+	if cap(dst)-n < sasSignatureSize {
+		grown := make([]byte, n, n+sasSignatureSize)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:n+sasSignatureSize]
+	base64.StdEncoding.Encode(dst[n:], sum)
+	return dst
+}
+
+// writeQueryEscaped write s escaped like url.QueryEscape
+func writeQueryEscaped(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		writeQueryByte(b, s[i])
+	}
+}
+
+func writeQueryByte(b *strings.Builder, ch byte) {
+	const hex = "0123456789ABCDEF"
+	switch {
+	case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9',
+		ch == '-', ch == '_', ch == '.', ch == '~':
+		b.WriteByte(ch)
+	case ch == ' ':
+		b.WriteByte('+')
+	default:
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&15])
+	}
+}
@@ -0,0 +1,66 @@
+package file
+
+import "context"
+
+// UsageReport is the storage consumed under a prefix
+type UsageReport struct {
+	Prefix        string
+	Objects       int64
+	Bytes         int64
+	ByContentType map[string]ContentTypeUsage
+}
+
+// ContentTypeUsage is the storage consumed by a single content type
+type ContentTypeUsage struct {
+	Objects int64
+	Bytes   int64
+}
+
+// Add count file into the report
+func (r *UsageReport) Add(info ObjectInfo) {
+	r.Objects++
+	r.Bytes += info.Size
+
+	if r.ByContentType == nil {
+		r.ByContentType = map[string]ContentTypeUsage{}
+	}
+	u := r.ByContentType[info.ContentType]
+	u.Objects++
+	u.Bytes += info.Size
+	r.ByContentType[info.ContentType] = u
+}
+
+// Merge add other report counts into the report
+func (r *UsageReport) Merge(other UsageReport) {
+	r.Objects += other.Objects
+	r.Bytes += other.Bytes
+
+	if r.ByContentType == nil {
+		r.ByContentType = map[string]ContentTypeUsage{}
+	}
+	for contentType, o := range other.ByContentType {
+		u := r.ByContentType[contentType]
+		u.Objects += o.Objects
+		u.Bytes += o.Bytes
+		r.ByContentType[contentType] = u
+	}
+}
+
+// Usage aggregate object count and total bytes under prefix, broken down by content type.
+// Files are counted by paginated listing, use it per tenant prefix to compute storage consumption.
+//
+//	Example:
+//	report, err := file.Usage(ctx, "tenant/42/")
+func (c *File) Usage(ctx context.Context, prefix string) (UsageReport, error) {
+	prefix = c.normalizedKey(prefix)
+	report := UsageReport{Prefix: prefix}
+	err := c.list(ctx, prefix, nil, func(info ObjectInfo) error {
+		report.Add(info)
+		return nil
+	})
+	if err != nil {
+		return UsageReport{}, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,191 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// ObjectVersion is the content a file had from LastModified until Until
+type ObjectVersion struct {
+	ObjectInfo
+	// VersionID identify the version, the snapshot time on Azure, empty for the base file
+	VersionID string
+	// Deleted report whether the version is soft deleted, RestoreVersion undelete it
+	Deleted bool
+	// Until is when the version was replaced or deleted, zero for the current content
+	Until time.Time
+}
+
+// VisibleAt report whether v was the content of its file at t
+func (v ObjectVersion) VisibleAt(t time.Time) bool {
+	return !v.LastModified.After(t) && (v.Until.IsZero() || t.Before(v.Until))
+}
+
+// VersionLister is implemented by stores keeping the history of files, e.g. *File
+type VersionLister interface {
+	ListVersions(ctx context.Context, prefix string) ([]ObjectVersion, error)
+}
+
+// ListAsOf return the files under prefix as they were at t, one version per file ordered by name,
+// from the first VersionLister of the store chain. It return ErrNotSupported when the chain keep no history.
+//
+//	Example:
+//	versions, err := file.ListAsOf(ctx, store, "tenants/42/", time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC))
+func ListAsOf(ctx context.Context, store IFile, prefix string, t time.Time) ([]ObjectVersion, error) {
+	for store != nil {
+		if l, ok := store.(VersionLister); ok {
+			versions, err := l.ListVersions(ctx, prefix)
+			if err != nil {
+				return nil, err
+			}
+			return VersionsAsOf(versions, t), nil
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, ErrNotSupported
+}
+
+// VersionsAsOf return the version of every file visible at t, ordered by name.
+// When versions overlap, e.g. a snapshot of unchanged content, the current or latest replaced one is kept.
+func VersionsAsOf(versions []ObjectVersion, t time.Time) []ObjectVersion {
+	visible := map[string]ObjectVersion{}
+	for _, v := range versions {
+		if !v.VisibleAt(t) {
+			continue
+		}
+		if prev, ok := visible[v.Name]; ok && !newerVersion(v, prev) {
+			continue
+		}
+		visible[v.Name] = v
+	}
+	result := make([]ObjectVersion, 0, len(visible))
+	for _, v := range visible {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// newerVersion report whether a replace b among the versions visible at the same time
+func newerVersion(a, b ObjectVersion) bool {
+	if !a.LastModified.Equal(b.LastModified) {
+		return a.LastModified.After(b.LastModified)
+	}
+	if a.Until.IsZero() || b.Until.IsZero() {
+		return a.Until.IsZero() && !b.Until.IsZero()
+	}
+	return a.Until.After(b.Until)
+}
+
+// ListVersions return every version of the files under prefix: the base files, their snapshots and the soft deleted ones.
+// Containers need blob soft delete, which keep a snapshot of the replaced content on every overwrite and the deleted files
+// for the retention days, older history is gone.
+func (c *File) ListVersions(ctx context.Context, prefix string) ([]ObjectVersion, error) {
+	prefix = c.normalizedKey(prefix)
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := listSegmentOptions(prefix)
+	opts.Details.Snapshots = true
+	opts.Details.Deleted = true
+	var versions []ObjectVersion
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		listBlob, err := containerURL.ListBlobsFlatSegment(ctx, marker, opts)
+		if err != nil {
+			return nil, err
+		}
+		marker = listBlob.NextMarker
+
+		for _, blobInfo := range listBlob.Segment.BlobItems {
+			v := ObjectVersion{ObjectInfo: objectInfo(blobInfo), VersionID: blobInfo.Snapshot, Deleted: blobInfo.Deleted}
+			switch {
+			case blobInfo.Snapshot != "":
+				// a snapshot hold the content of the time it was taken
+				if v.Until, err = time.Parse(time.RFC3339Nano, blobInfo.Snapshot); err != nil {
+					return nil, fmt.Errorf("file: snapshot %s of %s: %w", blobInfo.Snapshot, blobInfo.Name, err)
+				}
+			case blobInfo.Deleted && blobInfo.Properties.DeletedTime != nil:
+				v.Until = *blobInfo.Properties.DeletedTime
+			}
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// ListAsOf return the files under prefix as they were at t, see ListVersions for the history kept
+//
+//	Example:
+//	versions, err := file.ListAsOf(ctx, "tenants/42/", time.Now().Add(-2*time.Hour))
+//	for _, v := range versions {
+//		_, err = file.RestoreVersion(ctx, v)
+//	}
+func (c *File) ListAsOf(ctx context.Context, prefix string, t time.Time) ([]ObjectVersion, error) {
+	versions, err := c.ListVersions(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return VersionsAsOf(versions, t), nil
+}
+
+// RestoreVersion make v the current content of its file: soft deleted files are undeleted and snapshots copied over the base file.
+// Restoring the current content does nothing.
+func (c *File) RestoreVersion(ctx context.Context, v ObjectVersion) (string, error) {
+	if c.Anonymous {
+		return "", ErrReadOnly
+	}
+	if c.DryRun {
+		c.dryRun(ctx, Event{Type: EventCreated, Key: v.Name, Source: v.Name})
+		return c.GetBlobURL(v.Name, false), nil
+	}
+
+	containerURL, err := c.GetContainer()
+	if err != nil {
+		return "", err
+	}
+	blobURL := containerURL.NewBlobURL(v.Name)
+	if v.Deleted {
+		// undelete restore the base file and every soft deleted snapshot of it
+		if _, err := blobURL.Undelete(ctx); err != nil {
+			return "", err
+		}
+	}
+	if v.VersionID == "" {
+		return c.GetBlobURL(v.Name, false), nil
+	}
+
+	// the copy source is escaped like Copy
+	srcURL := containerURL.URL()
+	srcURL.RawPath = strings.TrimSuffix(srcURL.EscapedPath(), "/") + "/" + EscapeKey(v.Name)
+	srcURL.Path = strings.TrimSuffix(srcURL.Path, "/") + "/" + v.Name
+	query := srcURL.Query()
+	query.Set("snapshot", v.VersionID)
+	srcURL.RawQuery = query.Encode()
+
+	resp, err := blobURL.StartCopyFromURL(ctx, srcURL, azblob.Metadata{}, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{})
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	status, err := waitCopy(ctx, blobURL, resp.CopyStatus())
+	if err != nil {
+		return "", err
+	}
+	if status != azblob.CopyStatusSuccess {
+		return "", fmt.Errorf("file: restore %s of %s %s", v.VersionID, v.Name, status)
+	}
+	return c.GetBlobURL(v.Name, false), nil
+}
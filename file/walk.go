@@ -0,0 +1,238 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultWalkWorkers is the number of files processed at once by Walk
+const DefaultWalkWorkers = 8
+
+// ErrTooManyErrors returned in WalkError when Walk stopped after WalkOptions.MaxErrors failures
+var ErrTooManyErrors = errors.New("file: too many errors")
+
+// WalkFunc process one file, ctx is canceled when the walk is stopped
+type WalkFunc func(ctx context.Context, info ObjectInfo) error
+
+// WalkProgress is reported to WalkOptions.Checkpoint as files are processed
+type WalkProgress struct {
+	// Token resume the walk after every file processed so far, pass it as WalkOptions.ResumeToken
+	Token     string
+	Processed int
+	Failed    int
+}
+
+// WalkOptions configure Walk
+type WalkOptions struct {
+	// Workers is the number of files processed at once, DefaultWalkWorkers when 0
+	Workers int
+	// List filter the walked files, WithSort is ignored
+	List []ListOption
+	// ResumeToken continue a previous walk from its last checkpoint, see WithStartAfter:
+	// the files already walked are listed again and skipped
+	ResumeToken string
+	// MaxErrors stop the walk after that many failed files, 0 never stop
+	MaxErrors int
+	// Checkpoint is called every CheckpointEvery processed files and when the walk ends, e.g. to persist the token
+	Checkpoint      func(WalkProgress)
+	CheckpointEvery int
+	// Limiter adapt the number of files processed at once to throttling, up to Workers
+	Limiter *AdaptiveLimiter
+}
+
+// FileError is the failure of one walked file
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// WalkError returned by Walk when files failed or the walk was interrupted.
+// Failed files count as processed, Token resume after them and they have to be retried from Files.
+type WalkError struct {
+	Files []FileError
+	// Err is the listing, context or ErrTooManyErrors error that stopped the walk
+	Err   error
+	Token string
+}
+
+func (e *WalkError) Error() string {
+	if e.Err != nil && len(e.Files) > 0 {
+		return fmt.Sprintf("file: walk stopped: %v, %d files failed, first %v", e.Err, len(e.Files), e.Files[0])
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("file: walk stopped: %v", e.Err)
+	}
+	return fmt.Sprintf("file: walk %d files failed, first %v", len(e.Files), e.Files[0])
+}
+
+// Unwrap return the error that stopped the walk
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
+// Walk list files under prefix and dispatch them to a bounded pool of workers running fn.
+// Listing is streamed, memory stay bounded however many files are walked.
+// Progress is checkpointed with a resume token covering every file before it, so an
+// interrupted walk can be continued with WalkOptions.ResumeToken.
+//
+//	Example:
+//	err := file.Walk(ctx, store, "products/", func(ctx context.Context, info file.ObjectInfo) error {
+//		return retag(ctx, info)
+//	}, file.WalkOptions{Workers: 16, ResumeToken: saved, Checkpoint: save, CheckpointEvery: 1000})
+func Walk(ctx context.Context, store IFile, prefix string, fn WalkFunc, opts WalkOptions) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWalkWorkers
+	}
+
+	listOpts := opts.List
+	if opts.ResumeToken != "" {
+		after, err := decodeWalkToken(opts.ResumeToken)
+		if err != nil {
+			return err
+		}
+		listOpts = append(append([]ListOption(nil), listOpts...), WithStartAfter(after))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := &walkTracker{opts: opts, cancel: cancel, pending: map[int]string{}, token: opts.ResumeToken}
+
+	type walkJob struct {
+		seq  int
+		info ObjectInfo
+	}
+	jobs := make(chan walkJob)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if opts.Limiter == nil {
+					t.done(job.seq, job.info.Name, fn(ctx, job.info))
+					continue
+				}
+
+				if err := opts.Limiter.Acquire(ctx); err != nil {
+					t.done(job.seq, job.info.Name, err)
+					continue
+				}
+				err := fn(ctx, job.info)
+				opts.Limiter.Done(err)
+				t.done(job.seq, job.info.Name, err)
+			}
+		}()
+	}
+
+	var stopErr error
+	seq := 0
+dispatch:
+	for item := range store.ListStream(ctx, prefix, listOpts...) {
+		if item.Err != nil {
+			stopErr = item.Err
+			break
+		}
+
+		select {
+		case jobs <- walkJob{seq: seq, info: item.Info}:
+			seq++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.aborted {
+		stopErr = ErrTooManyErrors
+	} else if err := ctx.Err(); err != nil {
+		// canceled by the caller
+		stopErr = err
+	}
+
+	if opts.Checkpoint != nil {
+		opts.Checkpoint(t.progress())
+	}
+
+	if stopErr == nil && len(t.errs) == 0 {
+		return nil
+	}
+	return &WalkError{Files: t.errs, Err: stopErr, Token: t.token}
+}
+
+// Walk process files under prefix with a pool of workers, see Walk
+func (c *File) Walk(ctx context.Context, prefix string, fn WalkFunc, opts WalkOptions) error {
+	return Walk(ctx, c, prefix, fn, opts)
+}
+
+// walkTracker keep the resume token at the last file before which every file is processed
+type walkTracker struct {
+	opts   WalkOptions
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	next      int
+	pending   map[int]string
+	token     string
+	processed int
+	errs      []FileError
+	aborted   bool
+}
+
+func (t *walkTracker) done(seq int, name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.processed++
+	if err != nil {
+		t.errs = append(t.errs, FileError{Path: name, Err: err})
+		if t.opts.MaxErrors > 0 && len(t.errs) >= t.opts.MaxErrors && !t.aborted {
+			t.aborted = true
+			t.cancel()
+		}
+	}
+
+	t.pending[seq] = name
+	for {
+		name, ok := t.pending[t.next]
+		if !ok {
+			break
+		}
+		delete(t.pending, t.next)
+		t.token = encodeWalkToken(name)
+		t.next++
+	}
+
+	if t.opts.Checkpoint != nil && t.opts.CheckpointEvery > 0 && t.processed%t.opts.CheckpointEvery == 0 {
+		t.opts.Checkpoint(t.progress())
+	}
+}
+
+func (t *walkTracker) progress() WalkProgress {
+	return WalkProgress{Token: t.token, Processed: t.processed, Failed: len(t.errs)}
+}
+
+func encodeWalkToken(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func decodeWalkToken(token string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("file: invalid resume token: %v", err)
+	}
+	return string(name), nil
+}
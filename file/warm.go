@@ -0,0 +1,68 @@
+package file
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWarmUpTimeout bound the warm-up started by WithWarmUp
+const DefaultWarmUpTimeout = 10 * time.Second
+
+// WithWarmUp resolve DNS, open the TLS connection and check credentials in the background at construction,
+// so the first request doesn't pay for them. Wait for Ready before serving traffic, e.g. in a readiness probe.
+//
+//	Example:
+//	f := file.New(account, accessKey, rootURL, containerName, apiVersion, file.WithWarmUp()).(*file.File)
+//	<-f.Ready()
+func WithWarmUp() Option {
+	return func(c *File) {
+		c.WarmUp = true
+	}
+}
+
+// startWarmUp run the warm-up in the background when WarmUp is set, called by constructors
+func (c *File) startWarmUp() {
+	if !c.WarmUp {
+		return
+	}
+	c.readyOnce.Do(func() { c.ready = make(chan struct{}) })
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultWarmUpTimeout)
+		defer cancel()
+		c.Warm(ctx)
+	}()
+}
+
+// Warm send one listing request so the connection is established and pooled for later requests,
+// return the storage error when credentials or the container are wrong. Ready is closed once it returns.
+func (c *File) Warm(ctx context.Context) error {
+	_, err := c.List(ctx, ProbePrefix, WithMaxResults(1))
+
+	c.readyOnce.Do(func() { c.ready = make(chan struct{}) })
+	c.warmOnce.Do(func() {
+		c.warmErr = err
+		close(c.ready)
+	})
+	return err
+}
+
+// Ready return a channel closed once the first warm-up is done, already closed when warm-up is not enabled
+func (c *File) Ready() <-chan struct{} {
+	c.readyOnce.Do(func() {
+		c.ready = make(chan struct{})
+		if !c.WarmUp {
+			c.warmOnce.Do(func() { close(c.ready) })
+		}
+	})
+	return c.ready
+}
+
+// WarmUpErr return the error of the first warm-up, nil before Ready is closed
+func (c *File) WarmUpErr() error {
+	select {
+	case <-c.Ready():
+		return c.warmErr
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,70 @@
+package file
+
+import (
+	"context"
+	"io"
+)
+
+// objectWriter feed UploadStream with the writes, through a pipe read by the upload goroutine
+type objectWriter struct {
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// NewObjectWriter return a writer streaming its writes to filePath in blocks, so exports such as CSV or XLSX reports
+// are written row by row without building the file in memory. Close commit the file and return the upload error,
+// nothing is stored until then. Cancel ctx instead of calling Close to discard a report that failed midway.
+// An empty contentType is detected from the first 512 bytes. Writes fail as soon as the upload failed.
+//
+//	Example:
+//	w, err := file.NewObjectWriter(ctx, "exports/orders.csv", "text/csv")
+//	out := csv.NewWriter(w)
+//	for rows.Next() {
+//		out.Write(row)
+//	}
+//	out.Flush()
+//	err = w.Close()
+func (c *File) NewObjectWriter(ctx context.Context, filePath, contentType string) (io.WriteCloser, error) {
+	if _, err := c.key(filePath); err != nil {
+		return nil, err
+	}
+	if c.Anonymous {
+		return nil, ErrReadOnly
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	w := &objectWriter{pw: pw, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		_, w.err = c.UploadStream(ctx, filePath, contentType, pr, -1)
+		if w.err != nil {
+			// unblock and fail the writes still coming
+			pr.CloseWithError(w.err)
+			return
+		}
+		pr.Close()
+	}()
+	return w, nil
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	n, err := w.pw.Write(p)
+	if err != nil {
+		<-w.done
+		if w.err != nil {
+			return n, w.err
+		}
+	}
+	return n, err
+}
+
+// Close commit the written content and wait for the upload
+func (w *objectWriter) Close() error {
+	w.pw.Close()
+	<-w.done
+	w.cancel()
+	return w.err
+}
@@ -3,6 +3,7 @@ module github.com/ndv6/assets-sdk
 go 1.13
 
 require (
+	github.com/Azure/azure-pipeline-go v0.2.1
 	github.com/Azure/azure-storage-blob-go v0.8.0
 	github.com/Azure/go-autorest/autorest/adal v0.8.2 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
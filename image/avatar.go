@@ -0,0 +1,242 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"html"
+	stdimage "image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"unicode"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// DefaultAvatarSize is the width and height of avatars in pixels by default
+const DefaultAvatarSize = 256
+
+// AvatarColors are the background colors of initials avatars, picked by a hash of the seed
+var AvatarColors = []color.RGBA{
+	{0x1a, 0xbc, 0x9c, 0xff}, {0x34, 0x98, 0xdb, 0xff}, {0x9b, 0x59, 0xb6, 0xff}, {0xe6, 0x7e, 0x22, 0xff},
+	{0xe7, 0x4c, 0x3c, 0xff}, {0x16, 0xa0, 0x85, 0xff}, {0x2c, 0x3e, 0x50, 0xff}, {0x7f, 0x8c, 0x8d, 0xff},
+}
+
+// identiconBackground is the background of identicons
+var identiconBackground = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+
+// Avatar is a default profile picture rendered from a seed, the same seed always render the same avatar
+type Avatar struct {
+	// Name is displayed as initials, an identicon is rendered when empty
+	Name string
+	// Seed pick the colors and the identicon pattern, e.g. a user id. Name when empty
+	Seed string
+	// Size is the width and height in pixels, DefaultAvatarSize when 0
+	Size int
+}
+
+// Initials return the upper case first letters of the first and last words of name, e.g. "Ada King Lovelace" -> "AL"
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	initials := string(unicode.ToUpper([]rune(words[0])[0]))
+	if len(words) > 1 {
+		initials += string(unicode.ToUpper([]rune(words[len(words)-1])[0]))
+	}
+	return initials
+}
+
+func (a Avatar) seed() string {
+	if a.Seed != "" {
+		return a.Seed
+	}
+	return a.Name
+}
+
+func (a Avatar) size() int {
+	if a.Size > 0 {
+		return a.Size
+	}
+	return DefaultAvatarSize
+}
+
+// background return the initials background color picked by the seed
+func (a Avatar) background() color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(a.seed()))
+	return AvatarColors[h.Sum32()%uint32(len(AvatarColors))]
+}
+
+// identicon return the 5x5 cells of the identicon, mirrored around the middle column, and its color
+func (a Avatar) identicon() ([5][5]bool, color.RGBA) {
+	sum := sha256.Sum256([]byte(a.seed()))
+	var cells [5][5]bool
+	for i := 0; i < 15; i++ {
+		row, col := i/3, i%3
+		on := sum[i]&1 == 1
+		cells[row][col], cells[row][4-col] = on, on
+	}
+	// darker than the background, saturated enough to tell avatars apart
+	return cells, color.RGBA{0x30 + sum[16]%0xa0, 0x30 + sum[17]%0xa0, 0x30 + sum[18]%0xa0, 0xff}
+}
+
+// SVG render the avatar as svg, initials are drawn with the browser sans-serif font
+func (a Avatar) SVG() []byte {
+	size := a.size()
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+	if initials := Initials(a.Name); initials != "" {
+		bg := a.background()
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#%02x%02x%02x"/>`, size, size, bg.R, bg.G, bg.B)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" dy=".35em" fill="#fff" font-family="Helvetica, Arial, sans-serif" font-size="%d" text-anchor="middle">%s</text>`,
+			size/2, size/2, size*2/5, html.EscapeString(initials))
+	} else {
+		cells, fg := a.identicon()
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#%02x%02x%02x"/>`, size, size, identiconBackground.R, identiconBackground.G, identiconBackground.B)
+		margin, cell := identiconGrid(size)
+		for row := range cells {
+			for col, on := range cells[row] {
+				if on {
+					fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%02x%02x%02x"/>`,
+						margin+col*cell, margin+row*cell, cell, cell, fg.R, fg.G, fg.B)
+				}
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+// identiconGrid return the margin and cell size of a 5x5 identicon of size pixels
+func identiconGrid(size int) (int, int) {
+	cell := size * 5 / 6 / 5
+	return (size - cell*5) / 2, cell
+}
+
+// PNG render the avatar as png. Initials are drawn with a built-in font of latin letters and digits,
+// names whose initials are outside of it render an identicon.
+func (a Avatar) PNG() ([]byte, error) {
+	size := a.size()
+	img := stdimage.NewRGBA(stdimage.Rect(0, 0, size, size))
+	initials := Initials(a.Name)
+	if initials != "" && hasGlyphs(initials) {
+		draw.Draw(img, img.Bounds(), &stdimage.Uniform{C: a.background()}, stdimage.Point{}, draw.Src)
+		drawText(img, initials, color.White)
+	} else {
+		cells, fg := a.identicon()
+		draw.Draw(img, img.Bounds(), &stdimage.Uniform{C: identiconBackground}, stdimage.Point{}, draw.Src)
+		margin, cell := identiconGrid(size)
+		for row := range cells {
+			for col, on := range cells[row] {
+				if on {
+					r := stdimage.Rect(margin+col*cell, margin+row*cell, margin+(col+1)*cell, margin+(row+1)*cell)
+					draw.Draw(img, r, &stdimage.Uniform{C: fg}, stdimage.Point{}, draw.Src)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UploadAvatar render a and store it at key, as svg when key ends with ".svg", png otherwise
+//
+//	Example:
+//	url, err := image.UploadAvatar(ctx, store, "avatars/42.png", image.Avatar{Name: user.Name, Seed: user.ID})
+func UploadAvatar(ctx context.Context, store file.IFile, key string, a Avatar) (string, error) {
+	if strings.HasSuffix(strings.ToLower(key), ".svg") {
+		return store.Upload(ctx, key, "image/svg+xml", a.SVG())
+	}
+	data, err := a.PNG()
+	if err != nil {
+		return "", err
+	}
+	return store.Upload(ctx, key, PNG.ContentType, data)
+}
+
+// glyphs is a 5x7 bitmap font, one byte per row with the leftmost pixel in bit 4
+var glyphs = map[rune][7]uint8{
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+}
+
+func hasGlyphs(s string) bool {
+	for _, r := range s {
+		if _, ok := glyphs[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// drawText draw s centered on img with glyphs scaled to two fifths of the image height
+func drawText(img *stdimage.RGBA, s string, c color.Color) {
+	b := img.Bounds()
+	runes := []rune(s)
+	scale := b.Dy() * 2 / 5 / 7
+	if scale < 1 {
+		scale = 1
+	}
+	// glyphs are 5 pixels wide with one pixel between them
+	width := (len(runes)*6 - 1) * scale
+	x0, y0 := b.Min.X+(b.Dx()-width)/2, b.Min.Y+(b.Dy()-7*scale)/2
+	fill := &stdimage.Uniform{C: c}
+	for i, r := range runes {
+		g := glyphs[r]
+		for row, bits := range g {
+			for col := 0; col < 5; col++ {
+				if bits&(1<<uint(4-col)) == 0 {
+					continue
+				}
+				x, y := x0+(i*6+col)*scale, y0+row*scale
+				draw.Draw(img, stdimage.Rect(x, y, x+scale, y+scale), fill, stdimage.Point{}, draw.Src)
+			}
+		}
+	}
+}
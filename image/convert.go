@@ -0,0 +1,154 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdimage "image"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Decoder decode an image format the standard library can't read, e.g. heic
+type Decoder interface {
+	Decode(ctx context.Context, data []byte) (stdimage.Image, error)
+}
+
+// DecoderFunc is a function implementing Decoder
+type DecoderFunc func(ctx context.Context, data []byte) (stdimage.Image, error)
+
+// Decode call f
+func (f DecoderFunc) Decode(ctx context.Context, data []byte) (stdimage.Image, error) {
+	return f(ctx, data)
+}
+
+// CommandDecoder decode by running an external converter writing a png or jpeg file.
+// "{in}" and "{out}" in Args are replaced by the input and output file paths.
+type CommandDecoder struct {
+	Command string
+	Args    []string
+	// OutExt is the output file extension selecting the converter output format, ".png" when empty
+	OutExt string
+}
+
+// HEIFDecoder convert heic files with heif-convert from libheif, install it with "apt install libheif-examples"
+// or "brew install libheif"
+var HEIFDecoder = CommandDecoder{Command: "heif-convert", Args: []string{"{in}", "{out}"}}
+
+// Decode write data to a temporary file, run the converter and decode its output
+func (d CommandDecoder) Decode(ctx context.Context, data []byte) (stdimage.Image, error) {
+	dir, err := ioutil.TempDir("", "image-decode")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ext := d.OutExt
+	if ext == "" {
+		ext = ".png"
+	}
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out"+ext)
+	if err := ioutil.WriteFile(in, data, 0600); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, len(d.Args))
+	for i, a := range d.Args {
+		args[i] = strings.NewReplacer("{in}", in, "{out}", out).Replace(a)
+	}
+	if output, err := exec.CommandContext(ctx, d.Command, args...).CombinedOutput(); err != nil {
+		if output = bytes.TrimSpace(output); len(output) > 0 {
+			return nil, fmt.Errorf("image: %s: %v: %s", d.Command, err, output)
+		}
+		return nil, fmt.Errorf("image: %s: %v", d.Command, err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := stdimage.Decode(f)
+	return img, err
+}
+
+// Encoder write converted images
+type Encoder interface {
+	Format() Format
+	Encode(w io.Writer, img stdimage.Image) error
+}
+
+// JPEGEncoder encode converted images as jpeg, Quality 0 use jpeg.DefaultQuality
+type JPEGEncoder struct {
+	Quality int
+}
+
+// Format return JPEG
+func (JPEGEncoder) Format() Format {
+	return JPEG
+}
+
+// Encode img as jpeg
+func (e JPEGEncoder) Encode(w io.Writer, img stdimage.Image) error {
+	quality := e.Quality
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// WithConversion convert uploads of format with decoder, replacing the default heic decoder when format is HEIC
+func WithConversion(format Format, decoder Decoder) Option {
+	return func(u *Uploader) {
+		if u.Decoders == nil {
+			u.Decoders = map[string]Decoder{}
+		}
+		u.Decoders[format.Name] = decoder
+	}
+}
+
+// WithEncoder write converted uploads with encoder instead of JPEGEncoder, e.g. a webp encoder
+func WithEncoder(encoder Encoder) Option {
+	return func(u *Uploader) {
+		u.Encoder = encoder
+	}
+}
+
+// WithArchive keep the original of converted uploads at prefix + key + original extension
+func WithArchive(prefix string) Option {
+	return func(u *Uploader) {
+		u.ArchivePrefix = prefix
+	}
+}
+
+// convert decode data of format and encode it with the uploader encoder, then archive the original when configured,
+// return the converted content, its format and the archive key
+func (u *Uploader) convert(ctx context.Context, key string, format Format, data []byte) ([]byte, Format, string, error) {
+	img, err := u.Decoders[format.Name].Decode(ctx, data)
+	if err != nil {
+		return nil, Format{}, "", err
+	}
+
+	encoder := u.Encoder
+	if encoder == nil {
+		encoder = JPEGEncoder{}
+	}
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, Format{}, "", err
+	}
+
+	var original string
+	if u.ArchivePrefix != "" {
+		original = u.ArchivePrefix + key + format.Ext
+		if _, err := u.Store.Upload(ctx, original, format.ContentType, data); err != nil {
+			return nil, Format{}, "", err
+		}
+	}
+	return buf.Bytes(), encoder.Format(), original, nil
+}
@@ -0,0 +1,147 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+const (
+	// DefaultIndexPrefix is where StoreIndex keep rendition urls by content hash
+	DefaultIndexPrefix = "system/renditions/sha256/"
+	// HashValue is the asset value holding the hex sha256 of the original content, set by Dedupe
+	HashValue = "sha256"
+)
+
+// RenditionIndex remember the rendition urls produced for an original content hash
+type RenditionIndex interface {
+	// Lookup return the rendition urls by name of hash, file.ErrNotFound when unknown
+	Lookup(ctx context.Context, hash string) (map[string]string, error)
+	Save(ctx context.Context, hash string, urls map[string]string) error
+}
+
+// Dedupe hash the original content and, when renditions of identical content were already produced,
+// record their urls and end with pipeline.ErrDone so the steps depending on it are not run.
+// Run it before any step changing the content and IndexRenditions after the renditions are uploaded.
+// Reused renditions are shared by every identical original, don't delete them with one asset.
+func Dedupe(index RenditionIndex) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		sum := sha256.Sum256(a.Data())
+		hash := hex.EncodeToString(sum[:])
+		a.Set(HashValue, hash)
+
+		urls, err := index.Lookup(ctx, hash)
+		if err == file.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for name, url := range urls {
+			a.SetURL(name, url)
+		}
+		return pipeline.ErrDone
+	})
+}
+
+// IndexRenditions save the urls of the asset renditions under the hash computed by Dedupe
+func IndexRenditions(index RenditionIndex) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		v, ok := a.Get(HashValue)
+		hash, _ := v.(string)
+		if !ok || hash == "" {
+			return pipeline.Permanent(errors.New("image: IndexRenditions need a Dedupe step before it"))
+		}
+
+		all := a.URLs()
+		urls := map[string]string{}
+		for _, r := range a.Renditions() {
+			if url, ok := all[r.Name]; ok {
+				urls[r.Name] = url
+			}
+		}
+		if len(urls) == 0 {
+			return nil
+		}
+		return index.Save(ctx, hash, urls)
+	})
+}
+
+// MemoryIndex is an in process RenditionIndex
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	hashes map[string]map[string]string
+}
+
+// NewMemoryIndex create an empty in process RenditionIndex
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{hashes: map[string]map[string]string{}}
+}
+
+// Lookup return the rendition urls of hash
+func (m *MemoryIndex) Lookup(ctx context.Context, hash string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	urls, ok := m.hashes[hash]
+	if !ok {
+		return nil, file.ErrNotFound
+	}
+	copied := make(map[string]string, len(urls))
+	for k, v := range urls {
+		copied[k] = v
+	}
+	return copied, nil
+}
+
+// Save remember the rendition urls of hash
+func (m *MemoryIndex) Save(ctx context.Context, hash string, urls map[string]string) error {
+	copied := make(map[string]string, len(urls))
+	for k, v := range urls {
+		copied[k] = v
+	}
+	m.mu.Lock()
+	m.hashes[hash] = copied
+	m.mu.Unlock()
+	return nil
+}
+
+// StoreIndex keep rendition urls as JSON files of a store, shared by every instance
+type StoreIndex struct {
+	Store  file.IFile
+	Prefix string
+}
+
+// NewStoreIndex keep the index under DefaultIndexPrefix of store
+func NewStoreIndex(store file.IFile) *StoreIndex {
+	return &StoreIndex{Store: store, Prefix: DefaultIndexPrefix}
+}
+
+// Lookup return the rendition urls of hash
+func (s *StoreIndex) Lookup(ctx context.Context, hash string) (map[string]string, error) {
+	data, err := s.Store.Download(ctx, s.Prefix+hash+".json")
+	if err != nil {
+		return nil, err
+	}
+	var urls map[string]string
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return nil, fmt.Errorf("image: index %s: %v", hash, err)
+	}
+	return urls, nil
+}
+
+// Save remember the rendition urls of hash
+func (s *StoreIndex) Save(ctx context.Context, hash string, urls map[string]string) error {
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.Upload(ctx, s.Prefix+hash+".json", "application/json", data)
+	return err
+}
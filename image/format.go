@@ -0,0 +1,94 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrFormatNotAllowed is wrapped by FormatError, test it with errors.Is
+var ErrFormatNotAllowed = errors.New("image: format not allowed")
+
+// FormatError returned when an upload is not one of the allowed formats.
+// Format is the detected format name, empty when the content is not a known image format.
+type FormatError struct {
+	Format string
+}
+
+func (e *FormatError) Error() string {
+	if e.Format == "" {
+		return "image: unknown format not allowed"
+	}
+	return fmt.Sprintf("image: format %s not allowed", e.Format)
+}
+
+// Unwrap return ErrFormatNotAllowed
+func (e *FormatError) Unwrap() error {
+	return ErrFormatNotAllowed
+}
+
+// Format is an image format recognized by its magic bytes
+type Format struct {
+	Name        string
+	ContentType string
+	Ext         string
+	// Match report whether content start with the format signature
+	Match func(head []byte) bool
+}
+
+var (
+	JPEG = Format{Name: "jpeg", ContentType: "image/jpeg", Ext: ".jpg", Match: prefix("\xff\xd8\xff")}
+	PNG  = Format{Name: "png", ContentType: "image/png", Ext: ".png", Match: prefix("\x89PNG\r\n\x1a\n")}
+	GIF  = Format{Name: "gif", ContentType: "image/gif", Ext: ".gif", Match: func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte("GIF87a")) || bytes.HasPrefix(head, []byte("GIF89a"))
+	}}
+	WebP = Format{Name: "webp", ContentType: "image/webp", Ext: ".webp", Match: func(head []byte) bool {
+		return len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP"
+	}}
+	BMP  = Format{Name: "bmp", ContentType: "image/bmp", Ext: ".bmp", Match: prefix("BM")}
+	TIFF = Format{Name: "tiff", ContentType: "image/tiff", Ext: ".tiff", Match: func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*"))
+	}}
+	HEIC = Format{Name: "heic", ContentType: "image/heic", Ext: ".heic", Match: ftyp("heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1")}
+	AVIF = Format{Name: "avif", ContentType: "image/avif", Ext: ".avif", Match: ftyp("avif", "avis")}
+)
+
+// DefaultFormats are the formats accepted by Upload unless configured otherwise
+var DefaultFormats = []Format{JPEG, PNG, WebP, GIF}
+
+// KnownFormats are recognized by Detect, to name rejected formats
+var KnownFormats = []Format{JPEG, PNG, WebP, GIF, AVIF, HEIC, TIFF, BMP}
+
+func prefix(magic string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(magic))
+	}
+}
+
+// ftyp match ISO base media files whose major brand is one of brands
+func ftyp(brands ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		if len(head) < 12 || string(head[4:8]) != "ftyp" {
+			return false
+		}
+		for _, b := range brands {
+			if string(head[8:12]) == b {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Detect return the format of data among formats, KnownFormats when none are given
+func Detect(data []byte, formats ...Format) (Format, bool) {
+	if len(formats) == 0 {
+		formats = KnownFormats
+	}
+	for _, f := range formats {
+		if f.Match(data) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
@@ -0,0 +1,107 @@
+// Package image store user uploaded images, accepting only allowed formats checked by their magic bytes
+// rather than the content type declared by the client.
+//
+//	Example:
+//	info, err := image.Upload(ctx, store, "avatars/42", data)
+//	if errors.Is(err, image.ErrFormatNotAllowed) {
+//		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+//	}
+package image
+
+import (
+	"context"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// Option configure Uploader created by New
+type Option func(*Uploader)
+
+// Uploader store images of allowed formats, converting formats with a decoder on ingest
+type Uploader struct {
+	Store   file.IFile
+	Formats []Format
+	// Decoders convert uploads of a format name, by default heic with HEIFDecoder
+	Decoders map[string]Decoder
+	// Encoder write converted uploads, JPEGEncoder when nil
+	Encoder Encoder
+	// ArchivePrefix keep the original of converted uploads under this prefix, not kept when empty
+	ArchivePrefix string
+	// Scheme name rendition keys, SuffixScheme by default
+	Scheme *KeyScheme
+}
+
+// New create uploader into store accepting DefaultFormats and converting heic to jpeg
+func New(store file.IFile, opts ...Option) *Uploader {
+	u := &Uploader{
+		Store:    store,
+		Formats:  append([]Format(nil), DefaultFormats...),
+		Decoders: map[string]Decoder{HEIC.Name: HEIFDecoder},
+		Scheme:   SuffixScheme,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// WithFormats accept formats in addition to the allowed ones, e.g. image.AVIF or a custom Format
+func WithFormats(formats ...Format) Option {
+	return func(u *Uploader) {
+		u.Formats = append(u.Formats, formats...)
+	}
+}
+
+// WithOnlyFormats accept formats instead of DefaultFormats
+func WithOnlyFormats(formats ...Format) Option {
+	return func(u *Uploader) {
+		u.Formats = append([]Format(nil), formats...)
+	}
+}
+
+// WithKeyScheme name rendition keys with scheme, e.g. image.PrefixScheme or one built by NewKeyScheme
+func WithKeyScheme(scheme *KeyScheme) Option {
+	return func(u *Uploader) {
+		u.Scheme = scheme
+	}
+}
+
+// Upload store data at key when its magic bytes match an allowed format, with the format content type,
+// and return the image dimensions and capture date. Formats with a decoder, e.g. heic, are converted first.
+// Other content is rejected with a *FormatError.
+func (u *Uploader) Upload(ctx context.Context, key string, data []byte) (Info, error) {
+	var original string
+	format, ok := Detect(data, u.Formats...)
+	if !ok {
+		known, _ := Detect(data)
+		if _, convertible := u.Decoders[known.Name]; known.Name == "" || !convertible {
+			return Info{}, &FormatError{Format: known.Name}
+		}
+
+		converted, to, archived, err := u.convert(ctx, key, known, data)
+		if err != nil {
+			return Info{}, err
+		}
+		data, format, original = converted, to, archived
+	}
+
+	info, err := Inspect(data)
+	if err != nil {
+		return Info{}, err
+	}
+	info.Format, info.ContentType = format.Name, format.ContentType
+
+	url, err := u.Store.Upload(ctx, key, format.ContentType, data)
+	if err != nil {
+		return Info{}, err
+	}
+	info.URL, info.Key, info.Original = url, key, original
+	return info, nil
+}
+
+// Upload store data at key in store when it is one of DefaultFormats.
+// It is kept as a wrapper of Uploader.Upload with the same result, including the returned url,
+// so callers can move to New at their own pace.
+func Upload(ctx context.Context, store file.IFile, key string, data []byte) (Info, error) {
+	return New(store).Upload(ctx, key, data)
+}
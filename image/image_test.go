@@ -0,0 +1,133 @@
+package image_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	stdimage "image"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+	"github.com/ndv6/assets-sdk/image"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of image/testdata/golden")
+
+// golden is what Upload return and store for a fixture
+type golden struct {
+	Info        image.Info
+	Err         string `json:",omitempty"`
+	ContentType string `json:",omitempty"`
+	// Stored is the golden file of the stored content when it is not the fixture itself
+	Stored string `json:",omitempty"`
+	// Archived is whether the original was kept under the archive prefix
+	Archived bool `json:",omitempty"`
+}
+
+// patternDecoder stand in for heif-convert, decoding testdata/pattern.png whatever the input
+var patternDecoder = image.DecoderFunc(func(ctx context.Context, data []byte) (stdimage.Image, error) {
+	img, _, err := stdimage.Decode(bytes.NewReader(readFixture(nil, "pattern.png")))
+	return img, err
+})
+
+func readFixture(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil && t != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUploadGolden(t *testing.T) {
+	fixtures := []string{"pattern.png", "pattern.gif", "exif.jpg", "header.webp", "brand.heic", "not-an-image.txt"}
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := filetest.NewMemory()
+			u := image.New(store, image.WithConversion(image.HEIC, patternDecoder), image.WithArchive("originals/"))
+			data := readFixture(t, name)
+			key := "uploads/" + name
+
+			var got golden
+			info, err := u.Upload(ctx, key, data)
+			got.Info = info
+			if err != nil {
+				got.Err = err.Error()
+				if !errors.Is(err, image.ErrFormatNotAllowed) {
+					t.Errorf("Upload error %v is not ErrFormatNotAllowed", err)
+				}
+			}
+
+			stored, serr := store.Download(ctx, key)
+			if err == nil && serr != nil {
+				t.Fatalf("Download of the upload: %v", serr)
+			}
+			if err != nil && serr != file.ErrNotFound {
+				t.Errorf("rejected upload stored, Download = %v", serr)
+			}
+			if err == nil {
+				objects, lerr := store.List(ctx, key)
+				if lerr != nil || len(objects) != 1 {
+					t.Fatalf("List of the upload = %v, %v", objects, lerr)
+				}
+				got.ContentType = objects[0].ContentType
+				if !bytes.Equal(stored, data) {
+					got.Stored = "golden/" + name + image.JPEG.Ext
+					if *update {
+						if err := ioutil.WriteFile(filepath.Join("testdata", got.Stored), stored, 0644); err != nil {
+							t.Fatal(err)
+						}
+					} else if want := readFixture(t, got.Stored); !bytes.Equal(stored, want) {
+						t.Errorf("stored content differ from %s", got.Stored)
+					}
+				}
+			}
+			if info.Original != "" {
+				archived, aerr := store.Download(ctx, info.Original)
+				got.Archived = aerr == nil && bytes.Equal(archived, data)
+			}
+			compareGolden(t, filepath.Join("testdata", "golden", name+".json"), got)
+		})
+	}
+}
+
+// compareGolden compare got with the JSON golden file at path, rewritten with -update
+func compareGolden(t *testing.T, path string, got golden) {
+	t.Helper()
+	actual, err := json.MarshalIndent(got, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+	if *update {
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test ./image -update to create it", err)
+	}
+	if !bytes.Equal(actual, want) {
+		t.Errorf("Upload differ from %s\ngot:\n%s\nwant:\n%s", path, actual, want)
+	}
+}
+
+// the package level Upload is a wrapper of Uploader.Upload with the same result
+func TestUploadWrapper(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"pattern.png", "exif.jpg", "brand.heic", "not-an-image.txt"} {
+		data := readFixture(t, name)
+		want, werr := image.New(filetest.NewMemory()).Upload(ctx, "a/"+name, data)
+		got, err := image.Upload(ctx, filetest.NewMemory(), "a/"+name, data)
+		if got != want || (err == nil) != (werr == nil) {
+			t.Errorf("%s: Upload = %+v, %v, Uploader.Upload = %+v, %v", name, got, err, want, werr)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	stdimage "image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"time"
+)
+
+// Info describe an uploaded image
+type Info struct {
+	URL         string
+	Key         string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+	// Original is the archived original key of a converted upload
+	Original string
+	// TakenAt is the EXIF capture date of jpeg images, zero when the camera data was stripped.
+	// EXIF dates have no time zone, the camera wall clock is returned as UTC.
+	TakenAt time.Time
+}
+
+// Inspect return format, dimensions and capture date of data without decoding the pixels.
+// Dimensions are zero for formats the standard library and this package can't read, e.g. heic.
+func Inspect(data []byte) (Info, error) {
+	format, ok := Detect(data)
+	if !ok {
+		return Info{}, &FormatError{}
+	}
+
+	info := Info{Format: format.Name, ContentType: format.ContentType, Size: int64(len(data))}
+	switch format.Name {
+	case WebP.Name:
+		info.Width, info.Height = webpSize(data)
+	case JPEG.Name, PNG.Name, GIF.Name:
+		if cfg, _, err := stdimage.DecodeConfig(bytes.NewReader(data)); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	}
+	if format.Name == JPEG.Name {
+		info.TakenAt = exifDate(data)
+	}
+	return info, nil
+}
+
+// webpSize read the canvas size of lossy, lossless and extended webp files
+func webpSize(data []byte) (int, int) {
+	if len(data) < 30 {
+		return 0, 0
+	}
+	switch string(data[12:16]) {
+	case "VP8 ":
+		if data[23] != 0x9d || data[24] != 0x01 || data[25] != 0x2a {
+			return 0, 0
+		}
+		w := binary.LittleEndian.Uint16(data[26:28]) & 0x3fff
+		h := binary.LittleEndian.Uint16(data[28:30]) & 0x3fff
+		return int(w), int(h)
+	case "VP8L":
+		if data[20] != 0x2f {
+			return 0, 0
+		}
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1
+	case "VP8X":
+		w := uint32(data[24]) | uint32(data[25])<<8 | uint32(data[26])<<16
+		h := uint32(data[27]) | uint32(data[28])<<8 | uint32(data[29])<<16
+		return int(w) + 1, int(h) + 1
+	}
+	return 0, 0
+}
+
+// EXIF tags read by exifDate
+const (
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+// exifDate return DateTimeOriginal, or DateTime, of the EXIF segment of a jpeg
+func exifDate(data []byte) time.Time {
+	tiff := exifSegment(data)
+	if len(tiff) < 8 {
+		return time.Time{}
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:8]))
+	if exif, ok := ifdEntry(tiff, order, ifd0, tagExifIFD); ok {
+		if t, ok := ifdDate(tiff, order, int(order.Uint32(exif[8:12])), tagDateTimeOriginal); ok {
+			return t
+		}
+	}
+	t, _ := ifdDate(tiff, order, ifd0, tagDateTime)
+	return t
+}
+
+// exifSegment return the TIFF structure of the jpeg APP1 Exif segment
+func exifSegment(data []byte) []byte {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		// start of scan, no metadata after it
+		if marker == 0xda {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + size
+		if size < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[i+4 : end]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+// ifdEntry return the 12 byte entry of tag in the IFD at offset
+func ifdEntry(tiff []byte, order binary.ByteOrder, offset int, tag uint16) ([]byte, bool) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return nil, false
+	}
+	n := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < n; i++ {
+		start := offset + 2 + i*12
+		if start+12 > len(tiff) {
+			return nil, false
+		}
+		entry := tiff[start : start+12]
+		if order.Uint16(entry[:2]) == tag {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// ifdDate parse the "2006:01:02 15:04:05" ASCII value of tag
+func ifdDate(tiff []byte, order binary.ByteOrder, offset int, tag uint16) (time.Time, bool) {
+	entry, ok := ifdEntry(tiff, order, offset, tag)
+	if !ok {
+		return time.Time{}, false
+	}
+	count := int(order.Uint32(entry[4:8]))
+	valueOffset := int(order.Uint32(entry[8:12]))
+	if count < 19 || valueOffset+19 > len(tiff) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006:01:02 15:04:05", string(tiff[valueOffset:valueOffset+19]))
+	return t, err == nil
+}
@@ -0,0 +1,177 @@
+package image
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+const (
+	// DefaultLazyWait is how long a request for a missing rendition wait for its generation by default
+	DefaultLazyWait = 2 * time.Second
+	// DefaultLazyTimeout bound the generation of one rendition by default
+	DefaultLazyTimeout = time.Minute
+)
+
+// Lazy generate missing renditions on demand, so every rendition of Sizes can be requested as soon as
+// the original is stored, e.g. before the processing pipeline ran or for sizes added later.
+// Concurrent requests of a rendition share one generation. A request wait up to Wait then get
+// file.ErrPending while the generation continue in background, serve.Handler answer it with 202 or a placeholder.
+// Only keys following Scheme with a size of Sizes are generated, other missing keys are not found.
+type Lazy struct {
+	file.IFile
+	Scheme *KeyScheme
+	// Sizes are the renditions generated on demand, matched by name when the scheme has {size}, by width otherwise
+	Sizes []Rendition
+	// Wait is how long a request wait for a generation, DefaultLazyWait when 0
+	Wait time.Duration
+	// Timeout bound one generation, DefaultLazyTimeout when 0
+	Timeout time.Duration
+	// Processor return the processor adding rendition r to the original asset, Resize when nil
+	Processor func(r Rendition) pipeline.Processor
+
+	mu    sync.Mutex
+	calls map[string]*lazyCall
+}
+
+// lazyCall is a generation in flight, done is closed once err is set
+type lazyCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewLazy generate the renditions sizes named by scheme of next when they are missing
+//
+//	Example:
+//	store := image.NewLazy(store, image.SuffixScheme, image.Rendition{Name: "thumb", Width: 320}, image.Rendition{Name: "large", Width: 1280})
+//	data, err := store.Download(ctx, "products/42@320w.jpg") // generated from products/42.jpg when missing
+func NewLazy(next file.IFile, scheme *KeyScheme, sizes ...Rendition) *Lazy {
+	return &Lazy{IFile: next, Scheme: scheme, Sizes: sizes, calls: map[string]*lazyCall{}}
+}
+
+// Unwrap return the decorated store
+func (l *Lazy) Unwrap() file.IFile {
+	return l.IFile
+}
+
+// Download return the content of filePath, generating missing renditions first
+func (l *Lazy) Download(ctx context.Context, filePath string) ([]byte, error) {
+	data, err := l.IFile.Download(ctx, filePath)
+	if err != file.ErrNotFound {
+		return data, err
+	}
+	if err := l.Ensure(ctx, filePath); err != nil {
+		return nil, err
+	}
+	return l.IFile.Download(ctx, filePath)
+}
+
+// DownloadIfModified return the content of filePath unless it has knownETag, generating missing renditions first
+func (l *Lazy) DownloadIfModified(ctx context.Context, filePath, knownETag string) ([]byte, string, error) {
+	data, etag, err := l.IFile.DownloadIfModified(ctx, filePath, knownETag)
+	if err != file.ErrNotFound {
+		return data, etag, err
+	}
+	if err := l.Ensure(ctx, filePath); err != nil {
+		return nil, "", err
+	}
+	return l.IFile.DownloadIfModified(ctx, filePath, knownETag)
+}
+
+// Ensure generate the rendition key, without checking whether it exists. It return file.ErrNotFound
+// when key is not a rendition of Sizes or its original is missing, file.ErrPending when the generation
+// take longer than Wait.
+func (l *Lazy) Ensure(ctx context.Context, key string) error {
+	original, r, ok := l.Scheme.Parse(key)
+	if !ok {
+		return file.ErrNotFound
+	}
+	size, ok := l.size(r)
+	if !ok {
+		return file.ErrNotFound
+	}
+
+	call := l.start(key, original, size)
+	wait := l.Wait
+	if wait <= 0 {
+		wait = DefaultLazyWait
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-call.done:
+		return call.err
+	case <-timer.C:
+		return file.ErrPending
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// size return the rendition of Sizes matching the parsed rendition r
+func (l *Lazy) size(r Rendition) (Rendition, bool) {
+	for _, s := range l.Sizes {
+		if r.Name != "" && s.Name == r.Name || r.Name == "" && r.Width != 0 && s.Width == r.Width {
+			return s, true
+		}
+	}
+	return Rendition{}, false
+}
+
+// start return the generation of key in flight, starting it when there is none
+func (l *Lazy) start(key, original string, size Rendition) *lazyCall {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.calls == nil {
+		l.calls = map[string]*lazyCall{}
+	}
+	if call, ok := l.calls[key]; ok {
+		return call
+	}
+	call := &lazyCall{done: make(chan struct{})}
+	l.calls[key] = call
+
+	go func() {
+		// detached from the request, a cancelled request doesn't abort the generation others wait for
+		timeout := l.Timeout
+		if timeout <= 0 {
+			timeout = DefaultLazyTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		call.err = l.generate(ctx, key, original, size)
+		cancel()
+
+		// later requests find the stored rendition or retry a failed generation
+		l.mu.Lock()
+		delete(l.calls, key)
+		l.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// generate store rendition size of original at key
+func (l *Lazy) generate(ctx context.Context, key, original string, size Rendition) error {
+	data, err := l.IFile.Download(ctx, original)
+	if err != nil {
+		return err
+	}
+	processor := Resize(size.Name, size.Width)
+	if l.Processor != nil {
+		processor = l.Processor(size)
+	}
+	asset := pipeline.NewAsset(original, "", data)
+	if err := processor.Process(ctx, asset); err != nil {
+		return err
+	}
+	for _, r := range asset.Renditions() {
+		if r.Name == size.Name {
+			_, err := l.IFile.Upload(ctx, key, r.ContentType, r.Data)
+			return err
+		}
+	}
+	return ErrUnsupported
+}
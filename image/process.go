@@ -0,0 +1,225 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	stdimage "image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+// ErrUnsupported returned by processors that can't decode or encode a format, e.g. resizing webp
+var ErrUnsupported = errors.New("image: format not supported by processor")
+
+// RenditionQuality is the jpeg quality of renditions
+const RenditionQuality = 85
+
+// Validate reject assets whose magic bytes match none of formats, DefaultFormats when none are given,
+// and set the asset content type to the detected one
+func Validate(formats ...Format) pipeline.Processor {
+	if len(formats) == 0 {
+		formats = DefaultFormats
+	}
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		data := a.Data()
+		format, ok := Detect(data, formats...)
+		if !ok {
+			known, _ := Detect(data)
+			return pipeline.Permanent(&FormatError{Format: known.Name})
+		}
+		a.ContentType = format.ContentType
+		return nil
+	})
+}
+
+// StripEXIF remove camera metadata, location included, from jpeg (APP1 Exif and XMP, APP13 IPTC)
+// and png (eXIf and text chunks) without re-encoding. Orientation is lost with it.
+func StripEXIF() pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		data := a.Data()
+		switch {
+		case JPEG.Match(data):
+			a.SetData(stripJPEG(data))
+		case PNG.Match(data):
+			a.SetData(stripPNG(data))
+		}
+		return nil
+	})
+}
+
+// stripJPEG copy every segment except APP1 and APP13 up to the image data
+func stripJPEG(data []byte) []byte {
+	out := append([]byte(nil), data[:2]...)
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return data
+		}
+		marker := data[i+1]
+		if marker == 0xda {
+			return append(out, data[i:]...)
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return data
+		}
+		if marker != 0xe1 && marker != 0xed {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return data
+}
+
+// stripPNG copy every chunk except eXIf, tEXt, iTXt and zTXt
+func stripPNG(data []byte) []byte {
+	out := append([]byte(nil), data[:8]...)
+	for i := 8; i+12 <= len(data); {
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:i+4]))
+		if end > len(data) {
+			return data
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "iTXt", "zTXt":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// Resize add a rendition named name scaled down to width, keeping the aspect ratio.
+// Images narrower than width keep their size. Jpeg stay jpeg, png and gif become png.
+func Resize(name string, width int) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		src, format, err := stdimage.Decode(bytes.NewReader(a.Data()))
+		if err != nil {
+			return pipeline.Permanent(ErrUnsupported)
+		}
+
+		b := src.Bounds()
+		w, h := b.Dx(), b.Dy()
+		if width < w {
+			w, h = width, (h*width+b.Dx()/2)/b.Dx()
+			if h < 1 {
+				h = 1
+			}
+			src = scale(src, w, h)
+		}
+
+		r := pipeline.Rendition{Name: name, Width: w, Height: h}
+		r.Data, r.Ext, r.ContentType, err = encode(format, src)
+		if err != nil {
+			return err
+		}
+		a.AddRendition(r)
+		return nil
+	})
+}
+
+// Watermark draw mark in the bottom right corner of every rendition, margin pixels from the edges
+func Watermark(mark stdimage.Image, margin int) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		for _, r := range a.Renditions() {
+			src, format, err := stdimage.Decode(bytes.NewReader(r.Data))
+			if err != nil {
+				return pipeline.Permanent(ErrUnsupported)
+			}
+
+			dst := stdimage.NewRGBA(src.Bounds())
+			draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+			mb := mark.Bounds()
+			at := stdimage.Pt(dst.Bounds().Max.X-mb.Dx()-margin, dst.Bounds().Max.Y-mb.Dy()-margin)
+			draw.Draw(dst, stdimage.Rectangle{Min: at, Max: at.Add(mb.Size())}, mark, mb.Min, draw.Over)
+
+			r.Data, r.Ext, r.ContentType, err = encode(format, dst)
+			if err != nil {
+				return err
+			}
+			a.AddRendition(r)
+		}
+		return nil
+	})
+}
+
+// UploadRenditions store every rendition at the key named by scheme, recording urls by rendition name
+func UploadRenditions(store file.IFile, scheme *KeyScheme) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		for _, r := range a.Renditions() {
+			key := scheme.Key(a.Key, Rendition{Name: r.Name, Width: r.Width, Height: r.Height, Ext: r.Ext})
+			url, err := store.Upload(ctx, key, r.ContentType, r.Data)
+			if err != nil {
+				return err
+			}
+			a.SetURL(r.Name, url)
+		}
+		return nil
+	})
+}
+
+// UploadOriginal store the asset content at its key, recording the url as "original"
+func UploadOriginal(store file.IFile) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(ctx context.Context, a *pipeline.Asset) error {
+		url, err := store.Upload(ctx, a.Key, a.ContentType, a.Data())
+		if err != nil {
+			return err
+		}
+		a.SetURL("original", url)
+		return nil
+	})
+}
+
+// encode img in the format it was decoded from, png for formats without encoder
+func encode(format string, img stdimage.Image) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: RenditionQuality})
+		return buf.Bytes(), "jpg", JPEG.ContentType, err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "png", PNG.ContentType, err
+}
+
+// scale shrink src to w x h averaging the source pixels covered by each destination pixel
+func scale(src stdimage.Image, w, h int) *stdimage.RGBA {
+	b := src.Bounds()
+	rgba := stdimage.NewRGBA(stdimage.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := stdimage.NewRGBA(stdimage.Rect(0, 0, w, h))
+	sw, sh := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sum[0] += uint64(p[0])
+					sum[1] += uint64(p[1])
+					sum[2] += uint64(p[2])
+					sum[3] += uint64(p[3])
+				}
+			}
+			n := uint64((y1 - y0) * (x1 - x0))
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(sum[0]/n), uint8(sum[1]/n), uint8(sum[2]/n), uint8(sum[3]/n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,182 @@
+package image
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rendition is a resized variant of an original image
+type Rendition struct {
+	// Name is the rendition size name, e.g. "thumb"
+	Name   string
+	Width  int
+	Height int
+	// Ext is the rendition extension without dot, the original extension when empty
+	Ext string
+}
+
+// KeyScheme name rendition keys from a template and find the original of a rendition key.
+// Placeholders are {key} the original key, {dir} its directory, {name} its base name without extension,
+// {ext} the rendition extension, {size} the rendition name, {width} and {height}.
+// Parse recover the original key from {key}, or from {dir}, {name} and {ext} assuming renditions keep the original format.
+type KeyScheme struct {
+	Template string
+	re       *regexp.Regexp
+	names    []string
+}
+
+var (
+	// SuffixScheme store renditions next to the original, "products/42.jpg" -> "products/42@320w.jpg"
+	SuffixScheme = MustKeyScheme("{dir}/{name}@{width}w.{ext}")
+	// PrefixScheme store renditions in a tree per size, "products/42.jpg" -> "renditions/thumb/products/42.jpg"
+	PrefixScheme = MustKeyScheme("renditions/{size}/{key}")
+)
+
+var placeholderRe = regexp.MustCompile(`\{[a-z]+\}`)
+
+var placeholderPatterns = map[string]string{
+	"key":    `.+`,
+	"dir":    `.+`,
+	"name":   `[^/]+?`,
+	"ext":    `[^/.]+`,
+	"size":   `[^/]+`,
+	"width":  `[0-9]+`,
+	"height": `[0-9]+`,
+}
+
+// NewKeyScheme parse template, see KeyScheme for placeholders
+//
+//	Example:
+//	scheme, err := image.NewKeyScheme("{dir}/{name}-{size}.{ext}")
+func NewKeyScheme(template string) (*KeyScheme, error) {
+	s := &KeyScheme{Template: template}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	rest := template
+	// a leading "{dir}/" is optional, files at the root have no directory
+	if strings.HasPrefix(rest, "{dir}/") {
+		pattern.WriteString(`(?:(.+)/)?`)
+		s.names = append(s.names, "dir")
+		rest = strings.TrimPrefix(rest, "{dir}/")
+	}
+
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(rest, -1) {
+		name := rest[loc[0]+1 : loc[1]-1]
+		p, ok := placeholderPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("image: unknown placeholder {%s} in key template %q", name, template)
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[last:loc[0]]))
+		pattern.WriteString("(" + p + ")")
+		s.names = append(s.names, name)
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(rest[last:]) + "$")
+
+	if !s.has("key") && !s.has("name") {
+		return nil, fmt.Errorf("image: key template %q need {key} or {name}", template)
+	}
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	s.re = re
+	return s, nil
+}
+
+// MustKeyScheme is NewKeyScheme panicking on invalid templates
+func MustKeyScheme(template string) *KeyScheme {
+	s, err := NewKeyScheme(template)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *KeyScheme) has(name string) bool {
+	for _, n := range s.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Key return the key of rendition r of original
+func (s *KeyScheme) Key(original string, r Rendition) string {
+	dir, base := path.Split(original)
+	ext := path.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	if r.Ext != "" {
+		ext = "." + strings.TrimPrefix(r.Ext, ".")
+	}
+
+	template := s.Template
+	if dir == "" {
+		template = strings.TrimPrefix(template, "{dir}/")
+	}
+	key := strings.NewReplacer(
+		"{key}", original,
+		"{dir}", strings.TrimSuffix(dir, "/"),
+		"{name}", name,
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{size}", r.Name,
+		"{width}", strconv.Itoa(r.Width),
+		"{height}", strconv.Itoa(r.Height),
+	).Replace(template)
+	// an original without extension has no ".{ext}"
+	return strings.TrimSuffix(key, ".")
+}
+
+// Parse return the original key and rendition of a rendition key, false when key doesn't follow the scheme
+//
+//	Example:
+//	original, r, ok := image.SuffixScheme.Parse("products/42@320w.jpg") // "products/42.jpg", Rendition{Width: 320, Ext: "jpg"}
+func (s *KeyScheme) Parse(key string) (string, Rendition, bool) {
+	m := s.re.FindStringSubmatch(key)
+	if m == nil {
+		return "", Rendition{}, false
+	}
+
+	var (
+		r                   Rendition
+		original, dir, name string
+	)
+	for i, n := range s.names {
+		v := m[i+1]
+		switch n {
+		case "key":
+			original = v
+		case "dir":
+			dir = v
+		case "name":
+			name = v
+		case "ext":
+			r.Ext = v
+		case "size":
+			r.Name = v
+		case "width":
+			r.Width, _ = strconv.Atoi(v)
+		case "height":
+			r.Height, _ = strconv.Atoi(v)
+		}
+	}
+
+	if original == "" {
+		original = path.Join(dir, name)
+		if r.Ext != "" {
+			original += "." + r.Ext
+		}
+	}
+	return original, r, true
+}
+
+// IsRendition report whether key follow the scheme
+func (s *KeyScheme) IsRendition(key string) bool {
+	return s.re.MatchString(key)
+}
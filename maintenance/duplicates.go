@@ -0,0 +1,220 @@
+package maintenance
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// DuplicateGroup is a set of files with identical content
+type DuplicateGroup struct {
+	// Checksum is the hex MD5 of the content
+	Checksum string
+	Size     int64
+	// Keys are ordered oldest first, Keys[0] is kept by Deduplicate
+	Keys []string
+}
+
+// Savings is the bytes freed by keeping a single copy
+func (g DuplicateGroup) Savings() int64 {
+	return g.Size * int64(len(g.Keys)-1)
+}
+
+// DuplicateReport is the result of FindDuplicates
+type DuplicateReport struct {
+	// Scanned is the number of files listed
+	Scanned int
+	// Hashed is the number of files downloaded because storage had no checksum for them
+	Hashed int
+	// Groups are ordered by savings, largest first
+	Groups []DuplicateGroup
+	// Savings is the bytes freed by deduplicating every group
+	Savings int64
+}
+
+// DuplicateOptions configure FindDuplicates
+type DuplicateOptions struct {
+	// Workers is the number of files hashed at once, file.DefaultWalkWorkers when 0
+	Workers int
+	// MinSize ignore smaller files, empty files are always ignored
+	MinSize int64
+}
+
+// FindDuplicates report the files under prefix with identical content. Only files sharing their size are compared,
+// by the MD5 checksum stored with them, files without one are downloaded and hashed.
+// The listing is held in memory, scan large containers prefix by prefix.
+//
+//	Example:
+//	report, err := maintenance.FindDuplicates(ctx, store, "uploads/", maintenance.DuplicateOptions{})
+//	log.Printf("%d groups, %d bytes to save", len(report.Groups), report.Savings)
+func FindDuplicates(ctx context.Context, store file.IFile, prefix string, opts DuplicateOptions) (DuplicateReport, error) {
+	var report DuplicateReport
+	bySize := map[int64][]file.ObjectInfo{}
+	for item := range store.ListStream(ctx, prefix) {
+		if item.Err != nil {
+			return report, item.Err
+		}
+		report.Scanned++
+		if item.Info.Size > 0 && item.Info.Size >= opts.MinSize {
+			bySize[item.Info.Size] = append(bySize[item.Info.Size], item.Info)
+		}
+	}
+
+	var candidates []file.ObjectInfo
+	for _, infos := range bySize {
+		if len(infos) > 1 {
+			candidates = append(candidates, infos...)
+		}
+	}
+	checksums, hashed, err := checksums(ctx, store, candidates, opts.Workers)
+	if err != nil {
+		return report, err
+	}
+	report.Hashed = hashed
+
+	type groupKey struct {
+		size     int64
+		checksum string
+	}
+	groups := map[groupKey][]file.ObjectInfo{}
+	for i, info := range candidates {
+		k := groupKey{info.Size, checksums[i]}
+		groups[k] = append(groups[k], info)
+	}
+	for k, infos := range groups {
+		if len(infos) < 2 {
+			continue
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			if !infos[i].LastModified.Equal(infos[j].LastModified) {
+				return infos[i].LastModified.Before(infos[j].LastModified)
+			}
+			return infos[i].Name < infos[j].Name
+		})
+		g := DuplicateGroup{Checksum: k.checksum, Size: k.size}
+		for _, info := range infos {
+			g.Keys = append(g.Keys, info.Name)
+		}
+		report.Groups = append(report.Groups, g)
+		report.Savings += g.Savings()
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if si, sj := report.Groups[i].Savings(), report.Groups[j].Savings(); si != sj {
+			return si > sj
+		}
+		return report.Groups[i].Keys[0] < report.Groups[j].Keys[0]
+	})
+	return report, nil
+}
+
+// checksums return the hex MD5 of every file, downloading those without stored checksum with workers at once
+func checksums(ctx context.Context, store file.IFile, infos []file.ObjectInfo, workers int) ([]string, int, error) {
+	sums := make([]string, len(infos))
+	var missing []int
+	for i, info := range infos {
+		if len(info.ContentMD5) > 0 {
+			sums[i] = hex.EncodeToString(info.ContentMD5)
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if workers <= 0 {
+		workers = file.DefaultWalkWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		next  = make(chan int)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				data, err := store.Download(ctx, infos[i].Name)
+				if err != nil {
+					once.Do(func() {
+						first = err
+						cancel()
+					})
+					continue
+				}
+				sum := md5.Sum(data)
+				sums[i] = hex.EncodeToString(sum[:])
+			}
+		}()
+	}
+	for _, i := range missing {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	return sums, len(missing), first
+}
+
+// DedupeResult count the duplicates removed by Deduplicate
+type DedupeResult struct {
+	Removed int
+	Freed   int64
+	// Held are the duplicates kept by a legal hold or a retention policy, already relinked
+	Held []string
+}
+
+// Deduplicate keep the oldest file of every group of report and delete the others once relink pointed
+// the references of the duplicate to the kept file, e.g. by updating catalog rows. A duplicate whose content
+// no longer match the kept file is left untouched.
+func Deduplicate(ctx context.Context, store file.IFile, report DuplicateReport, relink func(ctx context.Context, duplicate, original string) error) (DedupeResult, error) {
+	var result DedupeResult
+	if relink == nil {
+		return result, errors.New("maintenance: deduplicate require relink")
+	}
+	for _, g := range report.Groups {
+		original, err := store.Download(ctx, g.Keys[0])
+		if err == file.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		for _, key := range g.Keys[1:] {
+			// the files may have changed since the report
+			data, err := store.Download(ctx, key)
+			if err == file.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+			if !bytes.Equal(data, original) {
+				continue
+			}
+			if err := relink(ctx, key, g.Keys[0]); err != nil {
+				return result, err
+			}
+			_, err = store.Delete(ctx, key)
+			if errors.Is(err, file.ErrHeld) {
+				result.Held = append(result.Held, key)
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+			result.Removed++
+			result.Freed += int64(len(data))
+		}
+	}
+	return result, nil
+}
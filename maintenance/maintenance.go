@@ -0,0 +1,368 @@
+// Package maintenance run resumable bulk jobs over stored files: rewriting cache control headers,
+// normalizing content types and re-encrypting files with a new key. Jobs walk a prefix with a pool
+// of workers and checkpoint their progress, an interrupted job continue where it stopped when run again.
+//
+//	Example:
+//	cp, err := maintenance.Run(ctx, store, maintenance.CacheControl("assets/", "public, max-age=31536000"), maintenance.Options{
+//		Workers:     16,
+//		Checkpoints: maintenance.NewStoreCheckpoints(store),
+//	})
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	// DefaultCheckpointPrefix is where StoreCheckpoints keep job progress
+	DefaultCheckpointPrefix = "system/maintenance/"
+	// DefaultCheckpointEvery is the number of processed files between checkpoints
+	DefaultCheckpointEvery = 500
+)
+
+// ApplyFunc update one file, it return whether the file was changed
+type ApplyFunc func(ctx context.Context, store file.IFile, info file.ObjectInfo) (changed bool, err error)
+
+// Job is a named maintenance operation applied to every file under Prefix.
+// Name identify the job checkpoint, changing the job parameters need a new name to start over.
+type Job struct {
+	Name   string
+	Prefix string
+	List   []file.ListOption
+	Apply  ApplyFunc
+}
+
+// Checkpoint is the persisted progress of a job
+type Checkpoint struct {
+	Token     string    `json:"token"`
+	Processed int       `json:"processed"`
+	Changed   int       `json:"changed"`
+	Failed    int       `json:"failed"`
+	Done      bool      `json:"done"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Checkpoints persist job progress
+type Checkpoints interface {
+	// Load return the job checkpoint, zero Checkpoint when the job never ran
+	Load(ctx context.Context, job string) (Checkpoint, error)
+	Save(ctx context.Context, job string, cp Checkpoint) error
+}
+
+// StoreCheckpoints keep checkpoints as JSON files in an IFile.
+// The default prefix is reserved by file.Guard, pass the store beneath the guard.
+type StoreCheckpoints struct {
+	Store  file.IFile
+	Prefix string
+}
+
+// NewStoreCheckpoints keep checkpoints under DefaultCheckpointPrefix of store
+func NewStoreCheckpoints(store file.IFile) *StoreCheckpoints {
+	return &StoreCheckpoints{Store: store, Prefix: DefaultCheckpointPrefix}
+}
+
+// Load read job checkpoint
+func (s *StoreCheckpoints) Load(ctx context.Context, job string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := s.Store.Download(ctx, s.Prefix+job+".json")
+	if err == file.ErrNotFound {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	return cp, json.Unmarshal(data, &cp)
+}
+
+// Save write job checkpoint
+func (s *StoreCheckpoints) Save(ctx context.Context, job string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.Upload(ctx, s.Prefix+job+".json", "application/json", data)
+	return err
+}
+
+// Options configure Run
+type Options struct {
+	// Workers is the number of files processed at once, file.DefaultWalkWorkers when 0
+	Workers int
+	// Limiter adapt the number of files processed at once to throttling, up to Workers
+	Limiter *file.AdaptiveLimiter
+	// Checkpoints persist progress, the job always start over when nil
+	Checkpoints Checkpoints
+	// CheckpointEvery is the number of processed files between checkpoints, DefaultCheckpointEvery when 0
+	CheckpointEvery int
+	// MaxErrors stop the job after that many failed files, 0 never stop
+	MaxErrors int
+	// Restart ignore the saved checkpoint, e.g. to run a finished job again
+	Restart bool
+	// Progress is called with every checkpoint
+	Progress func(job string, cp Checkpoint)
+}
+
+// Run apply job to every file under its prefix, continuing from the saved checkpoint.
+// A finished job is not run again unless Options.Restart is set.
+// Failed files are reported in *file.WalkError, the checkpoint moves past them.
+func Run(ctx context.Context, store file.IFile, job Job, opts Options) (Checkpoint, error) {
+	var cp Checkpoint
+	if opts.Checkpoints != nil && !opts.Restart {
+		var err error
+		if cp, err = opts.Checkpoints.Load(ctx, job.Name); err != nil {
+			return cp, err
+		}
+		if cp.Done {
+			return cp, nil
+		}
+	}
+	every := opts.CheckpointEvery
+	if every <= 0 {
+		every = DefaultCheckpointEvery
+	}
+
+	var (
+		mu      sync.Mutex
+		changed = cp.Changed
+		base    = cp
+	)
+	save := func(p file.WalkProgress, done bool) {
+		mu.Lock()
+		cp = Checkpoint{
+			Token:     p.Token,
+			Processed: base.Processed + p.Processed,
+			Changed:   changed,
+			Failed:    base.Failed + p.Failed,
+			Done:      done,
+			UpdatedAt: time.Now(),
+		}
+		current := cp
+		mu.Unlock()
+
+		if opts.Checkpoints != nil {
+			// a lost checkpoint only means redoing some files
+			opts.Checkpoints.Save(ctx, job.Name, current)
+		}
+		if opts.Progress != nil {
+			opts.Progress(job.Name, current)
+		}
+	}
+
+	var last file.WalkProgress
+	err := file.Walk(ctx, store, job.Prefix, func(ctx context.Context, info file.ObjectInfo) error {
+		ok, err := job.Apply(ctx, store, info)
+		if ok && err == nil {
+			mu.Lock()
+			changed++
+			mu.Unlock()
+		}
+		return err
+	}, file.WalkOptions{
+		Workers:         opts.Workers,
+		Limiter:         opts.Limiter,
+		List:            job.List,
+		ResumeToken:     cp.Token,
+		MaxErrors:       opts.MaxErrors,
+		CheckpointEvery: every,
+		Checkpoint: func(p file.WalkProgress) {
+			last = p
+			save(p, false)
+		},
+	})
+
+	// the walk report its final progress last, mark it done unless the walk was stopped
+	walkErr, _ := err.(*file.WalkError)
+	if err == nil || (walkErr != nil && walkErr.Err == nil) {
+		save(last, true)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return cp, err
+}
+
+// CacheControl set the Cache-Control header of files under prefix to value
+func CacheControl(prefix, value string) Job {
+	return Job{
+		Name:   "cache-control-" + jobID(prefix),
+		Prefix: prefix,
+		Apply: func(ctx context.Context, store file.IFile, info file.ObjectInfo) (bool, error) {
+			if info.CacheControl == value {
+				return false, nil
+			}
+			return true, store.SetHeaders(ctx, info.Name, file.Headers{CacheControl: value})
+		},
+	}
+}
+
+// contentTypeAliases map non standard content types to their registered name
+var contentTypeAliases = map[string]string{
+	"image/jpg":         "image/jpeg",
+	"image/pjpeg":       "image/jpeg",
+	"image/x-png":       "image/png",
+	"application/x-pdf": "application/pdf",
+	"text/json":         "application/json",
+	"text/xml":          "application/xml",
+}
+
+// NormalizeContentType return the canonical form of contentType for a file named name:
+// lower case, aliases resolved, and guessed from the extension when missing or generic.
+// It return "" when nothing better is known.
+func NormalizeContentType(name, contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType, params = "", nil
+	}
+	if alias, ok := contentTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+	if mediaType == "" {
+		byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(path.Ext(name))))
+		if err != nil {
+			return ""
+		}
+		mediaType = byExt
+		if strings.HasPrefix(mediaType, "text/") {
+			params = map[string]string{"charset": "utf-8"}
+		}
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// NormalizeContentTypes fix content types of files under prefix, see NormalizeContentType
+func NormalizeContentTypes(prefix string) Job {
+	return Job{
+		Name:   "content-types-" + jobID(prefix),
+		Prefix: prefix,
+		Apply: func(ctx context.Context, store file.IFile, info file.ObjectInfo) (bool, error) {
+			normalized := NormalizeContentType(info.Name, info.ContentType)
+			if normalized == "" || normalized == info.ContentType {
+				return false, nil
+			}
+			return true, store.SetHeaders(ctx, info.Name, file.Headers{ContentType: normalized})
+		},
+	}
+}
+
+// EncryptionScoper is a store able to re-encrypt files with another key, implemented by *file.File
+type EncryptionScoper interface {
+	SetEncryptionScope(ctx context.Context, filePath, scope string) error
+}
+
+// EncryptionScopeReader is a store reporting the encryption scope of files, implemented by *file.File
+type EncryptionScopeReader interface {
+	EncryptionScope(ctx context.Context, filePath string) (string, error)
+}
+
+// ErrScopeMismatch returned for files not encrypted with the expected encryption scope
+var ErrScopeMismatch = errors.New("maintenance: file not encrypted with the expected scope")
+
+// ReEncrypt rewrite files under prefix with the encryption scope holding the new key, e.g. for the yearly key rotation.
+// Files are rewritten by storage without downloading them, the store, or one it decorate, must implement EncryptionScoper.
+// When it also implement EncryptionScopeReader, files already in scope are left untouched and every rewritten
+// file is checked afterwards, failing with ErrScopeMismatch when storage didn't apply the scope.
+func ReEncrypt(prefix, scope string) Job {
+	return Job{
+		Name:   "re-encrypt-" + jobID(scope+"-"+prefix),
+		Prefix: prefix,
+		Apply: func(ctx context.Context, store file.IFile, info file.ObjectInfo) (bool, error) {
+			scoper, ok := asEncryptionScoper(store)
+			if !ok {
+				return false, file.ErrNotSupported
+			}
+			reader, verify := asEncryptionScopeReader(store)
+			if verify {
+				current, err := reader.EncryptionScope(ctx, info.Name)
+				if err != nil || current == scope {
+					return false, err
+				}
+			}
+			if err := scoper.SetEncryptionScope(ctx, info.Name, scope); err != nil {
+				return false, err
+			}
+			if verify {
+				if err := checkScope(ctx, reader, info.Name, scope); err != nil {
+					return true, err
+				}
+			}
+			return true, nil
+		},
+	}
+}
+
+// VerifyEncryption check that files under prefix are encrypted with scope, e.g. after ReEncrypt.
+// Files in another scope fail with ErrScopeMismatch and are reported in *file.WalkError, the store, or one it decorate, must implement EncryptionScopeReader.
+func VerifyEncryption(prefix, scope string) Job {
+	return Job{
+		Name:   "verify-encryption-" + jobID(scope+"-"+prefix),
+		Prefix: prefix,
+		Apply: func(ctx context.Context, store file.IFile, info file.ObjectInfo) (bool, error) {
+			reader, ok := asEncryptionScopeReader(store)
+			if !ok {
+				return false, file.ErrNotSupported
+			}
+			return false, checkScope(ctx, reader, info.Name, scope)
+		},
+	}
+}
+
+// asEncryptionScoper return the first store of the chain setting encryption scopes, looking through decorators
+func asEncryptionScoper(store file.IFile) (EncryptionScoper, bool) {
+	for store != nil {
+		if s, ok := store.(EncryptionScoper); ok {
+			return s, true
+		}
+		u, ok := store.(file.Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, false
+}
+
+// asEncryptionScopeReader return the first store of the chain reporting encryption scopes, looking through decorators
+func asEncryptionScopeReader(store file.IFile) (EncryptionScopeReader, bool) {
+	for store != nil {
+		if r, ok := store.(EncryptionScopeReader); ok {
+			return r, true
+		}
+		u, ok := store.(file.Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, false
+}
+
+// checkScope return ErrScopeMismatch unless filePath is encrypted with scope
+func checkScope(ctx context.Context, reader EncryptionScopeReader, filePath, scope string) error {
+	current, err := reader.EncryptionScope(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	if current != scope {
+		return fmt.Errorf("%w: %s is in %q", ErrScopeMismatch, filePath, current)
+	}
+	return nil
+}
+
+// jobID turn s into a checkpoint file name part
+func jobID(s string) string {
+	s = strings.Trim(s, file.Delimiter)
+	if s == "" {
+		return "all"
+	}
+	return strings.NewReplacer(file.Delimiter, "_", " ", "_").Replace(s)
+}
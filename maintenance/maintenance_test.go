@@ -0,0 +1,51 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+// scopedStore is a Memory recording the encryption scope of files
+type scopedStore struct {
+	*filetest.Memory
+	scopes map[string]string
+}
+
+func (s *scopedStore) SetEncryptionScope(ctx context.Context, filePath, scope string) error {
+	s.scopes[filePath] = scope
+	return nil
+}
+
+func (s *scopedStore) EncryptionScope(ctx context.Context, filePath string) (string, error) {
+	return s.scopes[filePath], nil
+}
+
+func TestEncryptionJobsThroughDecorators(t *testing.T) {
+	ctx := context.Background()
+	scoped := &scopedStore{Memory: filetest.NewMemory(), scopes: map[string]string{"docs/a.pdf": "key-2019"}}
+	store := file.NewGuard(scoped)
+	info := file.ObjectInfo{Name: "docs/a.pdf"}
+
+	changed, err := ReEncrypt("docs/", "key-2020").Apply(ctx, store, info)
+	if err != nil || !changed {
+		t.Fatalf("ReEncrypt through a Guard = %v, %v, want rewritten", changed, err)
+	}
+	if scoped.scopes["docs/a.pdf"] != "key-2020" {
+		t.Errorf("scope = %q, want key-2020", scoped.scopes["docs/a.pdf"])
+	}
+	if _, err := VerifyEncryption("docs/", "key-2020").Apply(ctx, store, info); err != nil {
+		t.Errorf("VerifyEncryption through a Guard = %v", err)
+	}
+	if _, err := VerifyEncryption("docs/", "key-2021").Apply(ctx, store, info); !errors.Is(err, ErrScopeMismatch) {
+		t.Errorf("VerifyEncryption of another scope = %v, want ErrScopeMismatch", err)
+	}
+
+	// stores without encryption scopes
+	if _, err := ReEncrypt("docs/", "key-2020").Apply(ctx, file.NewGuard(filetest.NewMemory()), info); err != file.ErrNotSupported {
+		t.Errorf("ReEncrypt of a Memory = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import "sync"
+
+// Rendition is a variant of the asset produced by a step, e.g. a thumbnail
+type Rendition struct {
+	Name        string
+	ContentType string
+	// Ext is the file extension without dot, the asset extension when empty
+	Ext    string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Asset is the file going through a pipeline, safe for concurrent steps
+type Asset struct {
+	Key         string
+	ContentType string
+
+	mu         sync.RWMutex
+	data       []byte
+	renditions []Rendition
+	urls       map[string]string
+	values     map[string]interface{}
+}
+
+// NewAsset create asset of key with its content
+func NewAsset(key, contentType string, data []byte) *Asset {
+	return &Asset{Key: key, ContentType: contentType, data: data, urls: map[string]string{}, values: map[string]interface{}{}}
+}
+
+// Data return the current content
+func (a *Asset) Data() []byte {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.data
+}
+
+// SetData replace the content, e.g. after stripping metadata
+func (a *Asset) SetData(data []byte) {
+	a.mu.Lock()
+	a.data = data
+	a.mu.Unlock()
+}
+
+// AddRendition add or replace the rendition with the same name
+func (a *Asset) AddRendition(r Rendition) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.renditions {
+		if a.renditions[i].Name == r.Name {
+			a.renditions[i] = r
+			return
+		}
+	}
+	a.renditions = append(a.renditions, r)
+}
+
+// Renditions return a copy of the renditions produced so far
+func (a *Asset) Renditions() []Rendition {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Rendition(nil), a.renditions...)
+}
+
+// SetURL record where an output named name was stored
+func (a *Asset) SetURL(name, url string) {
+	a.mu.Lock()
+	a.urls[name] = url
+	a.mu.Unlock()
+}
+
+// URLs return the stored outputs by name
+func (a *Asset) URLs() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	urls := make(map[string]string, len(a.urls))
+	for k, v := range a.urls {
+		urls[k] = v
+	}
+	return urls
+}
+
+// Set store a value for later steps
+func (a *Asset) Set(key string, value interface{}) {
+	a.mu.Lock()
+	a.values[key] = value
+	a.mu.Unlock()
+}
+
+// Get return a value stored by a previous step
+func (a *Asset) Get(key string) (interface{}, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	v, ok := a.values[key]
+	return v, ok
+}
@@ -0,0 +1,396 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	// DefaultWorkers is the number of goroutines of a WorkerPool by default
+	DefaultWorkers = 4
+	// DefaultJobPrefix is where StoreStatuses keep job statuses
+	DefaultJobPrefix = "system/jobs/"
+)
+
+var (
+	// ErrJobNotFound returned for unknown job ids
+	ErrJobNotFound = errors.New("pipeline: job not found")
+	// ErrUnknownPipeline returned when a job name a pipeline the runner doesn't have
+	ErrUnknownPipeline = errors.New("pipeline: unknown pipeline")
+)
+
+// JobState is the lifecycle state of a job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Job run a named pipeline on a stored file, it is small enough for any message queue
+type Job struct {
+	ID          string `json:"id"`
+	Pipeline    string `json:"pipeline"`
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+}
+
+// StepState is the state of one step of a job
+type StepState string
+
+const (
+	StepPending   StepState = "pending"
+	StepRunning   StepState = "running"
+	StepSucceeded StepState = "succeeded"
+	StepFailed    StepState = "failed"
+	StepSkipped   StepState = "skipped"
+)
+
+// StepStatus is the progress of one step of a job
+type StepStatus struct {
+	Name     string    `json:"name"`
+	State    StepState `json:"state"`
+	Attempts int       `json:"attempts,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// JobStatus is the progress of a job
+type JobStatus struct {
+	ID       string       `json:"id"`
+	Pipeline string       `json:"pipeline"`
+	Key      string       `json:"key"`
+	State    JobState     `json:"state"`
+	Steps    []StepStatus `json:"steps"`
+	// URLs are the stored outputs by name once the job succeeded, e.g. renditions
+	URLs map[string]string `json:"urls,omitempty"`
+	// Error and FailedStep describe why a job failed
+	Error      string    `json:"error,omitempty"`
+	FailedStep string    `json:"failedStep,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Done report whether the job finished, successfully or not
+func (s JobStatus) Done() bool {
+	return s.State == JobSucceeded || s.State == JobFailed
+}
+
+// Progress return the fraction of steps done, between 0 and 1
+func (s JobStatus) Progress() float64 {
+	if len(s.Steps) == 0 {
+		if s.Done() {
+			return 1
+		}
+		return 0
+	}
+	done := 0
+	for _, step := range s.Steps {
+		if step.State != StepPending && step.State != StepRunning {
+			done++
+		}
+	}
+	return float64(done) / float64(len(s.Steps))
+}
+
+// JobQueue deliver jobs to workers calling Runner.Process, e.g. a WorkerPool, SQS or Asynq
+type JobQueue interface {
+	Enqueue(ctx context.Context, job Job) error
+}
+
+// StatusStore keep job statuses, shared by every instance submitting or processing jobs
+type StatusStore interface {
+	Put(ctx context.Context, status JobStatus) error
+	Get(ctx context.Context, id string) (JobStatus, error)
+}
+
+// Runner submit jobs to a queue and process them by loading the file from Store
+type Runner struct {
+	Store     file.IFile
+	Pipelines map[string]*Pipeline
+	Queue     JobQueue
+	Statuses  StatusStore
+	// OnComplete is called with the final status of every processed job, e.g. Webhook
+	OnComplete func(ctx context.Context, status JobStatus)
+}
+
+// NewRunner create runner of pipelines by name, set Queue before submitting jobs
+//
+//	Example:
+//	runner := pipeline.NewRunner(store, pipeline.NewMemoryStatuses(), map[string]*pipeline.Pipeline{"renditions": p})
+//	runner.Queue = pipeline.NewWorkerPool(runner, 8)
+//	id, err := runner.Submit(ctx, "renditions", "products/42.jpg", "image/jpeg")
+func NewRunner(store file.IFile, statuses StatusStore, pipelines map[string]*Pipeline) *Runner {
+	return &Runner{Store: store, Statuses: statuses, Pipelines: pipelines}
+}
+
+// Submit queue pipeline for the stored file key, return the job id to query its status
+func (r *Runner) Submit(ctx context.Context, pipeline, key, contentType string) (string, error) {
+	if _, ok := r.Pipelines[pipeline]; !ok {
+		return "", ErrUnknownPipeline
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	job := Job{ID: hex.EncodeToString(id), Pipeline: pipeline, Key: key, ContentType: contentType}
+
+	now := time.Now().UTC()
+	status := JobStatus{ID: job.ID, Pipeline: pipeline, Key: key, State: JobQueued, Steps: pendingSteps(r.Pipelines[pipeline]), CreatedAt: now, UpdatedAt: now}
+	if err := r.Statuses.Put(ctx, status); err != nil {
+		return "", err
+	}
+	if err := r.Queue.Enqueue(ctx, job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// JobStatus return the status of job id, ErrJobNotFound when unknown
+func (r *Runner) JobStatus(ctx context.Context, id string) (JobStatus, error) {
+	return r.Statuses.Get(ctx, id)
+}
+
+// Process run a job delivered by the queue, recording the progress of every step.
+// The returned error let queues redeliver the job.
+func (r *Runner) Process(ctx context.Context, job Job) error {
+	status, err := r.Statuses.Get(ctx, job.ID)
+	if err == ErrJobNotFound {
+		status = JobStatus{ID: job.ID, Pipeline: job.Pipeline, Key: job.Key, CreatedAt: time.Now().UTC()}
+	} else if err != nil {
+		return err
+	}
+
+	p, ok := r.Pipelines[job.Pipeline]
+	if !ok {
+		return r.finish(ctx, status, nil, ErrUnknownPipeline)
+	}
+
+	status.State = JobRunning
+	status.Steps = pendingSteps(p)
+	status.Error, status.FailedStep = "", ""
+	status.UpdatedAt = time.Now().UTC()
+	if err := r.Statuses.Put(ctx, status); err != nil {
+		return err
+	}
+
+	data, err := r.Store.Download(ctx, job.Key)
+	if err != nil {
+		return r.finish(ctx, status, nil, err)
+	}
+
+	var mu sync.Mutex
+	runCtx := WithObserver(ctx, func(result StepResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range status.Steps {
+			if status.Steps[i].Name == result.Name {
+				status.Steps[i] = stepStatus(result)
+			}
+		}
+		status.UpdatedAt = time.Now().UTC()
+		// progress is best effort, the final status is always saved
+		r.Statuses.Put(ctx, status)
+	})
+
+	asset := NewAsset(job.Key, job.ContentType, data)
+	_, err = p.Run(runCtx, asset)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return r.finish(ctx, status, asset, err)
+}
+
+func pendingSteps(p *Pipeline) []StepStatus {
+	steps := make([]StepStatus, len(p.Steps))
+	for i, s := range p.Steps {
+		steps[i] = StepStatus{Name: s.Name, State: StepPending}
+	}
+	return steps
+}
+
+func stepStatus(r StepResult) StepStatus {
+	s := StepStatus{Name: r.Name, Attempts: r.Attempts}
+	switch {
+	case r.Err == ErrDone:
+		s.State = StepSucceeded
+	case r.Err == ErrSkipped:
+		s.State = StepSkipped
+	case r.Err != nil:
+		s.State, s.Error = StepFailed, r.Err.Error()
+	case r.Attempts == 0:
+		s.State = StepRunning
+	default:
+		s.State = StepSucceeded
+	}
+	return s
+}
+
+// finish record the job outcome, call OnComplete and return err
+func (r *Runner) finish(ctx context.Context, status JobStatus, asset *Asset, err error) error {
+	status.State = JobSucceeded
+	status.Error = ""
+	if asset != nil {
+		status.URLs = asset.URLs()
+	}
+	if err != nil {
+		status.State = JobFailed
+		status.Error = err.Error()
+		if serr, ok := err.(*StepError); ok {
+			status.FailedStep = serr.Step
+			status.Error = serr.Err.Error()
+		}
+	}
+	status.UpdatedAt = time.Now().UTC()
+
+	perr := r.Statuses.Put(ctx, status)
+	if r.OnComplete != nil {
+		r.OnComplete(ctx, status)
+	}
+	if perr != nil && err == nil {
+		return perr
+	}
+	return err
+}
+
+// WorkerPool is an in process JobQueue running jobs on a fixed number of goroutines
+type WorkerPool struct {
+	runner *Runner
+	jobs   chan Job
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWorkerPool start workers processing jobs with runner, DefaultWorkers when workers is 0
+func NewWorkerPool(runner *Runner, workers int) *WorkerPool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	w := &WorkerPool{runner: runner, jobs: make(chan Job, workers*16)}
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for job := range w.jobs {
+				w.runner.Process(context.Background(), job)
+			}
+		}()
+	}
+	return w
+}
+
+// Enqueue queue job, waiting while every worker is busy and the buffer is full
+func (w *WorkerPool) Enqueue(ctx context.Context, job Job) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return file.ErrClosed
+	}
+	select {
+	case w.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stop accepting jobs and wait for queued ones until ctx is done
+func (w *WorkerPool) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.jobs)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// MemoryStatuses is an in process StatusStore
+type MemoryStatuses struct {
+	mu       sync.RWMutex
+	statuses map[string]JobStatus
+}
+
+// NewMemoryStatuses create an empty in process StatusStore
+func NewMemoryStatuses() *MemoryStatuses {
+	return &MemoryStatuses{statuses: map[string]JobStatus{}}
+}
+
+// Put save status
+func (m *MemoryStatuses) Put(ctx context.Context, status JobStatus) error {
+	m.mu.Lock()
+	m.statuses[status.ID] = status
+	m.mu.Unlock()
+	return nil
+}
+
+// Get return the status of job id
+func (m *MemoryStatuses) Get(ctx context.Context, id string) (JobStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status, ok := m.statuses[id]
+	if !ok {
+		return JobStatus{}, ErrJobNotFound
+	}
+	return status, nil
+}
+
+// StoreStatuses keep job statuses as JSON files of a store, shared by every instance
+type StoreStatuses struct {
+	Store  file.IFile
+	Prefix string
+}
+
+// NewStoreStatuses keep statuses under DefaultJobPrefix of store
+func NewStoreStatuses(store file.IFile) *StoreStatuses {
+	return &StoreStatuses{Store: store, Prefix: DefaultJobPrefix}
+}
+
+// Put save status
+func (s *StoreStatuses) Put(ctx context.Context, status JobStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.Upload(ctx, s.Prefix+status.ID+".json", "application/json", data)
+	return err
+}
+
+// Get return the status of job id
+func (s *StoreStatuses) Get(ctx context.Context, id string) (JobStatus, error) {
+	data, err := s.Store.Download(ctx, s.Prefix+id+".json")
+	if err == file.ErrNotFound {
+		return JobStatus{}, ErrJobNotFound
+	}
+	if err != nil {
+		return JobStatus{}, err
+	}
+	var status JobStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return JobStatus{}, fmt.Errorf("pipeline: job %s: %v", id, err)
+	}
+	return status, nil
+}
@@ -0,0 +1,268 @@
+// Package pipeline run asset processing steps as a DAG: each step run once the steps it depends on
+// succeeded, independent steps run concurrently, failed attempts are retried with backoff
+// and every step is reported to Metrics.
+//
+//	Example:
+//	p, err := pipeline.New(
+//		pipeline.Step{Name: "validate", Processor: image.Validate()},
+//		pipeline.Step{Name: "strip-exif", Processor: image.StripEXIF(), After: []string{"validate"}},
+//		pipeline.Step{Name: "thumb", Processor: image.Resize("thumb", 320), After: []string{"strip-exif"}},
+//		pipeline.Step{Name: "large", Processor: image.Resize("large", 1280), After: []string{"strip-exif"}},
+//		pipeline.Step{Name: "upload", Processor: image.UploadRenditions(store, image.SuffixScheme), After: []string{"thumb", "large"}, Retries: 3},
+//	)
+//	report, err := p.Run(ctx, pipeline.NewAsset("products/42.jpg", "image/jpeg", data))
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultBackoff is the wait before the first retry of a step, doubled after every attempt
+const DefaultBackoff = 200 * time.Millisecond
+
+var (
+	// ErrSkipped is the error of steps not run because a step they depend on failed or returned ErrDone
+	ErrSkipped = errors.New("pipeline: skipped")
+	// ErrDone returned by a processor to succeed without running the steps depending on it,
+	// e.g. when their output already exist. It is not retried and does not fail the run.
+	ErrDone = errors.New("pipeline: done")
+)
+
+// Processor is one processing step
+type Processor interface {
+	Process(ctx context.Context, a *Asset) error
+}
+
+// ProcessorFunc is a function implementing Processor
+type ProcessorFunc func(ctx context.Context, a *Asset) error
+
+// Process call f
+func (f ProcessorFunc) Process(ctx context.Context, a *Asset) error {
+	return f(ctx, a)
+}
+
+// Step is a named processor run after the steps named in After
+type Step struct {
+	Name      string
+	Processor Processor
+	After     []string
+	// Retries is the number of attempts after the first one, errors marked Permanent are not retried
+	Retries int
+	// Backoff is the wait before the first retry, DefaultBackoff when 0
+	Backoff time.Duration
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent mark err as not worth retrying, e.g. a validation failure
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent report whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// StepError is the error of a failed pipeline run
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("pipeline: step %s: %v", e.Step, e.Err)
+}
+
+// Unwrap return the step error
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Metrics observe every step run, e.g. to export durations and failures
+type Metrics interface {
+	ObserveStep(step string, attempts int, d time.Duration, err error)
+}
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name     string
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+type observerKey struct{}
+
+// WithObserver return context calling fn whenever a step of a run with it starts, with Attempts 0,
+// and once it is done or skipped. fn is called from concurrent steps.
+func WithObserver(ctx context.Context, fn func(StepResult)) context.Context {
+	return context.WithValue(ctx, observerKey{}, fn)
+}
+
+func observe(ctx context.Context, r StepResult) {
+	if fn, ok := ctx.Value(observerKey{}).(func(StepResult)); ok {
+		fn(r)
+	}
+}
+
+// Report is the outcome of every step of a run, in step order
+type Report struct {
+	Steps []StepResult
+}
+
+// Pipeline is a validated DAG of steps
+type Pipeline struct {
+	Steps   []Step
+	Metrics Metrics
+}
+
+// New create pipeline from steps, checking names are unique, dependencies exist and there is no cycle
+func New(steps ...Step) (*Pipeline, error) {
+	byName := map[string]Step{}
+	for _, s := range steps {
+		if s.Name == "" || s.Processor == nil {
+			return nil, errors.New("pipeline: step need a name and a processor")
+		}
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("pipeline: duplicate step %s", s.Name)
+		}
+		byName[s.Name] = s
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("pipeline: cycle through step %s", name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].After {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("pipeline: step %s depend on unknown step %s", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	for _, s := range steps {
+		if err := visit(s.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Pipeline{Steps: steps}, nil
+}
+
+// Run process a through every step, return the report and the first failure as a *StepError.
+// Once a step failed, steps depending on it are skipped and steps not started yet are canceled.
+func (p *Pipeline) Run(ctx context.Context, a *Asset) (Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := Report{Steps: make([]StepResult, len(p.Steps))}
+	index := map[string]int{}
+	finished := make([]chan struct{}, len(p.Steps))
+	for i, s := range p.Steps {
+		index[s.Name] = i
+		finished[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, s := range p.Steps {
+		wg.Add(1)
+		go func(i int, s Step) {
+			defer wg.Done()
+			defer close(finished[i])
+
+			report.Steps[i].Name = s.Name
+			for _, dep := range s.After {
+				d := index[dep]
+				<-finished[d]
+				if report.Steps[d].Err != nil {
+					report.Steps[i].Err = ErrSkipped
+					observe(ctx, report.Steps[i])
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				report.Steps[i].Err = ErrSkipped
+				observe(ctx, report.Steps[i])
+				return
+			}
+
+			observe(ctx, StepResult{Name: s.Name})
+			report.Steps[i] = p.runStep(ctx, s, a)
+			observe(ctx, report.Steps[i])
+			if report.Steps[i].Err != nil && report.Steps[i].Err != ErrDone {
+				cancel()
+			}
+		}(i, s)
+	}
+	wg.Wait()
+
+	for _, r := range report.Steps {
+		if r.Err != nil && r.Err != ErrSkipped && r.Err != ErrDone {
+			return report, &StepError{Step: r.Name, Err: r.Err}
+		}
+	}
+	if err := ctx.Err(); err != nil && err != context.Canceled {
+		return report, err
+	}
+	return report, nil
+}
+
+// runStep run s with retries and report it to Metrics
+func (p *Pipeline) runStep(ctx context.Context, s Step, a *Asset) StepResult {
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	result := StepResult{Name: s.Name}
+	start := time.Now()
+	for {
+		result.Attempts++
+		result.Err = s.Processor.Process(ctx, a)
+		if result.Err == nil || result.Err == ErrDone || IsPermanent(result.Err) || result.Attempts > s.Retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			continue
+		}
+		break
+	}
+	result.Duration = time.Since(start)
+
+	if p.Metrics != nil {
+		p.Metrics.ObserveStep(s.Name, result.Attempts, result.Duration, result.Err)
+	}
+	return result
+}
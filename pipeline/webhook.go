@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader carry the hex HMAC-SHA256 of the webhook body, "sha256=<hex>"
+	SignatureHeader = "X-Signature"
+	// DefaultWebhookTimeout bound a webhook call
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+// Webhook return an OnComplete posting the final job status as JSON to url, signed with secret when set.
+// Failed calls are logged, poll JobStatus to catch missed notifications.
+//
+//	Example:
+//	runner.OnComplete = pipeline.Webhook("https://api.example.com/hooks/assets", secret, nil)
+func Webhook(url string, secret []byte, client *http.Client) func(ctx context.Context, status JobStatus) {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return func(ctx context.Context, status JobStatus) {
+		body, err := json.Marshal(status)
+		if err != nil {
+			log.Printf("pipeline: webhook job %s: %v", status.ID, err)
+			return
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("pipeline: webhook job %s: %v", status.ID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if len(secret) > 0 {
+			req.Header.Set(SignatureHeader, "sha256="+Sign(secret, body))
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			log.Printf("pipeline: webhook job %s: %v", status.ID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			log.Printf("pipeline: webhook job %s: %s", status.ID, resp.Status)
+		}
+	}
+}
+
+// Sign return the hex HMAC-SHA256 of body, compare it to the SignatureHeader of received webhooks with hmac.Equal
+func Sign(secret, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,147 @@
+package serve
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxFormFields bound the fields read before the file of an upload form
+const maxFormFields = 32
+
+// Form fields posted to an UploadHandler
+const (
+	FormKey      = "key"
+	FormRedirect = "success_action_redirect"
+	FormFile     = "file"
+	// FilenameVar in the key template is replaced by the uploaded file name
+	FilenameVar = "${filename}"
+)
+
+// UploadForm is everything a frontend needs to render a direct upload form, serializable to JSON.
+// Render an input for every field as hidden, then the file input named FileField last.
+type UploadForm struct {
+	Action    string            `json:"action"`
+	Method    string            `json:"method"`
+	Enctype   string            `json:"enctype"`
+	Fields    map[string]string `json:"fields"`
+	FileField string            `json:"fileField"`
+	MaxSize   int64             `json:"maxSize"`
+	Accept    string            `json:"accept,omitempty"`
+}
+
+// UploadForm return a form posting one file to the UploadHandler at action with a single use token.
+// keyTemplate is the stored key, relative keys are under claims.Prefix and FilenameVar is
+// replaced by the uploaded file name, e.g. "${filename}" or "claims/1234/scan-${filename}".
+// Browsers are redirected to claims.Redirect after the upload when set.
+//
+//	Example:
+//	form := tokens.UploadForm("https://assets.example.com/uploads/", serve.UploadClaims{
+//		Prefix:   "claims/1234/",
+//		MaxSize:  20 << 20,
+//		Redirect: "https://example.com/claims/1234/done",
+//	}, time.Hour, "${filename}")
+//	json.NewEncoder(w).Encode(form)
+func (s *TokenService) UploadForm(action string, claims UploadClaims, ttl time.Duration, keyTemplate string) UploadForm {
+	fields := map[string]string{
+		TokenParam: s.IssueUpload(claims, ttl),
+		FormKey:    keyTemplate,
+	}
+	if claims.Redirect != "" {
+		fields[FormRedirect] = claims.Redirect
+	}
+	return UploadForm{
+		Action:    action,
+		Method:    http.MethodPost,
+		Enctype:   "multipart/form-data",
+		Fields:    fields,
+		FileField: FormFile,
+		MaxSize:   claims.MaxSize,
+		Accept:    strings.Join(claims.ContentTypes, ","),
+	}
+}
+
+// serveForm store the file of a multipart form, fields must come before the file like S3 POST uploads
+func (h *UploadHandler) serveForm(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	fields := map[string]string{}
+	for n := 0; ; n++ {
+		part, err := mr.NextPart()
+		if err != nil {
+			http.Error(w, "missing file", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != FormFile {
+			if n >= maxFormFields {
+				http.Error(w, "too many form fields", http.StatusBadRequest)
+				return
+			}
+			value, err := readField(part)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			fields[part.FormName()] = value
+			continue
+		}
+
+		token := fields[TokenParam]
+		if token == "" {
+			token = r.URL.Query().Get(TokenParam)
+		}
+		claims, err := h.Tokens.VerifyUpload(token)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if redirect, ok := fields[FormRedirect]; ok && redirect != claims.Redirect {
+			http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+
+		key, ok := formKey(claims.Prefix, fields[FormKey], part.FileName())
+		if !ok {
+			h.Progress.reject(claims.ID, "invalid file name", claims.Expires)
+			http.Error(w, "invalid file name", http.StatusBadRequest)
+			return
+		}
+		h.store(w, r, claims, key, part.Header.Get("Content-Type"), part, -1, true)
+		return
+	}
+}
+
+// readField read a form value up to 4 KiB
+func readField(part *multipart.Part) (string, error) {
+	value, err := ioutil.ReadAll(io.LimitReader(part, 4<<10+1))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > 4<<10 {
+		return "", errors.New("serve: form field too large")
+	}
+	return string(value), nil
+}
+
+// formKey expand the key template with the uploaded file name and check it stays under prefix
+func formKey(prefix, template, filename string) (string, bool) {
+	filename = path.Base(strings.Replace(filename, "\\", "/", -1))
+	if filename == "." || filename == "/" {
+		filename = ""
+	}
+	if template == "" {
+		template = FilenameVar
+	}
+	key := strings.Replace(template, FilenameVar, filename, -1)
+	key = strings.TrimPrefix(key, prefix)
+	return uploadKey(prefix, key)
+}
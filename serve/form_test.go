@@ -0,0 +1,41 @@
+package serve
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+func postForm(h *UploadHandler, token string, extra int) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField(TokenParam, token)
+	for i := 0; i < extra; i++ {
+		mw.WriteField(fmt.Sprintf("x-%d", i%2), "value")
+	}
+	fw, _ := mw.CreateFormFile(FormFile, "scan.txt")
+	fw.Write([]byte("scan"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/uploads/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestFormFieldCount(t *testing.T) {
+	h, token := newUploadTest(t, filetest.NewMemory(), 1<<20)
+	// repeated field names count too
+	if w := postForm(h, token, maxFormFields); w.Code != http.StatusBadRequest {
+		t.Errorf("form with %d fields status %d, want 400", maxFormFields+1, w.Code)
+	}
+	if w := postForm(h, token, maxFormFields-1); w.Code != http.StatusCreated {
+		t.Errorf("form with %d fields status %d: %s", maxFormFields, w.Code, w.Body)
+	}
+}
@@ -0,0 +1,216 @@
+package serve
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+type claimsKey struct{}
+
+// ClaimsFromContext return the verified token claims of a request served by Handler
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
+
+// AuthorizeFunc decide whether request r may read key, returning an error to deny it.
+// Token claims are available with ClaimsFromContext. Return file.ErrNotFound to hide the file existence.
+type AuthorizeFunc func(ctx context.Context, key string, r *http.Request) error
+
+// Handler serve files of Store to requests carrying a token issued by Tokens and accepted by Authorize.
+// The file key is the request path without Prefix. Responses carry the file ETag
+// and If-None-Match requests are answered with 304 without transferring the file.
+type Handler struct {
+	Store  file.IFile
+	Tokens *TokenService
+	// Prefix is removed from the request path to get the file key, e.g. "/files/"
+	Prefix string
+	// CacheControl is sent with every file, "private, max-age=300" when empty
+	CacheControl string
+	// Authorize check per file permissions after the token, e.g. owner or entitlement checks.
+	// Tokens may be nil when Authorize authenticate requests itself, a handler with neither deny everything.
+	Authorize AuthorizeFunc
+	// AllowedOrigins protect every file from hotlinking, requests whose Origin or Referer
+	// is not one of them are rejected, e.g. "https://shop.example.com". Any origin when empty.
+	AllowedOrigins []string
+	// AllowNoReferer accept requests without Origin and Referer, sent by direct visits and some privacy settings
+	AllowNoReferer bool
+	// PendingPlaceholder is served uncached while a file is generated, e.g. a rendition of image.Lazy.
+	// Such requests are answered 202 with Retry-After when empty
+	PendingPlaceholder []byte
+	// Placeholders are served instead of 404 for missing files by key prefix, the longest matching prefix win,
+	// e.g. {"avatars/": serve.InitialsPlaceholder(nil)}
+	Placeholders map[string]Placeholder
+	// Revocations reject tokens revoked before their expiry, e.g. shared links, checked when set
+	Revocations RevocationList
+	// CachePolicy is set on the download context when not zero, so a file.Cached Store keep serving
+	// stale files, e.g. thumbnails, during brief storage outages. See file.WithCachePolicy.
+	CachePolicy file.CachePolicy
+}
+
+// NewHandler create handler serving store under prefix
+func NewHandler(store file.IFile, tokens *TokenService, prefix string) *Handler {
+	return &Handler{Store: store, Tokens: tokens, Prefix: prefix}
+}
+
+// ServeHTTP verify the request token and stream the file
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, h.Prefix)
+	if key == "" || (h.Prefix != "" && !strings.HasPrefix(r.URL.Path, h.Prefix)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, err := h.authorize(key, r)
+	if err == file.ErrNotFound {
+		h.notFound(w, r, key)
+		return
+	}
+	if err != nil {
+		// authorization errors may carry application details, only token errors are shown
+		msg := http.StatusText(http.StatusForbidden)
+		if err == ErrInvalidToken || err == ErrExpiredToken || err == ErrRevokedToken || err == ErrHotlink {
+			msg = err.Error()
+		}
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+
+	if h.CachePolicy != (file.CachePolicy{}) {
+		ctx = file.WithCachePolicy(ctx, h.CachePolicy)
+	}
+	data, etag, err := h.Store.DownloadIfModified(ctx, key, r.Header.Get("If-None-Match"))
+	switch {
+	case err == file.ErrNotModified:
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	case err == file.ErrNotFound:
+		h.notFound(w, r, key)
+		return
+	case err == file.ErrPending:
+		h.pending(w, r)
+		return
+	case err != nil:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	cacheControl := h.CacheControl
+	if cacheControl == "" {
+		cacheControl = "private, max-age=300"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// pending answer a request of a file being generated with PendingPlaceholder, or 202 to retry later
+func (h *Handler) pending(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", "1")
+	if len(h.PendingPlaceholder) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(h.PendingPlaceholder))
+	w.Header().Set("Content-Length", strconv.Itoa(len(h.PendingPlaceholder)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method != http.MethodHead {
+		w.Write(h.PendingPlaceholder)
+	}
+}
+
+// authorize verify the request token then run Authorize, return the request context with the token claims
+func (h *Handler) authorize(key string, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	if h.Tokens == nil && h.Authorize == nil {
+		return ctx, ErrForbidden
+	}
+
+	origin := requestOrigin(r)
+	if len(h.AllowedOrigins) > 0 && !h.originAllowed(origin, h.AllowedOrigins) {
+		return ctx, ErrHotlink
+	}
+
+	if h.Tokens != nil {
+		token := r.URL.Query().Get(TokenParam)
+		claims, err := h.Tokens.Verify(key, token)
+		if err != nil {
+			return ctx, err
+		}
+		if h.Revocations != nil {
+			revoked, err := h.Revocations.Revoked(ctx, key, token, claims.IssuedAt)
+			if err != nil {
+				return ctx, err
+			}
+			if revoked {
+				return ctx, ErrRevokedToken
+			}
+		}
+		if !claims.Perm.Has(PermRead) {
+			return ctx, ErrForbidden
+		}
+		if claims.Origin != "" && !h.originAllowed(origin, []string{claims.Origin}) {
+			return ctx, ErrHotlink
+		}
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+	}
+
+	if h.Authorize != nil {
+		if err := h.Authorize(ctx, key, r); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// requestOrigin return the scheme and host of the page that sent r, from Origin or Referer
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "null" {
+		return origin
+	}
+	ref, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || ref.Host == "" {
+		return ""
+	}
+	return ref.Scheme + "://" + ref.Host
+}
+
+func (h *Handler) originAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return h.AllowNoReferer
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	stdimage "image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ndv6/assets-sdk/image"
+)
+
+// PlaceholderCacheControl is sent with placeholders, short so a file stored again is served soon
+const PlaceholderCacheControl = "private, max-age=60"
+
+// Placeholder render the content served instead of a missing file, e.g. a default avatar for deleted users
+type Placeholder func(ctx context.Context, key string) (data []byte, contentType string, err error)
+
+// SolidPlaceholder serve a 1x1 png of color c, scaled by browsers to the image box
+func SolidPlaceholder(c color.Color) Placeholder {
+	img := stdimage.NewRGBA(stdimage.Rect(0, 0, 1, 1))
+	img.Set(0, 0, c)
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	data := buf.Bytes()
+	return func(ctx context.Context, key string) ([]byte, string, error) {
+		return data, "image/png", nil
+	}
+}
+
+// missingSVG is a framed landscape crossed out
+const missingSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 256 256">` +
+	`<rect width="256" height="256" fill="#eceff1"/>` +
+	`<rect x="56" y="72" width="144" height="112" rx="8" fill="none" stroke="#90a4ae" stroke-width="8"/>` +
+	`<circle cx="100" cy="108" r="12" fill="#90a4ae"/>` +
+	`<path d="M64 176l40-40 28 28 20-20 40 32z" fill="#90a4ae"/>` +
+	`<path d="M48 208L208 48" stroke="#78909c" stroke-width="10" stroke-linecap="round"/>` +
+	`</svg>`
+
+// MissingPlaceholder serve an "image missing" svg graphic
+func MissingPlaceholder() Placeholder {
+	return func(ctx context.Context, key string) ([]byte, string, error) {
+		return []byte(missingSVG), "image/svg+xml", nil
+	}
+}
+
+// InitialsPlaceholder serve an svg avatar with the initials of the name of key, see image.Avatar, so a user
+// keeps the same avatar. name return the display name of key, e.g. from the user database,
+// the base name of key is used when name is nil or return an empty string.
+//
+//	Example:
+//	handler.Placeholders = map[string]serve.Placeholder{"avatars/": serve.InitialsPlaceholder(func(ctx context.Context, key string) string {
+//		return users.Name(ctx, strings.TrimSuffix(path.Base(key), path.Ext(key)))
+//	})}
+func InitialsPlaceholder(name func(ctx context.Context, key string) string) Placeholder {
+	return func(ctx context.Context, key string) ([]byte, string, error) {
+		var n string
+		if name != nil {
+			n = name(ctx, key)
+		}
+		if n == "" {
+			n = strings.TrimSuffix(path.Base(key), path.Ext(key))
+		}
+		return image.Avatar{Name: n}.SVG(), "image/svg+xml", nil
+	}
+}
+
+// placeholder return the placeholder of the longest prefix of Placeholders matching key
+func (h *Handler) placeholder(key string) (Placeholder, bool) {
+	var best string
+	var found Placeholder
+	for prefix, p := range h.Placeholders {
+		if strings.HasPrefix(key, prefix) && (found == nil || len(prefix) > len(best)) {
+			best, found = prefix, p
+		}
+	}
+	return found, found != nil
+}
+
+// notFound answer a missing file with its placeholder, 404 when its prefix has none
+func (h *Handler) notFound(w http.ResponseWriter, r *http.Request, key string) {
+	p, ok := h.placeholder(key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, contentType, err := p(r.Context(), key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", PlaceholderCacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if contentType == "image/svg+xml" {
+		// placeholders are images, never documents running scripts
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
@@ -0,0 +1,314 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+const (
+	// DefaultProgressPoll is how often ProgressHandler read the job status by default
+	DefaultProgressPoll = time.Second
+	// DefaultProgressRetention is how long UploadProgress keep a finished upload by default
+	DefaultProgressRetention = 10 * time.Minute
+	// progressHeartbeat keep idle event streams open through proxies
+	progressHeartbeat = 15 * time.Second
+	// progressInterval is the shortest interval between two progress events
+	progressInterval = 200 * time.Millisecond
+)
+
+// UploadState is the state of an upload followed by UploadProgress
+type UploadState string
+
+const (
+	UploadPending   UploadState = "pending"
+	UploadReceiving UploadState = "receiving"
+	UploadStored    UploadState = "stored"
+	UploadFailed    UploadState = "failed"
+)
+
+// Progress is the progress of an upload and of its processing
+type Progress struct {
+	State    UploadState `json:"state"`
+	Key      string      `json:"key,omitempty"`
+	Received int64       `json:"received"`
+	// Total is the declared upload size, the request length for forms, -1 when unknown
+	Total int64  `json:"total"`
+	Error string `json:"error,omitempty"`
+	// JobID is the pipeline job processing the stored file
+	JobID string `json:"jobId,omitempty"`
+	// Job is the processing status, read by ProgressHandler
+	Job *pipeline.JobStatus `json:"job,omitempty"`
+}
+
+type progressEntry struct {
+	progress Progress
+	expires  time.Time
+	changed  chan struct{}
+}
+
+// UploadProgress follow the uploads of UploadHandler by upload token. It is in memory, progress requests
+// must reach the instance receiving the upload, e.g. with sticky sessions on the token.
+// Processing statuses are read from the pipeline StatusStore, shared by every instance.
+type UploadProgress struct {
+	// Retention is how long a finished upload is kept, DefaultProgressRetention when 0
+	Retention time.Duration
+
+	mu      sync.Mutex
+	uploads map[string]*progressEntry
+}
+
+// NewUploadProgress create an empty UploadProgress
+func NewUploadProgress() *UploadProgress {
+	return &UploadProgress{uploads: map[string]*progressEntry{}}
+}
+
+// Watch return the progress of the upload with token id and a channel closed on its next change,
+// so other transports than ProgressHandler, e.g. WebSocket, follow uploads. expires is the token expiry.
+func (p *UploadProgress) Watch(id string, expires time.Time) (Progress, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.entry(id, expires)
+	return e.progress, e.changed
+}
+
+// entry return the entry of id, created pending until expires, caller must hold mu
+func (p *UploadProgress) entry(id string, expires time.Time) *progressEntry {
+	if e, ok := p.uploads[id]; ok {
+		return e
+	}
+	now := time.Now()
+	for k, e := range p.uploads {
+		if now.After(e.expires) {
+			delete(p.uploads, k)
+		}
+	}
+	e := &progressEntry{progress: Progress{State: UploadPending, Total: -1}, expires: expires, changed: make(chan struct{})}
+	p.uploads[id] = e
+	return e
+}
+
+// update apply fn to the progress of id and wake its watchers
+func (p *UploadProgress) update(id string, fn func(e *progressEntry)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.entry(id, time.Now().Add(p.retention()))
+	fn(e)
+	e.notify()
+}
+
+// notify wake the watchers of e, caller must hold mu
+func (e *progressEntry) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// busy report whether a request is receiving or stored the upload
+func (e *progressEntry) busy() bool {
+	return e.progress.State == UploadReceiving || e.progress.State == UploadStored
+}
+
+func (p *UploadProgress) retention() time.Duration {
+	if p.Retention > 0 {
+		return p.Retention
+	}
+	return DefaultProgressRetention
+}
+
+// begin start following the upload of token id, false when another request is already receiving or stored it
+func (p *UploadProgress) begin(id, key string, total int64, expires time.Time) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.entry(id, expires)
+	if e.busy() {
+		return false
+	}
+	e.progress = Progress{State: UploadReceiving, Key: key, Total: total}
+	e.expires = expires
+	e.notify()
+	return true
+}
+
+// reader return r counting the bytes received for the upload id
+func (p *UploadProgress) reader(id string, r io.Reader) io.Reader {
+	return &progressReader{r: r, p: p, id: id}
+}
+
+// finish record the stored upload id processed by jobID, msg tell why processing did not start
+func (p *UploadProgress) finish(id, jobID, msg string) {
+	p.update(id, func(e *progressEntry) {
+		e.progress.State, e.progress.JobID, e.progress.Error = UploadStored, jobID, msg
+		e.expires = time.Now().Add(p.retention())
+	})
+}
+
+// fail record the failed upload id
+func (p *UploadProgress) fail(id, msg string) {
+	p.update(id, func(e *progressEntry) {
+		e.progress.State, e.progress.Error = UploadFailed, msg
+		e.expires = time.Now().Add(p.retention())
+	})
+}
+
+// reject record a request of token id rejected before its upload started,
+// unless another request is receiving or stored the upload
+func (p *UploadProgress) reject(id, msg string, expires time.Time) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.entry(id, expires)
+	if !e.busy() {
+		e.progress.State, e.progress.Error = UploadFailed, msg
+		e.notify()
+	}
+}
+
+type progressReader struct {
+	r  io.Reader
+	p  *UploadProgress
+	id string
+	n  int64
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		pr.n += int64(n)
+		received := pr.n
+		pr.p.update(pr.id, func(e *progressEntry) {
+			e.progress.Received = received
+		})
+	}
+	return n, err
+}
+
+// ProgressHandler stream the progress of an upload and of its processing as server-sent events,
+// so frontends show real progress bars without polling. The upload token is the TokenParam query parameter,
+// the stream can be opened before the upload starts. A "progress" event carry every change of Progress
+// as JSON, a "done" event end the stream once the upload failed or is stored and processed.
+//
+//	Example:
+//	const events = new EventSource("/uploads/progress?t=" + token)
+//	events.addEventListener("progress", e => render(JSON.parse(e.data)))
+//	events.addEventListener("done", e => events.close())
+type ProgressHandler struct {
+	Tokens   *TokenService
+	Progress *UploadProgress
+	// Runner is the runner of UploadHandler, job statuses are not followed when nil
+	Runner *pipeline.Runner
+	// PollInterval is how often the job status is read, DefaultProgressPoll when 0
+	PollInterval time.Duration
+}
+
+// NewProgressHandler create handler streaming the uploads followed by progress and their jobs run by runner
+func NewProgressHandler(tokens *TokenService, progress *UploadProgress, runner *pipeline.Runner) *ProgressHandler {
+	return &ProgressHandler{Tokens: tokens, Progress: progress, Runner: runner}
+}
+
+// ServeHTTP verify the upload token and stream its progress until done
+func (h *ProgressHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	claims, err := h.Tokens.VerifyUpload(r.URL.Query().Get(TokenParam))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// disable response buffering of nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	pollInterval := h.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultProgressPoll
+	}
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(progressHeartbeat)
+	defer heartbeat.Stop()
+	expired := time.NewTimer(time.Until(claims.Expires))
+	defer expired.Stop()
+
+	ctx := r.Context()
+	var sent []byte
+	for {
+		progress, changed := h.Progress.Watch(claims.ID, claims.Expires)
+		if progress.JobID != "" && h.Runner != nil {
+			if status, err := h.Runner.JobStatus(ctx, progress.JobID); err == nil {
+				progress.Job = &status
+			}
+		}
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(data, sent) {
+			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+			flusher.Flush()
+			sent = data
+		}
+		if h.done(progress) {
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			// coalesce the many changes of a fast upload
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(progressInterval):
+			}
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-expired.C:
+			if progress.State == UploadPending {
+				fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+				flusher.Flush()
+				return
+			}
+		}
+	}
+}
+
+// done report whether the upload failed, or is stored and its job finished
+func (h *ProgressHandler) done(p Progress) bool {
+	switch {
+	case p.State == UploadFailed:
+		return true
+	case p.State != UploadStored:
+		return false
+	case p.JobID == "" || h.Runner == nil:
+		return true
+	}
+	return p.Job != nil && p.Job.Done()
+}
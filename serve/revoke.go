@@ -0,0 +1,231 @@
+package serve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+// DefaultRevocationPrefix is where StoreRevocations keep revocations
+const DefaultRevocationPrefix = "system/revocations/"
+
+// RevocationList invalidate shared links before their expiry. Handler reject the tokens it revoked.
+// Revocations are kept until the tokens they revoke expire, Sweep drop them afterwards.
+type RevocationList interface {
+	// RevokeToken invalidate token, expires is the token expiry
+	RevokeToken(ctx context.Context, token string, expires time.Time) error
+	// RevokeKey invalidate every token of key issued up to now, expires is when the last of them expire,
+	// e.g. now plus the longest ttl the application issue
+	RevokeKey(ctx context.Context, key string, expires time.Time) error
+	// Revoked report whether token of key issued at issued was revoked
+	Revoked(ctx context.Context, key, token string, issued time.Time) (bool, error)
+	// Sweep drop the revocations whose tokens expired, it return the number dropped
+	Sweep(ctx context.Context) (int, error)
+}
+
+// Revoke invalidate token of key in list until its expiry, revoking an expired token does nothing.
+// The token signature is checked so only issued tokens fill the list.
+//
+//	Example:
+//	err := tokens.Revoke(ctx, revocations, "albums/42/cover.jpg", token)
+//	err = revocations.RevokeKey(ctx, "albums/42/cover.jpg", time.Now().Add(30*24*time.Hour)) // every link
+func (s *TokenService) Revoke(ctx context.Context, list RevocationList, key, token string) error {
+	claims, err := s.Verify(key, token)
+	if err == ErrExpiredToken {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return list.RevokeToken(ctx, token, claims.Expires)
+}
+
+// revocationID return the hex sha256 of s, tokens and keys are not kept in clear
+func revocationID(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// revocation is made at At and needed until Expires, for a key it revoke the tokens issued up to At
+type revocation struct {
+	At      time.Time `json:"at"`
+	Expires time.Time `json:"expires"`
+}
+
+// MemoryRevocations is an in memory RevocationList, for a single handler instance
+type MemoryRevocations struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	keys   map[string]revocation
+}
+
+// NewMemoryRevocations create an empty in memory RevocationList
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{tokens: map[string]time.Time{}, keys: map[string]revocation{}}
+}
+
+// RevokeToken invalidate token until expires
+func (m *MemoryRevocations) RevokeToken(ctx context.Context, token string, expires time.Time) error {
+	m.mu.Lock()
+	m.tokens[revocationID(token)] = expires
+	m.mu.Unlock()
+	return nil
+}
+
+// RevokeKey invalidate the tokens of key issued up to now
+func (m *MemoryRevocations) RevokeKey(ctx context.Context, key string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := revocationID(key)
+	if prev, ok := m.keys[id]; ok && prev.Expires.After(expires) {
+		expires = prev.Expires
+	}
+	m.keys[id] = revocation{At: time.Now(), Expires: expires}
+	return nil
+}
+
+// Revoked report whether token was revoked, alone or with every token of key
+func (m *MemoryRevocations) Revoked(ctx context.Context, key, token string, issued time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.tokens[revocationID(token)]; ok {
+		return true, nil
+	}
+	k, ok := m.keys[revocationID(key)]
+	return ok && revokedBefore(issued, k.At), nil
+}
+
+// Sweep drop the revocations of expired tokens
+func (m *MemoryRevocations) Sweep(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for id, expires := range m.tokens {
+		if now.After(expires) {
+			delete(m.tokens, id)
+			n++
+		}
+	}
+	for id, k := range m.keys {
+		if now.After(k.Expires) {
+			delete(m.keys, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// revokedBefore report whether a token issued at issued is revoked by a key revocation at at.
+// Issue times are to the second, tokens issued in the second of the revocation are revoked too.
+func revokedBefore(issued, at time.Time) bool {
+	return !issued.After(at.Truncate(time.Second))
+}
+
+// StoreRevocations keep revocations as JSON files of an IFile, shared by every handler instance.
+// Every request read up to two small files, put a cache in front of the store for busy handlers.
+// The default prefix is reserved by file.Guard, pass the store beneath the guard.
+type StoreRevocations struct {
+	Store  file.IFile
+	Prefix string
+}
+
+// NewStoreRevocations keep revocations under DefaultRevocationPrefix of store
+func NewStoreRevocations(store file.IFile) *StoreRevocations {
+	return &StoreRevocations{Store: store, Prefix: DefaultRevocationPrefix}
+}
+
+func (s *StoreRevocations) tokenPath(token string) string {
+	return s.Prefix + "tokens/" + revocationID(token) + ".json"
+}
+
+func (s *StoreRevocations) keyPath(key string) string {
+	return s.Prefix + "keys/" + revocationID(key) + ".json"
+}
+
+// RevokeToken invalidate token until expires
+func (s *StoreRevocations) RevokeToken(ctx context.Context, token string, expires time.Time) error {
+	return s.put(ctx, s.tokenPath(token), revocation{At: time.Now(), Expires: expires})
+}
+
+// RevokeKey invalidate the tokens of key issued up to now
+func (s *StoreRevocations) RevokeKey(ctx context.Context, key string, expires time.Time) error {
+	prev, found, err := s.get(ctx, s.keyPath(key))
+	if err != nil {
+		return err
+	}
+	if found && prev.Expires.After(expires) {
+		expires = prev.Expires
+	}
+	return s.put(ctx, s.keyPath(key), revocation{At: time.Now(), Expires: expires})
+}
+
+// Revoked report whether token was revoked, alone or with every token of key
+func (s *StoreRevocations) Revoked(ctx context.Context, key, token string, issued time.Time) (bool, error) {
+	_, found, err := s.get(ctx, s.tokenPath(token))
+	if err != nil || found {
+		return found, err
+	}
+	k, found, err := s.get(ctx, s.keyPath(key))
+	if err != nil {
+		return false, err
+	}
+	return found && revokedBefore(issued, k.At), nil
+}
+
+// Sweep delete the revocations of expired tokens
+func (s *StoreRevocations) Sweep(ctx context.Context) (int, error) {
+	// stop listing when returning early on an error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	now := time.Now()
+	n := 0
+	for item := range s.Store.ListStream(ctx, s.Prefix) {
+		if item.Err != nil {
+			return n, item.Err
+		}
+		if !strings.HasSuffix(item.Info.Name, ".json") {
+			continue
+		}
+		rev, found, err := s.get(ctx, item.Info.Name)
+		if err != nil {
+			return n, err
+		}
+		if !found || !now.After(rev.Expires) {
+			continue
+		}
+		if _, err := s.Store.Delete(ctx, item.Info.Name); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *StoreRevocations) get(ctx context.Context, filePath string) (revocation, bool, error) {
+	var rev revocation
+	data, err := s.Store.Download(ctx, filePath)
+	if err == file.ErrNotFound {
+		return rev, false, nil
+	}
+	if err != nil {
+		return rev, false, err
+	}
+	return rev, true, json.Unmarshal(data, &rev)
+}
+
+func (s *StoreRevocations) put(ctx context.Context, filePath string, rev revocation) error {
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.Upload(ctx, filePath, "application/json", data)
+	return err
+}
@@ -0,0 +1,606 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	// DefaultS3MaxSize is the largest object accepted by S3Handler by default, the S3 single PUT limit
+	DefaultS3MaxSize = 5 << 30
+	// s3MaxKeys is the most keys returned by one list request
+	s3MaxKeys = 1000
+	// s3MaxDeleteBody bound the DeleteObjects request body
+	s3MaxDeleteBody = 2 << 20
+	s3Namespace     = "http://s3.amazonaws.com/doc/2006-03-01/"
+	s3XMLTime       = "2006-01-02T15:04:05.000Z"
+	s3MetaPrefix    = "X-Amz-Meta-"
+)
+
+// S3Handler serve a minimal S3 compatible API over Store, so legacy tools that only speak S3,
+// e.g. backup agents or the aws cli, write into the asset store. Requests must be signed with
+// AWS signature version 4 by an access key of Credentials, in the Authorization header or as presigned url.
+// Buckets map to key prefixes of Store and are addressed path style, e.g. PUT /legacy/2020/01.pdf,
+// or virtual host style when the request host start with the bucket name.
+//
+// Supported operations are ListBuckets, HeadBucket, ListObjects, ListObjectsV2, PutObject, CopyObject,
+// GetObject, HeadObject, DeleteObject and DeleteObjects. Multipart uploads, versioning and ACLs answer NotImplemented.
+type S3Handler struct {
+	Store file.IFile
+	// Buckets map bucket names to key prefixes of Store, e.g. "legacy": "imports/legacy/"
+	Buckets map[string]string
+	// Credentials map access key ids to secret access keys
+	Credentials map[string]string
+	// Region is the region expected in request credentials, any region when empty
+	Region string
+	// MaxSize is the largest accepted object, DefaultS3MaxSize when 0
+	MaxSize int64
+}
+
+// NewS3Handler create handler serving buckets of store to clients signing with credentials
+//
+//	Example:
+//	s3 := serve.NewS3Handler(store, map[string]string{"legacy": "imports/legacy/"}, map[string]string{accessKeyID: secretAccessKey})
+//	http.ListenAndServe(":9000", s3)
+func NewS3Handler(store file.IFile, buckets, credentials map[string]string) *S3Handler {
+	return &S3Handler{Store: store, Buckets: buckets, Credentials: credentials}
+}
+
+// s3Error is an S3 error response
+type s3Error struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	return "serve: s3 " + e.Code + ": " + e.Message
+}
+
+var (
+	errS3AccessDenied         = &s3Error{http.StatusForbidden, "AccessDenied", "Access Denied"}
+	errS3ObjectLocked         = &s3Error{http.StatusForbidden, "AccessDenied", "The object is protected by a legal hold or a retention period"}
+	errS3InvalidAccessKey     = &s3Error{http.StatusForbidden, "InvalidAccessKeyId", "The access key id does not exist"}
+	errS3SignatureMismatch    = &s3Error{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature does not match"}
+	errS3TimeSkewed           = &s3Error{http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the server time is too large"}
+	errS3ExpiredRequest       = &s3Error{http.StatusForbidden, "AccessDenied", "Request has expired"}
+	errS3AuthorizationHeader  = &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed"}
+	errS3AuthorizationQuery   = &s3Error{http.StatusBadRequest, "AuthorizationQueryParametersError", "The presigned url is malformed"}
+	errS3InvalidArgument      = &s3Error{http.StatusBadRequest, "InvalidArgument", "Invalid argument"}
+	errS3InvalidDigest        = &s3Error{http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified is not valid"}
+	errS3BadDigest            = &s3Error{http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received"}
+	errS3PayloadMismatch      = &s3Error{http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided x-amz-content-sha256 header does not match what was computed"}
+	errS3IncompleteBody       = &s3Error{http.StatusBadRequest, "IncompleteBody", "The request body is malformed or shorter than declared"}
+	errS3MissingContentLength = &s3Error{http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header"}
+	errS3TooLarge             = &s3Error{http.StatusBadRequest, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size"}
+	errS3MalformedXML         = &s3Error{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed"}
+	errS3NoSuchBucket         = &s3Error{http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist"}
+	errS3NoSuchKey            = &s3Error{http.StatusNotFound, "NoSuchKey", "The specified key does not exist"}
+	errS3MethodNotAllowed     = &s3Error{http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource"}
+	errS3NotImplemented       = &s3Error{http.StatusNotImplemented, "NotImplemented", "A header or query you provided implies functionality that is not implemented"}
+	errS3Internal             = &s3Error{http.StatusInternalServerError, "InternalError", "We encountered an internal error, please try again"}
+)
+
+// s3ErrorOf map store and payload errors to S3 errors
+func s3ErrorOf(err error) *s3Error {
+	var se *s3Error
+	switch {
+	case errors.As(err, &se):
+		return se
+	case err == file.ErrNotFound:
+		return errS3NoSuchKey
+	case err == file.ErrTooLarge:
+		return errS3TooLarge
+	case err == file.ErrReadOnly:
+		return errS3AccessDenied
+	case errors.Is(err, file.ErrHeld):
+		return errS3ObjectLocked
+	case errors.Is(err, file.ErrInvalidKey):
+		return errS3InvalidArgument
+	}
+	return errS3Internal
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, e *s3Error) {
+	body := struct {
+		XMLName xml.Name `xml:"Error"`
+		*s3Error
+		Resource string `xml:"Resource"`
+	}{s3Error: e, Resource: r.URL.Path}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.Status)
+	if r.Method != http.MethodHead {
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeS3XML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+// ServeHTTP verify the request signature then run the S3 operation
+func (h *S3Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth, serr := h.authenticate(r)
+	if serr != nil {
+		writeS3Error(w, r, serr)
+		return
+	}
+
+	bucket, key := h.route(r)
+	if bucket == "" {
+		if r.Method != http.MethodGet {
+			writeS3Error(w, r, errS3MethodNotAllowed)
+			return
+		}
+		h.listBuckets(w)
+		return
+	}
+	prefix, ok := h.Buckets[bucket]
+	if !ok {
+		writeS3Error(w, r, errS3NoSuchBucket)
+		return
+	}
+
+	q := r.URL.Query()
+	for _, sub := range []string{"uploads", "uploadId", "versionId", "versioning", "acl", "policy", "tagging"} {
+		if _, ok := q[sub]; ok {
+			writeS3Error(w, r, errS3NotImplemented)
+			return
+		}
+	}
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodGet && hasQuery(q, "location"):
+			h.location(w)
+		case r.Method == http.MethodGet:
+			h.list(w, r, bucket, prefix)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && hasQuery(q, "delete"):
+			h.deleteObjects(w, r, auth, prefix)
+		default:
+			writeS3Error(w, r, errS3MethodNotAllowed)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			h.copy(w, r, prefix+key)
+			return
+		}
+		h.put(w, r, auth, prefix+key)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, prefix+key)
+	case http.MethodDelete:
+		if _, err := h.Store.Delete(r.Context(), prefix+key); err != nil && err != file.ErrNotFound {
+			writeS3Error(w, r, s3ErrorOf(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, r, errS3MethodNotAllowed)
+	}
+}
+
+func hasQuery(q url.Values, name string) bool {
+	_, ok := q[name]
+	return ok
+}
+
+// route return the bucket and object key of r, virtual host style when the host start with a bucket name
+func (h *S3Handler) route(r *http.Request) (string, string) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	for bucket := range h.Buckets {
+		if strings.HasPrefix(host, bucket+".") {
+			return bucket, p
+		}
+	}
+	parts := strings.SplitN(p, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+type s3Bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+func (h *S3Handler) listBuckets(w http.ResponseWriter) {
+	result := struct {
+		XMLName xml.Name   `xml:"ListAllMyBucketsResult"`
+		Xmlns   string     `xml:"xmlns,attr"`
+		Owner   string     `xml:"Owner>ID"`
+		Buckets []s3Bucket `xml:"Buckets>Bucket"`
+	}{Xmlns: s3Namespace, Owner: "assets"}
+	for name := range h.Buckets {
+		result.Buckets = append(result.Buckets, s3Bucket{Name: name, CreationDate: time.Time{}.Format(s3XMLTime)})
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool { return result.Buckets[i].Name < result.Buckets[j].Name })
+	writeS3XML(w, result)
+}
+
+func (h *S3Handler) location(w http.ResponseWriter) {
+	writeS3XML(w, struct {
+		XMLName xml.Name `xml:"LocationConstraint"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Region  string   `xml:",chardata"`
+	}{Xmlns: s3Namespace, Region: h.Region})
+}
+
+type s3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3Prefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type s3ListResult struct {
+	XMLName               xml.Name   `xml:"ListBucketResult"`
+	Xmlns                 string     `xml:"xmlns,attr"`
+	Name                  string     `xml:"Name"`
+	Prefix                string     `xml:"Prefix"`
+	Delimiter             string     `xml:"Delimiter,omitempty"`
+	MaxKeys               int        `xml:"MaxKeys"`
+	EncodingType          string     `xml:"EncodingType,omitempty"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	Marker                *string    `xml:"Marker"`
+	NextMarker            string     `xml:"NextMarker,omitempty"`
+	KeyCount              *int       `xml:"KeyCount"`
+	StartAfter            string     `xml:"StartAfter,omitempty"`
+	ContinuationToken     string     `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string     `xml:"NextContinuationToken,omitempty"`
+	Contents              []s3Object `xml:"Contents"`
+	CommonPrefixes        []s3Prefix `xml:"CommonPrefixes"`
+}
+
+// list answer ListObjects and ListObjectsV2 (list-type=2), listing keys of the bucket lazily in name order
+func (h *S3Handler) list(w http.ResponseWriter, r *http.Request, bucket, prefix string) {
+	q := r.URL.Query()
+	v2 := q.Get("list-type") == "2"
+	objPrefix, delimiter := q.Get("prefix"), q.Get("delimiter")
+	maxKeys := s3MaxKeys
+	if s := q.Get("max-keys"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeS3Error(w, r, errS3InvalidArgument)
+			return
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+	escape := func(s string) string { return s }
+	if q.Get("encoding-type") == "url" {
+		escape = url.QueryEscape
+	}
+
+	result := s3ListResult{
+		Xmlns:        s3Namespace,
+		Name:         bucket,
+		Prefix:       escape(objPrefix),
+		Delimiter:    escape(delimiter),
+		MaxKeys:      maxKeys,
+		EncodingType: q.Get("encoding-type"),
+	}
+	after := q.Get("marker")
+	if v2 {
+		after = q.Get("start-after")
+		result.StartAfter = escape(after)
+		if token := q.Get("continuation-token"); token != "" {
+			decoded, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				writeS3Error(w, r, errS3InvalidArgument)
+				return
+			}
+			after = string(decoded)
+			result.ContinuationToken = token
+		}
+	} else {
+		result.Marker = &after
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	var opts []file.ListOption
+	if after != "" {
+		opts = append(opts, file.WithStartAfter(prefix+after))
+	}
+	count, last := 0, ""
+	for item := range h.Store.ListStream(ctx, prefix+objPrefix, opts...) {
+		if item.Err != nil {
+			writeS3Error(w, r, s3ErrorOf(item.Err))
+			return
+		}
+		name := strings.TrimPrefix(item.Info.Name, prefix)
+		if delimiter != "" {
+			if i := strings.Index(name[len(objPrefix):], delimiter); i >= 0 {
+				// keys below a common prefix already returned, or before the marker, are rolled up
+				common := name[:len(objPrefix)+i+len(delimiter)]
+				if common == last || common <= after {
+					continue
+				}
+				if count == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes, s3Prefix{Prefix: escape(common)})
+				count, last = count+1, common
+				continue
+			}
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		result.Contents = append(result.Contents, s3Object{
+			Key:          escape(name),
+			LastModified: item.Info.LastModified.UTC().Format(s3XMLTime),
+			ETag:         s3ETag(item.Info),
+			Size:         item.Info.Size,
+			StorageClass: "STANDARD",
+		})
+		count, last = count+1, name
+	}
+
+	if v2 {
+		result.KeyCount = &count
+		if result.IsTruncated {
+			result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+		}
+	} else if result.IsTruncated {
+		result.NextMarker = escape(last)
+	}
+	writeS3XML(w, result)
+}
+
+// s3ETag return the quoted content MD5 of a file like S3, the store ETag when unknown
+func s3ETag(info file.ObjectInfo) string {
+	if len(info.ContentMD5) == md5.Size {
+		return `"` + hex.EncodeToString(info.ContentMD5) + `"`
+	}
+	return `"` + strings.Trim(info.ETag, `"`) + `"`
+}
+
+// stat return the info of key, file.ErrNotFound when missing
+func (h *S3Handler) stat(ctx context.Context, key string) (file.ObjectInfo, error) {
+	infos, err := h.Store.List(ctx, key, file.WithMaxResults(1))
+	if err != nil {
+		return file.ObjectInfo{}, err
+	}
+	if len(infos) == 0 || infos[0].Name != key {
+		return file.ObjectInfo{}, file.ErrNotFound
+	}
+	return infos[0], nil
+}
+
+func (h *S3Handler) get(w http.ResponseWriter, r *http.Request, key string) {
+	info, err := h.stat(r.Context(), key)
+	if err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", s3ETag(info))
+	if info.CacheControl != "" {
+		w.Header().Set("Cache-Control", info.CacheControl)
+	}
+	if info.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", info.ContentEncoding)
+	}
+	for k, v := range info.Metadata {
+		w.Header().Set(s3MetaPrefix+strings.Replace(k, "_", "-", -1), v)
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	if r.Method == http.MethodHead {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	data, err := h.Store.Download(r.Context(), key)
+	if err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+	// ServeContent answer Range and conditional requests
+	http.ServeContent(w, r, "", info.LastModified, bytes.NewReader(data))
+}
+
+// s3Body record the first error of a payload, so verification failures are told apart from store errors
+type s3Body struct {
+	r   io.Reader
+	err error
+}
+
+func (b *s3Body) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (h *S3Handler) put(w http.ResponseWriter, r *http.Request, auth *s3Auth, key string) {
+	payload, size, serr := auth.payload(r)
+	if serr != nil {
+		writeS3Error(w, r, serr)
+		return
+	}
+	maxSize := h.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultS3MaxSize
+	}
+	if size > maxSize {
+		writeS3Error(w, r, errS3TooLarge)
+		return
+	}
+
+	ctx := r.Context()
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, s3MetaPrefix) && len(values) > 0 {
+			// metadata keys are C# identifiers on Azure
+			ctx = file.WithMetadata(ctx, strings.ToLower(strings.Replace(strings.TrimPrefix(name, s3MetaPrefix), "-", "_", -1)), values[0])
+		}
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	sum := md5.New()
+	body := &s3Body{r: io.TeeReader(file.SizeLimitReader(payload, maxSize), sum)}
+	var err error
+	if streamer, ok := h.Store.(file.StreamUploader); ok {
+		_, err = streamer.UploadStream(ctx, key, contentType, body, size)
+	} else {
+		var data []byte
+		data, err = ioutil.ReadAll(body)
+		if err == nil {
+			if contentType == "" {
+				contentType = http.DetectContentType(data)
+			}
+			_, err = h.Store.Upload(ctx, key, contentType, data)
+		}
+	}
+	if body.err != nil {
+		err = body.err
+	}
+	if err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum.Sum(nil))+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copy answer CopyObject, the source is "/bucket/key" or "bucket/key", url encoded
+func (h *S3Handler) copy(w http.ResponseWriter, r *http.Request, key string) {
+	if strings.EqualFold(r.Header.Get("X-Amz-Metadata-Directive"), "REPLACE") {
+		writeS3Error(w, r, errS3NotImplemented)
+		return
+	}
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil || strings.Contains(source, "?") {
+		writeS3Error(w, r, errS3InvalidArgument)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(source, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		writeS3Error(w, r, errS3InvalidArgument)
+		return
+	}
+	prefix, ok := h.Buckets[parts[0]]
+	if !ok {
+		writeS3Error(w, r, errS3NoSuchBucket)
+		return
+	}
+
+	if _, err := h.Store.Copy(r.Context(), prefix+parts[1], key); err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+	info, err := h.stat(r.Context(), key)
+	if err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+	writeS3XML(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		Xmlns        string   `xml:"xmlns,attr"`
+		LastModified string   `xml:"LastModified"`
+		ETag         string   `xml:"ETag"`
+	}{Xmlns: s3Namespace, LastModified: info.LastModified.UTC().Format(s3XMLTime), ETag: s3ETag(info)})
+}
+
+type s3Deleted struct {
+	Key string `xml:"Key"`
+}
+
+type s3DeleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// deleteObjects answer DeleteObjects, deleting up to 1000 keys, missing keys count as deleted like on S3
+func (h *S3Handler) deleteObjects(w http.ResponseWriter, r *http.Request, auth *s3Auth, prefix string) {
+	payload, _, serr := auth.payload(r)
+	if serr != nil {
+		writeS3Error(w, r, serr)
+		return
+	}
+	var req struct {
+		Quiet   bool `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	data, err := ioutil.ReadAll(file.SizeLimitReader(payload, s3MaxDeleteBody))
+	if err != nil {
+		writeS3Error(w, r, s3ErrorOf(err))
+		return
+	}
+	if err := xml.Unmarshal(data, &req); err != nil || len(req.Objects) > s3MaxKeys {
+		writeS3Error(w, r, errS3MalformedXML)
+		return
+	}
+
+	result := struct {
+		XMLName xml.Name        `xml:"DeleteResult"`
+		Xmlns   string          `xml:"xmlns,attr"`
+		Deleted []s3Deleted     `xml:"Deleted"`
+		Errors  []s3DeleteError `xml:"Error"`
+	}{Xmlns: s3Namespace}
+	for _, o := range req.Objects {
+		if _, err := h.Store.Delete(r.Context(), prefix+o.Key); err != nil && err != file.ErrNotFound {
+			e := s3ErrorOf(err)
+			result.Errors = append(result.Errors, s3DeleteError{Key: o.Key, Code: e.Code, Message: e.Message})
+			continue
+		}
+		if !req.Quiet {
+			result.Deleted = append(result.Deleted, s3Deleted{Key: o.Key})
+		}
+	}
+	writeS3XML(w, result)
+}
@@ -0,0 +1,347 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	s3Algorithm        = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	s3StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	// s3StreamingTrailer is the unsigned aws-chunked payload with trailing checksums sent by recent SDKs
+	s3StreamingTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	s3TimeFormat       = "20060102T150405Z"
+	s3EmptySHA256      = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// S3MaxClockSkew is the largest accepted difference between the request date and the server clock
+	S3MaxClockSkew = 15 * time.Minute
+	// s3MaxPresignExpiry is the longest validity of a presigned url
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
+	// s3MaxChunkSize bound the memory held for one aws-chunked chunk
+	s3MaxChunkSize = 16 << 20
+)
+
+// s3Auth is a verified SigV4 request signature, needed to verify aws-chunked payloads
+type s3Auth struct {
+	date        time.Time
+	scope       string
+	signingKey  []byte
+	signature   string
+	payloadHash string
+}
+
+// authenticate verify the SigV4 signature of r, from its Authorization header or presigned url query
+func (h *S3Handler) authenticate(r *http.Request) (*s3Auth, *s3Error) {
+	q := r.URL.Query()
+	var credential, signedHeaders, signature, amzDate string
+	presigned := q.Get("X-Amz-Algorithm") != ""
+	if presigned {
+		if q.Get("X-Amz-Algorithm") != s3Algorithm {
+			return nil, errS3AuthorizationQuery
+		}
+		credential, signedHeaders, signature = q.Get("X-Amz-Credential"), q.Get("X-Amz-SignedHeaders"), q.Get("X-Amz-Signature")
+		amzDate = q.Get("X-Amz-Date")
+	} else {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			return nil, errS3AccessDenied
+		}
+		if !strings.HasPrefix(authorization, s3Algorithm+" ") {
+			return nil, errS3AuthorizationHeader
+		}
+		for _, field := range strings.Split(strings.TrimPrefix(authorization, s3Algorithm+" "), ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+			if len(kv) != 2 {
+				return nil, errS3AuthorizationHeader
+			}
+			switch kv[0] {
+			case "Credential":
+				credential = kv[1]
+			case "SignedHeaders":
+				signedHeaders = kv[1]
+			case "Signature":
+				signature = kv[1]
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+	}
+	if credential == "" || signedHeaders == "" || signature == "" {
+		return nil, errS3AuthorizationHeader
+	}
+
+	// Credential is <access key id>/<date>/<region>/s3/aws4_request
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return nil, errS3AuthorizationHeader
+	}
+	secret, ok := h.Credentials[parts[0]]
+	if !ok {
+		return nil, errS3InvalidAccessKey
+	}
+	if h.Region != "" && parts[2] != h.Region {
+		return nil, errS3AuthorizationHeader
+	}
+
+	var date time.Time
+	var err error
+	if amzDate != "" {
+		date, err = time.Parse(s3TimeFormat, amzDate)
+	} else {
+		date, err = http.ParseTime(r.Header.Get("Date"))
+		amzDate = date.UTC().Format(s3TimeFormat)
+	}
+	if err != nil || date.UTC().Format("20060102") != parts[1] {
+		return nil, errS3AccessDenied
+	}
+	now := time.Now()
+	if presigned {
+		expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+		if err != nil || expires < 0 || time.Duration(expires)*time.Second > s3MaxPresignExpiry {
+			return nil, errS3AuthorizationQuery
+		}
+		if now.After(date.Add(time.Duration(expires)*time.Second)) || date.After(now.Add(S3MaxClockSkew)) {
+			return nil, errS3ExpiredRequest
+		}
+	} else if date.Before(now.Add(-S3MaxClockSkew)) || date.After(now.Add(S3MaxClockSkew)) {
+		return nil, errS3TimeSkewed
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if presigned {
+		payloadHash = s3UnsignedPayload
+	} else if payloadHash == "" {
+		return nil, errS3AuthorizationHeader
+	}
+
+	headers := strings.Split(signedHeaders, ";")
+	signsHost := false
+	for _, name := range headers {
+		signsHost = signsHost || name == "host"
+	}
+	if !signsHost {
+		return nil, errS3AuthorizationHeader
+	}
+	var canonical bytes.Buffer
+	canonical.WriteString(r.Method + "\n")
+	canonical.WriteString(canonicalURI(r.URL.Path) + "\n")
+	canonical.WriteString(canonicalQuery(q, presigned) + "\n")
+	for _, name := range headers {
+		canonical.WriteString(name + ":" + canonicalHeader(r, name) + "\n")
+	}
+	canonical.WriteString("\n" + signedHeaders + "\n" + payloadHash)
+
+	scope := strings.Join(parts[1:], "/")
+	auth := &s3Auth{
+		date:        date,
+		scope:       scope,
+		signingKey:  s3SigningKey(secret, parts[1], parts[2]),
+		payloadHash: payloadHash,
+	}
+	auth.signature = auth.sign(amzDate, sha256Hex(canonical.Bytes()))
+	if !hmac.Equal([]byte(auth.signature), []byte(signature)) {
+		return nil, errS3SignatureMismatch
+	}
+	return auth, nil
+}
+
+// sign return the hex signature of a canonical request or chunk hash
+func (a *s3Auth) sign(amzDate, hashed string) string {
+	return hex.EncodeToString(hmacSHA256(a.signingKey, s3Algorithm+"\n"+amzDate+"\n"+a.scope+"\n"+hashed))
+}
+
+func s3SigningKey(secret, day, region string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalURI encode the path like SigV4 for S3, every byte but unreserved characters and "/"
+func canonicalURI(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return file.EscapeKey(p)
+}
+
+// canonicalQuery sort and encode the query like SigV4, without the signature of presigned urls
+func canonicalQuery(q url.Values, presigned bool) string {
+	var pairs []string
+	for k, values := range q {
+		if presigned && k == "X-Amz-Signature" {
+			continue
+		}
+		for _, v := range values {
+			pairs = append(pairs, s3QueryEscape(k)+"="+s3QueryEscape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func s3QueryEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// canonicalHeader return the values of header name with sequential spaces collapsed
+func canonicalHeader(r *http.Request, name string) string {
+	switch name {
+	case "host":
+		return r.Host
+	case "content-length":
+		if r.Header.Get("Content-Length") == "" {
+			return strconv.FormatInt(r.ContentLength, 10)
+		}
+	}
+	values := r.Header[http.CanonicalHeaderKey(name)]
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// payload return the request body checked against the signed payload hash and Content-MD5,
+// decoded from aws-chunked, with its decoded size, -1 when unknown
+func (a *s3Auth) payload(r *http.Request) (io.Reader, int64, *s3Error) {
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	switch a.payloadHash {
+	case s3UnsignedPayload:
+	case s3StreamingPayload, s3StreamingTrailer:
+		decoded, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || decoded < 0 {
+			return nil, 0, errS3MissingContentLength
+		}
+		body = &s3ChunkReader{r: bufio.NewReader(r.Body), auth: a, signed: a.payloadHash == s3StreamingPayload, prev: a.signature}
+		size = decoded
+	default:
+		want, err := hex.DecodeString(a.payloadHash)
+		if err != nil || len(want) != sha256.Size {
+			return nil, 0, errS3InvalidArgument
+		}
+		body = &digestReader{r: body, h: sha256.New(), want: want, err: errS3PayloadMismatch}
+	}
+	if contentMD5 := r.Header.Get("Content-Md5"); contentMD5 != "" {
+		want, err := base64.StdEncoding.DecodeString(contentMD5)
+		if err != nil || len(want) != md5.Size {
+			return nil, 0, errS3InvalidDigest
+		}
+		body = &digestReader{r: body, h: md5.New(), want: want, err: errS3BadDigest}
+	}
+	return body, size, nil
+}
+
+// digestReader fail with err at the end of r when its digest is not want
+type digestReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want []byte
+	err  error
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.h.Write(p[:n])
+	if err == io.EOF && !hmac.Equal(d.h.Sum(nil), d.want) {
+		return n, d.err
+	}
+	return n, err
+}
+
+// s3ChunkReader decode an aws-chunked payload, verifying the signature chaining every chunk when signed
+type s3ChunkReader struct {
+	r      *bufio.Reader
+	auth   *s3Auth
+	signed bool
+	prev   string
+	chunk  []byte
+	done   bool
+}
+
+func (c *s3ChunkReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+// next read the next chunk, "<hex size>;chunk-signature=<signature>\r\n<data>\r\n"
+func (c *s3ChunkReader) next() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return errS3IncompleteBody
+	}
+	fields := strings.SplitN(strings.TrimRight(line, "\r\n"), ";", 2)
+	size, err := strconv.ParseInt(fields[0], 16, 64)
+	if err != nil || size < 0 || size > s3MaxChunkSize {
+		return errS3IncompleteBody
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return errS3IncompleteBody
+	}
+
+	if c.signed {
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "chunk-signature=") {
+			return errS3IncompleteBody
+		}
+		// the string to sign of a chunk chain the previous signature, the first one is the request signature
+		want := hex.EncodeToString(hmacSHA256(c.auth.signingKey, "AWS4-HMAC-SHA256-PAYLOAD\n"+
+			c.auth.date.UTC().Format(s3TimeFormat)+"\n"+c.auth.scope+"\n"+c.prev+"\n"+s3EmptySHA256+"\n"+sha256Hex(data)))
+		got := strings.TrimPrefix(fields[1], "chunk-signature=")
+		if !hmac.Equal([]byte(got), []byte(want)) {
+			return errS3SignatureMismatch
+		}
+		c.prev = got
+	}
+
+	if size == 0 {
+		// the last chunk is followed by optional trailers and an empty line
+		c.done = true
+		for {
+			line, err := c.r.ReadString('\n')
+			if err != nil || strings.TrimRight(line, "\r\n") == "" {
+				return nil
+			}
+		}
+	}
+	if crlf, err := c.r.ReadString('\n'); err != nil || strings.TrimSuffix(crlf, "\r\n") != "" {
+		return errS3IncompleteBody
+	}
+	c.chunk = data
+	return nil
+}
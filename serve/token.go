@@ -0,0 +1,214 @@
+// Package serve deliver stored files over HTTP with short HMAC tokens issued by the application,
+// independent of storage provider signatures, so asset urls survive a provider migration
+// and carry user level permissions.
+//
+//	Example:
+//	tokens, err := serve.NewTokenService(secret)
+//	url := tokens.URL("https://assets.example.com/files", "users/42/avatar.jpg", serve.Claims{User: "42", Perm: serve.PermRead}, time.Hour)
+//	http.Handle("/files/", serve.NewHandler(store, tokens, "/files/"))
+package serve
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// tokenVersion is the first byte of tokens, other versions are rejected
+	tokenVersion = 2
+	// macSize is the truncated HMAC-SHA256 length, 128 bits
+	macSize = 16
+	// MinSecretSize is the shortest accepted signing secret
+	MinSecretSize = 32
+	// TokenParam is the query parameter holding the token
+	TokenParam = "t"
+)
+
+var (
+	// ErrInvalidToken returned when a token is malformed or its signature does not match
+	ErrInvalidToken = errors.New("serve: invalid token")
+	// ErrExpiredToken returned when a token is past its expiry
+	ErrExpiredToken = errors.New("serve: token expired")
+	// ErrForbidden returned when a token does not grant the requested permission
+	ErrForbidden = errors.New("serve: forbidden")
+	// ErrHotlink returned when a request comes from a page of a site that is not allowed
+	ErrHotlink = errors.New("serve: origin not allowed")
+	// ErrRevokedToken returned when a token was revoked before its expiry
+	ErrRevokedToken = errors.New("serve: token revoked")
+)
+
+// Perm is a set of permissions granted by a token
+type Perm uint8
+
+const (
+	PermRead Perm = 1 << iota
+	PermWrite
+	PermDelete
+)
+
+// Has report whether p grant every permission of want
+func (p Perm) Has(want Perm) bool {
+	return p&want == want
+}
+
+// Claims are what a token grant on one file
+type Claims struct {
+	// User is the user the token was issued to, empty for anonymous access
+	User    string
+	Perm    Perm
+	Expires time.Time
+	// IssuedAt is set by Issue, to the second
+	IssuedAt time.Time
+	// Origin bind the token to pages of one site, e.g. "https://shop.example.com".
+	// Handler reject requests whose Origin or Referer is another site, empty accept any.
+	Origin string
+}
+
+// TokenService issue and verify tokens bound to a file key.
+// Keys[0] sign new tokens, every key verify, so secrets can be rotated by prepending a new one.
+type TokenService struct {
+	Keys [][]byte
+}
+
+// NewTokenService create service signing with secret, secrets must be at least MinSecretSize bytes
+func NewTokenService(secret []byte, previous ...[]byte) (*TokenService, error) {
+	keys := append([][]byte{secret}, previous...)
+	for _, k := range keys {
+		if len(k) < MinSecretSize {
+			return nil, errors.New("serve: secret must be at least 32 bytes")
+		}
+	}
+	return &TokenService{Keys: keys}, nil
+}
+
+// Issue return token granting claims on key for ttl
+func (s *TokenService) Issue(key string, claims Claims, ttl time.Duration) string {
+	now := time.Now()
+	claims.Expires = now.Add(ttl)
+	claims.IssuedAt = now
+	payload := encodeClaims(claims)
+	mac := s.mac(s.Keys[0], key, payload)
+	return base64.RawURLEncoding.EncodeToString(append(payload, mac...))
+}
+
+// URL return baseURL/key with a token granting claims for ttl
+func (s *TokenService) URL(baseURL, key string, claims Claims, ttl time.Duration) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + escapeKey(key) + "?" + TokenParam + "=" + s.Issue(key, claims, ttl)
+}
+
+// Verify check token was issued for key and is not expired
+func (s *TokenService) Verify(key, token string) (Claims, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= macSize {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, mac := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+
+	valid := false
+	for _, k := range s.Keys {
+		if hmac.Equal(mac, s.mac(k, key, payload)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return Claims{}, err
+	}
+	if time.Now().After(claims.Expires) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (s *TokenService) mac(secret []byte, key string, payload []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(payload)
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return h.Sum(nil)[:macSize]
+}
+
+// encodeClaims write version | expiry unix varint | perm | user length varint | user | issued unix varint [| origin length varint | origin]
+func encodeClaims(c Claims) []byte {
+	var buf bytes.Buffer
+	tmp := make([]byte, binary.MaxVarintLen64)
+
+	buf.WriteByte(tokenVersion)
+	buf.Write(tmp[:binary.PutVarint(tmp, c.Expires.Unix())])
+	buf.WriteByte(byte(c.Perm))
+	buf.Write(tmp[:binary.PutUvarint(tmp, uint64(len(c.User)))])
+	buf.WriteString(c.User)
+	buf.Write(tmp[:binary.PutVarint(tmp, c.IssuedAt.Unix())])
+	if c.Origin != "" {
+		buf.Write(tmp[:binary.PutUvarint(tmp, uint64(len(c.Origin)))])
+		buf.WriteString(c.Origin)
+	}
+	return buf.Bytes()
+}
+
+func decodeClaims(payload []byte) (Claims, error) {
+	r := bytes.NewReader(payload)
+	version, err := r.ReadByte()
+	if err != nil || version != tokenVersion {
+		return Claims{}, ErrInvalidToken
+	}
+	expires, err := binary.ReadVarint(r)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	perm, err := r.ReadByte()
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	user, err := readString(r)
+	if err != nil {
+		return Claims{}, err
+	}
+	issued, err := binary.ReadVarint(r)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var origin string
+	if r.Len() > 0 {
+		if origin, err = readString(r); err != nil {
+			return Claims{}, err
+		}
+	}
+	if r.Len() > 0 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return Claims{User: user, Perm: Perm(perm), Expires: time.Unix(expires, 0), IssuedAt: time.Unix(issued, 0), Origin: origin}, nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", ErrInvalidToken
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b), nil
+}
+
+// escapeKey escape every path segment of key keeping the delimiters
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
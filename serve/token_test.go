@@ -0,0 +1,48 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+	tokens, err := NewTokenService([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := tokens.Issue("users/42/avatar.jpg", Claims{User: "42", Perm: PermRead, Origin: "https://shop.example.com"}, time.Hour)
+	claims, err := tokens.Verify("users/42/avatar.jpg", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.User != "42" || claims.Perm != PermRead || claims.Origin != "https://shop.example.com" || claims.IssuedAt.IsZero() {
+		t.Errorf("Verify = %+v", claims)
+	}
+	if _, err := tokens.Verify("users/43/avatar.jpg", token); err != ErrInvalidToken {
+		t.Errorf("Verify of another key = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestTokenOtherVersionRejected(t *testing.T) {
+	tokens, err := NewTokenService([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a correctly signed token without issue time, the former version 1
+	var buf bytes.Buffer
+	tmp := make([]byte, binary.MaxVarintLen64)
+	buf.WriteByte(1)
+	buf.Write(tmp[:binary.PutVarint(tmp, time.Now().Add(time.Hour).Unix())])
+	buf.WriteByte(byte(PermRead))
+	buf.Write(tmp[:binary.PutUvarint(tmp, 2)])
+	buf.WriteString("42")
+	payload := buf.Bytes()
+	mac := tokens.mac(tokens.Keys[0], "a.jpg", payload)
+	token := base64.RawURLEncoding.EncodeToString(append(payload, mac...))
+	if _, err := tokens.Verify("a.jpg", token); err != ErrInvalidToken {
+		t.Errorf("Verify of a version 1 token = %v, want ErrInvalidToken", err)
+	}
+}
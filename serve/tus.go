@@ -0,0 +1,484 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+)
+
+const (
+	// TusVersion is the tus resumable upload protocol version served by TusHandler
+	TusVersion = "1.0.0"
+	// TusExtensions are the tus extensions served by TusHandler
+	TusExtensions = "creation,creation-with-upload,expiration,termination"
+	// DefaultTusPartSize is the largest part TusHandler send to the store at once by default
+	DefaultTusPartSize = 8 << 20
+	// DefaultTusExpiry is how long an unfinished upload can be resumed by default
+	DefaultTusExpiry = 24 * time.Hour
+	// DefaultTusPrefix is where StoreTusUploads keep upload states
+	DefaultTusPrefix = "system/tus/"
+
+	tusContentType = "application/offset+octet-stream"
+)
+
+// ErrUploadNotFound returned by TusUploads for unknown upload ids
+var ErrUploadNotFound = errors.New("serve: upload not found")
+
+// TusUpload is the state of a resumable upload
+type TusUpload struct {
+	ID          string            `json:"id"`
+	Key         string            `json:"key"`
+	ContentType string            `json:"contentType"`
+	Length      int64             `json:"length"`
+	Offset      int64             `json:"offset"`
+	Parts       int               `json:"parts"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Expires     time.Time         `json:"expires"`
+	Completed   bool              `json:"completed"`
+}
+
+// TusUploads keep the state of resumable uploads, shared by every handler instance
+type TusUploads interface {
+	Get(ctx context.Context, id string) (TusUpload, error)
+	Put(ctx context.Context, upload TusUpload) error
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryTusUploads is an in memory TusUploads, for a single handler instance
+type MemoryTusUploads struct {
+	mu      sync.Mutex
+	uploads map[string]TusUpload
+}
+
+// NewMemoryTusUploads create an empty in memory TusUploads
+func NewMemoryTusUploads() *MemoryTusUploads {
+	return &MemoryTusUploads{uploads: map[string]TusUpload{}}
+}
+
+// Get return upload id, ErrUploadNotFound when unknown
+func (m *MemoryTusUploads) Get(ctx context.Context, id string) (TusUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return TusUpload{}, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// Put save upload, forgetting expired uploads
+func (m *MemoryTusUploads) Put(ctx context.Context, upload TusUpload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, u := range m.uploads {
+		if now.After(u.Expires) {
+			delete(m.uploads, id)
+		}
+	}
+	m.uploads[upload.ID] = upload
+	return nil
+}
+
+// Delete forget upload id
+func (m *MemoryTusUploads) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.uploads, id)
+	return nil
+}
+
+// StoreTusUploads keep upload states as JSON files under Prefix of Store
+type StoreTusUploads struct {
+	Store  file.IFile
+	Prefix string
+}
+
+// NewStoreTusUploads keep upload states in store under DefaultTusPrefix
+func NewStoreTusUploads(store file.IFile) *StoreTusUploads {
+	return &StoreTusUploads{Store: store, Prefix: DefaultTusPrefix}
+}
+
+// Get return upload id, ErrUploadNotFound when unknown
+func (s *StoreTusUploads) Get(ctx context.Context, id string) (TusUpload, error) {
+	data, err := s.Store.Download(ctx, s.Prefix+id+".json")
+	if err == file.ErrNotFound {
+		return TusUpload{}, ErrUploadNotFound
+	}
+	if err != nil {
+		return TusUpload{}, err
+	}
+	var upload TusUpload
+	return upload, json.Unmarshal(data, &upload)
+}
+
+// Put save upload
+func (s *StoreTusUploads) Put(ctx context.Context, upload TusUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	_, err = s.Store.Upload(ctx, s.Prefix+upload.ID+".json", "application/json", data)
+	return err
+}
+
+// Delete forget upload id
+func (s *StoreTusUploads) Delete(ctx context.Context, id string) error {
+	_, err := s.Store.Delete(ctx, s.Prefix+id+".json")
+	if err == file.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// TusHandler serve tus.io resumable uploads, so web and mobile clients resume interrupted uploads
+// with existing tus client libraries. An upload is created by a POST to Prefix with an upload token
+// issued by IssueUpload in the TokenParam query parameter and the file name in the "filename" metadata,
+// the returned upload url is then the capability for HEAD, PATCH and DELETE.
+// Parts are staged with file.MultipartUploader when Store implement it, as temporary files otherwise,
+// and the file is committed at its key once complete.
+type TusHandler struct {
+	Store   file.IFile
+	Tokens  *TokenService
+	Used    UsedTokens
+	Uploads TusUploads
+	// Prefix is the creation url path, upload urls are Prefix followed by the upload id, e.g. "/tus/"
+	Prefix string
+	// PartSize is the largest part sent to the store at once, DefaultTusPartSize when 0
+	PartSize int64
+	// Expiry is how long an unfinished upload can be resumed, DefaultTusExpiry when 0
+	Expiry time.Duration
+}
+
+// NewTusHandler create handler storing uploads into store, served under prefix
+//
+//	Example:
+//	tus := serve.NewTusHandler(store, tokens, serve.NewMemoryUsedTokens(), serve.NewStoreTusUploads(store), "/tus/")
+//	http.Handle("/tus/", tus)
+func NewTusHandler(store file.IFile, tokens *TokenService, used UsedTokens, uploads TusUploads, prefix string) *TusHandler {
+	return &TusHandler{Store: store, Tokens: tokens, Used: used, Uploads: uploads, Prefix: prefix}
+}
+
+// ServeHTTP serve the tus core protocol and its creation, expiration and termination extensions
+func (h *TusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && r.Method == http.MethodPost {
+		r.Method = override
+	}
+	w.Header().Set("Tus-Resumable", TusVersion)
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", TusVersion)
+		w.Header().Set("Tus-Extension", TusExtensions)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, h.Prefix) && r.URL.Path != strings.TrimSuffix(h.Prefix, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.Prefix), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		h.create(w, r)
+	case id != "" && r.Method == http.MethodHead:
+		h.head(w, r, id)
+	case id != "" && r.Method == http.MethodPatch:
+		h.patch(w, r, id)
+	case id != "" && r.Method == http.MethodDelete:
+		h.terminate(w, r, id)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *TusHandler) create(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.Tokens.VerifyUpload(r.URL.Query().Get(TokenParam))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "deferred upload length not supported", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > claims.MaxSize {
+		http.Error(w, file.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key, ok := uploadKey(claims.Prefix, metadata["filename"])
+	if !ok {
+		http.Error(w, "invalid file name", http.StatusBadRequest)
+		return
+	}
+	contentType := metadata["filetype"]
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !typeAllowed(mediaType, claims.ContentTypes) {
+		http.Error(w, file.ErrContentTypeNotAllowed.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err := h.Used.Use(r.Context(), claims.ID, claims.Expires); err != nil {
+		if err == ErrTokenUsed {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	id := make([]byte, uploadIDSize)
+	if _, err := rand.Read(id); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	expiry := h.Expiry
+	if expiry <= 0 {
+		expiry = DefaultTusExpiry
+	}
+	upload := TusUpload{
+		ID:          hex.EncodeToString(id),
+		Key:         key,
+		ContentType: contentType,
+		Length:      length,
+		Metadata:    metadata,
+		Expires:     time.Now().Add(expiry).UTC(),
+	}
+	if err := h.Uploads.Put(r.Context(), upload); err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", h.Prefix+upload.ID)
+	// creation-with-upload carry the first bytes, an empty file is complete at once
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == tusContentType || length == 0 {
+		if !h.write(w, r, &upload) {
+			return
+		}
+	}
+	h.progressHeaders(w, upload)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *TusHandler) head(w http.ResponseWriter, r *http.Request, id string) {
+	upload, ok := h.get(w, r, id)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	h.progressHeaders(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TusHandler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != tusContentType {
+		http.Error(w, "Content-Type must be "+tusContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	upload, ok := h.get(w, r, id)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		h.progressHeaders(w, upload)
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+	if upload.Completed {
+		h.progressHeaders(w, upload)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !h.write(w, r, &upload) {
+		return
+	}
+	h.progressHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TusHandler) terminate(w http.ResponseWriter, r *http.Request, id string) {
+	upload, ok := h.get(w, r, id)
+	if !ok {
+		return
+	}
+	if _, multipart := h.Store.(file.MultipartUploader); !multipart && !upload.Completed {
+		h.deleteParts(r.Context(), upload)
+	}
+	if err := h.Uploads.Delete(r.Context(), id); err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// get return upload id, answering 404 for unknown uploads and 410 for expired ones
+func (h *TusHandler) get(w http.ResponseWriter, r *http.Request, id string) (TusUpload, bool) {
+	upload, err := h.Uploads.Get(r.Context(), id)
+	if err == ErrUploadNotFound {
+		http.NotFound(w, r)
+		return upload, false
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return upload, false
+	}
+	if !upload.Completed && time.Now().After(upload.Expires) {
+		http.Error(w, "upload expired", http.StatusGone)
+		return upload, false
+	}
+	return upload, true
+}
+
+func (h *TusHandler) progressHeaders(w http.ResponseWriter, upload TusUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if !upload.Completed {
+		w.Header().Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+	}
+}
+
+// write store the request body as parts from upload offset, saving the progress after every part
+// so an interrupted request is resumed from the last stored part. The upload is committed once complete.
+// Return false when an error response was written.
+func (h *TusHandler) write(w http.ResponseWriter, r *http.Request, upload *TusUpload) bool {
+	ctx := r.Context()
+	partSize := h.PartSize
+	if partSize <= 0 {
+		partSize = DefaultTusPartSize
+	}
+	if remaining := upload.Length - upload.Offset; partSize > remaining && remaining > 0 {
+		partSize = remaining
+	}
+	body := file.SizeLimitReader(r.Body, upload.Length-upload.Offset)
+
+	buf := make([]byte, partSize)
+	for upload.Offset < upload.Length {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			if perr := h.uploadPart(ctx, *upload, buf[:n]); perr != nil {
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return false
+			}
+			upload.Parts++
+			upload.Offset += int64(n)
+			if perr := h.Uploads.Put(ctx, *upload); perr != nil {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return false
+			}
+		}
+		if err == file.ErrTooLarge {
+			http.Error(w, "body exceed Upload-Length", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		if err != nil {
+			// end of body or interrupted request, the client resume from the saved offset
+			break
+		}
+	}
+	if upload.Offset < upload.Length {
+		return true
+	}
+
+	if err := h.complete(ctx, upload); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return false
+	}
+	return true
+}
+
+func (h *TusHandler) uploadPart(ctx context.Context, upload TusUpload, data []byte) error {
+	if mp, ok := h.Store.(file.MultipartUploader); ok {
+		return mp.UploadPart(ctx, upload.Key, upload.ID, upload.Parts, data)
+	}
+	_, err := h.Store.Upload(ctx, partKey(upload.ID, upload.Parts), "application/octet-stream", data)
+	return err
+}
+
+// complete commit the parts of upload at its key
+func (h *TusHandler) complete(ctx context.Context, upload *TusUpload) error {
+	if mp, ok := h.Store.(file.MultipartUploader); ok {
+		if _, err := mp.CompleteUpload(ctx, upload.Key, upload.ID, upload.ContentType, upload.Parts); err != nil {
+			return err
+		}
+	} else {
+		data := make([]byte, 0, upload.Length)
+		for i := 0; i < upload.Parts; i++ {
+			part, err := h.Store.Download(ctx, partKey(upload.ID, i))
+			if err != nil {
+				return err
+			}
+			data = append(data, part...)
+		}
+		if _, err := h.Store.Upload(ctx, upload.Key, upload.ContentType, data); err != nil {
+			return err
+		}
+		h.deleteParts(ctx, *upload)
+	}
+	upload.Completed = true
+	return h.Uploads.Put(ctx, *upload)
+}
+
+func (h *TusHandler) deleteParts(ctx context.Context, upload TusUpload) {
+	for i := 0; i < upload.Parts; i++ {
+		h.Store.Delete(ctx, partKey(upload.ID, i))
+	}
+}
+
+// partKey is the temporary file of part n for stores without multipart uploads
+func partKey(id string, n int) string {
+	return fmt.Sprintf("%stus/%s/%06d", file.TempPrefix, id, n)
+}
+
+// parseTusMetadata parse Upload-Metadata, comma separated keys followed by a base64 value
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		fields := strings.Fields(pair)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid Upload-Metadata %q", pair)
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid Upload-Metadata value of %q", fields[0])
+			}
+			value = string(decoded)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata, nil
+}
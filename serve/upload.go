@@ -0,0 +1,443 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/pipeline"
+)
+
+const (
+	uploadTokenVersion = 2
+	uploadIDSize       = 16
+	// DefaultMemoryThreshold is the largest upload UploadHandler hold in memory by default
+	DefaultMemoryThreshold = 32 << 20
+)
+
+// ErrTokenUsed returned when a single use upload token was already redeemed
+var ErrTokenUsed = errors.New("serve: token already used")
+
+// UploadClaims are what an upload token grant: one upload under Prefix
+type UploadClaims struct {
+	// ID identify the token, filled by IssueUpload
+	ID string
+	// Prefix bound the uploaded key, e.g. "claims/1234/", a key without trailing slash allow exactly that key
+	Prefix string
+	// MaxSize is the largest accepted upload in bytes
+	MaxSize int64
+	// ContentTypes are accepted content type prefixes, e.g. "application/pdf", any type when empty
+	ContentTypes []string
+	// Redirect is where browsers posting an UploadForm are sent after the upload, with the key as query parameter
+	Redirect string
+	Expires  time.Time
+}
+
+// IssueUpload return a single use token granting one upload matching claims for ttl.
+// It panic when the system random generator fail, the token id would not be unique.
+//
+//	Example:
+//	token := tokens.IssueUpload(serve.UploadClaims{
+//		Prefix:       "claims/1234/",
+//		MaxSize:      20 << 20,
+//		ContentTypes: []string{"application/pdf", "image/"},
+//	}, 72*time.Hour)
+func (s *TokenService) IssueUpload(claims UploadClaims, ttl time.Duration) string {
+	id := make([]byte, uploadIDSize)
+	if _, err := rand.Read(id); err != nil {
+		panic("serve: read random upload token id: " + err.Error())
+	}
+	claims.ID = hex.EncodeToString(id)
+	claims.Expires = time.Now().Add(ttl)
+
+	payload := encodeUploadClaims(claims, id)
+	mac := s.mac(s.Keys[0], "", payload)
+	return base64.RawURLEncoding.EncodeToString(append(payload, mac...))
+}
+
+// UploadURL return the url of an UploadHandler mounted at baseURL with a single use upload token
+func (s *TokenService) UploadURL(baseURL string, claims UploadClaims, ttl time.Duration) string {
+	return strings.TrimSuffix(baseURL, "/") + "/?" + TokenParam + "=" + s.IssueUpload(claims, ttl)
+}
+
+// VerifyUpload check token is a valid upload token, it does not check whether it was used
+func (s *TokenService) VerifyUpload(token string) (UploadClaims, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= macSize {
+		return UploadClaims{}, ErrInvalidToken
+	}
+	payload, mac := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+
+	valid := false
+	for _, k := range s.Keys {
+		if hmac.Equal(mac, s.mac(k, "", payload)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return UploadClaims{}, ErrInvalidToken
+	}
+
+	claims, err := decodeUploadClaims(payload)
+	if err != nil {
+		return UploadClaims{}, err
+	}
+	if time.Now().After(claims.Expires) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// encodeUploadClaims write version | expiry unix varint | id | max size uvarint | prefix | types count uvarint | types [| redirect]
+func encodeUploadClaims(c UploadClaims, id []byte) []byte {
+	var buf bytes.Buffer
+	tmp := make([]byte, binary.MaxVarintLen64)
+	writeString := func(s string) {
+		buf.Write(tmp[:binary.PutUvarint(tmp, uint64(len(s)))])
+		buf.WriteString(s)
+	}
+
+	buf.WriteByte(uploadTokenVersion)
+	buf.Write(tmp[:binary.PutVarint(tmp, c.Expires.Unix())])
+	buf.Write(id)
+	buf.Write(tmp[:binary.PutUvarint(tmp, uint64(c.MaxSize))])
+	writeString(c.Prefix)
+	buf.Write(tmp[:binary.PutUvarint(tmp, uint64(len(c.ContentTypes)))])
+	for _, t := range c.ContentTypes {
+		writeString(t)
+	}
+	if c.Redirect != "" {
+		writeString(c.Redirect)
+	}
+	return buf.Bytes()
+}
+
+func decodeUploadClaims(payload []byte) (UploadClaims, error) {
+	r := bytes.NewReader(payload)
+	if v, err := r.ReadByte(); err != nil || v != uploadTokenVersion {
+		return UploadClaims{}, ErrInvalidToken
+	}
+	expires, err := binary.ReadVarint(r)
+	if err != nil || r.Len() < uploadIDSize {
+		return UploadClaims{}, ErrInvalidToken
+	}
+	id := make([]byte, uploadIDSize)
+	r.Read(id)
+	maxSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return UploadClaims{}, ErrInvalidToken
+	}
+	prefix, err := readString(r)
+	if err != nil {
+		return UploadClaims{}, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return UploadClaims{}, ErrInvalidToken
+	}
+	var types []string
+	for i := uint64(0); i < n; i++ {
+		t, err := readString(r)
+		if err != nil {
+			return UploadClaims{}, err
+		}
+		types = append(types, t)
+	}
+	var redirect string
+	if r.Len() > 0 {
+		if redirect, err = readString(r); err != nil {
+			return UploadClaims{}, err
+		}
+	}
+	if r.Len() > 0 {
+		return UploadClaims{}, ErrInvalidToken
+	}
+
+	return UploadClaims{
+		ID:           hex.EncodeToString(id),
+		Prefix:       prefix,
+		MaxSize:      int64(maxSize),
+		ContentTypes: types,
+		Redirect:     redirect,
+		Expires:      time.Unix(expires, 0),
+	}, nil
+}
+
+// UsedTokens remember redeemed upload tokens until they expire.
+// Use must return ErrTokenUsed when id was already used, atomically across every handler instance.
+type UsedTokens interface {
+	Use(ctx context.Context, id string, expires time.Time) error
+}
+
+// MemoryUsedTokens is an in memory UsedTokens, for a single handler instance
+type MemoryUsedTokens struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewMemoryUsedTokens create an empty in memory UsedTokens
+func NewMemoryUsedTokens() *MemoryUsedTokens {
+	return &MemoryUsedTokens{used: map[string]time.Time{}}
+}
+
+// Use mark id used until expires
+func (m *MemoryUsedTokens) Use(ctx context.Context, id string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range m.used {
+		if now.After(exp) {
+			delete(m.used, k)
+		}
+	}
+	if _, ok := m.used[id]; ok {
+		return ErrTokenUsed
+	}
+	m.used[id] = expires
+	return nil
+}
+
+// UploadHandler accept one upload per token issued by IssueUpload, for external users without an account.
+// The request body is the file, sent with PUT or POST to the handler path followed by the file name,
+// e.g. PUT /uploads/invoice.pdf?t=token stores "claims/1234/invoice.pdf".
+// Browsers may also POST a multipart form built by UploadForm.
+// The token is redeemed once the request is validated, a failed store upload does not give it back.
+type UploadHandler struct {
+	Store  file.IFile
+	Tokens *TokenService
+	// Used remember redeemed tokens, required
+	Used UsedTokens
+	// Prefix is removed from the request path to get the file name, e.g. "/uploads/"
+	Prefix string
+	// MemoryThreshold is the largest upload held in memory, DefaultMemoryThreshold when 0.
+	// Larger ones are streamed to stores implementing file.StreamUploader.
+	// Other stores, e.g. most decorators, can't stream: whole uploads up to the claims MaxSize are buffered,
+	// bound them with MaxBuffered.
+	MemoryThreshold int64
+	// MaxBuffered is the largest upload buffered for stores not implementing file.StreamUploader,
+	// larger ones are rejected with 413. The claims MaxSize when 0.
+	MaxBuffered int64
+	// Progress follow uploads for ProgressHandler, optional
+	Progress *UploadProgress
+	// Runner and Pipeline submit every stored upload, the job id is returned and followed by Progress.
+	// Not processed when Runner is nil
+	Runner   *pipeline.Runner
+	Pipeline string
+}
+
+// NewUploadHandler create handler storing uploads into store under prefix
+func NewUploadHandler(store file.IFile, tokens *TokenService, used UsedTokens, prefix string) *UploadHandler {
+	return &UploadHandler{Store: store, Tokens: tokens, Used: used, Prefix: prefix}
+}
+
+// ServeHTTP verify the upload token and claims then store the request body
+func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Used == nil {
+		// tokens could be redeemed again
+		http.Error(w, "serve: UploadHandler.Used not set", http.StatusInternalServerError)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Prefix != "" && !strings.HasPrefix(r.URL.Path, h.Prefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method == http.MethodPost && mediaType == "multipart/form-data" {
+		h.serveForm(w, r)
+		return
+	}
+
+	claims, err := h.Tokens.VerifyUpload(r.URL.Query().Get(TokenParam))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	key, ok := uploadKey(claims.Prefix, strings.TrimPrefix(r.URL.Path, h.Prefix))
+	if !ok {
+		h.Progress.reject(claims.ID, "invalid file name", claims.Expires)
+		http.Error(w, "invalid file name", http.StatusBadRequest)
+		return
+	}
+	if r.ContentLength > claims.MaxSize {
+		h.Progress.reject(claims.ID, file.ErrTooLarge.Error(), claims.Expires)
+		http.Error(w, file.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	h.store(w, r, claims, key, r.Header.Get("Content-Type"), r.Body, r.ContentLength, false)
+}
+
+// store check content type and size of body then redeem the token and upload body to key.
+// size is the declared body length, -1 when unknown. The upload is followed by Progress and submitted to Runner.
+func (h *UploadHandler) store(w http.ResponseWriter, r *http.Request, claims UploadClaims, key, contentType string, body io.Reader, size int64, redirect bool) {
+	total := size
+	if total < 0 && r.ContentLength > 0 {
+		total = r.ContentLength
+	}
+	tracked := h.Progress.begin(claims.ID, key, total, claims.Expires)
+	if tracked {
+		body = h.Progress.reader(claims.ID, body)
+	}
+	fail := func(msg string, code int) {
+		if tracked {
+			h.Progress.fail(claims.ID, msg)
+		}
+		http.Error(w, msg, code)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !typeAllowed(mediaType, claims.ContentTypes) {
+		fail(file.ErrContentTypeNotAllowed.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	threshold := h.MemoryThreshold
+	if threshold <= 0 {
+		threshold = DefaultMemoryThreshold
+	}
+	streamer, stream := h.Store.(file.StreamUploader)
+	if !stream {
+		// the whole upload is buffered
+		threshold = claims.MaxSize
+		if h.MaxBuffered > 0 && h.MaxBuffered < threshold {
+			threshold = h.MaxBuffered
+		}
+	}
+	if threshold > claims.MaxSize {
+		threshold = claims.MaxSize
+	}
+
+	// read one more byte than held in memory to detect larger content
+	head, err := ioutil.ReadAll(io.LimitReader(body, threshold+1))
+	if err != nil {
+		fail(http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if int64(len(head)) > claims.MaxSize || (!stream && int64(len(head)) > threshold) {
+		fail(file.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := h.Used.Use(r.Context(), claims.ID, claims.Expires); err != nil {
+		if err == ErrTokenUsed {
+			fail(err.Error(), http.StatusForbidden)
+			return
+		}
+		fail(http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	written := int64(len(head))
+	if written <= threshold {
+		_, err = h.Store.Upload(r.Context(), key, contentType, head)
+	} else {
+		// the rest of body is streamed, content over MaxSize fail the upload before it is committed
+		cr := &countingReader{r: file.SizeLimitReader(io.MultiReader(bytes.NewReader(head), body), claims.MaxSize)}
+		_, err = streamer.UploadStream(r.Context(), key, contentType, cr, size)
+		written = cr.n
+		if cr.tooLarge {
+			err = file.ErrTooLarge
+		}
+	}
+	if err == file.ErrTooLarge {
+		fail(err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		fail(http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	var jobID, jobErr string
+	if h.Runner != nil {
+		// the file is stored and the token redeemed, a failed submission does not fail the upload
+		if jobID, err = h.Runner.Submit(r.Context(), h.Pipeline, key, contentType); err != nil {
+			jobErr = "processing not started"
+		}
+	}
+	if tracked {
+		h.Progress.finish(claims.ID, jobID, jobErr)
+	}
+
+	if redirect && claims.Redirect != "" {
+		target, err := url.Parse(claims.Redirect)
+		if err == nil {
+			q := target.Query()
+			q.Set("key", key)
+			target.RawQuery = q.Encode()
+			http.Redirect(w, r, target.String(), http.StatusSeeOther)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]interface{}{"key": key, "size": written}
+	if jobID != "" {
+		response["job"] = jobID
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// countingReader count the bytes read from r and whether it failed with file.ErrTooLarge
+type countingReader struct {
+	r        io.Reader
+	n        int64
+	tooLarge bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err == file.ErrTooLarge {
+		c.tooLarge = true
+	}
+	return n, err
+}
+
+// uploadKey join prefix and name, rejecting names escaping prefix
+func uploadKey(prefix, name string) (string, bool) {
+	if !strings.HasSuffix(prefix, "/") {
+		// the token grant exactly one key
+		return prefix, name == "" || name == path.Base(prefix)
+	}
+	if name == "" || strings.HasPrefix(name, "/") || path.Clean(name) != name || name == "." || strings.HasPrefix(name, "../") || name == ".." {
+		return "", false
+	}
+	return prefix + name, true
+}
+
+func typeAllowed(mediaType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, prefix := range allowed {
+		if mediaType != "" && strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package serve
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ndv6/assets-sdk/file"
+	"github.com/ndv6/assets-sdk/file/filetest"
+)
+
+// countingBody is a synthetic request body of n bytes counting what was read
+type countingBody struct {
+	n, read int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	if b.read >= b.n {
+		return 0, io.EOF
+	}
+	if rest := b.n - b.read; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+// streamingStore is a file.StreamUploader recording how much of the request body was read when streaming started
+type streamingStore struct {
+	*filetest.Memory
+	body     *countingBody
+	streamed bool
+	readFrom int64
+}
+
+func (s *streamingStore) UploadStream(ctx context.Context, filePath, contentType string, r io.Reader, size int64) (string, error) {
+	s.streamed = true
+	s.readFrom = s.body.read
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return s.Memory.Upload(ctx, filePath, contentType, data)
+}
+
+func newUploadTest(t *testing.T, store file.IFile, maxSize int64) (*UploadHandler, string) {
+	tokens, err := NewTokenService([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := tokens.IssueUpload(UploadClaims{Prefix: "claims/1234/", MaxSize: maxSize}, time.Hour)
+	return NewUploadHandler(store, tokens, NewMemoryUsedTokens(), "/uploads/"), token
+}
+
+func putUpload(h *UploadHandler, token string, body io.Reader) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, "/uploads/scan.bin?"+TokenParam+"="+token, body)
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestUploadStreamedOverMemoryThreshold(t *testing.T) {
+	const threshold = 64 << 10
+	body := &countingBody{n: 1 << 20}
+	store := &streamingStore{Memory: filetest.NewMemory(), body: body}
+	h, token := newUploadTest(t, store, 4<<20)
+	h.MemoryThreshold = threshold
+
+	w := putUpload(h, token, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload status %d: %s", w.Code, w.Body)
+	}
+	if !store.streamed {
+		t.Fatal("upload over MemoryThreshold was not streamed")
+	}
+	// only the head detecting larger content is read before streaming
+	if store.readFrom > threshold+1 {
+		t.Errorf("%d bytes buffered before streaming, want at most %d", store.readFrom, threshold+1)
+	}
+	data, err := store.Download(context.Background(), "claims/1234/scan.bin")
+	if err != nil || int64(len(data)) != body.n {
+		t.Errorf("stored %d bytes, %v, want %d", len(data), err, body.n)
+	}
+}
+
+func TestUploadBufferedUnderMemoryThreshold(t *testing.T) {
+	body := &countingBody{n: 1 << 10}
+	store := &streamingStore{Memory: filetest.NewMemory(), body: body}
+	h, token := newUploadTest(t, store, 4<<20)
+	h.MemoryThreshold = 64 << 10
+
+	if w := putUpload(h, token, body); w.Code != http.StatusCreated {
+		t.Fatalf("upload status %d: %s", w.Code, w.Body)
+	}
+	if store.streamed {
+		t.Error("upload under MemoryThreshold was streamed")
+	}
+}
+
+func TestUploadMaxBuffered(t *testing.T) {
+	// filetest.Memory is not a StreamUploader, uploads are buffered
+	store := filetest.NewMemory()
+	h, token := newUploadTest(t, store, 4<<20)
+	h.MaxBuffered = 64 << 10
+
+	if w := putUpload(h, token, &countingBody{n: 1 << 20}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("upload over MaxBuffered status %d, want 413", w.Code)
+	}
+	// the token was not redeemed
+	if w := putUpload(h, token, &countingBody{n: 1 << 10}); w.Code != http.StatusCreated {
+		t.Fatalf("upload under MaxBuffered status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestUploadWithoutUsedTokens(t *testing.T) {
+	h, token := newUploadTest(t, filetest.NewMemory(), 1<<20)
+	h.Used = nil
+	if w := putUpload(h, token, &countingBody{n: 10}); w.Code != http.StatusInternalServerError {
+		t.Errorf("upload without Used status %d, want 500", w.Code)
+	}
+}